@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"govershop-api/internal/repository"
+	"govershop-api/internal/service/envelope"
+)
+
+// runCommand runs a maintenance command instead of the API server:
+//
+//	secrets encrypt  encrypts credentials still stored as plain text (once, after setting ENCRYPTION_KEYS)
+//	secrets rotate   re-wraps credentials under ENCRYPTION_KEY_ID after adding a new key
+func runCommand(ctx context.Context, db *pgxpool.Pool, keyring *envelope.Keyring, args []string) error {
+	if len(args) != 2 || args[0] != "secrets" {
+		return fmt.Errorf("unknown command %q, usage: secrets encrypt | secrets rotate", args)
+	}
+	if !keyring.Enabled() {
+		return fmt.Errorf("ENCRYPTION_KEYS is not set")
+	}
+
+	var transform func(value string) (string, bool, error)
+	switch args[1] {
+	case "encrypt":
+		transform = func(value string) (string, bool, error) {
+			if envelope.IsEncrypted(value) {
+				return value, false, nil
+			}
+			encrypted, err := keyring.Encrypt(value)
+			return encrypted, err == nil, err
+		}
+	case "rotate":
+		transform = keyring.Rewrap
+	default:
+		return fmt.Errorf("unknown secrets command %q, usage: secrets encrypt | secrets rotate", args[1])
+	}
+
+	secretRepo := repository.NewSecretRepository(db)
+	for _, col := range repository.EncryptedColumns {
+		updated, err := secretRepo.Rewrite(ctx, col, transform)
+		if err != nil {
+			return err
+		}
+		log.Printf("🔐 %s.%s: %d value(s) updated (key %s)", col.Table, col.Column, updated, keyring.PrimaryKeyID())
+	}
+	return nil
+}
@@ -36,17 +36,55 @@ type Config struct {
 	DefaultMarkupPercent       float64
 	DefaultMemberMarkupPercent float64
 
+	// Referral
+	ReferralCommissionType  string  // percent (of margin) or flat (per order)
+	ReferralCommissionValue float64 // 0 disables commission
+
+	// Loyalty Points
+	PointsRedeemRate float64 // Rupiah per point
+	PointsMinRedeem  int
+
+	// H2H API
+	TrustedProxyHops int // Reverse proxies in front of the API; the H2H allowlist, login history and sessions use the X-Forwarded-For entry they added (0 = connection address)
+
+	// Member Transfers
+	TransferMinAmount   float64
+	TransferDailyCap    float64 // Total a member can send per day, 0 = unlimited
+	TransferRequireAuth bool    // Require TOTP code (or password when TOTP is off)
+
+	// Member Bulk Orders
+	BatchMaxLines int
+
+	// Idempotency
+	IdempotencyTTLHours int // How long order responses are kept for replay
+
+	// Admin Balance Adjustments
+	AdjustmentTOTPThreshold float64 // Adjustments from this amount need TOTP, 0 = never
+
 	// Sync
 	ProductSyncInterval int // in minutes
 
 	// Admin Auth
-	AdminUsername string
-	AdminPassword string
-	JWTSecret     string
+	AdminUsername    string
+	JWTSecret        string
+	StepUpTTLMinutes int // Lifetime of the elevated token from POST /api/v1/admin/reauth
+
+	// Login Protection
+	LoginMaxAttempts       int // Failed logins before the account is locked
+	LoginLockoutMinutes    int // First lockout; doubles on each further lockout
+	LoginLockoutMaxMinutes int // Longest lockout
+
+	// Encryption at rest (TOTP, API and webhook secrets)
+	EncryptionKeys  string // "id:base64key,..." 32-byte AES keys; old keys stay listed until rotated out
+	EncryptionKeyID string // Key for new values, defaults to the first listed
 
 	// Member Auth
 	JWTSecretGovershop string
 
+	// Sessions
+	AccessTokenTTLMinutes int // Access JWT lifetime
+	RefreshTokenTTLDays   int // Refresh token lifetime (sliding, rotated on use)
+
 	// Email (SMTP)
 	SMTPHost string
 	SMTPPort int
@@ -99,17 +137,55 @@ func Load() *Config {
 		DefaultMarkupPercent:       getEnvFloat("DEFAULT_MARKUP_PERCENT", 3.0),
 		DefaultMemberMarkupPercent: getEnvFloat("DEFAULT_MEMBER_MARKUP_PERCENT", 0.7),
 
+		// Referral
+		ReferralCommissionType:  getEnv("REFERRAL_COMMISSION_TYPE", "percent"),
+		ReferralCommissionValue: getEnvFloat("REFERRAL_COMMISSION_VALUE", 10),
+
+		// Loyalty Points
+		PointsRedeemRate: getEnvFloat("POINTS_REDEEM_RATE", 1),
+		PointsMinRedeem:  getEnvInt("POINTS_MIN_REDEEM", 1000),
+
+		// H2H API
+		TrustedProxyHops: getEnvInt("TRUSTED_PROXY_HOPS", 0),
+
+		// Member Transfers
+		TransferMinAmount:   getEnvFloat("TRANSFER_MIN_AMOUNT", 10000),
+		TransferDailyCap:    getEnvFloat("TRANSFER_DAILY_CAP", 10000000),
+		TransferRequireAuth: getEnv("TRANSFER_REQUIRE_AUTH", "true") == "true",
+
+		// Member Bulk Orders
+		BatchMaxLines: getEnvInt("BATCH_MAX_LINES", 500),
+
+		// Idempotency
+		IdempotencyTTLHours: getEnvInt("IDEMPOTENCY_TTL_HOURS", 24),
+
+		// Admin Balance Adjustments
+		AdjustmentTOTPThreshold: getEnvFloat("ADJUSTMENT_TOTP_THRESHOLD", 1000000),
+
 		// Sync
 		ProductSyncInterval: getEnvInt("PRODUCT_SYNC_INTERVAL", 30),
 
 		// Admin Auth
-		AdminUsername: getEnv("ADMIN_USERNAME", "admin"),
-		AdminPassword: getEnv("ADMIN_PASSWORD", "admin123"),
-		JWTSecret:     getEnv("JWT_SECRET", "superdupersecretjwtkey"),
+		AdminUsername:    getEnv("ADMIN_USERNAME", "admin"),
+		JWTSecret:        getEnv("JWT_SECRET", "superdupersecretjwtkey"),
+		StepUpTTLMinutes: getEnvInt("STEP_UP_TTL_MINUTES", 5),
+
+		// Login Protection
+		LoginMaxAttempts:       getEnvInt("LOGIN_MAX_ATTEMPTS", 5),
+		LoginLockoutMinutes:    getEnvInt("LOGIN_LOCKOUT_MINUTES", 15),
+		LoginLockoutMaxMinutes: getEnvInt("LOGIN_LOCKOUT_MAX_MINUTES", 1440),
+
+		// Encryption at rest
+		EncryptionKeys:  getEnv("ENCRYPTION_KEYS", ""),
+		EncryptionKeyID: getEnv("ENCRYPTION_KEY_ID", ""),
 
 		// Member Auth
 		JWTSecretGovershop: getEnv("SECRET_JWT_GOVERSHOP", "membersecretkey"),
 
+		// Sessions
+		AccessTokenTTLMinutes: getEnvInt("ACCESS_TOKEN_TTL_MINUTES", 15),
+		RefreshTokenTTLDays:   getEnvInt("REFRESH_TOKEN_TTL_DAYS", 30),
+
 		// Email (SMTP)
 		SMTPHost: getEnv("SMTP_HOST", ""),
 		SMTPPort: getEnvInt("SMTP_PORT", 587),
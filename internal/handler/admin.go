@@ -15,7 +15,6 @@ import (
 
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -42,53 +41,66 @@ func (h *AdminHandler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if user == nil {
+		h.loginGuard.Fail(r, nil, req.Username, model.SessionChannelAdmin, model.LoginOutcomeUnknownUser)
 		http.Error(w, "Username atau password salah", http.StatusUnauthorized)
 		return
 	}
 
+	// Per-account lockout after too many failed attempts
+	lockFor, err := h.loginGuard.Check(r, user, model.SessionChannelAdmin)
+	if err != nil {
+		log.Printf("Error checking login lockout: %v", err)
+		InternalError(w, "Internal server error")
+		return
+	}
+	if lockFor > 0 {
+		http.Error(w, lockedMessage(w, lockFor), http.StatusTooManyRequests)
+		return
+	}
+
 	// Check if user is active
 	if user.Status != model.UserStatusActive {
+		h.loginGuard.Record(r, user, user.Username, model.SessionChannelAdmin, model.LoginOutcomeInactive)
 		http.Error(w, "Akun Anda telah dinonaktifkan. Hubungi admin.", http.StatusForbidden)
 		return
 	}
 
 	// Verify password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		if lockFor := h.loginGuard.Fail(r, user, user.Username, model.SessionChannelAdmin, model.LoginOutcomeInvalidPassword); lockFor > 0 {
+			http.Error(w, lockedMessage(w, lockFor), http.StatusTooManyRequests)
+			return
+		}
 		http.Error(w, "Username atau password salah", http.StatusUnauthorized)
 		return
 	}
 
-	// Generate JWT with role claims
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub":     user.Username,
-		"user_id": user.ID,
-		"role":    user.Role,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(), // 24 hours
-	})
-
-	tokenString, err := token.SignedString([]byte(h.config.JWTSecretGovershop))
+	// Start session: short-lived access JWT + rotating refresh token (HTTP-only cookies)
+	tokens, err := issueSession(r.Context(), w, r, h.config, h.sessionRepo, user, model.SessionChannelAdmin)
 	if err != nil {
-		log.Printf("Error signing token: %v", err)
+		log.Printf("Error creating session: %v", err)
 		InternalError(w, "Gagal generate token")
 		return
 	}
+	h.loginGuard.Succeed(r, user, model.SessionChannelAdmin)
 
-	// Set HTTP-only cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:     "auth_token",
-		Value:    tokenString,
-		Expires:  time.Now().Add(24 * time.Hour),
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   h.config.Env == "production",
-		SameSite: http.SameSiteLaxMode,
-	})
+	resp := map[string]interface{}{
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+		"user":          user.ToResponse(),
+		"role":          user.Role,
+	}
+	if user.Role == model.UserRoleAdmin {
+		adminRole, _, err := h.adminRepo.GetRole(r.Context(), user.ID)
+		if err != nil {
+			log.Printf("Error getting admin role: %v", err)
+		}
+		resp["admin_role"] = adminRole
+		resp["permissions"] = model.AdminPermissions[adminRole]
+	}
 
-	Success(w, "Login berhasil", map[string]interface{}{
-		"token": tokenString,
-		"user":  user.ToResponse(),
-		"role":  user.Role,
-	})
+	Success(w, "Login berhasil", resp)
 }
 
 // AdminHandler handles admin-related HTTP requests
@@ -102,6 +114,11 @@ type AdminHandler struct {
 	pakasirSvc     *pakasir.Service
 	webhookLogRepo *repository.WebhookLogRepository
 	userRepo       *repository.UserRepository
+	promoRepo      *repository.PromoRepository
+
+	sessionRepo *repository.SessionRepository
+	adminRepo   *repository.AdminRepository
+	loginGuard  *LoginGuard
 }
 
 // NewAdminHandler creates a new AdminHandler
@@ -115,6 +132,10 @@ func NewAdminHandler(
 	pakasirSvc *pakasir.Service,
 	webhookLogRepo *repository.WebhookLogRepository,
 	userRepo *repository.UserRepository,
+	promoRepo *repository.PromoRepository,
+	sessionRepo *repository.SessionRepository,
+	adminRepo *repository.AdminRepository,
+	loginGuard *LoginGuard,
 ) *AdminHandler {
 	return &AdminHandler{
 		config:         cfg,
@@ -126,6 +147,11 @@ func NewAdminHandler(
 		pakasirSvc:     pakasirSvc,
 		webhookLogRepo: webhookLogRepo,
 		userRepo:       userRepo,
+		promoRepo:      promoRepo,
+
+		sessionRepo: sessionRepo,
+		adminRepo:   adminRepo,
+		loginGuard:  loginGuard,
 	}
 }
 
@@ -265,6 +291,8 @@ func (h *AdminHandler) GetOrders(w http.ResponseWriter, r *http.Request) {
 		AdminNotes      string   `json:"admin_notes,omitempty"`
 		MemberID        *int     `json:"member_id,omitempty"`
 		MemberPrice     *float64 `json:"member_price,omitempty"`
+		PromoCode       string   `json:"promo_code,omitempty"`
+		DiscountAmount  float64  `json:"discount_amount,omitempty"`
 	}
 
 	// Rp 10 biaya admin per transaksi
@@ -302,6 +330,8 @@ func (h *AdminHandler) GetOrders(w http.ResponseWriter, r *http.Request) {
 			AdminNotes:      order.AdminNotes,
 			MemberID:        order.MemberID,
 			MemberPrice:     order.MemberPrice,
+			PromoCode:       order.PromoCode,
+			DiscountAmount:  order.DiscountAmount,
 		}
 
 		// Get payment status if exists
@@ -770,6 +800,11 @@ func (h *AdminHandler) CheckOrderStatus(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
+		// Give back promo usage
+		if err := h.promoRepo.ReverseRedemption(ctx, orderID); err != nil {
+			log.Printf("[Admin] Failed to reverse promo redemption: %v", err)
+		}
+
 		Success(w, "Order telah kadaluwarsa dan dibatalkan", map[string]interface{}{
 			"order_id": order.ID,
 			"ref_id":   order.RefID,
@@ -808,6 +843,11 @@ func (h *AdminHandler) CheckOrderStatus(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
+		// Give back promo usage
+		if err := h.promoRepo.ReverseRedemption(ctx, orderID); err != nil {
+			log.Printf("[Admin] Failed to reverse promo redemption: %v", err)
+		}
+
 		Success(w, "Order telah kadaluwarsa (dari Pakasir)", map[string]interface{}{
 			"order_id": order.ID,
 			"ref_id":   order.RefID,
@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"govershop-api/internal/middleware"
+	"govershop-api/internal/model"
+	"govershop-api/internal/repository"
+)
+
+// AdminAccountHandler handles admin accounts and roles
+type AdminAccountHandler struct {
+	adminRepo *repository.AdminRepository
+}
+
+// NewAdminAccountHandler creates a new AdminAccountHandler
+func NewAdminAccountHandler(adminRepo *repository.AdminRepository) *AdminAccountHandler {
+	return &AdminAccountHandler{
+		adminRepo: adminRepo,
+	}
+}
+
+// GetMe handles GET /api/v1/admin/me
+// Returns the logged in admin with their role and permissions
+func (h *AdminAccountHandler) GetMe(w http.ResponseWriter, r *http.Request) {
+	admin, err := h.adminRepo.GetByID(r.Context(), r.Context().Value("user_id").(int))
+	if err != nil || admin == nil {
+		log.Printf("Error getting admin: %v", err)
+		InternalError(w, "Gagal mengambil data admin")
+		return
+	}
+
+	Success(w, "", admin)
+}
+
+// GetAdmins handles GET /api/v1/admin/admins
+func (h *AdminAccountHandler) GetAdmins(w http.ResponseWriter, r *http.Request) {
+	admins, err := h.adminRepo.List(r.Context())
+	if err != nil {
+		log.Printf("Error getting admins: %v", err)
+		InternalError(w, "Gagal mengambil daftar admin")
+		return
+	}
+
+	Success(w, "", map[string]interface{}{
+		"admins":      admins,
+		"roles":       model.AdminRoles,
+		"permissions": model.AdminPermissions,
+	})
+}
+
+// CreateAdmin handles POST /api/v1/admin/admins
+func (h *AdminAccountHandler) CreateAdmin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req model.CreateAdminRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		BadRequest(w, "Format request tidak valid")
+		return
+	}
+	req.Username = strings.TrimSpace(req.Username)
+	req.FullName = strings.TrimSpace(req.FullName)
+
+	switch {
+	case len(req.Username) < 3 || len(req.Username) > 50:
+		BadRequest(w, "Username harus 3-50 karakter")
+		return
+	case len(req.Password) < 8:
+		BadRequest(w, "Password admin minimal 8 karakter")
+		return
+	case req.FullName == "":
+		BadRequest(w, "Nama lengkap wajib diisi")
+		return
+	case !model.IsAdminRole(req.AdminRole):
+		BadRequest(w, "admin_role harus salah satu dari: "+strings.Join(model.AdminRoles, ", "))
+		return
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		InternalError(w, "Gagal memproses password")
+		return
+	}
+
+	user := model.User{
+		Username: req.Username,
+		Password: string(hashed),
+		Email:    req.Email,
+		FullName: req.FullName,
+	}
+	if err := h.adminRepo.Create(ctx, &user, req.AdminRole); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrUsernameTaken):
+			Error(w, http.StatusConflict, "Username sudah digunakan")
+		case errors.Is(err, repository.ErrEmailTaken):
+			Error(w, http.StatusConflict, "Email sudah digunakan")
+		default:
+			log.Printf("Error creating admin: %v", err)
+			InternalError(w, "Gagal membuat admin")
+		}
+		return
+	}
+
+	admin, _ := h.adminRepo.GetByID(ctx, user.ID)
+	Created(w, "Admin berhasil dibuat", admin)
+}
+
+// UpdateAdmin handles PUT /api/v1/admin/admins/{id}
+// Changes role, status, name or password. Role, status and password changes end the admin's sessions.
+func (h *AdminAccountHandler) UpdateAdmin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		BadRequest(w, "Invalid admin ID")
+		return
+	}
+
+	var req model.UpdateAdminRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		BadRequest(w, "Format request tidak valid")
+		return
+	}
+	req.FullName = strings.TrimSpace(req.FullName)
+
+	if req.AdminRole != "" && !model.IsAdminRole(req.AdminRole) {
+		BadRequest(w, "admin_role harus salah satu dari: "+strings.Join(model.AdminRoles, ", "))
+		return
+	}
+	if req.Status != "" && req.Status != model.UserStatusActive && req.Status != model.UserStatusSuspended {
+		BadRequest(w, "status harus active atau suspended")
+		return
+	}
+	if req.Password != "" && len(req.Password) < 8 {
+		BadRequest(w, "Password admin minimal 8 karakter")
+		return
+	}
+
+	before, err := h.adminRepo.GetByID(ctx, id)
+	if err != nil {
+		log.Printf("Error getting admin: %v", err)
+		InternalError(w, "Gagal mengambil data admin")
+		return
+	}
+	if before == nil {
+		NotFound(w, "Admin tidak ditemukan")
+		return
+	}
+
+	var hashed string
+	if req.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			InternalError(w, "Gagal memproses password")
+			return
+		}
+		hashed = string(hash)
+	}
+
+	if err := h.adminRepo.Update(ctx, id, req.FullName, req.AdminRole, req.Status, hashed); err != nil {
+		if errors.Is(err, repository.ErrLastOwner) {
+			BadRequest(w, "Harus ada minimal satu owner aktif")
+			return
+		}
+		log.Printf("Error updating admin: %v", err)
+		InternalError(w, "Gagal mengupdate admin")
+		return
+	}
+
+	// The password hash is not part of the audit snapshot
+	middleware.AuditDetail(ctx, "password_changed", hashed != "")
+
+	admin, _ := h.adminRepo.GetByID(ctx, id)
+	Success(w, "Admin berhasil diupdate", admin)
+}
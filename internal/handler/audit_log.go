@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"govershop-api/internal/model"
+	"govershop-api/internal/repository"
+)
+
+// AuditLogHandler serves the admin audit trail
+type AuditLogHandler struct {
+	auditRepo *repository.AuditRepository
+}
+
+// NewAuditLogHandler creates a new AuditLogHandler
+func NewAuditLogHandler(auditRepo *repository.AuditRepository) *AuditLogHandler {
+	return &AuditLogHandler{
+		auditRepo: auditRepo,
+	}
+}
+
+// GetAuditLogs handles GET /api/v1/admin/audit-logs
+// Filters: actor, action (exact, or prefix ending in "."), entity, entity_id, date_from, date_to (YYYY-MM-DD)
+func (h *AuditLogHandler) GetAuditLogs(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	offset, _ := strconv.Atoi(q.Get("offset"))
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	filter := model.AuditLogFilter{
+		Actor:      q.Get("actor"),
+		Action:     q.Get("action"),
+		EntityType: q.Get("entity"),
+		EntityID:   q.Get("entity_id"),
+		DateFrom:   q.Get("date_from"),
+		DateTo:     q.Get("date_to"),
+		Limit:      limit,
+		Offset:     offset,
+	}
+	for _, d := range []string{filter.DateFrom, filter.DateTo} {
+		if d == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", d); err != nil {
+			BadRequest(w, "Format tanggal harus YYYY-MM-DD")
+			return
+		}
+	}
+
+	logs, total, err := h.auditRepo.List(r.Context(), filter)
+	if err != nil {
+		log.Printf("Error getting audit logs: %v", err)
+		InternalError(w, "Gagal mengambil audit log")
+		return
+	}
+
+	JSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"logs":    logs,
+		"total":   total,
+	})
+}
@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"govershop-api/internal/config"
+	"govershop-api/internal/middleware"
+	"govershop-api/internal/model"
+	"govershop-api/internal/repository"
+)
+
+// AdjustmentHandler handles admin balance adjustments
+type AdjustmentHandler struct {
+	config         *config.Config
+	adjustmentRepo *repository.AdjustmentRepository
+	userRepo       *repository.UserRepository
+	securityRepo   *repository.AdminSecurityRepository
+}
+
+// NewAdjustmentHandler creates a new AdjustmentHandler
+func NewAdjustmentHandler(cfg *config.Config, adjustmentRepo *repository.AdjustmentRepository, userRepo *repository.UserRepository, securityRepo *repository.AdminSecurityRepository) *AdjustmentHandler {
+	return &AdjustmentHandler{
+		config:         cfg,
+		adjustmentRepo: adjustmentRepo,
+		userRepo:       userRepo,
+		securityRepo:   securityRepo,
+	}
+}
+
+// CreateAdjustment handles POST /api/v1/admin/members/{id}/adjustments
+// Credits, debits or reverses a ledger entry of a member
+func (h *AdjustmentHandler) CreateAdjustment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	adminUsername := ctx.Value("user").(string)
+
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		BadRequest(w, "Invalid member ID")
+		return
+	}
+
+	var req model.BalanceAdjustmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		BadRequest(w, "Format request tidak valid")
+		return
+	}
+	req.Note = strings.TrimSpace(req.Note)
+
+	if !model.IsAdjustmentReasonCode(req.ReasonCode) {
+		BadRequest(w, "reason_code harus salah satu dari: "+strings.Join(model.AdjustmentReasonCodes, ", "))
+		return
+	}
+	if req.Note == "" {
+		BadRequest(w, "Catatan wajib diisi")
+		return
+	}
+	if len(req.Note) > 500 {
+		BadRequest(w, "Catatan maksimal 500 karakter")
+		return
+	}
+
+	user, err := h.userRepo.GetByID(ctx, userID)
+	if err != nil || user == nil || user.Role != model.UserRoleMember {
+		NotFound(w, "Member tidak ditemukan")
+		return
+	}
+
+	adj := model.BalanceAdjustment{
+		UserID:     userID,
+		Kind:       req.Kind,
+		Amount:     req.Amount,
+		ReasonCode: req.ReasonCode,
+		Note:       req.Note,
+		CreatedBy:  adminUsername,
+	}
+
+	switch req.Kind {
+	case model.AdjustmentKindCredit, model.AdjustmentKindDebit:
+		if req.Amount <= 0 {
+			BadRequest(w, "Nominal harus lebih dari 0")
+			return
+		}
+	case model.AdjustmentKindReversal:
+		deposit, err := h.adjustmentRepo.GetDeposit(ctx, userID, req.DepositID)
+		if err != nil {
+			log.Printf("Error getting deposit: %v", err)
+			InternalError(w, "Gagal memproses penyesuaian saldo")
+			return
+		}
+		if deposit == nil {
+			NotFound(w, "Mutasi tidak ditemukan")
+			return
+		}
+		adj.Amount = deposit.Amount
+		adj.ReversedDepositID = &deposit.ID
+	default:
+		BadRequest(w, "kind harus credit, debit atau reversal")
+		return
+	}
+
+	middleware.AuditDetail(ctx, "kind", adj.Kind)
+	middleware.AuditDetail(ctx, "amount", adj.Amount)
+	middleware.AuditDetail(ctx, "reason_code", adj.ReasonCode)
+	middleware.AuditDetail(ctx, "note", adj.Note)
+	if adj.ReversedDepositID != nil {
+		middleware.AuditDetail(ctx, "reversed_deposit_id", *adj.ReversedDepositID)
+	}
+
+	// Large adjustments need a step-up re-authentication that included TOTP
+	adj.TOTPVerified, _ = ctx.Value("step_up_totp").(bool)
+	if h.config.AdjustmentTOTPThreshold > 0 && adj.Amount >= h.config.AdjustmentTOTPThreshold && !adj.TOTPVerified {
+		Error(w, http.StatusForbidden, fmt.Sprintf("Aktifkan TOTP untuk penyesuaian saldo mulai Rp %.0f", h.config.AdjustmentTOTPThreshold))
+		return
+	}
+
+	if err := h.adjustmentRepo.Create(ctx, &adj); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrInsufficientBalance):
+			BadRequest(w, "Saldo member tidak mencukupi untuk debit ini")
+		case errors.Is(err, repository.ErrDepositNotFound):
+			NotFound(w, "Mutasi tidak ditemukan")
+		case errors.Is(err, repository.ErrDepositNotReversible):
+			BadRequest(w, "Hanya mutasi berstatus sukses yang bisa dibatalkan")
+		case errors.Is(err, repository.ErrDepositAlreadyReversed):
+			Error(w, http.StatusConflict, "Mutasi ini sudah pernah dibatalkan")
+		default:
+			log.Printf("Error creating balance adjustment: %v", err)
+			InternalError(w, "Gagal memproses penyesuaian saldo")
+		}
+		return
+	}
+
+	middleware.AuditDetail(ctx, "adjustment_id", adj.ID)
+	middleware.AuditDetail(ctx, "deposit_id", adj.DepositID)
+
+	Created(w, "Penyesuaian saldo berhasil", adj)
+}
+
+// GetAdjustments handles GET /api/v1/admin/members/{id}/adjustments
+func (h *AdjustmentHandler) GetAdjustments(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		BadRequest(w, "Invalid member ID")
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+	if limit <= 0 {
+		limit = 20
+	}
+
+	adjustments, total, err := h.adjustmentRepo.ListByUser(r.Context(), userID, limit, offset)
+	if err != nil {
+		log.Printf("Error getting balance adjustments: %v", err)
+		InternalError(w, "Gagal mengambil riwayat penyesuaian saldo")
+		return
+	}
+
+	JSON(w, http.StatusOK, map[string]interface{}{
+		"success":     true,
+		"adjustments": adjustments,
+		"total":       total,
+	})
+}
@@ -0,0 +1,350 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"govershop-api/internal/model"
+	"govershop-api/internal/repository"
+)
+
+// FlashSaleHandler handles flash sale campaign management
+type FlashSaleHandler struct {
+	flashSaleRepo *repository.FlashSaleRepository
+	productRepo   *repository.ProductRepository
+}
+
+// NewFlashSaleHandler creates a new FlashSaleHandler
+func NewFlashSaleHandler(flashSaleRepo *repository.FlashSaleRepository, productRepo *repository.ProductRepository) *FlashSaleHandler {
+	return &FlashSaleHandler{
+		flashSaleRepo: flashSaleRepo,
+		productRepo:   productRepo,
+	}
+}
+
+// CreateFlashSaleRequest is the request body for creating/updating a flash sale
+type CreateFlashSaleRequest struct {
+	Name          string   `json:"name"`
+	DiscountType  string   `json:"discount_type"` // "price" or "percent"
+	DiscountValue float64  `json:"discount_value"`
+	SKUs          []string `json:"skus,omitempty"`     // Explicit SKU list
+	Category      *string  `json:"category,omitempty"` // Or selector by category
+	Brand         *string  `json:"brand,omitempty"`    // and/or brand
+	QuotaPerSKU   *int     `json:"quota_per_sku,omitempty"`
+	StartAt       string   `json:"start_at"` // ISO format or "2006-01-02 15:04" (WIB)
+	EndAt         string   `json:"end_at"`   // ISO format or "2006-01-02 15:04" (WIB)
+}
+
+// parseWIBTime parses RFC3339 or a plain "YYYY-MM-DD HH:MM" timestamp interpreted as WIB
+func parseWIBTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.In(time.Local), nil
+	}
+	return time.ParseInLocation("2006-01-02 15:04", s, time.Local)
+}
+
+// toFlashSale validates the request and builds the flash sale model and SKU list
+func (h *FlashSaleHandler) toFlashSale(ctx context.Context, req *CreateFlashSaleRequest) (*model.FlashSale, []string, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return nil, nil, fmt.Errorf("Nama flash sale wajib diisi")
+	}
+	if req.DiscountType != model.FlashSaleDiscountPrice && req.DiscountType != model.FlashSaleDiscountPercent {
+		return nil, nil, fmt.Errorf("discount_type harus 'price' atau 'percent'")
+	}
+	if req.DiscountValue <= 0 {
+		return nil, nil, fmt.Errorf("discount_value harus lebih dari 0")
+	}
+	if req.DiscountType == model.FlashSaleDiscountPercent && req.DiscountValue >= 100 {
+		return nil, nil, fmt.Errorf("Diskon persen harus kurang dari 100")
+	}
+	if req.QuotaPerSKU != nil && *req.QuotaPerSKU <= 0 {
+		return nil, nil, fmt.Errorf("quota_per_sku harus lebih dari 0")
+	}
+
+	category := emptyToNil(req.Category)
+	brand := emptyToNil(req.Brand)
+
+	var skus []string
+	for _, sku := range req.SKUs {
+		sku = strings.TrimSpace(sku)
+		if sku != "" {
+			skus = append(skus, sku)
+		}
+	}
+	if len(skus) == 0 && category == nil && brand == nil {
+		return nil, nil, fmt.Errorf("Isi daftar SKU atau pilih brand/kategori")
+	}
+	if len(skus) > 0 && (category != nil || brand != nil) {
+		return nil, nil, fmt.Errorf("Gunakan daftar SKU atau brand/kategori, tidak keduanya")
+	}
+
+	// Fixed flash price only makes sense for explicit SKUs
+	if req.DiscountType == model.FlashSaleDiscountPrice && len(skus) == 0 {
+		return nil, nil, fmt.Errorf("Harga flash sale hanya bisa dipakai dengan daftar SKU, gunakan 'percent' untuk brand/kategori")
+	}
+
+	for _, sku := range skus {
+		product, err := h.productRepo.GetBySKU(ctx, sku)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Produk %s tidak ditemukan", sku)
+		}
+		if req.DiscountType == model.FlashSaleDiscountPrice && req.DiscountValue >= product.SellingPrice {
+			return nil, nil, fmt.Errorf("Harga flash sale %s harus lebih rendah dari harga jual", sku)
+		}
+	}
+
+	startAt, err := parseWIBTime(req.StartAt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Format start_at tidak valid")
+	}
+	endAt, err := parseWIBTime(req.EndAt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Format end_at tidak valid")
+	}
+	if !endAt.After(startAt) {
+		return nil, nil, fmt.Errorf("end_at harus setelah start_at")
+	}
+	if !endAt.After(time.Now()) {
+		return nil, nil, fmt.Errorf("end_at sudah lewat")
+	}
+
+	return &model.FlashSale{
+		Name:          req.Name,
+		DiscountType:  req.DiscountType,
+		DiscountValue: req.DiscountValue,
+		Category:      category,
+		Brand:         brand,
+		QuotaPerSKU:   req.QuotaPerSKU,
+		StartAt:       startAt,
+		EndAt:         endAt,
+	}, skus, nil
+}
+
+// GetFlashSales handles GET /api/v1/admin/flash-sales
+func (h *FlashSaleHandler) GetFlashSales(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	sales, err := h.flashSaleRepo.GetAll(ctx, r.URL.Query().Get("status"))
+	if err != nil {
+		InternalError(w, "Gagal mengambil data flash sale")
+		return
+	}
+
+	if sales == nil {
+		sales = []model.FlashSale{}
+	}
+
+	Success(w, "", map[string]interface{}{
+		"flash_sales": sales,
+		"total":       len(sales),
+	})
+}
+
+// GetFlashSaleByID handles GET /api/v1/admin/flash-sales/{id}
+func (h *FlashSaleHandler) GetFlashSaleByID(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		BadRequest(w, "ID tidak valid")
+		return
+	}
+
+	sale, err := h.flashSaleRepo.GetByID(ctx, id)
+	if err != nil {
+		InternalError(w, "Gagal mengambil data flash sale")
+		return
+	}
+	if sale == nil {
+		NotFound(w, "Flash sale tidak ditemukan")
+		return
+	}
+
+	Success(w, "", sale)
+}
+
+// CreateFlashSale handles POST /api/v1/admin/flash-sales
+func (h *FlashSaleHandler) CreateFlashSale(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req CreateFlashSaleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		BadRequest(w, "Format request tidak valid")
+		return
+	}
+
+	sale, skus, err := h.toFlashSale(ctx, &req)
+	if err != nil {
+		BadRequest(w, err.Error())
+		return
+	}
+
+	if err := h.flashSaleRepo.Create(ctx, sale, skus); err != nil {
+		log.Printf("[FlashSale] Failed to create flash sale: %v", err)
+		InternalError(w, "Gagal membuat flash sale")
+		return
+	}
+
+	// Activate right away if the start time has already passed
+	h.RunSchedule(ctx)
+
+	created, err := h.flashSaleRepo.GetByID(ctx, sale.ID)
+	if err != nil || created == nil {
+		Created(w, "Flash sale berhasil dibuat", sale)
+		return
+	}
+
+	Created(w, "Flash sale berhasil dibuat", created)
+}
+
+// UpdateFlashSale handles PUT /api/v1/admin/flash-sales/{id}
+func (h *FlashSaleHandler) UpdateFlashSale(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		BadRequest(w, "ID tidak valid")
+		return
+	}
+
+	var req CreateFlashSaleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		BadRequest(w, "Format request tidak valid")
+		return
+	}
+
+	sale, skus, err := h.toFlashSale(ctx, &req)
+	if err != nil {
+		BadRequest(w, err.Error())
+		return
+	}
+	sale.ID = id
+
+	if err := h.flashSaleRepo.Update(ctx, sale, skus); err != nil {
+		if err.Error() == "flash sale not editable" {
+			BadRequest(w, "Hanya flash sale yang belum dimulai yang dapat diubah")
+			return
+		}
+		log.Printf("[FlashSale] Failed to update flash sale %d: %v", id, err)
+		InternalError(w, "Gagal mengupdate flash sale")
+		return
+	}
+
+	h.RunSchedule(ctx)
+
+	updated, err := h.flashSaleRepo.GetByID(ctx, id)
+	if err != nil || updated == nil {
+		Success(w, "Flash sale berhasil diupdate", sale)
+		return
+	}
+
+	Success(w, "Flash sale berhasil diupdate", updated)
+}
+
+// StopFlashSale handles POST /api/v1/admin/flash-sales/{id}/stop
+func (h *FlashSaleHandler) StopFlashSale(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		BadRequest(w, "ID tidak valid")
+		return
+	}
+
+	if err := h.flashSaleRepo.Stop(ctx, id); err != nil {
+		if err.Error() == "flash sale not running" {
+			BadRequest(w, "Flash sale sudah selesai atau dibatalkan")
+			return
+		}
+		InternalError(w, "Gagal menghentikan flash sale")
+		return
+	}
+
+	Success(w, "Flash sale dihentikan, harga produk dikembalikan", nil)
+}
+
+// DeleteFlashSale handles DELETE /api/v1/admin/flash-sales/{id}
+func (h *FlashSaleHandler) DeleteFlashSale(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		BadRequest(w, "ID tidak valid")
+		return
+	}
+
+	if err := h.flashSaleRepo.Delete(ctx, id); err != nil {
+		if err.Error() == "flash sale not found" {
+			NotFound(w, "Flash sale tidak ditemukan")
+			return
+		}
+		InternalError(w, "Gagal menghapus flash sale")
+		return
+	}
+
+	Success(w, "Flash sale berhasil dihapus", nil)
+}
+
+// RunSchedule ends expired campaigns, activates due ones and applies their prices
+func (h *FlashSaleHandler) RunSchedule(ctx context.Context) {
+	if ended, err := h.flashSaleRepo.EndExpired(ctx); err != nil {
+		log.Printf("[FlashSale] Failed to end expired flash sales: %v", err)
+	} else if ended > 0 {
+		log.Printf("[FlashSale] Ended %d flash sale(s), prices reverted", ended)
+	}
+
+	if activated, err := h.flashSaleRepo.ActivateDue(ctx); err != nil {
+		log.Printf("[FlashSale] Failed to activate flash sales: %v", err)
+	} else if activated > 0 {
+		log.Printf("[FlashSale] Activated %d flash sale(s)", activated)
+	}
+
+	if applied, err := h.flashSaleRepo.ApplyPendingItems(ctx); err != nil {
+		log.Printf("[FlashSale] Failed to apply flash sale prices: %v", err)
+	} else if applied > 0 {
+		log.Printf("[FlashSale] Applied flash sale price to %d product(s)", applied)
+	}
+}
+
+// StartScheduler starts the background job that activates and deactivates flash sales
+func (h *FlashSaleHandler) StartScheduler(ctx context.Context) {
+	interval := 1 * time.Minute
+	ticker := time.NewTicker(interval)
+
+	log.Printf("[FlashSale] Scheduler initialized. Running every %v", interval)
+
+	go func() {
+		h.RunSchedule(context.Background())
+
+		for {
+			select {
+			case <-ctx.Done():
+				ticker.Stop()
+				return
+			case <-ticker.C:
+				h.RunSchedule(context.Background())
+			}
+		}
+	}()
+}
+
+// flashSaleItemFor returns the flash sale item ID when the order is charged the flash sale price,
+// so the order consumes quota. Lookup errors are logged and treated as "no flash sale".
+func flashSaleItemFor(ctx context.Context, flashSaleRepo *repository.FlashSaleRepository, sku string, chargedPrice float64) *int64 {
+	info, err := flashSaleRepo.GetActiveInfoBySKU(ctx, sku)
+	if err != nil {
+		log.Printf("[FlashSale] Failed to look up flash sale for %s: %v", sku, err)
+		return nil
+	}
+	if info == nil || info.FlashPrice != chargedPrice {
+		return nil
+	}
+	return &info.ItemID
+}
@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"govershop-api/internal/config"
+	"govershop-api/internal/model"
+	"govershop-api/internal/repository"
+	"govershop-api/internal/service/digiflazz"
+)
+
+// OrderFulfillment submits paid orders to Digiflazz and settles the provider outcome.
+// Guest, member and admin orders all go through it, so an immediate response and a
+// later webhook are recorded, refunded and notified the same way.
+type OrderFulfillment struct {
+	config       *config.Config
+	orderRepo    *repository.OrderRepository
+	userRepo     *repository.UserRepository
+	promoRepo    *repository.PromoRepository
+	referralRepo *repository.ReferralRepository
+	pointsRepo   *repository.PointsRepository
+	productRepo  *repository.ProductRepository
+	digiflazzSvc *digiflazz.Service
+
+	memberWebhookRepo *repository.MemberWebhookRepository
+}
+
+// NewOrderFulfillment creates a new OrderFulfillment
+func NewOrderFulfillment(
+	cfg *config.Config,
+	orderRepo *repository.OrderRepository,
+	userRepo *repository.UserRepository,
+	promoRepo *repository.PromoRepository,
+	referralRepo *repository.ReferralRepository,
+	pointsRepo *repository.PointsRepository,
+	productRepo *repository.ProductRepository,
+	digiflazzSvc *digiflazz.Service,
+	memberWebhookRepo *repository.MemberWebhookRepository,
+) *OrderFulfillment {
+	return &OrderFulfillment{
+		config:       cfg,
+		orderRepo:    orderRepo,
+		userRepo:     userRepo,
+		promoRepo:    promoRepo,
+		referralRepo: referralRepo,
+		pointsRepo:   pointsRepo,
+		productRepo:  productRepo,
+		digiflazzSvc: digiflazzSvc,
+
+		memberWebhookRepo: memberWebhookRepo,
+	}
+}
+
+// ProcessPaid sends a freshly paid order to Digiflazz. Runs in its own goroutine.
+func (f *OrderFulfillment) ProcessPaid(order *model.Order) {
+	// Use background context for goroutine operations
+	ctx := context.Background()
+
+	log.Printf("[Topup] Processing topup for order %s", order.ID)
+
+	// Update status to processing
+	_ = f.orderRepo.UpdateStatus(ctx, order.ID, model.OrderStatusProcessing)
+	order.Status = model.OrderStatusProcessing
+
+	if err := f.Submit(ctx, order); err != nil {
+		return
+	}
+
+	log.Printf("[Topup] Order %s submitted, status %s", order.ID, order.Status)
+}
+
+// Submit sends an order to Digiflazz and applies the immediate response.
+// The order is updated in place with the resulting status, RC, SN and message.
+// Returns the provider error when Digiflazz could not be called; the order is
+// then already marked failed and refunded.
+func (f *OrderFulfillment) Submit(ctx context.Context, order *model.Order) error {
+	// Force Testing: false because user wants real transactions even if ENV is not explicitly set to production
+	resp, err := f.digiflazzSvc.CreateTransaction(digiflazz.TopupRequest{
+		BuyerSKUCode: order.BuyerSKUCode,
+		CustomerNo:   order.CustomerNo,
+		RefID:        order.DigiflazzRefID(),
+		Testing:      false,
+	})
+	if err != nil {
+		log.Printf("[Fulfillment] Failed to create transaction for order %s: %v", order.ID, err)
+
+		changed, uErr := f.orderRepo.UpdateDigiflazzResponse(ctx, order.ID, model.OrderStatusFailed, "", "", "", err.Error())
+		if uErr != nil {
+			log.Printf("[Fulfillment] Failed to update order %s: %v", order.ID, uErr)
+		}
+		if !changed {
+			return err
+		}
+		order.Status = model.OrderStatusFailed
+		order.DigiflazzMsg = err.Error()
+		enqueueOrderCallback(ctx, f.memberWebhookRepo, f.orderRepo, order.ID)
+
+		f.settleFailed(ctx, order, fmt.Sprintf("Refund Gagal Transaksi (Initial) %s", order.RefID))
+		return err
+	}
+
+	// Log Raw Response for debugging
+	respJSON, _ := json.Marshal(resp)
+	log.Printf("[Fulfillment] Digiflazz Raw Response: %s", string(respJSON))
+
+	if _, err := f.ApplyResult(ctx, order, resp.Data.Status, resp.Data.RC, resp.Data.SN, resp.Data.Message); err != nil {
+		log.Printf("[Fulfillment] %v", err)
+	}
+	return nil
+}
+
+// ApplyResult records a Digiflazz result (immediate response or webhook) on an order
+// and settles it: refund on failure, rewards on success and a member callback either way.
+// Results for an order that is already settled, and repeated results, are ignored;
+// the order's current status is returned for them.
+func (f *OrderFulfillment) ApplyResult(ctx context.Context, order *model.Order, dfStatus, rc, sn, message string) (model.OrderStatus, error) {
+	status := digiflazzOrderStatus(dfStatus)
+
+	changed, err := f.orderRepo.UpdateDigiflazzResponse(ctx, order.ID, status, dfStatus, rc, sn, message)
+	if err != nil {
+		return status, fmt.Errorf("failed to update order %s: %w", order.ID, err)
+	}
+	if !changed {
+		log.Printf("[Fulfillment] Ignored Digiflazz status %q for order %s (status %s)", dfStatus, order.ID, order.Status)
+		return order.Status, nil
+	}
+	order.Status = status
+	order.DigiflazzStatus = dfStatus
+	order.DigiflazzRC = rc
+	order.SerialNumber = sn
+	order.DigiflazzMsg = message
+
+	log.Printf("[Fulfillment] Order %s updated to status %s", order.ID, status)
+	enqueueOrderCallback(ctx, f.memberWebhookRepo, f.orderRepo, order.ID)
+
+	if status == model.OrderStatusFailed {
+		f.settleFailed(ctx, order, fmt.Sprintf("Refund Gagal Transaksi %s", order.RefID))
+	} else if status == model.OrderStatusSuccess {
+		f.settleSuccess(ctx, order)
+	}
+
+	return status, nil
+}
+
+// settleFailed refunds member balance and gives back promo usage for a failed order
+func (f *OrderFulfillment) settleFailed(ctx context.Context, order *model.Order, refundDesc string) {
+	if order.MemberID != nil {
+		amount := order.MemberPrice
+		if amount == nil {
+			amount = &order.SellingPrice
+		}
+		err := f.userRepo.RefundBalance(ctx, *order.MemberID, *amount, refundDesc, order.RefID)
+		if errors.Is(err, repository.ErrDuplicateLedgerEntry) {
+			log.Printf("[Fulfillment] Order %s already refunded, skipping", order.ID)
+		} else if err != nil {
+			log.Printf("CRITICAL: Failed to refund member balance for order %s: %v", order.ID, err)
+		}
+	}
+
+	if err := f.promoRepo.ReverseRedemption(ctx, order.ID); err != nil {
+		log.Printf("[Fulfillment] Failed to reverse promo redemption for order %s: %v", order.ID, err)
+	}
+
+	reverseReferralCommission(ctx, f.userRepo, f.referralRepo, order)
+	clawbackOrderPoints(ctx, f.pointsRepo, order)
+}
+
+// settleSuccess credits post-success rewards (referral commission, loyalty points) for an order
+func (f *OrderFulfillment) settleSuccess(ctx context.Context, order *model.Order) {
+	creditReferralCommission(ctx, f.config, f.userRepo, f.referralRepo, order)
+	earnOrderPoints(ctx, f.pointsRepo, f.productRepo, order)
+}
+
+// digiflazzOrderStatus maps a Digiflazz transaction status to an order status
+func digiflazzOrderStatus(dfStatus string) model.OrderStatus {
+	switch dfStatus {
+	case "Sukses":
+		return model.OrderStatusSuccess
+	case "Gagal":
+		return model.OrderStatusFailed
+	default:
+		return model.OrderStatusProcessing
+	}
+}
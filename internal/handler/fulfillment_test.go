@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"testing"
+
+	"govershop-api/internal/model"
+)
+
+func TestDigiflazzOrderStatus(t *testing.T) {
+	tests := []struct {
+		dfStatus string
+		want     model.OrderStatus
+	}{
+		{dfStatus: "Sukses", want: model.OrderStatusSuccess},
+		{dfStatus: "Gagal", want: model.OrderStatusFailed},
+		{dfStatus: "Pending", want: model.OrderStatusProcessing},
+		// Anything unknown stays open rather than refunding or rewarding
+		{dfStatus: "", want: model.OrderStatusProcessing},
+		{dfStatus: "sukses", want: model.OrderStatusProcessing},
+		{dfStatus: "GAGAL", want: model.OrderStatusProcessing},
+	}
+
+	for _, tt := range tests {
+		if got := digiflazzOrderStatus(tt.dfStatus); got != tt.want {
+			t.Errorf("digiflazzOrderStatus(%q) = %q, want %q", tt.dfStatus, got, tt.want)
+		}
+	}
+}
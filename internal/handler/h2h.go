@@ -0,0 +1,481 @@
+package handler
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"govershop-api/internal/config"
+	"govershop-api/internal/model"
+	"govershop-api/internal/repository"
+)
+
+// H2HHandler handles the host-to-host reseller API and member API key management
+type H2HHandler struct {
+	config        *config.Config
+	apiKeyRepo    *repository.APIKeyRepository
+	userRepo      *repository.UserRepository
+	productRepo   *repository.ProductRepository
+	orderRepo     *repository.OrderRepository
+	memberHandler *MemberHandler
+}
+
+// NewH2HHandler creates a new H2HHandler
+func NewH2HHandler(
+	cfg *config.Config,
+	apiKeyRepo *repository.APIKeyRepository,
+	userRepo *repository.UserRepository,
+	productRepo *repository.ProductRepository,
+	orderRepo *repository.OrderRepository,
+	memberHandler *MemberHandler,
+) *H2HHandler {
+	return &H2HHandler{
+		config:        cfg,
+		apiKeyRepo:    apiKeyRepo,
+		userRepo:      userRepo,
+		productRepo:   productRepo,
+		orderRepo:     orderRepo,
+		memberHandler: memberHandler,
+	}
+}
+
+// ==========================================
+// MEMBER API KEY MANAGEMENT
+// ==========================================
+
+// GetAPIKey handles GET /api/v1/member/api-key
+func (h *H2HHandler) GetAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	key, err := h.apiKeyRepo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error getting api key: %v", err)
+		InternalError(w, "Gagal mengambil API key")
+		return
+	}
+	if key == nil {
+		NotFound(w, "API key belum dibuat")
+		return
+	}
+
+	Success(w, "", key)
+}
+
+// GenerateAPIKey handles POST /api/v1/member/api-key
+// Creates or regenerates the member's API key. The secret is only shown in this response.
+func (h *H2HHandler) GenerateAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	apiKey, err := randomHex(16)
+	if err != nil {
+		InternalError(w, "Gagal membuat API key")
+		return
+	}
+	apiSecret, err := randomHex(32)
+	if err != nil {
+		InternalError(w, "Gagal membuat API key")
+		return
+	}
+
+	key, err := h.apiKeyRepo.Upsert(r.Context(), userID, "gvs_"+apiKey, apiSecret)
+	if err != nil {
+		log.Printf("Error generating api key: %v", err)
+		InternalError(w, "Gagal membuat API key")
+		return
+	}
+
+	Success(w, "API key berhasil dibuat. Simpan secret ini, tidak akan ditampilkan lagi.", map[string]interface{}{
+		"api_key":      key.APIKey,
+		"api_secret":   apiSecret,
+		"ip_allowlist": key.IPAllowlist,
+		"is_active":    key.IsActive,
+	})
+}
+
+// UpdateAPIKey handles PUT /api/v1/member/api-key
+// Updates the IP allowlist and enables/disables the key
+func (h *H2HHandler) UpdateAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	var req model.UpdateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		BadRequest(w, "Invalid request body")
+		return
+	}
+
+	key, err := h.apiKeyRepo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		InternalError(w, "Gagal mengambil API key")
+		return
+	}
+	if key == nil {
+		NotFound(w, "API key belum dibuat")
+		return
+	}
+
+	allowlist := []string{}
+	for _, ip := range req.IPAllowlist {
+		ip = strings.TrimSpace(ip)
+		if ip == "" {
+			continue
+		}
+		if net.ParseIP(ip) == nil {
+			BadRequest(w, "IP tidak valid: "+ip)
+			return
+		}
+		allowlist = append(allowlist, ip)
+	}
+
+	isActive := key.IsActive
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	if err := h.apiKeyRepo.UpdateSettings(r.Context(), userID, allowlist, isActive); err != nil {
+		log.Printf("Error updating api key: %v", err)
+		InternalError(w, "Gagal mengupdate API key")
+		return
+	}
+
+	Success(w, "API key berhasil diupdate", map[string]interface{}{
+		"ip_allowlist": allowlist,
+		"is_active":    isActive,
+	})
+}
+
+// ==========================================
+// H2H ENDPOINTS (/api/h2h/v1)
+// ==========================================
+
+// h2hJSON writes a versioned H2H response
+func h2hJSON(w http.ResponseWriter, statusCode int, rc, message string, data interface{}) {
+	JSON(w, statusCode, model.H2HResponse{
+		Version: model.H2HVersion,
+		RC:      rc,
+		Message: message,
+		Data:    data,
+	})
+}
+
+// authenticate parses the request and verifies API key, IP allowlist and signature.
+// signPayload is the command-specific suffix of the md5 signature ("balance", "pricelist", or the ref_id).
+// On failure the response is written and nil is returned.
+func (h *H2HHandler) authenticate(w http.ResponseWriter, r *http.Request, signPayload func(req *model.H2HRequest) string) (*model.H2HRequest, *model.MemberAPIKey) {
+	ctx := r.Context()
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 64*1024))
+	if err != nil {
+		h2hJSON(w, http.StatusBadRequest, model.H2HRCInvalidRequest, "Request tidak valid", nil)
+		return nil, nil
+	}
+
+	var req model.H2HRequest
+	if err := json.Unmarshal(body, &req); err != nil || req.APIKey == "" {
+		h2hJSON(w, http.StatusBadRequest, model.H2HRCInvalidRequest, "Request tidak valid", nil)
+		return nil, nil
+	}
+
+	key, err := h.apiKeyRepo.GetByKey(ctx, req.APIKey)
+	if err != nil {
+		log.Printf("[H2H] Failed to get api key: %v", err)
+		h2hJSON(w, http.StatusInternalServerError, model.H2HRCInternalError, "Kesalahan sistem", nil)
+		return nil, nil
+	}
+	if key == nil || !key.IsActive {
+		h2hJSON(w, http.StatusUnauthorized, model.H2HRCInvalidSignature, "API key tidak valid", nil)
+		return nil, nil
+	}
+
+	ip := trustedClientIP(r, h.config.TrustedProxyHops)
+	if !key.AllowsIP(ip) {
+		log.Printf("[H2H] Rejected IP %s for user %d", ip, key.UserID)
+		h2hJSON(w, http.StatusForbidden, model.H2HRCIPNotAllowed, "IP tidak terdaftar: "+ip, nil)
+		return nil, nil
+	}
+
+	if !verifyH2HSignature(key, &req, body, r.Header.Get("X-Signature"), signPayload(&req)) {
+		h2hJSON(w, http.StatusUnauthorized, model.H2HRCInvalidSignature, "Signature tidak valid", nil)
+		return nil, nil
+	}
+
+	user, err := h.userRepo.GetByID(ctx, key.UserID)
+	if err != nil || user == nil || user.Role != model.UserRoleMember || user.Status != model.UserStatusActive {
+		h2hJSON(w, http.StatusUnauthorized, model.H2HRCInvalidSignature, "Akun tidak aktif", nil)
+		return nil, nil
+	}
+
+	if err := h.apiKeyRepo.TouchLastUsed(ctx, key.ID, ip); err != nil {
+		log.Printf("[H2H] %v", err)
+	}
+
+	return &req, key
+}
+
+// verifyH2HSignature checks X-Signature (HMAC-SHA256 of the raw body) if present,
+// otherwise the md5 "sign" field in Digiflazz style: md5(api_key + api_secret + payload)
+func verifyH2HSignature(key *model.MemberAPIKey, req *model.H2HRequest, body []byte, headerSig, payload string) bool {
+	if headerSig != "" {
+		mac := hmac.New(sha256.New, []byte(key.APISecret))
+		mac.Write(body)
+		expected := hex.EncodeToString(mac.Sum(nil))
+		return hmac.Equal([]byte(strings.ToLower(headerSig)), []byte(expected))
+	}
+
+	if req.Sign == "" {
+		return false
+	}
+	hash := md5.Sum([]byte(key.APIKey + key.APISecret + payload))
+	expected := hex.EncodeToString(hash[:])
+	return hmac.Equal([]byte(strings.ToLower(req.Sign)), []byte(expected))
+}
+
+// Balance handles POST /api/h2h/v1/balance
+// sign = md5(api_key + api_secret + "balance")
+func (h *H2HHandler) Balance(w http.ResponseWriter, r *http.Request) {
+	_, key := h.authenticate(w, r, func(*model.H2HRequest) string { return "balance" })
+	if key == nil {
+		return
+	}
+
+	user, err := h.userRepo.GetByID(r.Context(), key.UserID)
+	if err != nil || user == nil {
+		h2hJSON(w, http.StatusInternalServerError, model.H2HRCInternalError, "Kesalahan sistem", nil)
+		return
+	}
+
+	h2hJSON(w, http.StatusOK, model.H2HRCSuccess, "Sukses", model.H2HBalance{Balance: user.Balance})
+}
+
+// PriceList handles POST /api/h2h/v1/price-list
+// sign = md5(api_key + api_secret + "pricelist")
+func (h *H2HHandler) PriceList(w http.ResponseWriter, r *http.Request) {
+	req, key := h.authenticate(w, r, func(*model.H2HRequest) string { return "pricelist" })
+	if key == nil {
+		return
+	}
+
+	products, err := h.productRepo.GetAll(r.Context())
+	if err != nil {
+		log.Printf("[H2H] Failed to get products: %v", err)
+		h2hJSON(w, http.StatusInternalServerError, model.H2HRCInternalError, "Kesalahan sistem", nil)
+		return
+	}
+
+	items := []model.H2HProduct{}
+	for _, p := range products {
+		if req.Category != "" && !strings.EqualFold(p.Category, req.Category) {
+			continue
+		}
+		if req.Brand != "" && !strings.EqualFold(p.Brand, req.Brand) {
+			continue
+		}
+		resp := p.ToMemberResponse(0)
+		items = append(items, model.H2HProduct{
+			BuyerSKUCode: p.BuyerSKUCode,
+			ProductName:  p.ProductName,
+			Category:     p.Category,
+			Brand:        p.Brand,
+			Type:         p.Type,
+			Price:        resp.Price,
+			Available:    p.IsAvailable,
+		})
+	}
+
+	h2hJSON(w, http.StatusOK, model.H2HRCSuccess, "Sukses", items)
+}
+
+// Transaction handles POST /api/h2h/v1/transaction
+// sign = md5(api_key + api_secret + ref_id). Re-sending an existing ref_id returns that transaction.
+func (h *H2HHandler) Transaction(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req, key := h.authenticate(w, r, func(req *model.H2HRequest) string { return req.RefID })
+	if key == nil {
+		return
+	}
+
+	req.RefID = strings.TrimSpace(req.RefID)
+	if req.RefID == "" || len(req.RefID) > 100 || req.BuyerSKUCode == "" || req.CustomerNo == "" {
+		h2hJSON(w, http.StatusBadRequest, model.H2HRCInvalidRequest, "ref_id, buyer_sku_code dan customer_no wajib diisi", nil)
+		return
+	}
+
+	// Idempotent by ref_id: return the existing transaction instead of charging again
+	existing, err := h.orderRepo.GetByClientRefID(ctx, key.UserID, req.RefID)
+	if err != nil {
+		log.Printf("[H2H] Failed to look up ref_id: %v", err)
+		h2hJSON(w, http.StatusInternalServerError, model.H2HRCInternalError, "Kesalahan sistem", nil)
+		return
+	}
+	if existing != nil {
+		if existing.BuyerSKUCode != req.BuyerSKUCode || existing.CustomerNo != req.CustomerNo {
+			h2hJSON(w, http.StatusConflict, model.H2HRCDuplicateRefID, "ref_id sudah digunakan untuk transaksi lain", nil)
+			return
+		}
+		status, rc := model.H2HStatus(existing.Status)
+		h2hJSON(w, http.StatusOK, rc, existing.DigiflazzMsg, h.toH2HTransaction(ctx, existing, status, false))
+		return
+	}
+
+	order, err := h.memberHandler.placeOrder(ctx, key.UserID, model.MemberOrderRequest{
+		BuyerSKUCode:      req.BuyerSKUCode,
+		DestinationNumber: req.CustomerNo,
+		PromoCode:         req.PromoCode,
+	}, "h2h", req.RefID)
+	if err != nil {
+		var oErr *memberOrderError
+		if !errors.As(err, &oErr) {
+			h2hJSON(w, http.StatusInternalServerError, model.H2HRCInternalError, "Kesalahan sistem", nil)
+			return
+		}
+
+		var data interface{}
+		if order != nil {
+			data = h.toH2HTransaction(ctx, order, "failed", true)
+		}
+		h2hJSON(w, oErr.status, h2hRCForOrderError(oErr.code), oErr.message, data)
+		return
+	}
+
+	message := "Transaksi sedang diproses"
+	if order.Status == model.OrderStatusSuccess {
+		message = "Transaksi berhasil"
+	}
+	status, rc := model.H2HStatus(order.Status)
+	h2hJSON(w, http.StatusOK, rc, message, h.toH2HTransaction(ctx, order, status, true))
+}
+
+// Status handles POST /api/h2h/v1/status
+// sign = md5(api_key + api_secret + ref_id)
+func (h *H2HHandler) Status(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req, key := h.authenticate(w, r, func(req *model.H2HRequest) string { return req.RefID })
+	if key == nil {
+		return
+	}
+
+	if strings.TrimSpace(req.RefID) == "" {
+		h2hJSON(w, http.StatusBadRequest, model.H2HRCInvalidRequest, "ref_id wajib diisi", nil)
+		return
+	}
+
+	order, err := h.orderRepo.GetByClientRefID(ctx, key.UserID, strings.TrimSpace(req.RefID))
+	if err != nil {
+		log.Printf("[H2H] Failed to get order: %v", err)
+		h2hJSON(w, http.StatusInternalServerError, model.H2HRCInternalError, "Kesalahan sistem", nil)
+		return
+	}
+	if order == nil {
+		h2hJSON(w, http.StatusNotFound, model.H2HRCNotFound, "Transaksi tidak ditemukan", nil)
+		return
+	}
+
+	status, rc := model.H2HStatus(order.Status)
+	h2hJSON(w, http.StatusOK, rc, order.DigiflazzMsg, h.toH2HTransaction(ctx, order, status, false))
+}
+
+// toH2HTransaction converts an order to the H2H transaction payload
+func (h *H2HHandler) toH2HTransaction(ctx context.Context, order *model.Order, status string, withBalance bool) model.H2HTransaction {
+	price := order.SellingPrice
+	if order.MemberPrice != nil {
+		price = *order.MemberPrice
+	}
+
+	trx := model.H2HTransaction{
+		RefID:        order.ClientRefID,
+		TrxID:        order.RefID,
+		Status:       status,
+		BuyerSKUCode: order.BuyerSKUCode,
+		ProductName:  order.ProductName,
+		CustomerNo:   order.CustomerNo,
+		Price:        price,
+		SN:           order.SerialNumber,
+		Message:      order.DigiflazzMsg,
+		CreatedAt:    order.CreatedAt,
+		CompletedAt:  order.CompletedAt,
+	}
+
+	if withBalance && order.MemberID != nil {
+		if user, err := h.userRepo.GetByID(ctx, *order.MemberID); err == nil && user != nil {
+			trx.Balance = &user.Balance
+		}
+	}
+
+	return trx
+}
+
+// h2hRCForOrderError maps member order error codes to H2H response codes
+func h2hRCForOrderError(code string) string {
+	switch code {
+	case orderErrInvalidRequest:
+		return model.H2HRCInvalidRequest
+	case orderErrProductUnavailable:
+		return model.H2HRCProductUnavailable
+	case orderErrPromoInvalid:
+		return model.H2HRCPromoInvalid
+	case orderErrInsufficientBalance:
+		return model.H2HRCInsufficientBalance
+	case orderErrFlashSaleSoldOut:
+		return model.H2HRCFlashSaleSoldOut
+	case orderErrDuplicateRefID:
+		return model.H2HRCDuplicateRefID
+	case orderErrProvider:
+		return model.H2HRCProviderError
+	case model.LimitCodeOrderAmount:
+		return model.H2HRCLimitOrderAmount
+	case model.LimitCodeDailySpend:
+		return model.H2HRCLimitDailySpend
+	case model.LimitCodeHourlyOrder:
+		return model.H2HRCLimitHourlyOrders
+	case model.LimitCodeBrand:
+		return model.H2HRCBrandNotAllowed
+	}
+	return model.H2HRCInternalError
+}
+
+// trustedClientIP returns the caller's IP for the H2H allowlist, login history
+// and sessions. The leftmost
+// X-Forwarded-For entries are set by the caller and can be spoofed, so only the
+// entry added by our own proxies (counted from the right) is used. With no
+// trusted proxies it is the connection address.
+func trustedClientIP(r *http.Request, trustedHops int) string {
+	if trustedHops > 0 {
+		var hops []string
+		for _, header := range r.Header.Values("X-Forwarded-For") {
+			for _, hop := range strings.Split(header, ",") {
+				if hop = strings.TrimSpace(hop); hop != "" {
+					hops = append(hops, hop)
+				}
+			}
+		}
+		if len(hops) >= trustedHops {
+			return hops[len(hops)-trustedHops]
+		}
+	}
+
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	return ip
+}
+
+// randomHex returns n random bytes hex-encoded
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"govershop-api/internal/model"
+)
+
+func TestVerifyH2HSignature(t *testing.T) {
+	key := &model.MemberAPIKey{APIKey: "gvs_key", APISecret: "s3cret"}
+	body := []byte(`{"api_key":"gvs_key","ref_id":"TRX-1"}`)
+
+	mac := hmac.New(sha256.New, []byte(key.APISecret))
+	mac.Write(body)
+	hmacSig := hex.EncodeToString(mac.Sum(nil))
+
+	md5Sig := md5Hex(key.APIKey + key.APISecret + "TRX-1")
+
+	tests := []struct {
+		name      string
+		sign      string // md5 "sign" field
+		headerSig string // X-Signature
+		body      []byte
+		payload   string
+		want      bool
+	}{
+		{name: "valid hmac header", headerSig: hmacSig, body: body, payload: "TRX-1", want: true},
+		{name: "hmac header upper case", headerSig: strings.ToUpper(hmacSig), body: body, payload: "TRX-1", want: true},
+		{name: "hmac over a different body", headerSig: hmacSig, body: []byte(`{"ref_id":"TRX-2"}`), payload: "TRX-1", want: false},
+		{name: "wrong hmac", headerSig: strings.Repeat("0", 64), body: body, payload: "TRX-1", want: false},
+		{name: "header wins over a valid md5 sign", sign: md5Sig, headerSig: "bad", body: body, payload: "TRX-1", want: false},
+		{name: "valid md5 sign", sign: md5Sig, body: body, payload: "TRX-1", want: true},
+		{name: "md5 sign upper case", sign: strings.ToUpper(md5Sig), body: body, payload: "TRX-1", want: true},
+		{name: "md5 sign for another payload", sign: md5Sig, body: body, payload: "TRX-2", want: false},
+		{name: "md5 sign for balance", sign: md5Hex("gvs_key" + "s3cret" + "balance"), body: body, payload: "balance", want: true},
+		{name: "no signature", body: body, payload: "TRX-1", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &model.H2HRequest{Sign: tt.sign}
+			if got := verifyH2HSignature(key, req, tt.body, tt.headerSig, tt.payload); got != tt.want {
+				t.Errorf("verifyH2HSignature() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyH2HSignatureWrongSecret(t *testing.T) {
+	body := []byte(`{}`)
+	sign := md5Hex("gvs_key" + "s3cret" + "balance")
+
+	other := &model.MemberAPIKey{APIKey: "gvs_key", APISecret: "other"}
+	if verifyH2HSignature(other, &model.H2HRequest{Sign: sign}, body, "", "balance") {
+		t.Error("md5 sign made with another secret was accepted")
+	}
+}
+
+func TestTrustedClientIP(t *testing.T) {
+	tests := []struct {
+		name        string
+		remoteAddr  string
+		xff         []string
+		trustedHops int
+		want        string
+	}{
+		{name: "no proxy", remoteAddr: "203.0.113.7:51234", want: "203.0.113.7"},
+		{name: "no proxy ignores spoofed header", remoteAddr: "203.0.113.7:51234", xff: []string{"10.0.0.1"}, want: "203.0.113.7"},
+		{name: "remote addr without port", remoteAddr: "203.0.113.7", want: "203.0.113.7"},
+		{name: "ipv6 remote addr", remoteAddr: "[2001:db8::1]:443", want: "2001:db8::1"},
+		{name: "one proxy", remoteAddr: "10.0.0.2:80", xff: []string{"198.51.100.9"}, trustedHops: 1, want: "198.51.100.9"},
+		{name: "one proxy, spoofed first hop", remoteAddr: "10.0.0.2:80", xff: []string{"1.1.1.1, 198.51.100.9"}, trustedHops: 1, want: "198.51.100.9"},
+		{name: "two proxies", remoteAddr: "10.0.0.3:80", xff: []string{"1.1.1.1, 198.51.100.9, 10.0.0.2"}, trustedHops: 2, want: "198.51.100.9"},
+		{name: "hops split across headers", remoteAddr: "10.0.0.3:80", xff: []string{"1.1.1.1", "198.51.100.9, 10.0.0.2"}, trustedHops: 2, want: "198.51.100.9"},
+		{name: "blank entries skipped", remoteAddr: "10.0.0.2:80", xff: []string{"198.51.100.9, ,"}, trustedHops: 1, want: "198.51.100.9"},
+		{name: "fewer hops than proxies", remoteAddr: "10.0.0.2:80", xff: []string{"198.51.100.9"}, trustedHops: 2, want: "10.0.0.2"},
+		{name: "proxy without header", remoteAddr: "10.0.0.2:80", trustedHops: 1, want: "10.0.0.2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/api/h2h/v1/transaction", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, v := range tt.xff {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+			if got := trustedClientIP(r, tt.trustedHops); got != tt.want {
+				t.Errorf("trustedClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"govershop-api/internal/repository"
+)
+
+// LedgerHandler handles balance ledger reconciliation
+type LedgerHandler struct {
+	ledgerRepo *repository.LedgerRepository
+}
+
+// NewLedgerHandler creates a new LedgerHandler
+func NewLedgerHandler(ledgerRepo *repository.LedgerRepository) *LedgerHandler {
+	return &LedgerHandler{
+		ledgerRepo: ledgerRepo,
+	}
+}
+
+// GetReconciliation handles GET /api/v1/admin/ledger/reconciliation
+// Returns the latest reconciliation run
+func (h *LedgerHandler) GetReconciliation(w http.ResponseWriter, r *http.Request) {
+	result, err := h.ledgerRepo.GetLatest(r.Context())
+	if err != nil {
+		log.Printf("Error getting reconciliation: %v", err)
+		InternalError(w, "Gagal mengambil hasil rekonsiliasi")
+		return
+	}
+	if result == nil {
+		NotFound(w, "Rekonsiliasi belum pernah dijalankan")
+		return
+	}
+
+	Success(w, "", result)
+}
+
+// RunReconciliation handles POST /api/v1/admin/ledger/reconciliation
+// Runs a reconciliation right away
+func (h *LedgerHandler) RunReconciliation(w http.ResponseWriter, r *http.Request) {
+	adminUsername, _ := r.Context().Value("user").(string)
+
+	result, err := h.ledgerRepo.Reconcile(r.Context(), adminUsername)
+	if err != nil {
+		log.Printf("Error running reconciliation: %v", err)
+		InternalError(w, "Gagal menjalankan rekonsiliasi")
+		return
+	}
+
+	Success(w, "Rekonsiliasi selesai", result)
+}
+
+// StartReconciliationJob checks member balances against the ledger every 6 hours
+func (h *LedgerHandler) StartReconciliationJob(ctx context.Context) {
+	interval := 6 * time.Hour
+	ticker := time.NewTicker(interval)
+
+	log.Printf("[Ledger] Reconciliation job initialized. Running every %v", interval)
+
+	go func() {
+		h.runReconciliation()
+
+		for {
+			select {
+			case <-ctx.Done():
+				ticker.Stop()
+				return
+			case <-ticker.C:
+				h.runReconciliation()
+			}
+		}
+	}()
+}
+
+func (h *LedgerHandler) runReconciliation() {
+	result, err := h.ledgerRepo.Reconcile(context.Background(), "system")
+	if err != nil {
+		log.Printf("[Ledger] Reconciliation failed: %v", err)
+		return
+	}
+	for _, m := range result.Mismatches {
+		log.Printf("[Ledger] MISMATCH user %d (%s): balance %.2f, ledger %.2f, difference %.2f",
+			m.UserID, m.Username, m.Balance, m.LedgerBalance, m.Difference)
+	}
+	if result.MismatchCount > 0 {
+		log.Printf("[Ledger] Reconciliation found %d mismatched balances out of %d members", result.MismatchCount, result.CheckedUsers)
+	}
+}
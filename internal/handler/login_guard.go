@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"govershop-api/internal/config"
+	"govershop-api/internal/model"
+	"govershop-api/internal/repository"
+	"govershop-api/internal/service/email"
+)
+
+// LoginGuard protects the admin and member logins against password guessing.
+// Every attempt is recorded in login_events; failed attempts count per account
+// (not per IP) and lock it with a lockout that doubles each time.
+type LoginGuard struct {
+	config    *config.Config
+	loginRepo *repository.LoginSecurityRepository
+	emailSvc  *email.Service
+}
+
+// NewLoginGuard creates a new LoginGuard
+func NewLoginGuard(cfg *config.Config, loginRepo *repository.LoginSecurityRepository, emailSvc *email.Service) *LoginGuard {
+	return &LoginGuard{
+		config:    cfg,
+		loginRepo: loginRepo,
+		emailSvc:  emailSvc,
+	}
+}
+
+// Check returns how long the account stays locked (0 if it is not).
+// Attempts on a locked account are recorded but not counted.
+func (g *LoginGuard) Check(r *http.Request, user *model.User, channel string) (time.Duration, error) {
+	lockout, err := g.loginRepo.GetLockout(r.Context(), user.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	if !lockout.Locked(now) {
+		return 0, nil
+	}
+
+	g.Record(r, user, user.Username, channel, model.LoginOutcomeLocked)
+	return lockout.LockedUntil.Sub(now), nil
+}
+
+// Fail records a failed attempt. user is nil for unknown usernames, which are
+// only recorded. Returns the lock duration when this attempt locked the account.
+func (g *LoginGuard) Fail(r *http.Request, user *model.User, username, channel, outcome string) time.Duration {
+	g.Record(r, user, username, channel, outcome)
+	if user == nil {
+		return 0
+	}
+
+	lockFor, err := g.loginRepo.RecordFailure(r.Context(), user.ID, trustedClientIP(r, g.config.TrustedProxyHops),
+		g.config.LoginMaxAttempts,
+		time.Duration(g.config.LoginLockoutMinutes)*time.Minute,
+		time.Duration(g.config.LoginLockoutMaxMinutes)*time.Minute,
+	)
+	if err != nil {
+		log.Printf("[LoginGuard] Failed to record failure for user %d: %v", user.ID, err)
+		return 0
+	}
+	if lockFor == 0 {
+		return 0
+	}
+
+	log.Printf("[LoginGuard] 🔒 Locked %s (id=%d) for %v after failed logins from %s", user.Username, user.ID, lockFor, trustedClientIP(r, g.config.TrustedProxyHops))
+	if user.Email != nil && *user.Email != "" {
+		toEmail, fullName, ip := *user.Email, user.FullName, trustedClientIP(r, g.config.TrustedProxyHops)
+		until := time.Now().Add(lockFor).Format("02 January 2006 15:04") + " WIB"
+		go func() {
+			if err := g.emailSvc.SendAccountLockedEmail(toEmail, fullName, ip, until); err != nil {
+				log.Printf("[LoginGuard] Failed to send lockout email: %v", err)
+			}
+		}()
+	}
+	return lockFor
+}
+
+// Succeed clears the failed attempts, records the login and emails the user
+// when it comes from a device their account has not logged in from before
+func (g *LoginGuard) Succeed(r *http.Request, user *model.User, channel string) {
+	ctx := r.Context()
+
+	if _, err := g.loginRepo.ClearLockout(ctx, user.ID); err != nil {
+		log.Printf("[LoginGuard] Failed to clear lockout for user %d: %v", user.ID, err)
+	}
+
+	device := deviceFromUserAgent(r.UserAgent())
+	hasHistory, known, err := g.loginRepo.KnownDevice(ctx, user.ID, device)
+	if err != nil {
+		log.Printf("[LoginGuard] %v", err)
+		known = true
+	}
+	// The first recorded login has nothing to compare against, so it is not alerted
+	newDevice := hasHistory && !known
+
+	g.record(ctx, r, user, user.Username, channel, model.LoginOutcomeSuccess, newDevice)
+
+	if newDevice && user.Email != nil && *user.Email != "" {
+		toEmail, fullName, ip := *user.Email, user.FullName, trustedClientIP(r, g.config.TrustedProxyHops)
+		loginTime := time.Now().Format("02 January 2006 15:04") + " WIB"
+		go func() {
+			if err := g.emailSvc.SendNewDeviceLoginEmail(toEmail, fullName, device, ip, loginTime); err != nil {
+				log.Printf("[LoginGuard] Failed to send new device email: %v", err)
+			}
+		}()
+	}
+}
+
+// Record stores a login attempt that does not count towards a lockout
+func (g *LoginGuard) Record(r *http.Request, user *model.User, username, channel, outcome string) {
+	g.record(r.Context(), r, user, username, channel, outcome, false)
+}
+
+func (g *LoginGuard) record(ctx context.Context, r *http.Request, user *model.User, username, channel, outcome string, newDevice bool) {
+	userAgent := r.UserAgent()
+	if len(userAgent) > 500 {
+		userAgent = userAgent[:500]
+	}
+	if len(username) > 100 {
+		username = username[:100]
+	}
+
+	event := &model.LoginEvent{
+		Username:  username,
+		Channel:   channel,
+		Outcome:   outcome,
+		IPAddress: trustedClientIP(r, g.config.TrustedProxyHops),
+		UserAgent: userAgent,
+		Device:    deviceFromUserAgent(userAgent),
+		NewDevice: newDevice,
+	}
+	if user != nil {
+		event.UserID = &user.ID
+	}
+	if err := g.loginRepo.CreateEvent(ctx, event); err != nil {
+		log.Printf("[LoginGuard] %v", err)
+	}
+}
+
+// lockedMessage sets Retry-After and returns the message for a locked account
+func lockedMessage(w http.ResponseWriter, lockFor time.Duration) string {
+	minutes := int(math.Ceil(lockFor.Minutes()))
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(lockFor.Seconds()))))
+	return fmt.Sprintf("Akun dikunci sementara karena terlalu banyak percobaan login gagal. Coba lagi dalam %d menit.", minutes)
+}
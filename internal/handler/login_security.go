@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"govershop-api/internal/model"
+	"govershop-api/internal/repository"
+)
+
+// LoginSecurityHandler lets admins review and clear login lockouts and see login history
+type LoginSecurityHandler struct {
+	loginRepo *repository.LoginSecurityRepository
+	userRepo  *repository.UserRepository
+}
+
+// NewLoginSecurityHandler creates a new LoginSecurityHandler
+func NewLoginSecurityHandler(loginRepo *repository.LoginSecurityRepository, userRepo *repository.UserRepository) *LoginSecurityHandler {
+	return &LoginSecurityHandler{
+		loginRepo: loginRepo,
+		userRepo:  userRepo,
+	}
+}
+
+// GetLockouts handles GET /api/v1/admin/login-lockouts
+// Lists accounts with failed logins; ?locked=true only returns accounts that are locked now
+func (h *LoginSecurityHandler) GetLockouts(w http.ResponseWriter, r *http.Request) {
+	lockedOnly := r.URL.Query().Get("locked") == "true"
+
+	lockouts, err := h.loginRepo.ListLockouts(r.Context(), lockedOnly)
+	if err != nil {
+		log.Printf("Error getting login lockouts: %v", err)
+		InternalError(w, "Gagal mengambil data akun terkunci")
+		return
+	}
+	if lockouts == nil {
+		lockouts = []model.LoginLockout{}
+	}
+
+	Success(w, "", lockouts)
+}
+
+// ClearLockout handles DELETE /api/v1/admin/login-lockouts/{id}
+// Unlocks an account and resets its failed attempts. Admin accounts need admins.manage.
+func (h *LoginSecurityHandler) ClearLockout(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		BadRequest(w, "Invalid user ID")
+		return
+	}
+
+	user, err := h.userRepo.GetByID(ctx, userID)
+	if err != nil || user == nil {
+		NotFound(w, "User tidak ditemukan")
+		return
+	}
+	if user.Role == model.UserRoleAdmin {
+		adminRole, _ := ctx.Value("admin_role").(string)
+		if !model.AdminHasPermission(adminRole, model.PermAdminsManage) {
+			Error(w, http.StatusForbidden, "Anda tidak memiliki akses untuk membuka kunci akun admin")
+			return
+		}
+	}
+
+	cleared, err := h.loginRepo.ClearLockout(ctx, userID)
+	if err != nil {
+		log.Printf("Error clearing login lockout: %v", err)
+		InternalError(w, "Gagal membuka kunci akun")
+		return
+	}
+	if !cleared {
+		NotFound(w, "Akun tidak sedang terkunci")
+		return
+	}
+
+	log.Printf("[LoginGuard] Lockout of %s (id=%d) cleared by %v", user.Username, user.ID, ctx.Value("user"))
+	Success(w, "Kunci akun berhasil dibuka", nil)
+}
+
+// GetMemberLoginHistory handles GET /api/v1/admin/members/{id}/login-history
+func (h *LoginSecurityHandler) GetMemberLoginHistory(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		BadRequest(w, "Invalid member ID")
+		return
+	}
+
+	user, err := h.userRepo.GetByID(r.Context(), userID)
+	if err != nil || user == nil || user.Role != model.UserRoleMember {
+		NotFound(w, "Member tidak ditemukan")
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	events, total, err := h.loginRepo.ListEvents(r.Context(), userID, limit, offset)
+	if err != nil {
+		log.Printf("Error getting login history: %v", err)
+		InternalError(w, "Gagal mengambil riwayat login")
+		return
+	}
+
+	lockout, err := h.loginRepo.GetLockout(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error getting login lockout: %v", err)
+	}
+
+	JSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"events":  events,
+		"total":   total,
+		"lockout": lockout,
+	})
+}
@@ -1,7 +1,9 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -21,12 +23,23 @@ import (
 
 // MemberHandler handles member-related HTTP requests
 type MemberHandler struct {
-	config       *config.Config
-	userRepo     *repository.UserRepository
-	productRepo  *repository.ProductRepository
-	orderRepo    *repository.OrderRepository
-	digiflazzSvc *digiflazz.Service
-	emailSvc     *email.Service
+	config        *config.Config
+	userRepo      *repository.UserRepository
+	productRepo   *repository.ProductRepository
+	orderRepo     *repository.OrderRepository
+	promoRepo     *repository.PromoRepository
+	flashSaleRepo *repository.FlashSaleRepository
+	pointsRepo    *repository.PointsRepository
+	digiflazzSvc  *digiflazz.Service
+	emailSvc      *email.Service
+
+	memberWebhookRepo  *repository.MemberWebhookRepository
+	memberSecurityRepo *repository.MemberSecurityRepository
+	sessionRepo        *repository.SessionRepository
+	fulfillment        *OrderFulfillment
+	favoriteRepo       *repository.FavoriteRepository
+	limitRepo          *repository.SpendingLimitRepository
+	loginGuard         *LoginGuard
 }
 
 // NewMemberHandler creates a new MemberHandler
@@ -35,16 +48,37 @@ func NewMemberHandler(
 	userRepo *repository.UserRepository,
 	productRepo *repository.ProductRepository,
 	orderRepo *repository.OrderRepository,
+	promoRepo *repository.PromoRepository,
+	flashSaleRepo *repository.FlashSaleRepository,
+	pointsRepo *repository.PointsRepository,
 	digiflazzSvc *digiflazz.Service,
 	emailSvc *email.Service,
+	memberWebhookRepo *repository.MemberWebhookRepository,
+	memberSecurityRepo *repository.MemberSecurityRepository,
+	sessionRepo *repository.SessionRepository,
+	fulfillment *OrderFulfillment,
+	favoriteRepo *repository.FavoriteRepository,
+	limitRepo *repository.SpendingLimitRepository,
+	loginGuard *LoginGuard,
 ) *MemberHandler {
 	return &MemberHandler{
-		config:       cfg,
-		userRepo:     userRepo,
-		productRepo:  productRepo,
-		orderRepo:    orderRepo,
-		digiflazzSvc: digiflazzSvc,
-		emailSvc:     emailSvc,
+		config:        cfg,
+		userRepo:      userRepo,
+		productRepo:   productRepo,
+		orderRepo:     orderRepo,
+		promoRepo:     promoRepo,
+		flashSaleRepo: flashSaleRepo,
+		pointsRepo:    pointsRepo,
+		digiflazzSvc:  digiflazzSvc,
+		emailSvc:      emailSvc,
+
+		memberWebhookRepo:  memberWebhookRepo,
+		memberSecurityRepo: memberSecurityRepo,
+		sessionRepo:        sessionRepo,
+		fulfillment:        fulfillment,
+		favoriteRepo:       favoriteRepo,
+		limitRepo:          limitRepo,
+		loginGuard:         loginGuard,
 	}
 }
 
@@ -69,61 +103,92 @@ func (h *MemberHandler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if user == nil {
+		h.loginGuard.Fail(r, nil, req.Username, model.SessionChannelMember, model.LoginOutcomeUnknownUser)
 		Unauthorized(w, "Username atau password salah")
 		return
 	}
 
 	// Verify only members can login here
 	if user.Role != model.UserRoleMember {
+		h.loginGuard.Fail(r, nil, req.Username, model.SessionChannelMember, model.LoginOutcomeUnknownUser)
 		Unauthorized(w, "Username atau password salah")
 		return
 	}
 
+	// Per-account lockout after too many failed attempts
+	lockFor, err := h.loginGuard.Check(r, user, model.SessionChannelMember)
+	if err != nil {
+		log.Printf("Error checking login lockout: %v", err)
+		InternalError(w, "Internal server error")
+		return
+	}
+	if lockFor > 0 {
+		Error(w, http.StatusTooManyRequests, lockedMessage(w, lockFor))
+		return
+	}
+
 	// Check if user is active
+	if user.Status == model.UserStatusPendingVerification {
+		h.loginGuard.Record(r, user, user.Username, model.SessionChannelMember, model.LoginOutcomeInactive)
+		Error(w, http.StatusForbidden, "Email belum diverifikasi. Silakan cek email Anda untuk link verifikasi.")
+		return
+	}
 	if user.Status != model.UserStatusActive {
+		h.loginGuard.Record(r, user, user.Username, model.SessionChannelMember, model.LoginOutcomeInactive)
 		Error(w, http.StatusForbidden, "Akun Anda telah dinonaktifkan. Hubungi admin.")
 		return
 	}
 
 	// Verify password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		if lockFor := h.loginGuard.Fail(r, user, user.Username, model.SessionChannelMember, model.LoginOutcomeInvalidPassword); lockFor > 0 {
+			Error(w, http.StatusTooManyRequests, lockedMessage(w, lockFor))
+			return
+		}
 		Unauthorized(w, "Username atau password salah")
 		return
 	}
 
-	// Generate JWT token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub":     user.Username,
-		"user_id": user.ID,
-		"role":    user.Role,
-		"exp":     time.Now().Add(24 * time.Hour).Unix(),
-	})
-
-	tokenString, err := token.SignedString([]byte(h.config.JWTSecretGovershop))
+	// Members with TOTP enabled get a short-lived pre-auth token for the second step
+	security, err := h.memberSecurityRepo.GetByUserID(r.Context(), user.ID)
 	if err != nil {
-		log.Printf("Error signing token: %v", err)
+		log.Printf("Error getting member security: %v", err)
 		InternalError(w, "Internal server error")
 		return
 	}
+	if security != nil && security.TOTPEnabled {
+		preAuthToken, err := h.signPreAuthToken(user.ID)
+		if err != nil {
+			log.Printf("Error signing pre-auth token: %v", err)
+			InternalError(w, "Internal server error")
+			return
+		}
 
-	// Set HTTP-only cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:     "member_token",
-		Value:    tokenString,
-		Expires:  time.Now().Add(24 * time.Hour),
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   h.config.Env == "production", // Secure in production
-		SameSite: http.SameSiteLaxMode,         // Lax is usually checking for CSRF but allows top-level nav. For API, None might be needed if cross-site?
-		// Since we are likely on same domain or handling via CORS with credentials:
-		// If frontend is localhost:3000 and backend localhost:8080, they are cross-port but same-site (localhost).
-		// However, some browsers treat ports differently.
-		// Let's use SameSiteLaxMode which usually works for localhost.
-	})
+		JSON(w, http.StatusOK, map[string]interface{}{
+			"success":        true,
+			"totp_required":  true,
+			"pre_auth_token": preAuthToken,
+		})
+		return
+	}
+
+	h.loginGuard.Succeed(r, user, model.SessionChannelMember)
+	h.writeMemberSession(w, r, user)
+}
+
+// writeMemberSession starts a session, sets the auth cookies and writes the login response
+func (h *MemberHandler) writeMemberSession(w http.ResponseWriter, r *http.Request, user *model.User) {
+	tokens, err := issueSession(r.Context(), w, r, h.config, h.sessionRepo, user, model.SessionChannelMember)
+	if err != nil {
+		log.Printf("Error creating session: %v", err)
+		InternalError(w, "Internal server error")
+		return
+	}
 
 	JSON(w, http.StatusOK, map[string]interface{}{
-		"success": true,
-		"user":    user.ToResponse(),
+		"success":    true,
+		"user":       user.ToResponse(),
+		"expires_in": tokens.ExpiresIn,
 	})
 }
 
@@ -254,6 +319,30 @@ func (h *MemberHandler) GetDashboard(w http.ResponseWriter, r *http.Request) {
 		total, success, pending, today = 0, 0, 0, 0
 	}
 
+	// Get loyalty points
+	points, err := h.pointsRepo.GetSummary(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error getting points summary: %v", err)
+		points = &model.PointsSummary{}
+	}
+	points.RedeemRate = h.config.PointsRedeemRate
+	points.MinRedeem = h.config.PointsMinRedeem
+
+	pointsHistory, _, err := h.pointsRepo.GetHistory(r.Context(), userID, 5, 0)
+	if err != nil {
+		log.Printf("Error getting points history: %v", err)
+	}
+	if pointsHistory == nil {
+		pointsHistory = []model.PointEntry{}
+	}
+
+	limits, err := h.limitRepo.GetUsage(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error getting spending usage: %v", err)
+	} else {
+		limits.Override = nil
+	}
+
 	JSON(w, http.StatusOK, map[string]interface{}{
 		"success": true,
 		"data": model.MemberDashboardResponse{
@@ -262,6 +351,11 @@ func (h *MemberHandler) GetDashboard(w http.ResponseWriter, r *http.Request) {
 			SuccessOrders: success,
 			PendingOrders: pending,
 			TodayOrders:   today,
+
+			Points:        points,
+			PointsHistory: pointsHistory,
+
+			Limits: limits,
 		},
 	})
 }
@@ -297,7 +391,7 @@ func (h *MemberHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 		updates["whatsapp"] = *req.WhatsApp
 	}
 
-	if err := h.userRepo.Update(r.Context(), userID, updates); err != nil {
+	if _, err := h.userRepo.Update(r.Context(), userID, updates); err != nil {
 		log.Printf("Error updating profile: %v", err)
 		InternalError(w, "Failed to update profile")
 		return
@@ -410,7 +504,6 @@ func (h *MemberHandler) GetProductBySku(w http.ResponseWriter, r *http.Request)
 func (h *MemberHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	userID := ctx.Value("user_id").(int)
-	// username is not used
 
 	var req model.MemberOrderRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -418,41 +511,242 @@ func (h *MemberHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.BuyerSKUCode == "" || req.DestinationNumber == "" {
-		BadRequest(w, "Produk dan nomor tujuan wajib diisi")
+	req.ClientRefID = strings.TrimSpace(req.ClientRefID)
+	if len(req.ClientRefID) > 100 {
+		BadRequest(w, "client_ref_id maksimal 100 karakter")
+		return
+	}
+
+	// A saved favorite can stand in for the destination number
+	var favorite *model.MemberFavorite
+	if req.FavoriteID != 0 && strings.TrimSpace(req.DestinationNumber) == "" {
+		var err error
+		favorite, err = h.resolveFavorite(ctx, userID, req.FavoriteID, req.BuyerSKUCode)
+		if err != nil {
+			var oErr *memberOrderError
+			if errors.As(err, &oErr) {
+				writeMemberOrderError(w, oErr)
+				return
+			}
+			InternalError(w, "Gagal memproses transaksi")
+			return
+		}
+		req.DestinationNumber = favorite.CustomerNo
+	}
+
+	order, err := h.placeOrder(ctx, userID, req, "member", req.ClientRefID)
+	if err != nil {
+		var oErr *memberOrderError
+		if errors.As(err, &oErr) {
+			writeMemberOrderError(w, oErr)
+			return
+		}
+		InternalError(w, "Gagal memproses transaksi")
 		return
 	}
 
+	if favorite != nil {
+		if err := h.favoriteRepo.MarkUsed(ctx, userID, favorite.ID); err != nil {
+			log.Printf("Error marking favorite used: %v", err)
+		}
+	}
+
+	// Get latest user balance
+	user, _ := h.userRepo.GetByID(ctx, userID)
+
+	message := "Transaksi sedang diproses"
+	if order.Status == model.OrderStatusSuccess {
+		message = "Transaksi berhasil"
+	}
+
+	JSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": message,
+		"data": map[string]interface{}{
+			"order_id":      order.ID,
+			"ref_id":        order.RefID,
+			"status":        order.Status,
+			"serial_number": order.SerialNumber,
+			"balance":       user.Balance,
+		},
+	})
+}
+
+// spendingLimitMessage is the user-facing message of a spending limit error
+func spendingLimitMessage(e *repository.SpendingLimitError) string {
+	switch e.Code {
+	case model.LimitCodeOrderAmount:
+		return fmt.Sprintf("Nominal transaksi melebihi batas Rp %.0f per transaksi", e.Limit)
+	case model.LimitCodeDailySpend:
+		return fmt.Sprintf("Batas belanja harian Rp %.0f sudah tercapai", e.Limit)
+	case model.LimitCodeHourlyOrder:
+		return fmt.Sprintf("Batas %.0f transaksi per jam sudah tercapai", e.Limit)
+	case model.LimitCodeBrand:
+		return "Produk ini tidak diizinkan untuk akun Anda"
+	}
+	return "Transaksi melebihi batas akun Anda"
+}
+
+// resolveFavorite returns a member's favorite for an order of sku, checking that it
+// was saved for the product's brand
+func (h *MemberHandler) resolveFavorite(ctx context.Context, userID, favoriteID int, sku string) (*model.MemberFavorite, error) {
+	favorite, err := h.favoriteRepo.GetByID(ctx, userID, favoriteID)
+	if err != nil {
+		log.Printf("Error getting favorite: %v", err)
+		return nil, err
+	}
+	if favorite == nil {
+		return nil, newMemberOrderError(http.StatusNotFound, orderErrInvalidRequest, "Favorit tidak ditemukan")
+	}
+
+	product, err := h.productRepo.GetBySKU(ctx, sku)
+	if err != nil {
+		log.Printf("Error getting product: %v", err)
+		return nil, err
+	}
+	if product != nil && !sameBrand(product.Brand, favorite.Brand) {
+		return nil, newMemberOrderError(http.StatusBadRequest, orderErrInvalidRequest,
+			fmt.Sprintf("Favorit ini untuk %s, bukan %s", favorite.Brand, product.Brand))
+	}
+
+	return favorite, nil
+}
+
+// sameBrand compares brand names ignoring case and spaces
+func sameBrand(a, b string) bool {
+	normalize := func(s string) string { return strings.ToLower(strings.ReplaceAll(s, " ", "")) }
+	return normalize(a) == normalize(b)
+}
+
+// Member order error codes (stable, exposed by the H2H API)
+const (
+	orderErrInvalidRequest      = "invalid_request"
+	orderErrProductUnavailable  = "product_unavailable"
+	orderErrPromoInvalid        = "promo_invalid"
+	orderErrInsufficientBalance = "insufficient_balance"
+	orderErrFlashSaleSoldOut    = "flash_sale_sold_out"
+	orderErrDuplicateRefID      = "duplicate_ref_id"
+	orderErrProvider            = "provider_error"
+	orderErrTOTPRequired        = "totp_required"
+	orderErrInternal            = "internal_error"
+)
+
+// memberOrderError is a user-facing reason why a member order could not be placed
+type memberOrderError struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *memberOrderError) Error() string {
+	return e.message
+}
+
+func newMemberOrderError(status int, code, message string) *memberOrderError {
+	return &memberOrderError{status: status, code: code, message: message}
+}
+
+// writeMemberOrderError writes a member order error with its code, so clients can
+// tell e.g. which spending limit was hit
+func writeMemberOrderError(w http.ResponseWriter, e *memberOrderError) {
+	resp := map[string]interface{}{
+		"success": false,
+		"error":   e.message,
+		"code":    e.code,
+	}
+	if e.code == orderErrTOTPRequired {
+		resp["totp_required"] = true
+	}
+	JSON(w, e.status, resp)
+}
+
+// orderPayment takes an order's amount from the member's balance
+type orderPayment func(ctx context.Context, amount float64, brand, description, refID string) error
+
+// orderOptions changes how placeOrderWith pays for and authorizes an order
+type orderOptions struct {
+	pay      orderPayment // nil deducts from the member's balance
+	skipTOTP bool         // TOTP was already checked by the caller (e.g. for a whole batch)
+}
+
+// placeOrder prices the product for the member, deducts balance, creates the order
+// and sends it to Digiflazz. Balance is refunded if any step after the deduction fails.
+// source is the order_source ("member", "h2h"); clientRefID is the reseller's own ref ID (H2H, batch).
+func (h *MemberHandler) placeOrder(ctx context.Context, userID int, req model.MemberOrderRequest, source, clientRefID string) (*model.Order, error) {
+	return h.placeOrderWith(ctx, userID, req, source, clientRefID, orderOptions{})
+}
+
+// placeOrderWith is placeOrder with a custom payment or TOTP handling
+func (h *MemberHandler) placeOrderWith(ctx context.Context, userID int, req model.MemberOrderRequest, source, clientRefID string, opts orderOptions) (*model.Order, error) {
+	if req.BuyerSKUCode == "" || req.DestinationNumber == "" {
+		return nil, newMemberOrderError(http.StatusBadRequest, orderErrInvalidRequest, "Produk dan nomor tujuan wajib diisi")
+	}
+
 	// 1. Get Product
 	product, err := h.productRepo.GetBySKU(ctx, req.BuyerSKUCode)
 	if err != nil {
 		log.Printf("Error getting product: %v", err)
-		InternalError(w, "Internal server error")
-		return
+		return nil, newMemberOrderError(http.StatusInternalServerError, orderErrInternal, "Internal server error")
 	}
 	if product == nil || !product.IsAvailable {
-		BadRequest(w, "Produk tidak tersedia")
-		return
+		return nil, newMemberOrderError(http.StatusBadRequest, orderErrProductUnavailable, "Produk tidak tersedia")
 	}
 
 	// 2. Calculate Member Price
 	defaultMarkup := 0.0 // Default member markup
 	resp := product.ToMemberResponse(defaultMarkup)
-	amount := resp.Price
+	basePrice := resp.Price
+
+	// Apply promo code if provided
+	var promo *model.Promo
+	var discount float64
+	if strings.TrimSpace(req.PromoCode) != "" {
+		memberID := userID
+		promo, discount, err = applyPromoCode(ctx, h.promoRepo, h.orderRepo, req.PromoCode, product, basePrice, &promoCustomer{MemberID: &memberID})
+		if err != nil {
+			var pErr *promoError
+			if errors.As(err, &pErr) {
+				return nil, newMemberOrderError(http.StatusBadRequest, orderErrPromoInvalid, pErr.Error())
+			}
+			return nil, newMemberOrderError(http.StatusInternalServerError, orderErrInternal, "Gagal memeriksa kode promo")
+		}
+	}
+	amount := basePrice - discount
+
+	// Large orders from the member area need a TOTP code when the member opted in.
+	// H2H orders are authenticated by signature instead.
+	if source == "member" && !opts.skipTOTP {
+		security, err := h.memberSecurityRepo.GetByUserID(ctx, userID)
+		if err != nil {
+			log.Printf("Error getting member security: %v", err)
+			return nil, newMemberOrderError(http.StatusInternalServerError, orderErrInternal, "Internal server error")
+		}
+		if security.RequiresTOTPForOrder(amount) && !validTOTPCode(req.TOTPCode, security.TOTPSecret) {
+			return nil, newMemberOrderError(http.StatusUnauthorized, orderErrTOTPRequired, "Kode TOTP diperlukan untuk transaksi ini")
+		}
+	}
 
 	// 3. Generate Order Ref ID (INV-...)
 	refID := fmt.Sprintf("INV-%d-%s", time.Now().Unix(), generateRandomString(5))
 
 	// 4. Deduct Balance (Transaction)
+	pay := opts.pay
+	if pay == nil {
+		pay = func(ctx context.Context, amount float64, brand, description, refID string) error {
+			return h.userRepo.DeductOrderBalance(ctx, userID, amount, brand, description, refID)
+		}
+	}
 	description := fmt.Sprintf("Pembelian %s - %s", product.ProductName, req.DestinationNumber)
-	if err := h.userRepo.DeductBalance(ctx, userID, amount, description, refID); err != nil {
+	if err := pay(ctx, amount, product.Brand, description, refID); err != nil {
 		log.Printf("Error deducting balance: %v", err)
-		if err.Error() == "insufficient balance" {
-			BadRequest(w, "Saldo tidak mencukupi")
-		} else {
-			InternalError(w, "Gagal memproses transaksi")
+		if errors.Is(err, repository.ErrInsufficientBalance) {
+			return nil, newMemberOrderError(http.StatusBadRequest, orderErrInsufficientBalance, "Saldo tidak mencukupi")
 		}
-		return
+		var limitErr *repository.SpendingLimitError
+		if errors.As(err, &limitErr) {
+			return nil, newMemberOrderError(http.StatusForbidden, limitErr.Code, spendingLimitMessage(limitErr))
+		}
+		return nil, newMemberOrderError(http.StatusInternalServerError, orderErrInternal, "Gagal memproses transaksi")
 	}
 
 	// 5. Create Order
@@ -468,7 +762,19 @@ func (h *MemberHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 		Status:       model.OrderStatusProcessing, // Already paid via balance
 		SellingPrice: amount,
 		BuyPrice:     product.BuyPrice,
-		OrderSource:  "member",
+		OrderSource:  source,
+		ClientRefID:  clientRefID,
+	}
+
+	if promo != nil {
+		order.PromoID = &promo.ID
+		order.PromoCode = *promo.Code
+		order.DiscountAmount = discount
+	}
+
+	// Flash sale price consumes quota
+	if resp.IsPromo {
+		order.FlashSaleItemID = flashSaleItemFor(ctx, h.flashSaleRepo, product.BuyerSKUCode, basePrice)
 	}
 
 	if err := h.orderRepo.Create(ctx, order); err != nil {
@@ -476,52 +782,31 @@ func (h *MemberHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 
 		// REFUND BALANCE
 		refundDesc := fmt.Sprintf("Refund Failed Order %s", refID)
-		if refundErr := h.userRepo.TopupBalance(ctx, userID, amount, refundDesc, "SYSTEM"); refundErr != nil {
+		if refundErr := h.userRepo.RefundBalance(ctx, userID, amount, refundDesc, refID); refundErr != nil {
 			log.Printf("CRITICAL: Failed to refund balance. UserID: %d, Amount: %f. Error: %v", userID, amount, refundErr)
 		}
 
-		InternalError(w, "Gagal membuat order. Saldo telah dikembalikan.")
-		return
+		if errors.Is(err, repository.ErrFlashSaleSoldOut) {
+			return nil, newMemberOrderError(http.StatusBadRequest, orderErrFlashSaleSoldOut, "Kuota flash sale sudah habis, harga kembali normal. Saldo telah dikembalikan.")
+		}
+		if msg, ok := promoErrorMessage(err); ok {
+			return nil, newMemberOrderError(http.StatusBadRequest, orderErrPromoInvalid, msg+". Saldo telah dikembalikan.")
+		}
+		if clientRefID != "" && strings.Contains(err.Error(), "idx_orders_member_client_ref") {
+			return nil, newMemberOrderError(http.StatusConflict, orderErrDuplicateRefID, "ref_id sudah digunakan. Saldo telah dikembalikan.")
+		}
+		return nil, newMemberOrderError(http.StatusInternalServerError, orderErrInternal, "Gagal membuat order. Saldo telah dikembalikan.")
 	}
 
-	// 6. Call Digiflazz
-	// Use Digiflazz Service to topup
-	dfReq := digiflazz.TopupRequest{
-		BuyerSKUCode: product.BuyerSKUCode,
-		CustomerNo:   req.DestinationNumber,
-		RefID:        refID,
+	// 6. Submit to Digiflazz; the immediate result is recorded, refunded and notified
+	if err := h.fulfillment.Submit(ctx, order); err != nil {
+		return order, newMemberOrderError(http.StatusInternalServerError, orderErrProvider, "Gagal memproses ke provider. Saldo dikembalikan.")
 	}
-
-	_, err = h.digiflazzSvc.CreateTransaction(dfReq)
-
-	// Handle failure calling Digiflazz
-	if err != nil {
-		log.Printf("Error calling Digiflazz: %v", err)
-
-		// Mark order as Failed and Refund
-		h.orderRepo.UpdateStatus(ctx, order.ID, model.OrderStatusFailed)
-
-		refundDesc := fmt.Sprintf("Refund Gagal Transaksi %s", refID)
-		h.userRepo.TopupBalance(ctx, userID, amount, refundDesc, "SYSTEM")
-
-		InternalError(w, "Gagal memproses ke provider. Saldo dikembalikan.")
-		return
+	if order.Status == model.OrderStatusFailed {
+		return order, newMemberOrderError(http.StatusBadRequest, orderErrProvider, fmt.Sprintf("Transaksi gagal: %s. Saldo dikembalikan.", order.DigiflazzMsg))
 	}
 
-	// 7. Return Success
-	// Get latest user balance
-	user, _ := h.userRepo.GetByID(ctx, userID)
-
-	JSON(w, http.StatusOK, map[string]interface{}{
-		"success": true,
-		"message": "Transaksi sedang diproses",
-		"data": map[string]interface{}{
-			"order_id": order.ID,
-			"ref_id":   order.RefID,
-			"status":   order.Status,
-			"balance":  user.Balance,
-		},
-	})
+	return order, nil
 }
 
 // ValidateMemberAccount handles POST /api/v1/member/validate-account
@@ -576,7 +861,7 @@ func (h *MemberHandler) ValidateMemberAccount(w http.ResponseWriter, r *http.Req
 	// 1. Deduct balance first
 	description := fmt.Sprintf("Check User %s - %s", req.Brand, req.CustomerNo)
 	if err := h.userRepo.DeductBalance(ctx, userID, validationFee, description, refID); err != nil {
-		if err.Error() == "insufficient balance" {
+		if errors.Is(err, repository.ErrInsufficientBalance) {
 			BadRequest(w, "Saldo tidak mencukupi untuk melakukan pengecekan ID")
 		} else {
 			InternalError(w, "Gagal memproses transaksi pengecekan ID")
@@ -602,7 +887,7 @@ func (h *MemberHandler) ValidateMemberAccount(w http.ResponseWriter, r *http.Req
 
 	if err := h.orderRepo.Create(ctx, order); err != nil {
 		// Refund since we can't save order
-		h.userRepo.TopupBalance(ctx, userID, validationFee, fmt.Sprintf("Refund Gagal System %s", refID), "SYSTEM")
+		h.userRepo.RefundBalance(ctx, userID, validationFee, fmt.Sprintf("Refund Gagal System %s", refID), refID)
 		InternalError(w, "Gagal membuat riwayat. Saldo dikembalikan.")
 		return
 	}
@@ -617,7 +902,7 @@ func (h *MemberHandler) ValidateMemberAccount(w http.ResponseWriter, r *http.Req
 
 	if digiErr != nil {
 		h.orderRepo.UpdateStatus(ctx, order.ID, model.OrderStatusFailed)
-		h.userRepo.TopupBalance(ctx, userID, validationFee, fmt.Sprintf("Refund Gagal Provider %s", refID), "SYSTEM")
+		h.userRepo.RefundBalance(ctx, userID, validationFee, fmt.Sprintf("Refund Gagal Provider %s", refID), refID)
 		InternalError(w, "Gagal validasi ke provider. Saldo dikembalikan.")
 		return
 	}
@@ -668,7 +953,14 @@ func (h *MemberHandler) ValidateMemberAccount(w http.ResponseWriter, r *http.Req
 	// So if status is Failed (isValid == false and not pending), we refund!
 	if updateStatus == model.OrderStatusFailed {
 		refundDesc := fmt.Sprintf("Refund Invalid ID %s", refID)
-		h.userRepo.TopupBalance(ctx, userID, validationFee, refundDesc, "SYSTEM")
+		h.userRepo.RefundBalance(ctx, userID, validationFee, refundDesc, refID)
+	}
+
+	// Keep the account name on matching favorites
+	if isValid && resp.Data.SN != "" {
+		if err := h.favoriteRepo.SetAccountName(ctx, userID, req.Brand, req.CustomerNo, resp.Data.SN); err != nil {
+			log.Printf("Error saving favorite account name: %v", err)
+		}
 	}
 
 	Success(w, "Validasi selesai", map[string]interface{}{
@@ -752,6 +1044,7 @@ func (h *MemberHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		CurrentPassword string `json:"current_password"`
 		NewPassword     string `json:"new_password"`
+		TOTPCode        string `json:"totp_code,omitempty"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		BadRequest(w, "Invalid request body")
@@ -781,6 +1074,18 @@ func (h *MemberHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Verify TOTP if the member requires it for password changes
+	security, err := h.memberSecurityRepo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error getting member security: %v", err)
+		InternalError(w, "Internal server error")
+		return
+	}
+	if security.RequiresTOTPForPassword() && !validTOTPCode(req.TOTPCode, security.TOTPSecret) {
+		Unauthorized(w, "Kode TOTP tidak valid")
+		return
+	}
+
 	// Hash new password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), 12)
 	if err != nil {
@@ -851,6 +1156,12 @@ func (h *MemberHandler) CreateMember(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	referrer, err := resolveReferrer(r.Context(), h.userRepo, req.ReferralCode)
+	if err != nil {
+		BadRequest(w, "Kode referral tidak valid")
+		return
+	}
+
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), 12)
 	if err != nil {
 		log.Printf("Error hashing password: %v", err)
@@ -858,6 +1169,7 @@ func (h *MemberHandler) CreateMember(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	referralCode := generateReferralCode()
 	user := &model.User{
 		Username: req.Username,
 		Password: string(hashedPassword),
@@ -865,6 +1177,12 @@ func (h *MemberHandler) CreateMember(w http.ResponseWriter, r *http.Request) {
 		Role:     model.UserRoleMember,
 		Balance:  0,
 		Status:   model.UserStatusActive,
+
+		ReferralCode: &referralCode,
+	}
+
+	if referrer != nil {
+		user.ReferredBy = &referrer.ID
 	}
 
 	if req.Email != "" {
@@ -875,6 +1193,9 @@ func (h *MemberHandler) CreateMember(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.userRepo.Create(r.Context(), user); err != nil {
+		if writeUniqueUserError(w, err) {
+			return
+		}
 		log.Printf("Error creating member: %v", err)
 		InternalError(w, "Failed to create member")
 		return
@@ -936,11 +1257,26 @@ func (h *MemberHandler) UpdateMember(w http.ResponseWriter, r *http.Request) {
 		updates["status"] = *req.Status
 	}
 
-	if err := h.userRepo.Update(r.Context(), id, updates); err != nil {
+	updated, err := h.userRepo.Update(r.Context(), id, updates)
+	if err != nil {
+		if writeUniqueUserError(w, err) {
+			return
+		}
 		log.Printf("Error updating member: %v", err)
 		InternalError(w, "Failed to update member")
 		return
 	}
+	if !updated {
+		NotFound(w, "Member not found")
+		return
+	}
+
+	// Suspending (or un-verifying) a member ends all of their sessions immediately
+	if req.Status != nil && *req.Status != model.UserStatusActive {
+		if err := h.sessionRepo.RevokeAllForUser(r.Context(), id, model.SessionRevokeSuspended); err != nil {
+			log.Printf("Error revoking sessions for member %d: %v", id, err)
+		}
+	}
 
 	Success(w, "Member berhasil diupdate", nil)
 }
@@ -954,11 +1290,16 @@ func (h *MemberHandler) DeleteMember(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.userRepo.Delete(r.Context(), id); err != nil {
+	deleted, err := h.userRepo.Delete(r.Context(), id)
+	if err != nil {
 		log.Printf("Error deleting member: %v", err)
 		InternalError(w, "Failed to delete member")
 		return
 	}
+	if !deleted {
+		NotFound(w, "Member not found")
+		return
+	}
 
 	Success(w, "Member berhasil dinonaktifkan", nil)
 }
@@ -983,6 +1324,17 @@ func (h *MemberHandler) TopupMember(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	member, err := h.userRepo.GetByID(r.Context(), id)
+	if err != nil {
+		log.Printf("Error getting member: %v", err)
+		InternalError(w, "Failed to get member")
+		return
+	}
+	if member == nil || member.Role != model.UserRoleMember {
+		NotFound(w, "Member not found")
+		return
+	}
+
 	adminUsername := r.Context().Value("user").(string)
 
 	description := req.Description
@@ -0,0 +1,502 @@
+package handler
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"govershop-api/internal/config"
+	"govershop-api/internal/model"
+	"govershop-api/internal/repository"
+)
+
+// batchMaxUploadBytes caps the size of a bulk order upload (JSON or CSV)
+const batchMaxUploadBytes = 2 << 20
+
+// BatchHandler handles member bulk orders
+type BatchHandler struct {
+	config             *config.Config
+	batchRepo          *repository.BatchRepository
+	productRepo        *repository.ProductRepository
+	orderRepo          *repository.OrderRepository
+	memberSecurityRepo *repository.MemberSecurityRepository
+	memberHandler      *MemberHandler
+}
+
+// NewBatchHandler creates a new BatchHandler
+func NewBatchHandler(
+	cfg *config.Config,
+	batchRepo *repository.BatchRepository,
+	productRepo *repository.ProductRepository,
+	orderRepo *repository.OrderRepository,
+	memberSecurityRepo *repository.MemberSecurityRepository,
+	memberHandler *MemberHandler,
+) *BatchHandler {
+	return &BatchHandler{
+		config:             cfg,
+		batchRepo:          batchRepo,
+		productRepo:        productRepo,
+		orderRepo:          orderRepo,
+		memberSecurityRepo: memberSecurityRepo,
+		memberHandler:      memberHandler,
+	}
+}
+
+// CreateBatch handles POST /api/v1/member/batches
+// Accepts a JSON body {"lines": [...]} or a multipart CSV upload (field "file").
+// All lines are validated and the total is reserved before anything is ordered.
+func (h *BatchHandler) CreateBatch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value("user_id").(int)
+	username, _ := ctx.Value("user").(string)
+
+	r.Body = http.MaxBytesReader(w, r.Body, batchMaxUploadBytes)
+
+	req, err := parseBatchRequest(r)
+	if err != nil {
+		BadRequest(w, err.Error())
+		return
+	}
+
+	if len(req.Lines) == 0 {
+		BadRequest(w, "Batch tidak berisi order")
+		return
+	}
+	if len(req.Lines) > h.config.BatchMaxLines {
+		BadRequest(w, fmt.Sprintf("Maksimal %d order per batch", h.config.BatchMaxLines))
+		return
+	}
+
+	lines, lineErrors, err := h.validateLines(ctx, userID, req.Lines)
+	if err != nil {
+		log.Printf("Error validating batch: %v", err)
+		InternalError(w, "Gagal memvalidasi batch")
+		return
+	}
+	if len(lineErrors) > 0 {
+		JSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success":     false,
+			"error":       fmt.Sprintf("%d baris tidak valid, batch tidak diproses", len(lineErrors)),
+			"line_errors": lineErrors,
+		})
+		return
+	}
+
+	var total float64
+	for _, l := range lines {
+		total += l.Price
+	}
+
+	security, err := h.memberSecurityRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		log.Printf("Error getting member security: %v", err)
+		InternalError(w, "Internal server error")
+		return
+	}
+	if security.RequiresTOTPForOrder(total) && !validTOTPCode(req.TOTPCode, security.TOTPSecret) {
+		JSON(w, http.StatusUnauthorized, map[string]interface{}{
+			"success":       false,
+			"error":         "Kode TOTP diperlukan untuk transaksi ini",
+			"totp_required": true,
+		})
+		return
+	}
+
+	batch := &model.OrderBatch{
+		RefID:  fmt.Sprintf("BATCH-%d-%s", time.Now().Unix(), generateRandomString(5)),
+		UserID: userID,
+	}
+	if err := h.batchRepo.Create(ctx, batch, lines, username); err != nil {
+		if errors.Is(err, repository.ErrInsufficientBalance) {
+			BadRequest(w, fmt.Sprintf("Saldo tidak mencukupi untuk batch ini (total Rp %.0f)", total))
+			return
+		}
+		log.Printf("Error creating batch: %v", err)
+		InternalError(w, "Gagal membuat batch")
+		return
+	}
+
+	log.Printf("[Batch] User %d submitted %s: %d lines, Rp %.0f reserved", userID, batch.RefID, batch.TotalLines, batch.ReservedAmount)
+
+	JSON(w, http.StatusAccepted, map[string]interface{}{
+		"success": true,
+		"message": "Batch diterima dan sedang diproses",
+		"data":    batch,
+	})
+}
+
+// parseBatchRequest reads the lines from a JSON body or a CSV upload
+func parseBatchRequest(r *http.Request) (*model.OrderBatchRequest, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	switch mediaType {
+	case "multipart/form-data":
+		if err := r.ParseMultipartForm(batchMaxUploadBytes); err != nil {
+			return nil, fmt.Errorf("Upload tidak valid")
+		}
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			return nil, fmt.Errorf("File CSV wajib diunggah (field \"file\")")
+		}
+		defer file.Close()
+
+		lines, err := parseBatchCSV(file)
+		if err != nil {
+			return nil, err
+		}
+		return &model.OrderBatchRequest{Lines: lines, TOTPCode: r.FormValue("totp_code")}, nil
+
+	case "text/csv":
+		lines, err := parseBatchCSV(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		return &model.OrderBatchRequest{Lines: lines, TOTPCode: r.Header.Get("X-TOTP-Code")}, nil
+
+	default:
+		var req model.OrderBatchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, fmt.Errorf("Format request tidak valid")
+		}
+		return &req, nil
+	}
+}
+
+// parseBatchCSV reads lines from a CSV with a header row. Columns: buyer_sku_code (or sku),
+// destination_number (or destination, customer_no) and optionally client_ref_id (or ref_id).
+func parseBatchCSV(src io.Reader) ([]model.OrderBatchLineRequest, error) {
+	reader := csv.NewReader(src)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("File CSV kosong atau tidak valid")
+	}
+
+	skuCol, destCol, refCol := -1, -1, -1
+	for i, name := range header {
+		switch strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))) {
+		case "buyer_sku_code", "sku":
+			skuCol = i
+		case "destination_number", "destination", "customer_no":
+			destCol = i
+		case "client_ref_id", "ref_id":
+			refCol = i
+		}
+	}
+	if skuCol < 0 || destCol < 0 {
+		return nil, fmt.Errorf("Header CSV harus berisi kolom buyer_sku_code dan destination_number")
+	}
+
+	field := func(record []string, col int) string {
+		if col < 0 || col >= len(record) {
+			return ""
+		}
+		return record[col]
+	}
+
+	var lines []model.OrderBatchLineRequest
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("File CSV tidak valid: %v", err)
+		}
+		// Skip blank rows
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+		lines = append(lines, model.OrderBatchLineRequest{
+			BuyerSKUCode:      field(record, skuCol),
+			DestinationNumber: field(record, destCol),
+			ClientRefID:       field(record, refCol),
+		})
+	}
+
+	return lines, nil
+}
+
+// validateLines checks every line and prices it at the member price.
+// Returns the lines to store, or the reasons lines were rejected.
+func (h *BatchHandler) validateLines(ctx context.Context, userID int, reqLines []model.OrderBatchLineRequest) ([]model.OrderBatchLine, []model.OrderBatchLineError, error) {
+	products := make(map[string]*model.Product)
+	seenRefs := make(map[string]int)
+
+	var lines []model.OrderBatchLine
+	var lineErrors []model.OrderBatchLineError
+	reject := func(lineNo int, msg string) {
+		lineErrors = append(lineErrors, model.OrderBatchLineError{LineNo: lineNo, Error: msg})
+	}
+
+	for i, req := range reqLines {
+		lineNo := i + 1
+		sku := strings.TrimSpace(req.BuyerSKUCode)
+		dest := strings.TrimSpace(req.DestinationNumber)
+		clientRef := strings.TrimSpace(req.ClientRefID)
+
+		if sku == "" || dest == "" {
+			reject(lineNo, "Produk dan nomor tujuan wajib diisi")
+			continue
+		}
+		if len(dest) > 100 {
+			reject(lineNo, "Nomor tujuan terlalu panjang")
+			continue
+		}
+
+		if clientRef != "" {
+			if len(clientRef) > 100 {
+				reject(lineNo, "client_ref_id maksimal 100 karakter")
+				continue
+			}
+			if first, dup := seenRefs[clientRef]; dup {
+				reject(lineNo, fmt.Sprintf("client_ref_id sama dengan baris %d", first))
+				continue
+			}
+			seenRefs[clientRef] = lineNo
+
+			existing, err := h.orderRepo.GetByClientRefID(ctx, userID, clientRef)
+			if err != nil {
+				return nil, nil, err
+			}
+			if existing != nil {
+				reject(lineNo, "client_ref_id sudah digunakan")
+				continue
+			}
+		}
+
+		product, cached := products[sku]
+		if !cached {
+			p, err := h.productRepo.GetBySKU(ctx, sku)
+			if err != nil {
+				return nil, nil, err
+			}
+			product = p
+			products[sku] = p
+		}
+		if product == nil || !product.IsAvailable {
+			reject(lineNo, "Produk tidak tersedia")
+			continue
+		}
+
+		// Same price placeOrder charges (no promo codes in batches)
+		price := product.ToMemberResponse(0).Price
+
+		lines = append(lines, model.OrderBatchLine{
+			LineNo:            lineNo,
+			BuyerSKUCode:      product.BuyerSKUCode,
+			DestinationNumber: dest,
+			ClientRefID:       clientRef,
+			Price:             price,
+		})
+	}
+
+	return lines, lineErrors, nil
+}
+
+// GetBatches handles GET /api/v1/member/batches
+func (h *BatchHandler) GetBatches(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	if limit <= 0 || limit > 100 {
+		limit = 10
+	}
+
+	batches, total, err := h.batchRepo.ListByUser(r.Context(), userID, limit, offset)
+	if err != nil {
+		log.Printf("Error getting batches: %v", err)
+		InternalError(w, "Gagal mengambil daftar batch")
+		return
+	}
+
+	JSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"batches": batches,
+		"total":   total,
+	})
+}
+
+// GetBatch handles GET /api/v1/member/batches/{id}
+// Returns the batch with the status of every line
+func (h *BatchHandler) GetBatch(w http.ResponseWriter, r *http.Request) {
+	batch, ok := h.loadBatch(w, r)
+	if !ok {
+		return
+	}
+
+	Success(w, "", batch)
+}
+
+// DownloadBatchResults handles GET /api/v1/member/batches/{id}/results
+// Streams the per-line results as CSV
+func (h *BatchHandler) DownloadBatchResults(w http.ResponseWriter, r *http.Request) {
+	batch, ok := h.loadBatch(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", batch.RefID+".csv"))
+
+	out := csv.NewWriter(w)
+	out.Write([]string{
+		"line_no", "buyer_sku_code", "destination_number", "client_ref_id", "price",
+		"status", "order_ref", "order_status", "serial_number", "error_code", "error_message",
+	})
+	for _, l := range batch.Lines {
+		orderRef, orderStatus := "", ""
+		if l.OrderRef != nil {
+			orderRef = *l.OrderRef
+		}
+		if l.OrderStatus != nil {
+			orderStatus = *l.OrderStatus
+		}
+		out.Write([]string{
+			strconv.Itoa(l.LineNo), l.BuyerSKUCode, l.DestinationNumber, l.ClientRefID,
+			strconv.FormatFloat(l.Price, 'f', 0, 64),
+			l.Status, orderRef, orderStatus, l.SerialNumber, l.ErrorCode, l.ErrorMessage,
+		})
+	}
+	out.Flush()
+}
+
+// loadBatch gets the member's batch from the {id} path value, with its lines
+func (h *BatchHandler) loadBatch(w http.ResponseWriter, r *http.Request) (*model.OrderBatch, bool) {
+	ctx := r.Context()
+	userID := ctx.Value("user_id").(int)
+
+	batch, err := h.batchRepo.GetByRefID(ctx, userID, r.PathValue("id"))
+	if err != nil {
+		log.Printf("Error getting batch: %v", err)
+		InternalError(w, "Gagal mengambil batch")
+		return nil, false
+	}
+	if batch == nil {
+		NotFound(w, "Batch tidak ditemukan")
+		return nil, false
+	}
+
+	batch.Lines, err = h.batchRepo.GetLines(ctx, batch.ID)
+	if err != nil {
+		log.Printf("Error getting batch lines: %v", err)
+		InternalError(w, "Gagal mengambil batch")
+		return nil, false
+	}
+
+	return batch, true
+}
+
+// StartBatchWorker places pending batch lines every 5 seconds
+func (h *BatchHandler) StartBatchWorker(ctx context.Context) {
+	interval := 5 * time.Second
+	ticker := time.NewTicker(interval)
+
+	log.Printf("[Batch] Worker initialized. Running every %v", interval)
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				ticker.Stop()
+				return
+			case <-ticker.C:
+				h.processDue(ctx)
+			}
+		}
+	}()
+}
+
+func (h *BatchHandler) processDue(ctx context.Context) {
+	h.recoverInterrupted(ctx)
+
+	lines, err := h.batchRepo.ClaimLines(ctx, 20)
+	if err != nil {
+		log.Printf("[Batch] Failed to claim lines: %v", err)
+		return
+	}
+
+	for _, line := range lines {
+		h.processLine(ctx, line)
+	}
+
+	completed, err := h.batchRepo.CompleteReady(ctx)
+	if err != nil {
+		log.Printf("[Batch] Failed to complete batches: %v", err)
+	}
+	for _, b := range completed {
+		log.Printf("[Batch] %s completed, Rp %.0f unused reservation returned", b.RefID, b.RefundedAmount)
+	}
+}
+
+// processLine places one line's order, paying from the batch reservation
+func (h *BatchHandler) processLine(ctx context.Context, line model.OrderBatchLine) {
+	opts := orderOptions{
+		pay: func(ctx context.Context, amount float64, brand, description, refID string) error {
+			return h.batchRepo.PayLine(ctx, line, amount, brand, description, refID)
+		},
+		skipTOTP: true, // Checked for the batch total on submit
+	}
+
+	order, err := h.memberHandler.placeOrderWith(ctx, line.UserID, model.MemberOrderRequest{
+		BuyerSKUCode:      line.BuyerSKUCode,
+		DestinationNumber: line.DestinationNumber,
+	}, "member", line.ClientRefID, opts)
+
+	// The order exists (possibly already failed and refunded): its status tells the rest
+	if order != nil {
+		if mErr := h.batchRepo.MarkSubmitted(ctx, line.ID, order.RefID); mErr != nil {
+			log.Printf("[Batch] %v", mErr)
+		}
+		return
+	}
+
+	code, message := orderErrInternal, "Gagal memproses transaksi"
+	var oErr *memberOrderError
+	if errors.As(err, &oErr) {
+		code, message = oErr.code, oErr.message
+	}
+	log.Printf("[Batch] %s line %d failed: %s", line.BatchRefID, line.LineNo, message)
+	if mErr := h.batchRepo.MarkFailed(ctx, line.ID, code, message); mErr != nil {
+		log.Printf("[Batch] %v", mErr)
+	}
+}
+
+// recoverInterrupted settles lines that were charged by a worker that died before
+// marking them: linked to their order if it exists, refunded otherwise
+func (h *BatchHandler) recoverInterrupted(ctx context.Context) {
+	lines, err := h.batchRepo.ClaimInterrupted(ctx)
+	if err != nil {
+		log.Printf("[Batch] Failed to claim interrupted lines: %v", err)
+		return
+	}
+
+	for _, line := range lines {
+		if line.OrderStatus != nil {
+			if mErr := h.batchRepo.MarkSubmitted(ctx, line.ID, *line.OrderRef); mErr != nil {
+				log.Printf("[Batch] %v", mErr)
+			}
+			continue
+		}
+
+		if err := h.batchRepo.RefundLine(ctx, line); err != nil && !errors.Is(err, repository.ErrDuplicateLedgerEntry) {
+			log.Printf("CRITICAL: Failed to refund interrupted batch line %s/%d: %v", line.BatchRefID, line.LineNo, err)
+			continue
+		}
+		if mErr := h.batchRepo.MarkFailed(ctx, line.ID, orderErrInternal, "Gagal memproses transaksi. Saldo telah dikembalikan."); mErr != nil {
+			log.Printf("[Batch] %v", mErr)
+		}
+	}
+}
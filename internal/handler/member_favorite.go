@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"govershop-api/internal/model"
+	"govershop-api/internal/repository"
+)
+
+// FavoriteHandler handles member saved destination numbers
+type FavoriteHandler struct {
+	favoriteRepo *repository.FavoriteRepository
+}
+
+// NewFavoriteHandler creates a new FavoriteHandler
+func NewFavoriteHandler(favoriteRepo *repository.FavoriteRepository) *FavoriteHandler {
+	return &FavoriteHandler{
+		favoriteRepo: favoriteRepo,
+	}
+}
+
+// GetFavorites handles GET /api/v1/member/favorites?brand=&sort=recent|popular|label|created
+func (h *FavoriteHandler) GetFavorites(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	favorites, err := h.favoriteRepo.List(r.Context(), userID, r.URL.Query().Get("brand"), r.URL.Query().Get("sort"))
+	if err != nil {
+		log.Printf("Error getting favorites: %v", err)
+		InternalError(w, "Gagal mengambil daftar favorit")
+		return
+	}
+
+	Success(w, "", favorites)
+}
+
+// CreateFavorite handles POST /api/v1/member/favorites
+func (h *FavoriteHandler) CreateFavorite(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	fav, ok := decodeFavorite(w, r)
+	if !ok {
+		return
+	}
+	fav.UserID = userID
+
+	if err := h.favoriteRepo.Create(r.Context(), fav); err != nil {
+		if errors.Is(err, repository.ErrDuplicateFavorite) {
+			Error(w, http.StatusConflict, "Nomor tujuan ini sudah ada di favorit")
+			return
+		}
+		log.Printf("Error creating favorite: %v", err)
+		InternalError(w, "Gagal menyimpan favorit")
+		return
+	}
+
+	Created(w, "Favorit berhasil disimpan", fav)
+}
+
+// UpdateFavorite handles PUT /api/v1/member/favorites/{id}
+func (h *FavoriteHandler) UpdateFavorite(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		NotFound(w, "Favorit tidak ditemukan")
+		return
+	}
+
+	fav, ok := decodeFavorite(w, r)
+	if !ok {
+		return
+	}
+	fav.ID = id
+	fav.UserID = userID
+
+	updated, err := h.favoriteRepo.Update(r.Context(), fav)
+	if err != nil {
+		if errors.Is(err, repository.ErrDuplicateFavorite) {
+			Error(w, http.StatusConflict, "Nomor tujuan ini sudah ada di favorit")
+			return
+		}
+		log.Printf("Error updating favorite: %v", err)
+		InternalError(w, "Gagal menyimpan favorit")
+		return
+	}
+	if updated == nil {
+		NotFound(w, "Favorit tidak ditemukan")
+		return
+	}
+
+	Success(w, "Favorit berhasil diupdate", updated)
+}
+
+// DeleteFavorite handles DELETE /api/v1/member/favorites/{id}
+func (h *FavoriteHandler) DeleteFavorite(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		NotFound(w, "Favorit tidak ditemukan")
+		return
+	}
+
+	deleted, err := h.favoriteRepo.Delete(r.Context(), userID, id)
+	if err != nil {
+		log.Printf("Error deleting favorite: %v", err)
+		InternalError(w, "Gagal menghapus favorit")
+		return
+	}
+	if !deleted {
+		NotFound(w, "Favorit tidak ditemukan")
+		return
+	}
+
+	Success(w, "Favorit berhasil dihapus", nil)
+}
+
+// decodeFavorite reads and validates a favorite request body
+func decodeFavorite(w http.ResponseWriter, r *http.Request) (*model.MemberFavorite, bool) {
+	var req model.MemberFavoriteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		BadRequest(w, "Format request tidak valid")
+		return nil, false
+	}
+
+	fav := &model.MemberFavorite{
+		Label:       strings.TrimSpace(req.Label),
+		Brand:       strings.TrimSpace(req.Brand),
+		CustomerNo:  strings.TrimSpace(req.CustomerNo),
+		AccountName: strings.TrimSpace(req.AccountName),
+	}
+
+	switch {
+	case fav.Brand == "":
+		BadRequest(w, "brand wajib diisi")
+	case fav.CustomerNo == "":
+		BadRequest(w, "customer_no wajib diisi")
+	case len(fav.Label) > 100:
+		BadRequest(w, "Label maksimal 100 karakter")
+	case len(fav.Brand) > 100 || len(fav.CustomerNo) > 100:
+		BadRequest(w, "brand dan customer_no maksimal 100 karakter")
+	case len(fav.AccountName) > 255:
+		BadRequest(w, "account_name maksimal 255 karakter")
+	default:
+		if fav.Label == "" {
+			fav.Label = fav.CustomerNo
+		}
+		return fav, true
+	}
+	return nil, false
+}
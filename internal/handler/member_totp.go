@@ -0,0 +1,350 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image/png"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pquerna/otp/totp"
+	"golang.org/x/crypto/bcrypt"
+
+	"govershop-api/internal/model"
+)
+
+// preAuthTokenTTL is how long a member has to enter the TOTP code after the password step
+const preAuthTokenTTL = 5 * time.Minute
+
+// ==========================================
+// MEMBER TOTP (2FA) ENDPOINTS
+// ==========================================
+
+// GetTOTPStatus handles GET /api/v1/member/totp/status
+func (h *MemberHandler) GetTOTPStatus(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	security, err := h.memberSecurityRepo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error getting member security: %v", err)
+		InternalError(w, "Gagal mengecek status TOTP")
+		return
+	}
+	if security == nil {
+		Success(w, "", map[string]interface{}{
+			"enabled": false,
+			"setup":   false,
+		})
+		return
+	}
+
+	Success(w, "", map[string]interface{}{
+		"enabled":               security.TOTPEnabled,
+		"setup":                 security.TOTPSecret != "",
+		"require_totp_password": security.RequireTOTPPassword,
+		"require_totp_orders":   security.RequireTOTPOrders,
+		"order_totp_threshold":  security.OrderTOTPThreshold,
+	})
+}
+
+// SetupTOTP handles POST /api/v1/member/totp/setup
+// Generates a new TOTP secret and returns the QR code
+func (h *MemberHandler) SetupTOTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value("user_id").(int)
+
+	security, err := h.memberSecurityRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		InternalError(w, "Gagal mengecek status TOTP")
+		return
+	}
+	if security != nil && security.TOTPEnabled {
+		BadRequest(w, "TOTP sudah aktif. Nonaktifkan dulu untuk setup ulang.")
+		return
+	}
+
+	user, err := h.userRepo.GetByID(ctx, userID)
+	if err != nil || user == nil {
+		NotFound(w, "User not found")
+		return
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      "Govershop",
+		AccountName: user.Username,
+		SecretSize:  32,
+	})
+	if err != nil {
+		InternalError(w, "Gagal generate TOTP key")
+		return
+	}
+
+	// Save secret (not enabled yet until verified)
+	if err := h.memberSecurityRepo.SetTOTPSecret(ctx, userID, key.Secret()); err != nil {
+		log.Printf("Error saving member TOTP secret: %v", err)
+		InternalError(w, "Gagal menyimpan TOTP secret")
+		return
+	}
+
+	img, err := key.Image(200, 200)
+	if err != nil {
+		InternalError(w, "Gagal generate QR code")
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		InternalError(w, "Gagal encode QR code")
+		return
+	}
+	qrBase64 := base64.StdEncoding.EncodeToString(buf.Bytes())
+
+	Success(w, "Scan QR code dengan Google Authenticator", map[string]interface{}{
+		"qr_code": "data:image/png;base64," + qrBase64,
+		"secret":  key.Secret(), // For manual entry
+		"issuer":  "Govershop",
+		"account": user.Username,
+	})
+}
+
+// EnableTOTP handles POST /api/v1/member/totp/enable
+// Verifies the first code and enables 2FA
+func (h *MemberHandler) EnableTOTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value("user_id").(int)
+
+	var req model.MemberTOTPCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		BadRequest(w, "Format request tidak valid")
+		return
+	}
+
+	if len(req.Code) != 6 {
+		BadRequest(w, "Kode TOTP harus 6 digit")
+		return
+	}
+
+	security, err := h.memberSecurityRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		InternalError(w, "Gagal mengecek status TOTP")
+		return
+	}
+	if security == nil || security.TOTPSecret == "" {
+		BadRequest(w, "TOTP belum di-setup. Jalankan setup dulu.")
+		return
+	}
+	if security.TOTPEnabled {
+		BadRequest(w, "TOTP sudah aktif")
+		return
+	}
+
+	if !totp.Validate(req.Code, security.TOTPSecret) {
+		Unauthorized(w, "Kode TOTP tidak valid")
+		return
+	}
+
+	if err := h.memberSecurityRepo.EnableTOTP(ctx, userID); err != nil {
+		log.Printf("Error enabling member TOTP: %v", err)
+		InternalError(w, "Gagal mengaktifkan TOTP")
+		return
+	}
+
+	log.Printf("[MemberTOTP] User %d enabled TOTP", userID)
+	Success(w, "2FA berhasil diaktifkan!", nil)
+}
+
+// DisableTOTP handles POST /api/v1/member/totp/disable
+// Requires both the current password and a valid code
+func (h *MemberHandler) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value("user_id").(int)
+
+	var req model.MemberTOTPCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		BadRequest(w, "Format request tidak valid")
+		return
+	}
+
+	security, err := h.memberSecurityRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		InternalError(w, "Gagal mengecek status TOTP")
+		return
+	}
+	if security == nil || !security.TOTPEnabled {
+		BadRequest(w, "TOTP tidak aktif")
+		return
+	}
+
+	user, err := h.userRepo.GetByID(ctx, userID)
+	if err != nil || user == nil {
+		NotFound(w, "User not found")
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		Unauthorized(w, "Password salah")
+		return
+	}
+
+	if !totp.Validate(req.Code, security.TOTPSecret) {
+		Unauthorized(w, "Kode TOTP tidak valid")
+		return
+	}
+
+	if err := h.memberSecurityRepo.DisableTOTP(ctx, userID); err != nil {
+		log.Printf("Error disabling member TOTP: %v", err)
+		InternalError(w, "Gagal menonaktifkan TOTP")
+		return
+	}
+
+	log.Printf("[MemberTOTP] User %d disabled TOTP", userID)
+	Success(w, "2FA berhasil dinonaktifkan", nil)
+}
+
+// UpdateTOTPSettings handles PUT /api/v1/member/totp/settings
+// Chooses whether password changes and large orders require a code
+func (h *MemberHandler) UpdateTOTPSettings(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value("user_id").(int)
+
+	var req model.MemberTOTPSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		BadRequest(w, "Format request tidak valid")
+		return
+	}
+
+	if req.OrderTOTPThreshold < 0 {
+		BadRequest(w, "Batas nominal tidak valid")
+		return
+	}
+
+	security, err := h.memberSecurityRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		InternalError(w, "Gagal mengecek status TOTP")
+		return
+	}
+	if security == nil || !security.TOTPEnabled {
+		BadRequest(w, "Aktifkan TOTP terlebih dahulu")
+		return
+	}
+
+	if !totp.Validate(req.Code, security.TOTPSecret) {
+		Unauthorized(w, "Kode TOTP tidak valid")
+		return
+	}
+
+	if err := h.memberSecurityRepo.UpdateRequirements(ctx, userID, req.RequireTOTPPassword, req.RequireTOTPOrders, req.OrderTOTPThreshold); err != nil {
+		log.Printf("Error updating member TOTP settings: %v", err)
+		InternalError(w, "Gagal menyimpan pengaturan TOTP")
+		return
+	}
+
+	Success(w, "Pengaturan TOTP berhasil disimpan", nil)
+}
+
+// LoginTOTP handles POST /api/v1/member/login/totp
+// Second login step: exchanges the pre-auth token and a valid code for a session
+func (h *MemberHandler) LoginTOTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req model.MemberLoginTOTPRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		BadRequest(w, "Invalid request body")
+		return
+	}
+
+	userID, err := h.parsePreAuthToken(req.PreAuthToken)
+	if err != nil {
+		Unauthorized(w, "Sesi login sudah kadaluarsa. Silakan login ulang.")
+		return
+	}
+
+	user, err := h.userRepo.GetByID(ctx, userID)
+	if err != nil || user == nil || user.Role != model.UserRoleMember {
+		Unauthorized(w, "Sesi login sudah kadaluarsa. Silakan login ulang.")
+		return
+	}
+	if user.Status != model.UserStatusActive {
+		Error(w, http.StatusForbidden, "Akun Anda telah dinonaktifkan. Hubungi admin.")
+		return
+	}
+
+	security, err := h.memberSecurityRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		log.Printf("Error getting member security: %v", err)
+		InternalError(w, "Internal server error")
+		return
+	}
+	if security == nil || !security.TOTPEnabled {
+		Unauthorized(w, "Sesi login sudah kadaluarsa. Silakan login ulang.")
+		return
+	}
+
+	// The TOTP step shares the password step's lockout so codes cannot be guessed with fresh pre-auth tokens
+	lockFor, err := h.loginGuard.Check(r, user, model.SessionChannelMember)
+	if err != nil {
+		log.Printf("Error checking login lockout: %v", err)
+		InternalError(w, "Internal server error")
+		return
+	}
+	if lockFor > 0 {
+		Error(w, http.StatusTooManyRequests, lockedMessage(w, lockFor))
+		return
+	}
+
+	if !totp.Validate(req.Code, security.TOTPSecret) {
+		if lockFor := h.loginGuard.Fail(r, user, user.Username, model.SessionChannelMember, model.LoginOutcomeInvalidTOTP); lockFor > 0 {
+			Error(w, http.StatusTooManyRequests, lockedMessage(w, lockFor))
+			return
+		}
+		Unauthorized(w, "Kode TOTP tidak valid")
+		return
+	}
+
+	h.loginGuard.Succeed(r, user, model.SessionChannelMember)
+	h.writeMemberSession(w, r, user)
+}
+
+// signPreAuthToken issues the short-lived token for the TOTP login step.
+// It carries no role, so member/admin middleware never accept it as a session.
+func (h *MemberHandler) signPreAuthToken(userID int) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": userID,
+		"type":    "member_pre_auth",
+		"exp":     time.Now().Add(preAuthTokenTTL).Unix(),
+	})
+	return token.SignedString([]byte(h.config.JWTSecretGovershop))
+}
+
+func (h *MemberHandler) parsePreAuthToken(tokenString string) (int, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method")
+		}
+		return []byte(h.config.JWTSecretGovershop), nil
+	})
+	if err != nil || !token.Valid {
+		return 0, fmt.Errorf("invalid pre-auth token")
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["type"] != "member_pre_auth" {
+		return 0, fmt.Errorf("invalid pre-auth token")
+	}
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return 0, fmt.Errorf("invalid pre-auth token")
+	}
+	return int(userID), nil
+}
+
+// validTOTPCode checks a 6-digit code against a secret
+func validTOTPCode(code, secret string) bool {
+	if len(code) != 6 || secret == "" {
+		return false
+	}
+	return totp.Validate(code, secret)
+}
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"govershop-api/internal/config"
+	"govershop-api/internal/model"
+	"govershop-api/internal/repository"
+)
+
+// TransferHandler handles member-to-member balance transfers
+type TransferHandler struct {
+	config             *config.Config
+	transferRepo       *repository.TransferRepository
+	userRepo           *repository.UserRepository
+	memberSecurityRepo *repository.MemberSecurityRepository
+}
+
+// NewTransferHandler creates a new TransferHandler
+func NewTransferHandler(cfg *config.Config, transferRepo *repository.TransferRepository, userRepo *repository.UserRepository, memberSecurityRepo *repository.MemberSecurityRepository) *TransferHandler {
+	return &TransferHandler{
+		config:             cfg,
+		transferRepo:       transferRepo,
+		userRepo:           userRepo,
+		memberSecurityRepo: memberSecurityRepo,
+	}
+}
+
+// CreateTransfer handles POST /api/v1/member/transfers
+// Sends balance from the logged-in member to another member
+func (h *TransferHandler) CreateTransfer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value("user_id").(int)
+
+	var req model.TransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		BadRequest(w, "Format request tidak valid")
+		return
+	}
+
+	req.ToUsername = strings.TrimSpace(req.ToUsername)
+	req.Note = strings.TrimSpace(req.Note)
+	if req.ToUsername == "" {
+		BadRequest(w, "Username penerima wajib diisi")
+		return
+	}
+	if req.Amount < h.config.TransferMinAmount {
+		BadRequest(w, fmt.Sprintf("Minimum transfer adalah Rp %.0f", h.config.TransferMinAmount))
+		return
+	}
+	if len(req.Note) > 255 {
+		BadRequest(w, "Catatan maksimal 255 karakter")
+		return
+	}
+
+	sender, err := h.userRepo.GetByID(ctx, userID)
+	if err != nil || sender == nil {
+		NotFound(w, "User not found")
+		return
+	}
+
+	recipient, err := h.userRepo.GetByUsername(ctx, req.ToUsername)
+	if err != nil {
+		log.Printf("Error getting transfer recipient: %v", err)
+		InternalError(w, "Gagal memproses transfer")
+		return
+	}
+	if recipient == nil || recipient.Role != model.UserRoleMember {
+		NotFound(w, "Member penerima tidak ditemukan")
+		return
+	}
+	if recipient.ID == sender.ID {
+		BadRequest(w, "Tidak bisa transfer ke akun sendiri")
+		return
+	}
+	if recipient.Status != model.UserStatusActive {
+		BadRequest(w, "Akun penerima tidak aktif")
+		return
+	}
+
+	if h.config.TransferRequireAuth {
+		if ok := h.verifySender(w, r, sender, req); !ok {
+			return
+		}
+	}
+
+	transfer := &model.MemberTransfer{
+		RefID:        fmt.Sprintf("TRF-%d-%s", time.Now().Unix(), generateRandomString(5)),
+		FromUserID:   sender.ID,
+		ToUserID:     recipient.ID,
+		FromUsername: sender.Username,
+		ToUsername:   recipient.Username,
+		Amount:       req.Amount,
+		Note:         req.Note,
+	}
+
+	if err := h.transferRepo.Create(ctx, transfer, h.config.TransferDailyCap); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrInsufficientBalance):
+			BadRequest(w, "Saldo tidak mencukupi")
+		case errors.Is(err, repository.ErrTransferDailyCap):
+			BadRequest(w, fmt.Sprintf("Melebihi batas transfer harian Rp %.0f", h.config.TransferDailyCap))
+		default:
+			log.Printf("Error creating transfer: %v", err)
+			InternalError(w, "Gagal memproses transfer")
+		}
+		return
+	}
+
+	log.Printf("[Transfer] %s: user %d -> user %d, Rp %.0f", transfer.RefID, sender.ID, recipient.ID, transfer.Amount)
+
+	Created(w, "Transfer berhasil", transfer)
+}
+
+// verifySender checks the sender's TOTP code, or password when TOTP is not enabled.
+// Writes the error response and returns false when verification fails.
+func (h *TransferHandler) verifySender(w http.ResponseWriter, r *http.Request, sender *model.User, req model.TransferRequest) bool {
+	security, err := h.memberSecurityRepo.GetByUserID(r.Context(), sender.ID)
+	if err != nil {
+		log.Printf("Error getting member security: %v", err)
+		InternalError(w, "Gagal memproses transfer")
+		return false
+	}
+
+	if security != nil && security.TOTPEnabled {
+		if req.TOTPCode == "" {
+			JSON(w, http.StatusUnauthorized, map[string]interface{}{
+				"success":       false,
+				"error":         "Kode TOTP diperlukan untuk transfer",
+				"totp_required": true,
+			})
+			return false
+		}
+		if !validTOTPCode(req.TOTPCode, security.TOTPSecret) {
+			Unauthorized(w, "Kode TOTP tidak valid")
+			return false
+		}
+		return true
+	}
+
+	if req.Password == "" {
+		BadRequest(w, "Password diperlukan untuk transfer")
+		return false
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(sender.Password), []byte(req.Password)); err != nil {
+		Unauthorized(w, "Password salah")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,300 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"govershop-api/internal/config"
+	"govershop-api/internal/model"
+	"govershop-api/internal/repository"
+	"govershop-api/internal/service/callback"
+)
+
+// MemberWebhookHandler handles member callback registration and delivery
+type MemberWebhookHandler struct {
+	config      *config.Config
+	webhookRepo *repository.MemberWebhookRepository
+	callbackSvc *callback.Service
+}
+
+// NewMemberWebhookHandler creates a new MemberWebhookHandler
+func NewMemberWebhookHandler(
+	cfg *config.Config,
+	webhookRepo *repository.MemberWebhookRepository,
+	callbackSvc *callback.Service,
+) *MemberWebhookHandler {
+	return &MemberWebhookHandler{
+		config:      cfg,
+		webhookRepo: webhookRepo,
+		callbackSvc: callbackSvc,
+	}
+}
+
+// GetWebhook handles GET /api/v1/member/webhook
+func (h *MemberWebhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	hook, err := h.webhookRepo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error getting member webhook: %v", err)
+		InternalError(w, "Gagal mengambil pengaturan webhook")
+		return
+	}
+	if hook == nil {
+		NotFound(w, "Webhook belum diatur")
+		return
+	}
+
+	Success(w, "", hook)
+}
+
+// UpdateWebhook handles PUT /api/v1/member/webhook
+// Registers or updates the callback URL. The secret is only returned on first registration.
+func (h *MemberWebhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	var req model.MemberWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		BadRequest(w, "Invalid request body")
+		return
+	}
+
+	req.URL = strings.TrimSpace(req.URL)
+	if !h.validCallbackURL(req.URL) {
+		BadRequest(w, "URL webhook tidak valid (wajib https)")
+		return
+	}
+
+	existing, err := h.webhookRepo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		InternalError(w, "Gagal mengambil pengaturan webhook")
+		return
+	}
+
+	isActive := true
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	secret := ""
+	if existing == nil {
+		secret, err = randomHex(32)
+		if err != nil {
+			InternalError(w, "Gagal membuat secret webhook")
+			return
+		}
+	}
+
+	hook, err := h.webhookRepo.Upsert(r.Context(), userID, req.URL, secret, isActive)
+	if err != nil {
+		log.Printf("Error saving member webhook: %v", err)
+		InternalError(w, "Gagal menyimpan webhook")
+		return
+	}
+
+	if existing == nil {
+		Success(w, "Webhook berhasil disimpan. Simpan secret ini, tidak akan ditampilkan lagi.", map[string]interface{}{
+			"url":       hook.URL,
+			"secret":    secret,
+			"is_active": hook.IsActive,
+		})
+		return
+	}
+
+	Success(w, "Webhook berhasil diperbarui", hook)
+}
+
+// RegenerateSecret handles POST /api/v1/member/webhook/secret
+func (h *MemberWebhookHandler) RegenerateSecret(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	secret, err := randomHex(32)
+	if err != nil {
+		InternalError(w, "Gagal membuat secret webhook")
+		return
+	}
+
+	if err := h.webhookRepo.SetSecret(r.Context(), userID, secret); err != nil {
+		if err.Error() == "member webhook not found" {
+			NotFound(w, "Webhook belum diatur")
+			return
+		}
+		log.Printf("Error regenerating webhook secret: %v", err)
+		InternalError(w, "Gagal membuat secret webhook")
+		return
+	}
+
+	Success(w, "Secret webhook berhasil dibuat ulang. Simpan secret ini, tidak akan ditampilkan lagi.", map[string]interface{}{
+		"secret": secret,
+	})
+}
+
+// GetDeliveries handles GET /api/v1/member/webhook/deliveries
+func (h *MemberWebhookHandler) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	limit := 20
+	offset := 0
+	if l, err := parseInt(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+	if o, err := parseInt(r.URL.Query().Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+	status := r.URL.Query().Get("status")
+
+	deliveries, total, err := h.webhookRepo.ListByUser(r.Context(), userID, status, limit, offset)
+	if err != nil {
+		log.Printf("Error getting webhook deliveries: %v", err)
+		InternalError(w, "Gagal mengambil riwayat webhook")
+		return
+	}
+	if deliveries == nil {
+		deliveries = []model.MemberWebhookDelivery{}
+	}
+
+	Success(w, "", map[string]interface{}{
+		"deliveries": deliveries,
+		"total":      total,
+		"limit":      limit,
+		"offset":     offset,
+	})
+}
+
+// ResendDelivery handles POST /api/v1/member/webhook/deliveries/{id}/resend
+func (h *MemberWebhookHandler) ResendDelivery(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	id, err := parseInt(r.PathValue("id"))
+	if err != nil {
+		BadRequest(w, "Invalid delivery ID")
+		return
+	}
+
+	if err := h.webhookRepo.Resend(r.Context(), userID, id); err != nil {
+		if err.Error() == "delivery not found or not failed" {
+			NotFound(w, "Pengiriman tidak ditemukan atau tidak dalam status gagal")
+			return
+		}
+		log.Printf("Error resending webhook delivery: %v", err)
+		InternalError(w, "Gagal mengirim ulang webhook")
+		return
+	}
+
+	Success(w, "Webhook akan dikirim ulang", nil)
+}
+
+// StartDeliveryWorker sends due webhook deliveries every 10 seconds
+func (h *MemberWebhookHandler) StartDeliveryWorker(ctx context.Context) {
+	interval := 10 * time.Second
+	ticker := time.NewTicker(interval)
+
+	log.Printf("[MemberWebhook] Delivery worker initialized. Running every %v", interval)
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				ticker.Stop()
+				return
+			case <-ticker.C:
+				h.deliverDue(ctx)
+			}
+		}
+	}()
+}
+
+func (h *MemberWebhookHandler) deliverDue(ctx context.Context) {
+	deliveries, err := h.webhookRepo.ClaimDue(ctx, 50)
+	if err != nil {
+		log.Printf("[MemberWebhook] Failed to claim deliveries: %v", err)
+		return
+	}
+
+	for _, d := range deliveries {
+		result, err := h.callbackSvc.Send(ctx, d.URL, d.Secret, d.Event, d.ID, d.Payload)
+		if err == nil {
+			if mErr := h.webhookRepo.MarkSuccess(ctx, d.ID, result.StatusCode, result.Body); mErr != nil {
+				log.Printf("[MemberWebhook] %v", mErr)
+			}
+			continue
+		}
+
+		var statusCode *int
+		body := ""
+		if result != nil {
+			statusCode = &result.StatusCode
+			body = result.Body
+		}
+		log.Printf("[MemberWebhook] Delivery %d to user %d failed (attempt %d): %v", d.ID, d.UserID, d.Attempts+1, err)
+		if mErr := h.webhookRepo.MarkFailure(ctx, d.ID, d.Attempts, statusCode, body, err.Error()); mErr != nil {
+			log.Printf("[MemberWebhook] %v", mErr)
+		}
+	}
+}
+
+// validCallbackURL requires an absolute https URL (http allowed in development)
+func (h *MemberWebhookHandler) validCallbackURL(raw string) bool {
+	if raw == "" || len(raw) > 500 {
+		return false
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if u.Scheme == "https" {
+		return true
+	}
+	return u.Scheme == "http" && h.config.IsDevelopment()
+}
+
+// enqueueOrderCallback queues an order.status callback to the order's member.
+// Reloads the order so the payload carries the stored status, SN and message.
+// Guest orders, validation orders and members without a callback URL are skipped.
+func enqueueOrderCallback(ctx context.Context, webhookRepo *repository.MemberWebhookRepository, orderRepo *repository.OrderRepository, orderID string) {
+	if webhookRepo == nil {
+		return
+	}
+
+	order, err := orderRepo.GetByID(ctx, orderID)
+	if err != nil || order == nil {
+		log.Printf("[MemberWebhook] Failed to load order %s for callback: %v", orderID, err)
+		return
+	}
+	if order.MemberID == nil || strings.HasPrefix(order.RefID, "MVAL-") {
+		return
+	}
+
+	price := order.SellingPrice
+	if order.MemberPrice != nil {
+		price = *order.MemberPrice
+	}
+
+	payload, err := json.Marshal(model.OrderStatusPayload{
+		Event:        model.MemberWebhookEventOrderStatus,
+		OrderID:      order.ID,
+		RefID:        order.RefID,
+		ClientRefID:  order.ClientRefID,
+		Status:       order.Status,
+		BuyerSKUCode: order.BuyerSKUCode,
+		ProductName:  order.ProductName,
+		CustomerNo:   order.CustomerNo,
+		Price:        price,
+		SN:           order.SerialNumber,
+		Message:      order.DigiflazzMsg,
+		UpdatedAt:    order.UpdatedAt,
+	})
+	if err != nil {
+		log.Printf("[MemberWebhook] Failed to encode callback for order %s: %v", order.ID, err)
+		return
+	}
+
+	if _, err := webhookRepo.Enqueue(ctx, *order.MemberID, order.ID, model.MemberWebhookEventOrderStatus, payload); err != nil {
+		log.Printf("[MemberWebhook] Failed to enqueue callback for order %s: %v", order.ID, err)
+	}
+}
@@ -1,10 +1,13 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"govershop-api/internal/config"
@@ -18,14 +21,18 @@ import (
 
 // OrderHandler handles order-related HTTP requests
 type OrderHandler struct {
-	config       *config.Config
-	orderRepo    *repository.OrderRepository
-	paymentRepo  *repository.PaymentRepository
-	productRepo  *repository.ProductRepository
-	digiflazzSvc *digiflazz.Service
-	pakasirSvc   *pakasir.Service
-	qrispwSvc    *qrispw.Service
-	emailSvc     *email.Service
+	config        *config.Config
+	orderRepo     *repository.OrderRepository
+	paymentRepo   *repository.PaymentRepository
+	productRepo   *repository.ProductRepository
+	promoRepo     *repository.PromoRepository
+	flashSaleRepo *repository.FlashSaleRepository
+	digiflazzSvc  *digiflazz.Service
+	pakasirSvc    *pakasir.Service
+	qrispwSvc     *qrispw.Service
+	emailSvc      *email.Service
+
+	fulfillment *OrderFulfillment
 }
 
 // NewOrderHandler creates a new OrderHandler
@@ -34,20 +41,27 @@ func NewOrderHandler(
 	orderRepo *repository.OrderRepository,
 	paymentRepo *repository.PaymentRepository,
 	productRepo *repository.ProductRepository,
+	promoRepo *repository.PromoRepository,
+	flashSaleRepo *repository.FlashSaleRepository,
 	digiflazzSvc *digiflazz.Service,
 	pakasirSvc *pakasir.Service,
 	qrispwSvc *qrispw.Service,
 	emailSvc *email.Service,
+	fulfillment *OrderFulfillment,
 ) *OrderHandler {
 	return &OrderHandler{
-		config:       cfg,
-		orderRepo:    orderRepo,
-		paymentRepo:  paymentRepo,
-		productRepo:  productRepo,
-		digiflazzSvc: digiflazzSvc,
-		pakasirSvc:   pakasirSvc,
-		qrispwSvc:    qrispwSvc,
-		emailSvc:     emailSvc,
+		config:        cfg,
+		orderRepo:     orderRepo,
+		paymentRepo:   paymentRepo,
+		productRepo:   productRepo,
+		promoRepo:     promoRepo,
+		flashSaleRepo: flashSaleRepo,
+		digiflazzSvc:  digiflazzSvc,
+		pakasirSvc:    pakasirSvc,
+		qrispwSvc:     qrispwSvc,
+		emailSvc:      emailSvc,
+
+		fulfillment: fulfillment,
 	}
 }
 
@@ -83,6 +97,29 @@ func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Determine base price (use discount if available)
+	basePrice := product.SellingPrice
+	if product.DiscountPrice != nil && *product.DiscountPrice > 0 {
+		basePrice = *product.DiscountPrice
+	}
+
+	// Validate promo code before touching anything else
+	var promo *model.Promo
+	var discount float64
+	if strings.TrimSpace(req.PromoCode) != "" {
+		customer := &promoCustomer{Phone: req.CustomerPhone, Email: req.CustomerEmail}
+		promo, discount, err = applyPromoCode(ctx, h.promoRepo, h.orderRepo, req.PromoCode, product, basePrice, customer)
+		if err != nil {
+			var pErr *promoError
+			if errors.As(err, &pErr) {
+				BadRequest(w, pErr.Error())
+				return
+			}
+			InternalError(w, "Gagal memeriksa kode promo")
+			return
+		}
+	}
+
 	// ============================================================
 	// CHECK DIGIFLAZZ BALANCE (cached, fail-open strategy)
 	// ============================================================
@@ -133,12 +170,9 @@ func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	// Generate unique ref_id for Digiflazz
 	refID := fmt.Sprintf("GVS-%d-%s", time.Now().UnixMilli(), generateRandomString(6))
 
-	// Determine selling price (use discount if available)
+	// Selling price = base price - promo discount
 	// Plus flat admin fee (validasi akun) of Rp 10
-	sellingPrice := product.SellingPrice
-	if product.DiscountPrice != nil && *product.DiscountPrice > 0 {
-		sellingPrice = *product.DiscountPrice
-	}
+	sellingPrice := basePrice - discount
 	sellingPrice += 10 // Flat admin fee
 
 	// Create order
@@ -155,7 +189,25 @@ func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 		CustomerName:  req.CustomerName,
 	}
 
+	if promo != nil {
+		// Usage count is incremented together with the order insert
+		order.PromoID = &promo.ID
+		order.PromoCode = *promo.Code
+		order.DiscountAmount = discount
+	}
+
+	// Flash sale price consumes quota
+	order.FlashSaleItemID = flashSaleItemFor(ctx, h.flashSaleRepo, product.BuyerSKUCode, basePrice)
+
 	if err := h.orderRepo.Create(ctx, order); err != nil {
+		if msg, ok := promoErrorMessage(err); ok {
+			BadRequest(w, msg)
+			return
+		}
+		if errors.Is(err, repository.ErrFlashSaleSoldOut) {
+			BadRequest(w, "Kuota flash sale sudah habis, harga kembali normal. Silakan ulangi pesanan")
+			return
+		}
 		InternalError(w, "Gagal membuat order")
 		return
 	}
@@ -354,6 +406,11 @@ func (h *OrderHandler) CancelOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Give back promo usage
+	if err := h.promoRepo.ReverseRedemption(ctx, orderID); err != nil {
+		log.Printf("[CancelOrder] Failed to reverse promo redemption for order %s: %v", orderID, err)
+	}
+
 	Success(w, "Order berhasil dibatalkan", nil)
 }
 
@@ -382,12 +439,19 @@ func (h *OrderHandler) GetOrderStatus(w http.ResponseWriter, r *http.Request) {
 		if err == nil && qrisStatus.Success {
 			if qrisStatus.Status == "paid" {
 				_ = h.paymentRepo.UpdateStatusByOrderID(ctx, orderID, model.PaymentStatusCompleted)
-				_ = h.orderRepo.UpdateStatus(ctx, orderID, model.OrderStatusPaid)
 				payment.Status = model.PaymentStatusCompleted
-				order.Status = model.OrderStatusPaid
+				// The payment webhook may never come; whoever marks the order paid sends the topup
+				if paid, _ := h.orderRepo.MarkPaid(ctx, orderID); paid {
+					order.Status = model.OrderStatusPaid
+					paidOrder := *order
+					go h.fulfillment.ProcessPaid(&paidOrder)
+				}
 			} else if qrisStatus.Status == "expired" {
 				_ = h.paymentRepo.UpdateStatusByOrderID(ctx, orderID, model.PaymentStatusExpired)
 				payment.Status = model.PaymentStatusExpired
+				if expireUnpaidOrder(ctx, h.orderRepo, h.promoRepo, orderID) {
+					order.Status = model.OrderStatusExpired
+				}
 			}
 		}
 	}
@@ -410,6 +474,61 @@ func (h *OrderHandler) GetOrderStatus(w http.ResponseWriter, r *http.Request) {
 	Success(w, "", response)
 }
 
+// unpaidOrderMaxAge is how long an order without a payment stays open
+const unpaidOrderMaxAge = time.Hour
+
+// expireUnpaidOrder closes an order whose payment expired, so it stops holding
+// flash sale quota, and gives back its promo usage. Returns false if the order
+// was already paid or closed.
+func expireUnpaidOrder(ctx context.Context, orderRepo *repository.OrderRepository, promoRepo *repository.PromoRepository, orderID string) bool {
+	expired, err := orderRepo.ExpireUnpaid(ctx, orderID)
+	if err != nil {
+		log.Printf("[OrderExpiry] Failed to expire order %s: %v", orderID, err)
+		return false
+	}
+	if !expired {
+		return false
+	}
+
+	log.Printf("[OrderExpiry] Order %s expired unpaid", orderID)
+	if err := promoRepo.ReverseRedemption(ctx, orderID); err != nil {
+		log.Printf("[OrderExpiry] Failed to reverse promo redemption for order %s: %v", orderID, err)
+	}
+	return true
+}
+
+// StartExpiryJob expires unpaid orders whose payment has timed out, every minute.
+// Covers payments whose expiry webhook never arrived and orders never paid at all.
+func (h *OrderHandler) StartExpiryJob(ctx context.Context) {
+	interval := 1 * time.Minute
+	ticker := time.NewTicker(interval)
+
+	log.Printf("[OrderExpiry] Expiry job initialized. Running every %v", interval)
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				ticker.Stop()
+				return
+			case <-ticker.C:
+				h.expireOverdueOrders(context.Background())
+			}
+		}
+	}()
+}
+
+func (h *OrderHandler) expireOverdueOrders(ctx context.Context) {
+	ids, err := h.orderRepo.GetOverdueUnpaidIDs(ctx, unpaidOrderMaxAge)
+	if err != nil {
+		log.Printf("[OrderExpiry] %v", err)
+		return
+	}
+	for _, id := range ids {
+		expireUnpaidOrder(ctx, h.orderRepo, h.promoRepo, id)
+	}
+}
+
 // GetPaymentMethods handles GET /api/v1/payment-methods
 func (h *OrderHandler) GetPaymentMethods(w http.ResponseWriter, r *http.Request) {
 	methods := model.GetAvailablePaymentMethods()
@@ -0,0 +1,399 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"govershop-api/internal/config"
+	"govershop-api/internal/model"
+	"govershop-api/internal/repository"
+)
+
+// PointsHandler handles loyalty points HTTP requests
+type PointsHandler struct {
+	config     *config.Config
+	pointsRepo *repository.PointsRepository
+	userRepo   *repository.UserRepository
+}
+
+// NewPointsHandler creates a new PointsHandler
+func NewPointsHandler(cfg *config.Config, pointsRepo *repository.PointsRepository, userRepo *repository.UserRepository) *PointsHandler {
+	return &PointsHandler{
+		config:     cfg,
+		pointsRepo: pointsRepo,
+		userRepo:   userRepo,
+	}
+}
+
+// PointRuleRequest is the request body for creating/updating a point rule
+type PointRuleRequest struct {
+	Name           string  `json:"name"`
+	Brand          *string `json:"brand,omitempty"`
+	Category       *string `json:"category,omitempty"`
+	EarnType       string  `json:"earn_type"` // "percent" or "fixed"
+	EarnValue      float64 `json:"earn_value"`
+	MinOrderAmount float64 `json:"min_order_amount"`
+	ExpiryDays     int     `json:"expiry_days"`
+	IsActive       bool    `json:"is_active"`
+}
+
+// toPointRule validates the request and builds a point rule model
+func (req *PointRuleRequest) toPointRule() (*model.PointRule, error) {
+	req.Name = strings.TrimSpace(req.Name)
+
+	if req.Name == "" {
+		return nil, fmt.Errorf("Nama aturan poin wajib diisi")
+	}
+	if req.EarnType != model.PointEarnPercent && req.EarnType != model.PointEarnFixed {
+		return nil, fmt.Errorf("earn_type harus 'percent' atau 'fixed'")
+	}
+	if req.EarnValue <= 0 {
+		return nil, fmt.Errorf("earn_value harus lebih dari 0")
+	}
+	if req.EarnType == model.PointEarnPercent && req.EarnValue > 100 {
+		return nil, fmt.Errorf("Poin persen maksimal 100")
+	}
+	if req.MinOrderAmount < 0 {
+		return nil, fmt.Errorf("min_order_amount tidak boleh negatif")
+	}
+	if req.ExpiryDays <= 0 {
+		req.ExpiryDays = 90
+	}
+
+	return &model.PointRule{
+		Name:           req.Name,
+		Brand:          emptyToNil(req.Brand),
+		Category:       emptyToNil(req.Category),
+		EarnType:       req.EarnType,
+		EarnValue:      req.EarnValue,
+		MinOrderAmount: req.MinOrderAmount,
+		ExpiryDays:     req.ExpiryDays,
+		IsActive:       req.IsActive,
+	}, nil
+}
+
+// ==========================================
+// ADMIN POINT RULES
+// ==========================================
+
+// GetPointRules handles GET /api/v1/admin/point-rules
+func (h *PointsHandler) GetPointRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.pointsRepo.GetRules(r.Context(), false)
+	if err != nil {
+		InternalError(w, "Gagal mengambil aturan poin")
+		return
+	}
+
+	if rules == nil {
+		rules = []model.PointRule{}
+	}
+
+	Success(w, "", map[string]interface{}{
+		"rules": rules,
+		"total": len(rules),
+	})
+}
+
+// CreatePointRule handles POST /api/v1/admin/point-rules
+func (h *PointsHandler) CreatePointRule(w http.ResponseWriter, r *http.Request) {
+	var req PointRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		BadRequest(w, "Format request tidak valid")
+		return
+	}
+
+	rule, err := req.toPointRule()
+	if err != nil {
+		BadRequest(w, err.Error())
+		return
+	}
+
+	if err := h.pointsRepo.CreateRule(r.Context(), rule); err != nil {
+		InternalError(w, "Gagal membuat aturan poin")
+		return
+	}
+
+	Created(w, "Aturan poin berhasil dibuat", rule)
+}
+
+// UpdatePointRule handles PUT /api/v1/admin/point-rules/{id}
+func (h *PointsHandler) UpdatePointRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		BadRequest(w, "ID tidak valid")
+		return
+	}
+
+	var req PointRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		BadRequest(w, "Format request tidak valid")
+		return
+	}
+
+	rule, err := req.toPointRule()
+	if err != nil {
+		BadRequest(w, err.Error())
+		return
+	}
+	rule.ID = id
+
+	if err := h.pointsRepo.UpdateRule(r.Context(), rule); err != nil {
+		if err.Error() == "point rule not found" {
+			NotFound(w, "Aturan poin tidak ditemukan")
+			return
+		}
+		InternalError(w, "Gagal mengupdate aturan poin")
+		return
+	}
+
+	Success(w, "Aturan poin berhasil diupdate", rule)
+}
+
+// DeletePointRule handles DELETE /api/v1/admin/point-rules/{id}
+func (h *PointsHandler) DeletePointRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		BadRequest(w, "ID tidak valid")
+		return
+	}
+
+	if err := h.pointsRepo.DeleteRule(r.Context(), id); err != nil {
+		if err.Error() == "point rule not found" {
+			NotFound(w, "Aturan poin tidak ditemukan")
+			return
+		}
+		InternalError(w, "Gagal menghapus aturan poin")
+		return
+	}
+
+	Success(w, "Aturan poin berhasil dihapus", nil)
+}
+
+// ==========================================
+// MEMBER POINTS
+// ==========================================
+
+// GetPoints handles GET /api/v1/member/points
+func (h *PointsHandler) GetPoints(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value("user_id").(int)
+
+	limit, _ := parseInt(r.URL.Query().Get("limit"))
+	offset, _ := parseInt(r.URL.Query().Get("offset"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	summary, err := h.pointsRepo.GetSummary(ctx, userID)
+	if err != nil {
+		log.Printf("Error getting points summary: %v", err)
+		InternalError(w, "Gagal mengambil data poin")
+		return
+	}
+	summary.RedeemRate = h.config.PointsRedeemRate
+	summary.MinRedeem = h.config.PointsMinRedeem
+
+	history, total, err := h.pointsRepo.GetHistory(ctx, userID, limit, offset)
+	if err != nil {
+		log.Printf("Error getting points history: %v", err)
+		InternalError(w, "Gagal mengambil riwayat poin")
+		return
+	}
+
+	if history == nil {
+		history = []model.PointEntry{}
+	}
+
+	Success(w, "", map[string]interface{}{
+		"summary": summary,
+		"history": history,
+		"total":   total,
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
+
+// RedeemPoints handles POST /api/v1/member/points/redeem
+func (h *PointsHandler) RedeemPoints(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value("user_id").(int)
+
+	var req model.RedeemPointsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		BadRequest(w, "Invalid request body")
+		return
+	}
+
+	if req.Points < h.config.PointsMinRedeem {
+		BadRequest(w, fmt.Sprintf("Minimal penukaran adalah %d poin", h.config.PointsMinRedeem))
+		return
+	}
+
+	refID := fmt.Sprintf("PTS-%d-%s", time.Now().Unix(), generateRandomString(5))
+
+	amount, err := h.pointsRepo.Redeem(ctx, userID, req.Points, h.config.PointsRedeemRate, refID)
+	if err != nil {
+		if errors.Is(err, repository.ErrInsufficientPoints) {
+			BadRequest(w, "Poin tidak mencukupi")
+			return
+		}
+		log.Printf("Error redeeming points: %v", err)
+		InternalError(w, "Gagal menukar poin")
+		return
+	}
+
+	user, _ := h.userRepo.GetByID(ctx, userID)
+	summary, _ := h.pointsRepo.GetSummary(ctx, userID)
+
+	data := map[string]interface{}{
+		"reference_id": refID,
+		"points":       req.Points,
+		"amount":       amount,
+	}
+	if user != nil {
+		data["balance"] = user.Balance
+	}
+	if summary != nil {
+		data["points_balance"] = summary.Balance
+	}
+
+	Success(w, "Poin berhasil ditukar ke saldo", data)
+}
+
+// ==========================================
+// BACKGROUND JOBS
+// ==========================================
+
+// StartExpiryJob expires unspent points every hour
+func (h *PointsHandler) StartExpiryJob(ctx context.Context) {
+	interval := 1 * time.Hour
+	ticker := time.NewTicker(interval)
+
+	log.Printf("[Points] Expiry job initialized. Running every %v", interval)
+
+	go func() {
+		h.runExpiry()
+
+		for {
+			select {
+			case <-ctx.Done():
+				ticker.Stop()
+				return
+			case <-ticker.C:
+				h.runExpiry()
+			}
+		}
+	}()
+}
+
+func (h *PointsHandler) runExpiry() {
+	expired, err := h.pointsRepo.ExpirePoints(context.Background())
+	if err != nil {
+		log.Printf("[Points] Failed to expire points: %v", err)
+		return
+	}
+	if expired > 0 {
+		log.Printf("[Points] Expired %d point entries", expired)
+	}
+}
+
+// ==========================================
+// ORDER HOOKS
+// ==========================================
+
+// pickPointRule returns the most specific active rule for a product, or nil
+func pickPointRule(rules []model.PointRule, product *model.Product) *model.PointRule {
+	var best *model.PointRule
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.AppliesTo(product.Brand, product.Category) {
+			continue
+		}
+		if best == nil || rule.Specificity() > best.Specificity() {
+			best = rule
+		}
+	}
+	return best
+}
+
+// earnOrderPoints awards points for a successful member order.
+// Safe to call more than once for the same order.
+func earnOrderPoints(ctx context.Context, pointsRepo *repository.PointsRepository, productRepo *repository.ProductRepository, order *model.Order) {
+	// Only real member purchases earn points (not Check User validation orders)
+	if order.MemberID == nil || strings.HasPrefix(order.RefID, "MVAL-") {
+		return
+	}
+
+	product, err := productRepo.GetBySKU(ctx, order.BuyerSKUCode)
+	if err != nil || product == nil {
+		return
+	}
+
+	rules, err := pointsRepo.GetRules(ctx, true)
+	if err != nil {
+		log.Printf("[Points] Failed to get point rules: %v", err)
+		return
+	}
+
+	rule := pickPointRule(rules, product)
+	if rule == nil {
+		return
+	}
+
+	paid := order.SellingPrice
+	if order.MemberPrice != nil {
+		paid = *order.MemberPrice
+	}
+
+	points := rule.CalculatePoints(paid)
+	if points <= 0 {
+		return
+	}
+
+	orderID := order.ID
+	refID := order.RefID
+	description := fmt.Sprintf("Poin dari %s", order.ProductName)
+	entry := &model.PointEntry{
+		UserID:      *order.MemberID,
+		Points:      points,
+		OrderID:     &orderID,
+		RuleID:      &rule.ID,
+		ReferenceID: &refID,
+		Description: &description,
+	}
+
+	earned, err := pointsRepo.Earn(ctx, entry, rule.ExpiryDays)
+	if err != nil {
+		log.Printf("[Points] Failed to award points for order %s: %v", order.ID, err)
+		return
+	}
+	if earned {
+		log.Printf("[Points] Awarded %d points to user %d for order %s", points, *order.MemberID, order.RefID)
+	}
+}
+
+// clawbackOrderPoints takes back points awarded to a refunded order
+func clawbackOrderPoints(ctx context.Context, pointsRepo *repository.PointsRepository, order *model.Order) {
+	if order.MemberID == nil {
+		return
+	}
+
+	taken, err := pointsRepo.Clawback(ctx, order.ID, order.RefID)
+	if err != nil {
+		log.Printf("[Points] Failed to claw back points for order %s: %v", order.ID, err)
+		return
+	}
+	if taken > 0 {
+		log.Printf("[Points] Clawed back %d points from user %d for order %s", taken, *order.MemberID, order.RefID)
+	}
+}
@@ -10,16 +10,26 @@ import (
 
 // ProductHandler handles product-related HTTP requests
 type ProductHandler struct {
-	productRepo *repository.ProductRepository
+	productRepo   *repository.ProductRepository
+	flashSaleRepo *repository.FlashSaleRepository
 }
 
 // NewProductHandler creates a new ProductHandler
-func NewProductHandler(productRepo *repository.ProductRepository) *ProductHandler {
+func NewProductHandler(productRepo *repository.ProductRepository, flashSaleRepo *repository.FlashSaleRepository) *ProductHandler {
 	return &ProductHandler{
-		productRepo: productRepo,
+		productRepo:   productRepo,
+		flashSaleRepo: flashSaleRepo,
 	}
 }
 
+// withFlashSale attaches running flash sale info (end time, remaining quota) to a product response
+func withFlashSale(resp model.ProductResponse, flashSales map[string]model.FlashSaleInfo) model.ProductResponse {
+	if info, ok := flashSales[resp.BuyerSKUCode]; ok && resp.IsPromo && resp.Price == info.FlashPrice {
+		resp.FlashSale = &info
+	}
+	return resp
+}
+
 // GetProducts handles GET /api/v1/products
 func (h *ProductHandler) GetProducts(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -31,6 +41,11 @@ func (h *ProductHandler) GetProducts(w http.ResponseWriter, r *http.Request) {
 	var products []interface{}
 	var err error
 
+	flashSales, fsErr := h.flashSaleRepo.GetActiveInfo(ctx)
+	if fsErr != nil {
+		log.Printf("Error getting flash sales: %v", fsErr)
+	}
+
 	if category != "" {
 		dbProducts, err := h.productRepo.GetByCategory(ctx, category)
 		if err != nil {
@@ -38,7 +53,7 @@ func (h *ProductHandler) GetProducts(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		for _, p := range dbProducts {
-			products = append(products, p.ToResponse())
+			products = append(products, withFlashSale(p.ToResponse(), flashSales))
 		}
 	} else if brand != "" {
 		dbProducts, err := h.productRepo.GetByBrand(ctx, brand)
@@ -47,7 +62,7 @@ func (h *ProductHandler) GetProducts(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		for _, p := range dbProducts {
-			products = append(products, p.ToResponse())
+			products = append(products, withFlashSale(p.ToResponse(), flashSales))
 		}
 	} else {
 		dbProducts, err := h.productRepo.GetAll(ctx)
@@ -56,7 +71,7 @@ func (h *ProductHandler) GetProducts(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		for _, p := range dbProducts {
-			products = append(products, p.ToResponse())
+			products = append(products, withFlashSale(p.ToResponse(), flashSales))
 		}
 	}
 
@@ -88,7 +103,14 @@ func (h *ProductHandler) GetProductBySKU(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	Success(w, "", product.ToResponse())
+	resp := product.ToResponse()
+	if resp.IsPromo {
+		if info, err := h.flashSaleRepo.GetActiveInfoBySKU(ctx, sku); err == nil && info != nil && resp.Price == info.FlashPrice {
+			resp.FlashSale = info
+		}
+	}
+
+	Success(w, "", resp)
 }
 
 // GetCategories handles GET /api/v1/products/categories
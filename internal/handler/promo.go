@@ -0,0 +1,373 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"govershop-api/internal/model"
+	"govershop-api/internal/repository"
+)
+
+// PromoHandler handles promo code management HTTP requests
+type PromoHandler struct {
+	promoRepo *repository.PromoRepository
+}
+
+// NewPromoHandler creates a new PromoHandler
+func NewPromoHandler(promoRepo *repository.PromoRepository) *PromoHandler {
+	return &PromoHandler{
+		promoRepo: promoRepo,
+	}
+}
+
+// CreatePromoRequest is the request body for creating/updating a promo
+type CreatePromoRequest struct {
+	Name             string   `json:"name"`
+	Code             string   `json:"code"`
+	Description      string   `json:"description"`
+	DiscountType     string   `json:"discount_type"` // "percent" or "fixed"
+	DiscountValue    float64  `json:"discount_value"`
+	MinPurchase      float64  `json:"min_purchase"`
+	MaxDiscount      *float64 `json:"max_discount,omitempty"`
+	UsageLimit       *int     `json:"usage_limit,omitempty"`
+	Category         *string  `json:"category,omitempty"`
+	Brand            *string  `json:"brand,omitempty"`
+	BuyerSKUCode     *string  `json:"buyer_sku_code,omitempty"`
+	PerCustomerLimit *int     `json:"per_customer_limit,omitempty"`
+	FirstOrderOnly   bool     `json:"first_order_only"`
+	StartDate        string   `json:"start_date"` // ISO format
+	EndDate          string   `json:"end_date"`   // ISO format
+	IsActive         bool     `json:"is_active"`
+}
+
+// toPromo validates the request and builds a promo model
+func (req *CreatePromoRequest) toPromo() (*model.Promo, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	req.Code = strings.ToUpper(strings.TrimSpace(req.Code))
+
+	if req.Name == "" || req.Code == "" {
+		return nil, fmt.Errorf("Nama dan kode promo wajib diisi")
+	}
+	if strings.ContainsAny(req.Code, " \t") || len(req.Code) > 50 {
+		return nil, fmt.Errorf("Kode promo tidak boleh mengandung spasi dan maksimal 50 karakter")
+	}
+	if req.DiscountType != model.PromoDiscountPercent && req.DiscountType != model.PromoDiscountFixed {
+		return nil, fmt.Errorf("discount_type harus 'percent' atau 'fixed'")
+	}
+	if req.DiscountValue <= 0 {
+		return nil, fmt.Errorf("discount_value harus lebih dari 0")
+	}
+	if req.DiscountType == model.PromoDiscountPercent && req.DiscountValue > 100 {
+		return nil, fmt.Errorf("Diskon persen maksimal 100")
+	}
+	if req.MinPurchase < 0 {
+		return nil, fmt.Errorf("min_purchase tidak boleh negatif")
+	}
+	if req.MaxDiscount != nil && *req.MaxDiscount <= 0 {
+		return nil, fmt.Errorf("max_discount harus lebih dari 0")
+	}
+	if req.UsageLimit != nil && *req.UsageLimit <= 0 {
+		return nil, fmt.Errorf("usage_limit harus lebih dari 0")
+	}
+	if req.PerCustomerLimit != nil && *req.PerCustomerLimit <= 0 {
+		return nil, fmt.Errorf("per_customer_limit harus lebih dari 0")
+	}
+
+	startDate, err := time.Parse(time.RFC3339, req.StartDate)
+	if err != nil {
+		return nil, fmt.Errorf("Format start_date tidak valid (gunakan ISO 8601)")
+	}
+	endDate, err := time.Parse(time.RFC3339, req.EndDate)
+	if err != nil {
+		return nil, fmt.Errorf("Format end_date tidak valid (gunakan ISO 8601)")
+	}
+	if !endDate.After(startDate) {
+		return nil, fmt.Errorf("end_date harus setelah start_date")
+	}
+
+	code := req.Code
+	return &model.Promo{
+		Name:             req.Name,
+		Code:             &code,
+		Description:      req.Description,
+		DiscountType:     req.DiscountType,
+		DiscountValue:    req.DiscountValue,
+		MinPurchase:      req.MinPurchase,
+		MaxDiscount:      req.MaxDiscount,
+		UsageLimit:       req.UsageLimit,
+		Category:         emptyToNil(req.Category),
+		Brand:            emptyToNil(req.Brand),
+		BuyerSKUCode:     emptyToNil(req.BuyerSKUCode),
+		PerCustomerLimit: req.PerCustomerLimit,
+		FirstOrderOnly:   req.FirstOrderOnly,
+		StartDate:        startDate,
+		EndDate:          endDate,
+		IsActive:         req.IsActive,
+	}, nil
+}
+
+// emptyToNil converts blank optional strings to nil (nil target = all products)
+func emptyToNil(s *string) *string {
+	if s == nil || strings.TrimSpace(*s) == "" {
+		return nil
+	}
+	v := strings.TrimSpace(*s)
+	return &v
+}
+
+// GetPromos handles GET /api/v1/admin/promos
+func (h *PromoHandler) GetPromos(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit := 50
+	offset := 0
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := parseInt(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsed, err := parseInt(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	search := r.URL.Query().Get("search")
+	status := r.URL.Query().Get("status")
+
+	promos, total, err := h.promoRepo.GetAll(ctx, limit, offset, search, status)
+	if err != nil {
+		InternalError(w, "Gagal mengambil data promo")
+		return
+	}
+
+	if promos == nil {
+		promos = []model.Promo{}
+	}
+
+	Success(w, "", map[string]interface{}{
+		"promos": promos,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// GetPromoByID handles GET /api/v1/admin/promos/{id}
+func (h *PromoHandler) GetPromoByID(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		BadRequest(w, "ID tidak valid")
+		return
+	}
+
+	promo, err := h.promoRepo.GetByID(ctx, id)
+	if err != nil {
+		InternalError(w, "Gagal mengambil data promo")
+		return
+	}
+	if promo == nil {
+		NotFound(w, "Promo tidak ditemukan")
+		return
+	}
+
+	Success(w, "", promo)
+}
+
+// CreatePromo handles POST /api/v1/admin/promos
+func (h *PromoHandler) CreatePromo(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req CreatePromoRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		BadRequest(w, "Format request tidak valid")
+		return
+	}
+
+	promo, err := req.toPromo()
+	if err != nil {
+		BadRequest(w, err.Error())
+		return
+	}
+
+	existing, err := h.promoRepo.GetByCode(ctx, *promo.Code)
+	if err != nil {
+		InternalError(w, "Gagal memeriksa kode promo")
+		return
+	}
+	if existing != nil {
+		BadRequest(w, "Kode promo sudah digunakan")
+		return
+	}
+
+	if err := h.promoRepo.Create(ctx, promo); err != nil {
+		InternalError(w, "Gagal membuat promo")
+		return
+	}
+
+	Created(w, "Promo berhasil dibuat", promo)
+}
+
+// UpdatePromo handles PUT /api/v1/admin/promos/{id}
+func (h *PromoHandler) UpdatePromo(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		BadRequest(w, "ID tidak valid")
+		return
+	}
+
+	var req CreatePromoRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		BadRequest(w, "Format request tidak valid")
+		return
+	}
+
+	promo, err := req.toPromo()
+	if err != nil {
+		BadRequest(w, err.Error())
+		return
+	}
+	promo.ID = id
+
+	existing, err := h.promoRepo.GetByCode(ctx, *promo.Code)
+	if err != nil {
+		InternalError(w, "Gagal memeriksa kode promo")
+		return
+	}
+	if existing != nil && existing.ID != id {
+		BadRequest(w, "Kode promo sudah digunakan")
+		return
+	}
+
+	if err := h.promoRepo.Update(ctx, promo); err != nil {
+		if err.Error() == "promo not found" {
+			NotFound(w, "Promo tidak ditemukan")
+			return
+		}
+		InternalError(w, "Gagal mengupdate promo")
+		return
+	}
+
+	updated, err := h.promoRepo.GetByID(ctx, id)
+	if err != nil || updated == nil {
+		Success(w, "Promo berhasil diupdate", promo)
+		return
+	}
+
+	Success(w, "Promo berhasil diupdate", updated)
+}
+
+// DeletePromo handles DELETE /api/v1/admin/promos/{id}
+func (h *PromoHandler) DeletePromo(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		BadRequest(w, "ID tidak valid")
+		return
+	}
+
+	if err := h.promoRepo.Delete(ctx, id); err != nil {
+		if err.Error() == "promo not found" {
+			NotFound(w, "Promo tidak ditemukan")
+			return
+		}
+		InternalError(w, "Gagal menghapus promo")
+		return
+	}
+
+	Success(w, "Promo berhasil dihapus", nil)
+}
+
+// promoError is a user-facing reason why a promo code cannot be applied
+type promoError struct {
+	message string
+}
+
+func (e *promoError) Error() string {
+	return e.message
+}
+
+// promoCustomer identifies who is redeeming a promo (member, or guest phone/email)
+type promoCustomer struct {
+	MemberID *int
+	Phone    string
+	Email    string
+}
+
+// applyPromoCode looks up a promo code and calculates its discount for the product price.
+// When customer is nil, per-customer conditions are not checked (e.g. price preview).
+// Returns a *promoError when the code is not applicable so callers can show it to the user.
+func applyPromoCode(ctx context.Context, promoRepo *repository.PromoRepository, orderRepo *repository.OrderRepository, code string, product *model.Product, price float64, customer *promoCustomer) (*model.Promo, float64, error) {
+	promo, err := promoRepo.GetByCode(ctx, code)
+	if err != nil {
+		return nil, 0, err
+	}
+	if promo == nil {
+		return nil, 0, &promoError{"Kode promo tidak ditemukan"}
+	}
+	if !promo.IsValid() {
+		return nil, 0, &promoError{"Kode promo sudah tidak berlaku"}
+	}
+	if !promo.AppliesTo(product) {
+		return nil, 0, &promoError{"Kode promo tidak berlaku untuk produk ini"}
+	}
+	if price < promo.MinPurchase {
+		return nil, 0, &promoError{fmt.Sprintf("Minimal pembelian untuk promo ini adalah Rp %.0f", promo.MinPurchase)}
+	}
+
+	if customer != nil && promo.RequiresCustomerKey() {
+		if customer.MemberID == nil && model.NormalizePhone(customer.Phone) == "" && model.NormalizeEmail(customer.Email) == "" {
+			return nil, 0, &promoError{"Promo ini memerlukan nomor HP atau email"}
+		}
+
+		if promo.FirstOrderOnly {
+			if customer.MemberID == nil && model.NormalizePhone(customer.Phone) == "" {
+				return nil, 0, &promoError{"Promo pembelian pertama memerlukan nomor HP"}
+			}
+			var phones []string
+			if customer.MemberID == nil {
+				phones = model.PhoneVariants(customer.Phone)
+			}
+			hasOrder, err := orderRepo.HasPreviousOrder(ctx, customer.MemberID, phones)
+			if err != nil {
+				return nil, 0, err
+			}
+			if hasOrder {
+				return nil, 0, &promoError{"Promo ini hanya berlaku untuk pembelian pertama"}
+			}
+		}
+	}
+
+	// Discount in whole rupiah
+	discount := math.Floor(promo.CalculateDiscount(price))
+	if discount <= 0 {
+		return nil, 0, &promoError{"Kode promo tidak berlaku untuk produk ini"}
+	}
+
+	return promo, discount, nil
+}
+
+// promoErrorMessage maps repository promo errors raised at order creation to user messages
+func promoErrorMessage(err error) (string, bool) {
+	switch {
+	case errors.Is(err, repository.ErrPromoUnavailable):
+		return "Kode promo sudah tidak berlaku atau kuota habis", true
+	case errors.Is(err, repository.ErrPromoCustomerLimit):
+		return "Batas penggunaan kode promo untuk akun/nomor ini sudah tercapai", true
+	case errors.Is(err, repository.ErrPromoNotFirstOrder):
+		return "Promo ini hanya berlaku untuk pembelian pertama", true
+	}
+	return "", false
+}
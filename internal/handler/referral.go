@@ -0,0 +1,243 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"govershop-api/internal/config"
+	"govershop-api/internal/model"
+	"govershop-api/internal/repository"
+)
+
+// ReferralHandler handles member referral endpoints
+type ReferralHandler struct {
+	config       *config.Config
+	userRepo     *repository.UserRepository
+	referralRepo *repository.ReferralRepository
+}
+
+// NewReferralHandler creates a new ReferralHandler
+func NewReferralHandler(cfg *config.Config, userRepo *repository.UserRepository, referralRepo *repository.ReferralRepository) *ReferralHandler {
+	return &ReferralHandler{
+		config:       cfg,
+		userRepo:     userRepo,
+		referralRepo: referralRepo,
+	}
+}
+
+// GetReferrals handles GET /api/v1/member/referrals
+// Returns the member's referral code, referred members and commission history
+func (h *ReferralHandler) GetReferrals(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value("user_id").(int)
+
+	limit, _ := parseInt(r.URL.Query().Get("limit"))
+	offset, _ := parseInt(r.URL.Query().Get("offset"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	user, err := h.userRepo.GetByID(ctx, userID)
+	if err != nil || user == nil {
+		NotFound(w, "User not found")
+		return
+	}
+
+	// Members created before the referral program get their code on first visit
+	if user.ReferralCode == nil {
+		code := generateReferralCode()
+		if err := h.userRepo.SetReferralCode(ctx, userID, code); err != nil {
+			log.Printf("Error setting referral code: %v", err)
+			InternalError(w, "Gagal membuat kode referral")
+			return
+		}
+		user, err = h.userRepo.GetByID(ctx, userID)
+		if err != nil || user == nil || user.ReferralCode == nil {
+			InternalError(w, "Gagal membuat kode referral")
+			return
+		}
+	}
+
+	totalReferrals, totalEarned, totalReversed, err := h.referralRepo.GetSummary(ctx, userID)
+	if err != nil {
+		log.Printf("Error getting referral summary: %v", err)
+		InternalError(w, "Gagal mengambil data referral")
+		return
+	}
+
+	referrals, _, err := h.referralRepo.GetReferredMembers(ctx, userID, limit, offset)
+	if err != nil {
+		log.Printf("Error getting referred members: %v", err)
+		InternalError(w, "Gagal mengambil data referral")
+		return
+	}
+
+	earnings, totalEarnings, err := h.referralRepo.GetCommissions(ctx, userID, limit, offset)
+	if err != nil {
+		log.Printf("Error getting referral commissions: %v", err)
+		InternalError(w, "Gagal mengambil data komisi")
+		return
+	}
+
+	if referrals == nil {
+		referrals = []model.ReferredMember{}
+	}
+	if earnings == nil {
+		earnings = []model.ReferralCommission{}
+	}
+
+	Success(w, "", map[string]interface{}{
+		"summary": model.ReferralSummary{
+			ReferralCode:   *user.ReferralCode,
+			ReferralLink:   fmt.Sprintf("%s/member/register?ref=%s", strings.TrimRight(h.config.FrontendURL, "/"), *user.ReferralCode),
+			CommissionType: h.config.ReferralCommissionType,
+			CommissionRate: h.config.ReferralCommissionValue,
+			TotalReferrals: totalReferrals,
+			TotalEarned:    totalEarned,
+			TotalReversed:  totalReversed,
+		},
+		"referrals":      referrals,
+		"earnings":       earnings,
+		"total_earnings": totalEarnings,
+		"limit":          limit,
+		"offset":         offset,
+	})
+}
+
+// generateReferralCode returns a random 8-character referral code
+func generateReferralCode() string {
+	const charset = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789" // No 0/O, 1/I
+	code := make([]byte, 8)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
+		if err != nil {
+			code[i] = charset[i%len(charset)]
+			continue
+		}
+		code[i] = charset[n.Int64()]
+	}
+	return string(code)
+}
+
+// resolveReferrer looks up the active member owning a referral code.
+// Returns nil without error when code is empty.
+func resolveReferrer(ctx context.Context, userRepo *repository.UserRepository, code string) (*model.User, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return nil, nil
+	}
+
+	referrer, err := userRepo.GetByReferralCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if referrer == nil || referrer.Role != model.UserRoleMember || referrer.Status != model.UserStatusActive {
+		return nil, fmt.Errorf("invalid referral code")
+	}
+	return referrer, nil
+}
+
+// creditReferralCommission credits the referrer of the member who placed a successful order.
+// Safe to call more than once for the same order.
+func creditReferralCommission(ctx context.Context, cfg *config.Config, userRepo *repository.UserRepository, referralRepo *repository.ReferralRepository, order *model.Order) {
+	// Only real member purchases earn commission (not Check User validation orders)
+	if order.MemberID == nil || strings.HasPrefix(order.RefID, "MVAL-") {
+		return
+	}
+
+	member, err := userRepo.GetByID(ctx, *order.MemberID)
+	if err != nil || member == nil || member.ReferredBy == nil {
+		return
+	}
+
+	referrer, err := userRepo.GetByID(ctx, *member.ReferredBy)
+	if err != nil || referrer == nil || referrer.Status != model.UserStatusActive {
+		return
+	}
+
+	paid := order.SellingPrice
+	if order.MemberPrice != nil {
+		paid = *order.MemberPrice
+	}
+	margin := paid - order.BuyPrice
+
+	amount := model.CalculateReferralCommission(cfg.ReferralCommissionType, cfg.ReferralCommissionValue, margin)
+	if amount <= 0 {
+		return
+	}
+
+	commission := &model.ReferralCommission{
+		ReferrerID:      referrer.ID,
+		ReferredID:      member.ID,
+		OrderID:         order.ID,
+		RefID:           order.RefID,
+		CommissionType:  cfg.ReferralCommissionType,
+		CommissionValue: cfg.ReferralCommissionValue,
+		OrderMargin:     margin,
+		Amount:          amount,
+	}
+
+	created, err := referralRepo.CreateCommission(ctx, commission)
+	if err != nil {
+		log.Printf("[Referral] Failed to record commission for order %s: %v", order.ID, err)
+		return
+	}
+	if !created {
+		return
+	}
+
+	description := fmt.Sprintf("Komisi referral %s (%s)", order.RefID, member.Username)
+	if err := userRepo.TopupBalanceWithType(ctx, referrer.ID, amount, model.DepositTypeReferral, description, order.RefID, "SYSTEM"); err != nil {
+		log.Printf("CRITICAL: Failed to credit referral commission for order %s to user %d: %v", order.ID, referrer.ID, err)
+		return
+	}
+
+	credited, err := referralRepo.MarkCredited(ctx, commission.ID)
+	if err != nil {
+		log.Printf("[Referral] Failed to mark commission %d credited: %v", commission.ID, err)
+		return
+	}
+
+	// Order was refunded while we were crediting; take it back right away
+	if !credited {
+		reversalDesc := fmt.Sprintf("Pembatalan komisi referral %s", order.RefID)
+		if err := userRepo.ClawbackBalance(ctx, referrer.ID, amount, model.DepositTypeReferralReversal, reversalDesc, order.RefID); err != nil {
+			log.Printf("CRITICAL: Failed to claw back referral commission for order %s: %v", order.ID, err)
+		}
+		return
+	}
+
+	log.Printf("[Referral] Credited Rp %.0f to user %d for order %s", amount, referrer.ID, order.RefID)
+}
+
+// reverseReferralCommission reverses the referral commission of a refunded order
+func reverseReferralCommission(ctx context.Context, userRepo *repository.UserRepository, referralRepo *repository.ReferralRepository, order *model.Order) {
+	if order.MemberID == nil {
+		return
+	}
+
+	commission, wasCredited, err := referralRepo.Reverse(ctx, order.ID)
+	if err != nil {
+		log.Printf("[Referral] Failed to reverse commission for order %s: %v", order.ID, err)
+		return
+	}
+	if commission == nil || !wasCredited {
+		return
+	}
+
+	description := fmt.Sprintf("Pembatalan komisi referral %s", order.RefID)
+	if err := userRepo.ClawbackBalance(ctx, commission.ReferrerID, commission.Amount, model.DepositTypeReferralReversal, description, order.RefID); err != nil {
+		log.Printf("CRITICAL: Failed to claw back referral commission for order %s: %v", order.ID, err)
+		return
+	}
+
+	log.Printf("[Referral] Reversed Rp %.0f from user %d for order %s", commission.Amount, commission.ReferrerID, order.RefID)
+}
@@ -0,0 +1,342 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+
+	"govershop-api/internal/config"
+	"govershop-api/internal/model"
+	"govershop-api/internal/repository"
+	"govershop-api/internal/service/email"
+)
+
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.]{3,50}$`)
+
+// RegistrationHandler handles member self-registration and email verification
+type RegistrationHandler struct {
+	config       *config.Config
+	userRepo     *repository.UserRepository
+	settingsRepo *repository.SettingsRepository
+	emailSvc     *email.Service
+}
+
+// NewRegistrationHandler creates a new RegistrationHandler
+func NewRegistrationHandler(
+	cfg *config.Config,
+	userRepo *repository.UserRepository,
+	settingsRepo *repository.SettingsRepository,
+	emailSvc *email.Service,
+) *RegistrationHandler {
+	return &RegistrationHandler{
+		config:       cfg,
+		userRepo:     userRepo,
+		settingsRepo: settingsRepo,
+		emailSvc:     emailSvc,
+	}
+}
+
+// registrationMode returns the current registration mode (closed if unset)
+func (h *RegistrationHandler) registrationMode(ctx context.Context) (string, error) {
+	mode, err := h.settingsRepo.Get(ctx, model.SettingRegistrationMode, model.RegistrationModeClosed)
+	if err != nil {
+		return "", err
+	}
+	if !model.ValidRegistrationMode(mode) {
+		return model.RegistrationModeClosed, nil
+	}
+	return mode, nil
+}
+
+// GetPublicSettings handles GET /api/v1/member/register/settings
+func (h *RegistrationHandler) GetPublicSettings(w http.ResponseWriter, r *http.Request) {
+	mode, err := h.registrationMode(r.Context())
+	if err != nil {
+		log.Printf("Error getting registration mode: %v", err)
+		InternalError(w, "Internal server error")
+		return
+	}
+
+	Success(w, "", model.RegistrationSettings{Mode: mode})
+}
+
+// Register handles POST /api/v1/member/register
+// Creates a member in pending_verification status and sends a verification link.
+func (h *RegistrationHandler) Register(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req model.RegisterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		BadRequest(w, "Invalid request body")
+		return
+	}
+
+	mode, err := h.registrationMode(ctx)
+	if err != nil {
+		log.Printf("Error getting registration mode: %v", err)
+		InternalError(w, "Internal server error")
+		return
+	}
+	if mode == model.RegistrationModeClosed {
+		Error(w, http.StatusForbidden, "Pendaftaran member sedang ditutup")
+		return
+	}
+
+	req.Username = strings.TrimSpace(req.Username)
+	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+	req.FullName = strings.TrimSpace(req.FullName)
+	req.WhatsApp = strings.TrimSpace(req.WhatsApp)
+
+	if req.Username == "" || req.Password == "" || req.Email == "" || req.FullName == "" {
+		BadRequest(w, "Username, password, email, dan nama lengkap wajib diisi")
+		return
+	}
+	if !usernamePattern.MatchString(req.Username) {
+		BadRequest(w, "Username hanya boleh berisi huruf, angka, titik, dan underscore (3-50 karakter)")
+		return
+	}
+	if len(req.Password) < 6 {
+		BadRequest(w, "Password minimal 6 karakter")
+		return
+	}
+	if addr, err := mail.ParseAddress(req.Email); err != nil || addr.Address != req.Email {
+		BadRequest(w, "Format email tidak valid")
+		return
+	}
+
+	if mode == model.RegistrationModeInvite && strings.TrimSpace(req.ReferralCode) == "" {
+		BadRequest(w, "Kode undangan wajib diisi")
+		return
+	}
+	referrer, err := resolveReferrer(ctx, h.userRepo, req.ReferralCode)
+	if err != nil {
+		BadRequest(w, "Kode referral tidak valid")
+		return
+	}
+
+	// Pre-check for friendly errors; the unique constraints still guard concurrent registrations
+	if existing, _ := h.userRepo.GetByUsername(ctx, req.Username); existing != nil {
+		Error(w, http.StatusConflict, "Username sudah digunakan")
+		return
+	}
+	if existing, _ := h.userRepo.GetByEmail(ctx, req.Email); existing != nil {
+		Error(w, http.StatusConflict, "Email sudah terdaftar")
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), 12)
+	if err != nil {
+		log.Printf("Error hashing password: %v", err)
+		InternalError(w, "Internal server error")
+		return
+	}
+
+	referralCode := generateReferralCode()
+	user := &model.User{
+		Username: req.Username,
+		Password: string(hashedPassword),
+		Email:    &req.Email,
+		FullName: req.FullName,
+		Role:     model.UserRoleMember,
+		Balance:  0,
+		Status:   model.UserStatusPendingVerification,
+
+		ReferralCode: &referralCode,
+	}
+	if referrer != nil {
+		user.ReferredBy = &referrer.ID
+	}
+	if req.WhatsApp != "" {
+		user.WhatsApp = &req.WhatsApp
+	}
+
+	if err := h.userRepo.Create(ctx, user); err != nil {
+		if writeUniqueUserError(w, err) {
+			return
+		}
+		log.Printf("Error registering member: %v", err)
+		InternalError(w, "Gagal mendaftarkan akun")
+		return
+	}
+
+	log.Printf("[Register] New member %s (id=%d) registered, pending verification", user.Username, user.ID)
+
+	if err := h.sendVerification(user); err != nil {
+		log.Printf("❌ Failed to send verification email to %s: %v", req.Email, err)
+		Created(w, "Pendaftaran berhasil, namun email verifikasi gagal dikirim. Silakan minta kirim ulang.", user.ToResponse())
+		return
+	}
+
+	Created(w, "Pendaftaran berhasil. Silakan cek email Anda untuk verifikasi akun.", user.ToResponse())
+}
+
+// VerifyEmail handles POST /api/v1/member/verify-email
+func (h *RegistrationHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req model.VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		BadRequest(w, "Invalid request body")
+		return
+	}
+
+	token, err := jwt.Parse(req.Token, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method")
+		}
+		return []byte(h.config.JWTSecretGovershop), nil
+	})
+	if err != nil || !token.Valid {
+		BadRequest(w, "Token tidak valid atau sudah kadaluarsa")
+		return
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["type"] != "email_verification" {
+		BadRequest(w, "Token tidak valid")
+		return
+	}
+
+	userIDFloat, ok := claims["user_id"].(float64)
+	emailAddr, _ := claims["email"].(string)
+	if !ok || emailAddr == "" {
+		BadRequest(w, "Token tidak valid")
+		return
+	}
+	userID := int(userIDFloat)
+
+	verified, err := h.userRepo.VerifyEmail(r.Context(), userID, emailAddr)
+	if err != nil {
+		log.Printf("Error verifying email: %v", err)
+		InternalError(w, "Internal server error")
+		return
+	}
+
+	if !verified {
+		user, err := h.userRepo.GetByID(r.Context(), userID)
+		if err == nil && user != nil && user.Status == model.UserStatusActive {
+			Success(w, "Email sudah terverifikasi. Silakan login.", nil)
+			return
+		}
+		BadRequest(w, "Token tidak valid")
+		return
+	}
+
+	log.Printf("[Register] Member id=%d verified email", userID)
+	Success(w, "Email berhasil diverifikasi. Silakan login.", nil)
+}
+
+// ResendVerification handles POST /api/v1/member/resend-verification
+// Always answers with the same message so it cannot be used to probe registered emails.
+func (h *RegistrationHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	var req model.ResendVerificationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		BadRequest(w, "Invalid request body")
+		return
+	}
+
+	const message = "Jika email terdaftar dan belum diverifikasi, link verifikasi telah dikirim."
+
+	emailAddr := strings.ToLower(strings.TrimSpace(req.Email))
+	if emailAddr == "" {
+		BadRequest(w, "Email wajib diisi")
+		return
+	}
+
+	user, err := h.userRepo.GetByEmail(r.Context(), emailAddr)
+	if err != nil {
+		log.Printf("Error getting user by email: %v", err)
+		InternalError(w, "Internal server error")
+		return
+	}
+
+	if user != nil && user.Status == model.UserStatusPendingVerification {
+		if err := h.sendVerification(user); err != nil {
+			log.Printf("❌ Failed to resend verification email to %s: %v", emailAddr, err)
+			InternalError(w, "Gagal mengirim email verifikasi. Silakan coba lagi.")
+			return
+		}
+	}
+
+	Success(w, message, nil)
+}
+
+// GetSettings handles GET /api/v1/admin/settings/registration
+func (h *RegistrationHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	mode, err := h.registrationMode(r.Context())
+	if err != nil {
+		log.Printf("Error getting registration mode: %v", err)
+		InternalError(w, "Gagal mengambil pengaturan pendaftaran")
+		return
+	}
+
+	Success(w, "", model.RegistrationSettings{Mode: mode})
+}
+
+// UpdateSettings handles PUT /api/v1/admin/settings/registration
+func (h *RegistrationHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	var req model.RegistrationSettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		BadRequest(w, "Invalid request body")
+		return
+	}
+
+	if !model.ValidRegistrationMode(req.Mode) {
+		BadRequest(w, "Mode harus open, invite, atau closed")
+		return
+	}
+
+	admin, _ := r.Context().Value("user").(string)
+	if err := h.settingsRepo.Set(r.Context(), model.SettingRegistrationMode, req.Mode, admin); err != nil {
+		log.Printf("Error updating registration mode: %v", err)
+		InternalError(w, "Gagal menyimpan pengaturan pendaftaran")
+		return
+	}
+
+	log.Printf("[Register] Registration mode set to %s by %s", req.Mode, admin)
+	Success(w, "Pengaturan pendaftaran berhasil disimpan", req)
+}
+
+// sendVerification signs a 24-hour verification token and emails the link
+func (h *RegistrationHandler) sendVerification(user *model.User) error {
+	if user.Email == nil {
+		return fmt.Errorf("user has no email")
+	}
+
+	verifyToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": user.ID,
+		"email":   *user.Email,
+		"type":    "email_verification",
+		"exp":     time.Now().Add(24 * time.Hour).Unix(),
+	})
+
+	tokenString, err := verifyToken.SignedString([]byte(h.config.JWTSecretGovershop))
+	if err != nil {
+		return fmt.Errorf("failed to sign verification token: %w", err)
+	}
+
+	verifyLink := fmt.Sprintf("%s/member/verify-email?token=%s", h.config.FrontendURL, tokenString)
+	return h.emailSvc.SendVerificationEmail(*user.Email, user.FullName, verifyLink)
+}
+
+// writeUniqueUserError writes a 409 for username/email uniqueness errors.
+// Returns false if err is not a uniqueness error.
+func writeUniqueUserError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, repository.ErrUsernameTaken):
+		Error(w, http.StatusConflict, "Username sudah digunakan")
+	case errors.Is(err, repository.ErrEmailTaken):
+		Error(w, http.StatusConflict, "Email sudah terdaftar")
+	default:
+		return false
+	}
+	return true
+}
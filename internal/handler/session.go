@@ -0,0 +1,374 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"govershop-api/internal/config"
+	"govershop-api/internal/model"
+	"govershop-api/internal/repository"
+)
+
+// Session cookies
+const (
+	adminAccessCookie  = "auth_token"
+	memberAccessCookie = "member_token"
+	refreshCookie      = "refresh_token"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// sessionTokens is the result of issuing or refreshing a session
+type sessionTokens struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int // Access token lifetime in seconds
+	Session      *model.Session
+}
+
+// SessionHandler handles token refresh, logout and session listing
+type SessionHandler struct {
+	config      *config.Config
+	sessionRepo *repository.SessionRepository
+	userRepo    *repository.UserRepository
+}
+
+// NewSessionHandler creates a new SessionHandler
+func NewSessionHandler(
+	cfg *config.Config,
+	sessionRepo *repository.SessionRepository,
+	userRepo *repository.UserRepository,
+) *SessionHandler {
+	return &SessionHandler{
+		config:      cfg,
+		sessionRepo: sessionRepo,
+		userRepo:    userRepo,
+	}
+}
+
+// Refresh handles POST /api/v1/auth/refresh
+// Rotates the refresh token (body or cookie) and issues a new access token
+func (h *SessionHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req model.RefreshTokenRequest
+	_ = json.NewDecoder(r.Body).Decode(&req) // Body is optional
+
+	fromBody := req.RefreshToken != ""
+	presented := req.RefreshToken
+	if !fromBody {
+		if cookie, err := r.Cookie(refreshCookie); err == nil {
+			presented = cookie.Value
+		}
+	}
+	if presented == "" {
+		Unauthorized(w, "Sesi berakhir. Silakan login ulang.")
+		return
+	}
+
+	newRefresh, err := randomHex(32)
+	if err != nil {
+		InternalError(w, "Internal server error")
+		return
+	}
+
+	session, err := h.sessionRepo.Rotate(ctx, hashToken(presented), hashToken(newRefresh), h.refreshTTL())
+	if err != nil {
+		if errors.Is(err, repository.ErrRefreshReused) {
+			log.Printf("⚠️ [Session] Refresh token reuse detected from %s, session revoked", trustedClientIP(r, h.config.TrustedProxyHops))
+		} else if !errors.Is(err, repository.ErrSessionNotFound) && !errors.Is(err, repository.ErrSessionExpired) {
+			log.Printf("Error rotating session: %v", err)
+			InternalError(w, "Internal server error")
+			return
+		}
+		clearSessionCookies(w, h.config)
+		Unauthorized(w, "Sesi berakhir. Silakan login ulang.")
+		return
+	}
+
+	user, err := h.userRepo.GetByID(ctx, session.UserID)
+	if err != nil || user == nil || user.Status != model.UserStatusActive {
+		_ = h.sessionRepo.Revoke(ctx, session.UserID, session.ID, model.SessionRevokeSuspended)
+		clearSessionCookies(w, h.config)
+		Unauthorized(w, "Sesi berakhir. Silakan login ulang.")
+		return
+	}
+
+	tokens, err := writeSessionTokens(w, h.config, user, session, newRefresh)
+	if err != nil {
+		log.Printf("Error signing token: %v", err)
+		InternalError(w, "Gagal generate token")
+		return
+	}
+
+	data := map[string]interface{}{
+		"token":      tokens.AccessToken,
+		"expires_in": tokens.ExpiresIn,
+	}
+	if fromBody {
+		data["refresh_token"] = tokens.RefreshToken
+	}
+	Success(w, "", data)
+}
+
+// Logout handles POST /api/v1/member/logout and POST /api/v1/admin/logout
+func (h *SessionHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value("user_id").(int)
+	sessionID, _ := ctx.Value("session_id").(string)
+
+	if err := h.sessionRepo.Revoke(ctx, userID, sessionID, model.SessionRevokeLogout); err != nil && !errors.Is(err, repository.ErrSessionNotFound) {
+		log.Printf("Error revoking session: %v", err)
+		InternalError(w, "Gagal logout")
+		return
+	}
+
+	clearSessionCookies(w, h.config)
+	Success(w, "Logout berhasil", nil)
+}
+
+// LogoutAll handles POST /api/v1/member/logout-all and POST /api/v1/admin/logout-all
+// Revokes every session of the user, including the current one
+func (h *SessionHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value("user_id").(int)
+
+	if err := h.sessionRepo.RevokeAllForUser(ctx, userID, model.SessionRevokeLogoutAll); err != nil {
+		log.Printf("Error revoking sessions: %v", err)
+		InternalError(w, "Gagal logout dari semua perangkat")
+		return
+	}
+
+	clearSessionCookies(w, h.config)
+	Success(w, "Berhasil logout dari semua perangkat", nil)
+}
+
+// GetSessions handles GET /api/v1/member/sessions and GET /api/v1/admin/sessions
+func (h *SessionHandler) GetSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value("user_id").(int)
+	sessionID, _ := ctx.Value("session_id").(string)
+
+	sessions, err := h.sessionRepo.ListActive(ctx, userID)
+	if err != nil {
+		log.Printf("Error getting sessions: %v", err)
+		InternalError(w, "Gagal mengambil daftar sesi")
+		return
+	}
+	if sessions == nil {
+		sessions = []model.Session{}
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == sessionID
+	}
+
+	Success(w, "", sessions)
+}
+
+// RevokeSession handles DELETE /api/v1/member/sessions/{id} and DELETE /api/v1/admin/sessions/{id}
+func (h *SessionHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value("user_id").(int)
+	id := r.PathValue("id")
+
+	if !uuidPattern.MatchString(id) {
+		BadRequest(w, "Invalid session ID")
+		return
+	}
+
+	if err := h.sessionRepo.Revoke(ctx, userID, id, model.SessionRevokeLogout); err != nil {
+		if errors.Is(err, repository.ErrSessionNotFound) {
+			NotFound(w, "Sesi tidak ditemukan")
+			return
+		}
+		log.Printf("Error revoking session: %v", err)
+		InternalError(w, "Gagal mengakhiri sesi")
+		return
+	}
+
+	Success(w, "Sesi berhasil diakhiri", nil)
+}
+
+// StartCleanupJob deletes old expired/revoked sessions once a day
+func (h *SessionHandler) StartCleanupJob(ctx context.Context) {
+	interval := 24 * time.Hour
+	ticker := time.NewTicker(interval)
+
+	log.Printf("[Session] Cleanup job initialized. Running every %v", interval)
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				ticker.Stop()
+				return
+			case <-ticker.C:
+				deleted, err := h.sessionRepo.DeleteExpired(context.Background())
+				if err != nil {
+					log.Printf("[Session] Failed to delete expired sessions: %v", err)
+					continue
+				}
+				if deleted > 0 {
+					log.Printf("[Session] Deleted %d expired sessions", deleted)
+				}
+			}
+		}
+	}()
+}
+
+func (h *SessionHandler) refreshTTL() time.Duration {
+	return time.Duration(h.config.RefreshTokenTTLDays) * 24 * time.Hour
+}
+
+// issueSession creates a session for a successful login and writes the auth cookies
+func issueSession(ctx context.Context, w http.ResponseWriter, r *http.Request, cfg *config.Config, sessionRepo *repository.SessionRepository, user *model.User, channel string) (*sessionTokens, error) {
+	refresh, err := randomHex(32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	userAgent := r.UserAgent()
+	if len(userAgent) > 500 {
+		userAgent = userAgent[:500]
+	}
+
+	session := &model.Session{
+		UserID:    user.ID,
+		Channel:   channel,
+		Device:    deviceFromUserAgent(userAgent),
+		IPAddress: trustedClientIP(r, cfg.TrustedProxyHops),
+		UserAgent: userAgent,
+	}
+	refreshTTL := time.Duration(cfg.RefreshTokenTTLDays) * 24 * time.Hour
+	if err := sessionRepo.Create(ctx, session, hashToken(refresh), refreshTTL); err != nil {
+		return nil, err
+	}
+
+	return writeSessionTokens(w, cfg, user, session, refresh)
+}
+
+// writeSessionTokens signs the access token for a session and sets the access and refresh cookies
+func writeSessionTokens(w http.ResponseWriter, cfg *config.Config, user *model.User, session *model.Session, refresh string) (*sessionTokens, error) {
+	now := time.Now()
+	accessTTL := time.Duration(cfg.AccessTokenTTLMinutes) * time.Minute
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":     user.Username,
+		"user_id": user.ID,
+		"role":    user.Role,
+		"sid":     session.ID,
+		"iat":     now.Unix(),
+		"exp":     now.Add(accessTTL).Unix(),
+	})
+
+	tokenString, err := token.SignedString([]byte(cfg.JWTSecretGovershop))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	accessCookie := memberAccessCookie
+	if session.Channel == model.SessionChannelAdmin {
+		accessCookie = adminAccessCookie
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     accessCookie,
+		Value:    tokenString,
+		Expires:  now.Add(accessTTL),
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   cfg.Env == "production",
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     refreshCookie,
+		Value:    refresh,
+		Expires:  now.Add(time.Duration(cfg.RefreshTokenTTLDays) * 24 * time.Hour),
+		Path:     "/api/v1/auth",
+		HttpOnly: true,
+		Secure:   cfg.Env == "production",
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return &sessionTokens{
+		AccessToken:  tokenString,
+		RefreshToken: refresh,
+		ExpiresIn:    int(accessTTL.Seconds()),
+		Session:      session,
+	}, nil
+}
+
+// clearSessionCookies removes the access and refresh cookies
+func clearSessionCookies(w http.ResponseWriter, cfg *config.Config) {
+	for _, c := range []struct{ name, path string }{
+		{adminAccessCookie, "/"},
+		{memberAccessCookie, "/"},
+		{refreshCookie, "/api/v1/auth"},
+	} {
+		http.SetCookie(w, &http.Cookie{
+			Name:     c.name,
+			Value:    "",
+			Path:     c.path,
+			MaxAge:   -1,
+			HttpOnly: true,
+			Secure:   cfg.Env == "production",
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+}
+
+// hashToken returns the SHA-256 hex digest stored for refresh tokens
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// deviceFromUserAgent returns a short "Browser on OS" label
+func deviceFromUserAgent(ua string) string {
+	if ua == "" {
+		return "Unknown"
+	}
+
+	os := "Unknown OS"
+	switch {
+	case strings.Contains(ua, "Android"):
+		os = "Android"
+	case strings.Contains(ua, "iPhone"), strings.Contains(ua, "iPad"):
+		os = "iOS"
+	case strings.Contains(ua, "Windows"):
+		os = "Windows"
+	case strings.Contains(ua, "Mac OS"):
+		os = "macOS"
+	case strings.Contains(ua, "Linux"):
+		os = "Linux"
+	}
+
+	browser := "Unknown browser"
+	switch {
+	case strings.Contains(ua, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "OPR/"):
+		browser = "Opera"
+	case strings.Contains(ua, "Chrome/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "Firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "Safari/"):
+		browser = "Safari"
+	case strings.HasPrefix(ua, "curl/"), strings.HasPrefix(ua, "Go-http-client"), strings.HasPrefix(ua, "PostmanRuntime"):
+		return strings.SplitN(ua, " ", 2)[0]
+	}
+
+	return browser + " on " + os
+}
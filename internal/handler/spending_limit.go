@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"govershop-api/internal/model"
+	"govershop-api/internal/repository"
+)
+
+var memberLevelPattern = regexp.MustCompile(`^[a-z0-9_-]{1,30}$`)
+
+// SpendingLimitHandler handles member levels and spending limits
+type SpendingLimitHandler struct {
+	limitRepo *repository.SpendingLimitRepository
+	userRepo  *repository.UserRepository
+}
+
+// NewSpendingLimitHandler creates a new SpendingLimitHandler
+func NewSpendingLimitHandler(limitRepo *repository.SpendingLimitRepository, userRepo *repository.UserRepository) *SpendingLimitHandler {
+	return &SpendingLimitHandler{
+		limitRepo: limitRepo,
+		userRepo:  userRepo,
+	}
+}
+
+// GetMyLimits handles GET /api/v1/member/limits
+func (h *SpendingLimitHandler) GetMyLimits(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	usage, err := h.limitRepo.GetUsage(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error getting spending usage: %v", err)
+		InternalError(w, "Gagal mengambil batas transaksi")
+		return
+	}
+	usage.Override = nil // Admin notes stay internal
+
+	Success(w, "", usage)
+}
+
+// GetMemberLimits handles GET /api/v1/admin/members/{id}/limits
+func (h *SpendingLimitHandler) GetMemberLimits(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.memberID(w, r)
+	if !ok {
+		return
+	}
+
+	usage, err := h.limitRepo.GetUsage(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error getting spending usage: %v", err)
+		InternalError(w, "Gagal mengambil batas transaksi")
+		return
+	}
+
+	Success(w, "", usage)
+}
+
+// UpdateMemberLimits handles PUT /api/v1/admin/members/{id}/limits
+// Overrides the level limits of one member (null inherits, 0 lifts the limit)
+func (h *SpendingLimitHandler) UpdateMemberLimits(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := h.memberID(w, r)
+	if !ok {
+		return
+	}
+
+	var req model.MemberLimitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		BadRequest(w, "Format request tidak valid")
+		return
+	}
+	if msg := validateSpendingLimits(&req.SpendingLimits); msg != "" {
+		BadRequest(w, msg)
+		return
+	}
+
+	override := model.MemberLimitOverride{
+		UserID:         userID,
+		Note:           strings.TrimSpace(req.Note),
+		UpdatedBy:      ctx.Value("user").(string),
+		SpendingLimits: req.SpendingLimits,
+	}
+
+	if err := h.limitRepo.SetOverride(ctx, &override, strings.TrimSpace(req.Level)); err != nil {
+		if errors.Is(err, repository.ErrMemberLevelNotFound) {
+			BadRequest(w, "Level member tidak ditemukan")
+			return
+		}
+		log.Printf("Error saving member limits: %v", err)
+		InternalError(w, "Gagal menyimpan batas transaksi")
+		return
+	}
+
+	usage, err := h.limitRepo.GetUsage(ctx, userID)
+	if err != nil {
+		log.Printf("Error getting spending usage: %v", err)
+		InternalError(w, "Gagal mengambil batas transaksi")
+		return
+	}
+
+	Success(w, "Batas transaksi member berhasil disimpan", usage)
+}
+
+// DeleteMemberLimits handles DELETE /api/v1/admin/members/{id}/limits
+// Removes the override so the member's level limits apply again
+func (h *SpendingLimitHandler) DeleteMemberLimits(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.memberID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.limitRepo.DeleteOverride(r.Context(), userID); err != nil {
+		log.Printf("Error deleting member limits: %v", err)
+		InternalError(w, "Gagal menghapus batas transaksi")
+		return
+	}
+
+	Success(w, "Batas transaksi member dikembalikan ke level", nil)
+}
+
+// GetLevels handles GET /api/v1/admin/member-levels
+func (h *SpendingLimitHandler) GetLevels(w http.ResponseWriter, r *http.Request) {
+	levels, err := h.limitRepo.ListLevels(r.Context())
+	if err != nil {
+		log.Printf("Error getting member levels: %v", err)
+		InternalError(w, "Gagal mengambil level member")
+		return
+	}
+
+	Success(w, "", levels)
+}
+
+// UpdateLevel handles PUT /api/v1/admin/member-levels/{level}
+// Creates the level if it does not exist
+func (h *SpendingLimitHandler) UpdateLevel(w http.ResponseWriter, r *http.Request) {
+	level := r.PathValue("level")
+	if !memberLevelPattern.MatchString(level) {
+		BadRequest(w, "Kode level hanya boleh huruf kecil, angka, _ dan - (maks 30)")
+		return
+	}
+
+	var req model.MemberLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		BadRequest(w, "Format request tidak valid")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		req.Name = level
+	}
+	if msg := validateSpendingLimits(&req.SpendingLimits); msg != "" {
+		BadRequest(w, msg)
+		return
+	}
+
+	l := model.MemberLevel{
+		Level:          level,
+		Name:           req.Name,
+		UpdatedBy:      r.Context().Value("user").(string),
+		SpendingLimits: req.SpendingLimits,
+	}
+	if err := h.limitRepo.UpsertLevel(r.Context(), &l); err != nil {
+		log.Printf("Error saving member level: %v", err)
+		InternalError(w, "Gagal menyimpan level member")
+		return
+	}
+
+	Success(w, "Level member berhasil disimpan", l)
+}
+
+// memberID parses the member ID path value and checks the member exists
+func (h *SpendingLimitHandler) memberID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		BadRequest(w, "Invalid member ID")
+		return 0, false
+	}
+
+	user, err := h.userRepo.GetByID(r.Context(), userID)
+	if err != nil || user == nil || user.Role != model.UserRoleMember {
+		NotFound(w, "Member tidak ditemukan")
+		return 0, false
+	}
+	return userID, true
+}
+
+// validateSpendingLimits rejects negative limits and tidies the brand list
+func validateSpendingLimits(l *model.SpendingLimits) string {
+	if (l.MaxOrderAmount != nil && *l.MaxOrderAmount < 0) ||
+		(l.MaxDailySpend != nil && *l.MaxDailySpend < 0) ||
+		(l.MaxOrdersPerHour != nil && *l.MaxOrdersPerHour < 0) {
+		return "Batas tidak boleh negatif"
+	}
+
+	if l.AllowedBrands != nil {
+		brands := make([]string, 0, len(l.AllowedBrands))
+		for _, b := range l.AllowedBrands {
+			if b = strings.TrimSpace(b); b != "" {
+				brands = append(brands, b)
+			}
+		}
+		l.AllowedBrands = brands
+	}
+	return ""
+}
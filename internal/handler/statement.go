@@ -0,0 +1,265 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"time"
+
+	"govershop-api/internal/model"
+	"govershop-api/internal/repository"
+	"govershop-api/internal/service/statement"
+)
+
+// Statement date range limits
+const (
+	statementDirectMaxDays = 31  // Longer ranges must go through a background job
+	statementMaxDays       = 366 // Longest range a job accepts
+)
+
+var statementIDPattern = regexp.MustCompile(`^[0-9a-fA-F-]{36}$`)
+
+// StatementHandler handles member statement exports
+type StatementHandler struct {
+	statementRepo *repository.StatementRepository
+}
+
+// NewStatementHandler creates a new StatementHandler
+func NewStatementHandler(statementRepo *repository.StatementRepository) *StatementHandler {
+	return &StatementHandler{
+		statementRepo: statementRepo,
+	}
+}
+
+// DownloadStatement handles GET /api/v1/member/statement?date_from=&date_to=&format=csv|pdf
+// Generates the statement right away for ranges up to 31 days
+func (h *StatementHandler) DownloadStatement(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value("user_id").(int)
+
+	q := r.URL.Query()
+	format := q.Get("format")
+	if format == "" {
+		format = model.StatementFormatCSV
+	}
+
+	days, msg := validateStatementRequest(q.Get("date_from"), q.Get("date_to"), format)
+	if msg != "" {
+		BadRequest(w, msg)
+		return
+	}
+	if days > statementDirectMaxDays {
+		BadRequest(w, fmt.Sprintf("Periode lebih dari %d hari, gunakan export di latar belakang", statementDirectMaxDays))
+		return
+	}
+
+	st, err := h.statementRepo.Build(ctx, userID, q.Get("date_from"), q.Get("date_to"))
+	if err != nil {
+		log.Printf("Error building statement: %v", err)
+		InternalError(w, "Gagal membuat laporan mutasi")
+		return
+	}
+
+	data, err := renderStatement(st, format)
+	if err != nil {
+		log.Printf("Error rendering statement: %v", err)
+		InternalError(w, "Gagal membuat laporan mutasi")
+		return
+	}
+
+	writeStatementFile(w, format, statement.FileName(st, format), data)
+}
+
+// CreateStatementJob handles POST /api/v1/member/statements
+// Queues a statement export for ranges up to a year
+func (h *StatementHandler) CreateStatementJob(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value("user_id").(int)
+
+	var req model.StatementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		BadRequest(w, "Format request tidak valid")
+		return
+	}
+	if req.Format == "" {
+		req.Format = model.StatementFormatPDF
+	}
+
+	if _, msg := validateStatementRequest(req.DateFrom, req.DateTo, req.Format); msg != "" {
+		BadRequest(w, msg)
+		return
+	}
+
+	job, err := h.statementRepo.CreateJob(ctx, userID, req.DateFrom, req.DateTo, req.Format)
+	if err != nil {
+		log.Printf("Error creating statement job: %v", err)
+		InternalError(w, "Gagal membuat laporan mutasi")
+		return
+	}
+
+	JSON(w, http.StatusAccepted, map[string]interface{}{
+		"success": true,
+		"message": "Laporan mutasi sedang dibuat",
+		"data":    job,
+	})
+}
+
+// GetStatementJobs handles GET /api/v1/member/statements
+func (h *StatementHandler) GetStatementJobs(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	jobs, err := h.statementRepo.ListJobs(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error getting statement jobs: %v", err)
+		InternalError(w, "Gagal mengambil daftar laporan")
+		return
+	}
+
+	Success(w, "", jobs)
+}
+
+// DownloadStatementJob handles GET /api/v1/member/statements/{id}/download
+func (h *StatementHandler) DownloadStatementJob(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value("user_id").(int)
+
+	id := r.PathValue("id")
+	if !statementIDPattern.MatchString(id) {
+		NotFound(w, "Laporan tidak ditemukan")
+		return
+	}
+
+	job, data, err := h.statementRepo.GetJobFile(ctx, userID, id)
+	if err != nil {
+		log.Printf("Error getting statement job: %v", err)
+		InternalError(w, "Gagal mengambil laporan")
+		return
+	}
+	if job == nil {
+		NotFound(w, "Laporan tidak ditemukan")
+		return
+	}
+	if job.Status != model.StatementStatusReady {
+		Error(w, http.StatusConflict, "Laporan belum siap")
+		return
+	}
+
+	writeStatementFile(w, job.Format, job.FileName, data)
+}
+
+// validateStatementRequest checks the date range and format.
+// Returns the number of days in the range, or a user-facing error message.
+func validateStatementRequest(dateFrom, dateTo, format string) (int, string) {
+	if format != model.StatementFormatCSV && format != model.StatementFormatPDF {
+		return 0, "Format harus csv atau pdf"
+	}
+
+	from, err := time.Parse("2006-01-02", dateFrom)
+	if err != nil {
+		return 0, "date_from tidak valid (format YYYY-MM-DD)"
+	}
+	to, err := time.Parse("2006-01-02", dateTo)
+	if err != nil {
+		return 0, "date_to tidak valid (format YYYY-MM-DD)"
+	}
+	if to.Before(from) {
+		return 0, "date_to harus setelah date_from"
+	}
+
+	days := int(to.Sub(from).Hours()/24) + 1
+	if days > statementMaxDays {
+		return 0, fmt.Sprintf("Periode maksimal %d hari", statementMaxDays)
+	}
+	return days, ""
+}
+
+func renderStatement(st *model.Statement, format string) ([]byte, error) {
+	if format == model.StatementFormatPDF {
+		return statement.RenderPDF(st), nil
+	}
+
+	var buf bytes.Buffer
+	if err := statement.WriteCSV(&buf, st); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeStatementFile(w http.ResponseWriter, format, fileName string, data []byte) {
+	contentType := "text/csv; charset=utf-8"
+	if format == model.StatementFormatPDF {
+		contentType = "application/pdf"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// StartStatementWorker generates queued statements every 15 seconds and
+// removes expired files every hour
+func (h *StatementHandler) StartStatementWorker(ctx context.Context) {
+	interval := 15 * time.Second
+	ticker := time.NewTicker(interval)
+
+	log.Printf("[Statement] Worker initialized. Running every %v", interval)
+
+	go func() {
+		var lastCleanup time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				ticker.Stop()
+				return
+			case <-ticker.C:
+				h.processJobs(ctx)
+
+				if time.Since(lastCleanup) > time.Hour {
+					lastCleanup = time.Now()
+					if deleted, err := h.statementRepo.DeleteExpired(ctx); err != nil {
+						log.Printf("[Statement] Failed to delete expired statements: %v", err)
+					} else if deleted > 0 {
+						log.Printf("[Statement] Deleted %d expired statements", deleted)
+					}
+				}
+			}
+		}
+	}()
+}
+
+func (h *StatementHandler) processJobs(ctx context.Context) {
+	for {
+		job, err := h.statementRepo.ClaimJob(ctx)
+		if err != nil {
+			log.Printf("[Statement] Failed to claim job: %v", err)
+			return
+		}
+		if job == nil {
+			return
+		}
+
+		st, err := h.statementRepo.Build(ctx, job.UserID, job.DateFrom, job.DateTo)
+		var data []byte
+		if err == nil {
+			data, err = renderStatement(st, job.Format)
+		}
+		if err != nil {
+			log.Printf("[Statement] Job %s failed: %v", job.ID, err)
+			if fErr := h.statementRepo.FailJob(ctx, job.ID, "Gagal membuat laporan mutasi"); fErr != nil {
+				log.Printf("[Statement] %v", fErr)
+			}
+			continue
+		}
+
+		if err := h.statementRepo.CompleteJob(ctx, job.ID, statement.FileName(st, job.Format), data); err != nil {
+			log.Printf("[Statement] %v", err)
+			continue
+		}
+		log.Printf("[Statement] Job %s ready (%d entries, %s)", job.ID, len(st.Entries), job.Format)
+	}
+}
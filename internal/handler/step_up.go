@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+
+	"govershop-api/internal/config"
+	"govershop-api/internal/middleware"
+	"govershop-api/internal/repository"
+)
+
+// StepUpHandler issues elevated tokens for sensitive admin actions
+type StepUpHandler struct {
+	config       *config.Config
+	userRepo     *repository.UserRepository
+	securityRepo *repository.AdminSecurityRepository
+}
+
+// NewStepUpHandler creates a new StepUpHandler
+func NewStepUpHandler(cfg *config.Config, userRepo *repository.UserRepository, securityRepo *repository.AdminSecurityRepository) *StepUpHandler {
+	return &StepUpHandler{
+		config:       cfg,
+		userRepo:     userRepo,
+		securityRepo: securityRepo,
+	}
+}
+
+// Reauth handles POST /api/v1/admin/reauth
+// Checks the admin's own password (and TOTP or a recovery code when enabled) and
+// returns a short-lived token for the X-Step-Up-Token header. The token is bound
+// to the current session.
+func (h *StepUpHandler) Reauth(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value("user_id").(int)
+	sessionID, _ := ctx.Value("session_id").(string)
+
+	var req struct {
+		Password string `json:"password"`
+		TOTPCode string `json:"totp_code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		BadRequest(w, "Format request tidak valid")
+		return
+	}
+
+	user, err := h.userRepo.GetByID(ctx, userID)
+	if err != nil || user == nil {
+		InternalError(w, "Gagal mengambil data admin")
+		return
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)) != nil {
+		Unauthorized(w, "Password tidak valid")
+		return
+	}
+
+	security, err := h.securityRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		log.Printf("Error getting admin security: %v", err)
+		InternalError(w, "Gagal mengecek status TOTP")
+		return
+	}
+	if security.TOTPEnabled && !verifyAdminCode(r, h.securityRepo, security.TOTPSecret, req.TOTPCode) {
+		JSON(w, http.StatusUnauthorized, map[string]interface{}{
+			"success":       false,
+			"error":         "Kode TOTP atau kode pemulihan tidak valid",
+			"totp_required": true,
+		})
+		return
+	}
+
+	ttl := time.Duration(h.config.StepUpTTLMinutes) * time.Minute
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": userID,
+		"sid":     sessionID,
+		"type":    "admin_step_up",
+		"totp":    security.TOTPEnabled,
+		"iat":     now.Unix(),
+		"exp":     now.Add(ttl).Unix(),
+	})
+	signed, err := token.SignedString([]byte(h.config.JWTSecretGovershop))
+	if err != nil {
+		InternalError(w, "Gagal generate token")
+		return
+	}
+
+	middleware.AuditDetail(ctx, "totp", security.TOTPEnabled)
+
+	Success(w, "Verifikasi berhasil", map[string]interface{}{
+		"step_up_token": signed,
+		"expires_in":    int(ttl.Seconds()),
+		"totp_verified": security.TOTPEnabled,
+	})
+}
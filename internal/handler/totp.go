@@ -7,13 +7,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"image/png"
+	"log"
 	"net/http"
 	"time"
 
 	"govershop-api/internal/config"
+	"govershop-api/internal/middleware"
 	"govershop-api/internal/model"
 	"govershop-api/internal/repository"
 	"govershop-api/internal/service/digiflazz"
+	"govershop-api/internal/service/email"
 
 	"github.com/pquerna/otp/totp"
 )
@@ -26,6 +29,12 @@ type TOTPHandler struct {
 	paymentRepo      *repository.PaymentRepository
 	digiflazzSvc     *digiflazz.Service
 	maxTopupsPerHour int
+
+	memberWebhookRepo *repository.MemberWebhookRepository
+	fulfillment       *OrderFulfillment
+
+	userRepo *repository.UserRepository
+	emailSvc *email.Service
 }
 
 // NewTOTPHandler creates a new TOTPHandler
@@ -35,6 +44,10 @@ func NewTOTPHandler(
 	orderRepo *repository.OrderRepository,
 	paymentRepo *repository.PaymentRepository,
 	digiflazzSvc *digiflazz.Service,
+	memberWebhookRepo *repository.MemberWebhookRepository,
+	fulfillment *OrderFulfillment,
+	userRepo *repository.UserRepository,
+	emailSvc *email.Service,
 ) *TOTPHandler {
 	return &TOTPHandler{
 		config:           cfg,
@@ -43,6 +56,12 @@ func NewTOTPHandler(
 		paymentRepo:      paymentRepo,
 		digiflazzSvc:     digiflazzSvc,
 		maxTopupsPerHour: 20, // Rate limit
+
+		memberWebhookRepo: memberWebhookRepo,
+		fulfillment:       fulfillment,
+
+		userRepo: userRepo,
+		emailSvc: emailSvc,
 	}
 }
 
@@ -50,7 +69,7 @@ func NewTOTPHandler(
 func (h *TOTPHandler) GetTOTPStatus(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	security, err := h.securityRepo.GetPrimary(ctx)
+	security, err := h.securityRepo.GetByUserID(ctx, ctx.Value("user_id").(int))
 	if err != nil {
 		// If not found, TOTP is not set up
 		Success(w, "", map[string]interface{}{
@@ -60,9 +79,15 @@ func (h *TOTPHandler) GetTOTPStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	remaining, err := h.securityRepo.CountRecoveryCodes(ctx, ctx.Value("user_id").(int))
+	if err != nil {
+		log.Printf("Error counting recovery codes: %v", err)
+	}
+
 	Success(w, "", map[string]interface{}{
-		"enabled": security.TOTPEnabled,
-		"setup":   security.TOTPSecret != "",
+		"enabled":                  security.TOTPEnabled,
+		"setup":                    security.TOTPSecret != "",
+		"recovery_codes_remaining": remaining,
 	})
 }
 
@@ -72,16 +97,17 @@ func (h *TOTPHandler) SetupTOTP(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	// Check if already enabled
-	security, _ := h.securityRepo.GetPrimary(ctx)
+	security, _ := h.securityRepo.GetByUserID(ctx, ctx.Value("user_id").(int))
 	if security != nil && security.TOTPEnabled {
 		BadRequest(w, "TOTP sudah aktif. Nonaktifkan dulu untuk setup ulang.")
 		return
 	}
 
-	// Generate new TOTP key
+	// Generate new TOTP key (one per admin account)
+	account := ctx.Value("user").(string) + "@govershop"
 	key, err := totp.Generate(totp.GenerateOpts{
 		Issuer:      "Govershop Admin",
-		AccountName: "admin@govershop",
+		AccountName: account,
 		SecretSize:  32,
 	})
 	if err != nil {
@@ -90,7 +116,7 @@ func (h *TOTPHandler) SetupTOTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Save secret (not enabled yet until verified)
-	err = h.securityRepo.SetTOTPSecret(ctx, key.Secret())
+	err = h.securityRepo.SetTOTPSecret(ctx, ctx.Value("user_id").(int), key.Secret())
 	if err != nil {
 		InternalError(w, "Gagal menyimpan TOTP secret")
 		return
@@ -111,14 +137,11 @@ func (h *TOTPHandler) SetupTOTP(w http.ResponseWriter, r *http.Request) {
 	}
 	qrBase64 := base64.StdEncoding.EncodeToString(buf.Bytes())
 
-	// Log action
-	h.securityRepo.CreateAuditLog(ctx, "totp_setup", "", getClientIP(r), nil, true, "")
-
 	Success(w, "Scan QR code dengan Google Authenticator", map[string]interface{}{
 		"qr_code": "data:image/png;base64," + qrBase64,
 		"secret":  key.Secret(), // For manual entry
 		"issuer":  "Govershop Admin",
-		"account": "admin@govershop",
+		"account": account,
 	})
 }
 
@@ -141,7 +164,7 @@ func (h *TOTPHandler) EnableTOTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get secret
-	security, err := h.securityRepo.GetPrimary(ctx)
+	security, err := h.securityRepo.GetByUserID(ctx, ctx.Value("user_id").(int))
 	if err != nil || security.TOTPSecret == "" {
 		BadRequest(w, "TOTP belum di-setup. Jalankan setup dulu.")
 		return
@@ -150,72 +173,59 @@ func (h *TOTPHandler) EnableTOTP(w http.ResponseWriter, r *http.Request) {
 	// Verify code
 	valid := totp.Validate(req.Code, security.TOTPSecret)
 	if !valid {
-		h.securityRepo.CreateAuditLog(ctx, "totp_enable", "", getClientIP(r), nil, false, "Invalid code")
 		Unauthorized(w, "Kode TOTP tidak valid")
 		return
 	}
 
 	// Enable TOTP
-	err = h.securityRepo.EnableTOTP(ctx, true)
+	err = h.securityRepo.EnableTOTP(ctx, ctx.Value("user_id").(int), true)
 	if err != nil {
 		InternalError(w, "Gagal mengaktifkan TOTP")
 		return
 	}
 
-	h.securityRepo.CreateAuditLog(ctx, "totp_enable", "", getClientIP(r), nil, true, "")
+	codes, err := h.issueRecoveryCodes(ctx, ctx.Value("user_id").(int))
+	if err != nil {
+		log.Printf("Error generating recovery codes: %v", err)
+	}
 
-	Success(w, "2FA berhasil diaktifkan!", nil)
+	Success(w, "2FA berhasil diaktifkan! Simpan kode pemulihan di tempat aman.", map[string]interface{}{
+		"recovery_codes": codes,
+	})
 }
 
 // DisableTOTP handles POST /api/v1/admin/totp/disable
+// The password and TOTP code are checked by the step-up re-authentication
 func (h *TOTPHandler) DisableTOTP(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	var req struct {
-		Code string `json:"code"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		BadRequest(w, "Format request tidak valid")
-		return
-	}
-
-	// Get secret
-	security, err := h.securityRepo.GetPrimary(ctx)
+	security, err := h.securityRepo.GetByUserID(ctx, ctx.Value("user_id").(int))
 	if err != nil || !security.TOTPEnabled {
 		BadRequest(w, "TOTP tidak aktif")
 		return
 	}
 
-	// Verify code before disabling
-	valid := totp.Validate(req.Code, security.TOTPSecret)
-	if !valid {
-		Unauthorized(w, "Kode TOTP tidak valid")
-		return
-	}
-
 	// Disable TOTP
-	err = h.securityRepo.EnableTOTP(ctx, false)
+	err = h.securityRepo.EnableTOTP(ctx, ctx.Value("user_id").(int), false)
 	if err != nil {
 		InternalError(w, "Gagal menonaktifkan TOTP")
 		return
 	}
 
-	// Clear secret
-	h.securityRepo.SetTOTPSecret(ctx, "")
-
-	h.securityRepo.CreateAuditLog(ctx, "totp_disable", "", getClientIP(r), nil, true, "")
+	// Clear secret and recovery codes
+	h.securityRepo.SetTOTPSecret(ctx, ctx.Value("user_id").(int), "")
+	h.securityRepo.ReplaceRecoveryCodes(ctx, ctx.Value("user_id").(int), nil)
 
 	Success(w, "2FA berhasil dinonaktifkan", nil)
 }
 
 // ManualTopup handles POST /api/v1/admin/orders/{id}/manual-topup
-// Retries a failed order with TOTP verification
+// Retries a failed order; requires step-up re-authentication
 func (h *TOTPHandler) ManualTopup(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	orderID := r.PathValue("id")
 
 	var req struct {
-		TOTPCode   string `json:"totp_code"`
 		CustomerNo string `json:"customer_no"` // Optional: new customer_no if wrong input
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -223,29 +233,6 @@ func (h *TOTPHandler) ManualTopup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get TOTP status
-	security, err := h.securityRepo.GetPrimary(ctx)
-	if err != nil {
-		InternalError(w, "Gagal mengecek status TOTP")
-		return
-	}
-
-	// Verify TOTP if enabled
-	if security.TOTPEnabled {
-		if req.TOTPCode == "" || len(req.TOTPCode) != 6 {
-			BadRequest(w, "Kode TOTP diperlukan (6 digit)")
-			return
-		}
-
-		valid := totp.Validate(req.TOTPCode, security.TOTPSecret)
-		if !valid {
-			h.securityRepo.CreateAuditLog(ctx, "manual_topup", orderID, getClientIP(r),
-				map[string]interface{}{"reason": "invalid_totp"}, false, "Invalid TOTP code")
-			Unauthorized(w, "Kode TOTP tidak valid")
-			return
-		}
-	}
-
 	// Rate limiting check
 	recentCount, err := h.securityRepo.CountRecentManualTopups(ctx)
 	if err != nil {
@@ -253,8 +240,6 @@ func (h *TOTPHandler) ManualTopup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if recentCount >= h.maxTopupsPerHour {
-		h.securityRepo.CreateAuditLog(ctx, "manual_topup", orderID, getClientIP(r),
-			map[string]interface{}{"reason": "rate_limit"}, false, "Rate limit exceeded")
 		BadRequest(w, fmt.Sprintf("Rate limit tercapai (%d topup/jam). Coba lagi nanti.", h.maxTopupsPerHour))
 		return
 	}
@@ -268,8 +253,6 @@ func (h *TOTPHandler) ManualTopup(w http.ResponseWriter, r *http.Request) {
 
 	// Validate: order must be failed
 	if order.Status != "failed" {
-		h.securityRepo.CreateAuditLog(ctx, "manual_topup", orderID, getClientIP(r),
-			map[string]interface{}{"reason": "invalid_status", "status": string(order.Status)}, false, "Order status not failed")
 		BadRequest(w, fmt.Sprintf("Order tidak dalam status gagal (status: %s)", order.Status))
 		return
 	}
@@ -277,8 +260,6 @@ func (h *TOTPHandler) ManualTopup(w http.ResponseWriter, r *http.Request) {
 	// Validate: payment must be completed
 	payment, err := h.paymentRepo.GetByOrderID(ctx, orderID)
 	if err != nil || payment == nil || payment.Status != "completed" {
-		h.securityRepo.CreateAuditLog(ctx, "manual_topup", orderID, getClientIP(r),
-			map[string]interface{}{"reason": "payment_not_completed"}, false, "Payment not completed")
 		BadRequest(w, "Pembayaran belum completed. Manual topup hanya untuk order yang sudah dibayar.")
 		return
 	}
@@ -289,79 +270,51 @@ func (h *TOTPHandler) ManualTopup(w http.ResponseWriter, r *http.Request) {
 		customerNo = req.CustomerNo
 	}
 
-	// Generate new ref_id for retry
+	// Generate new ref_id for retry; the order keeps it so the Digiflazz webhook finds the order
 	newRefID := fmt.Sprintf("RETRY-%d", time.Now().UnixMilli())
 
-	// Call Digiflazz
-	resp, err := h.digiflazzSvc.CreateTransaction(digiflazz.TopupRequest{
-		BuyerSKUCode: order.BuyerSKUCode,
-		CustomerNo:   customerNo,
-		RefID:        newRefID,
-		Testing:      false,
-	})
+	middleware.AuditDetail(ctx, "original_ref_id", order.RefID)
+	middleware.AuditDetail(ctx, "retry_ref_id", newRefID)
+	middleware.AuditDetail(ctx, "original_customer", order.CustomerNo)
+	middleware.AuditDetail(ctx, "retry_customer", customerNo)
 
-	auditDetails := map[string]interface{}{
-		"original_ref_id":   order.RefID,
-		"new_ref_id":        newRefID,
-		"sku":               order.BuyerSKUCode,
-		"original_customer": order.CustomerNo,
-		"retry_customer":    customerNo,
+	started, err := h.orderRepo.StartRetry(ctx, orderID, newRefID, customerNo)
+	if err != nil {
+		log.Printf("Error starting manual topup retry for order %s: %v", orderID, err)
+		InternalError(w, "Gagal memulai topup ulang")
+		return
 	}
+	if !started {
+		BadRequest(w, "Order sedang diproses ulang")
+		return
+	}
+	order.Status = model.OrderStatusProcessing
+	order.RetryRefID = newRefID
+	order.CustomerNo = customerNo
 
-	if err != nil {
-		h.securityRepo.CreateAuditLog(ctx, "manual_topup", orderID, getClientIP(r), auditDetails, false, err.Error())
+	// Submit through the normal fulfillment so refunds, rewards and callbacks apply
+	if err := h.fulfillment.Submit(ctx, order); err != nil {
 		InternalError(w, fmt.Sprintf("Gagal topup: %v", err))
 		return
 	}
 
-	// Update order based on response
-	if resp.Data.Status == "Sukses" {
-		// Success!
-		err = h.orderRepo.UpdateDigiflazzResponse(ctx, orderID, model.OrderStatusSuccess, resp.Data.Status, resp.Data.RC, resp.Data.SN, resp.Data.Message)
-		if err != nil {
-			InternalError(w, "Gagal update order status")
-			return
-		}
-
-		// Update customer_no if changed
-		if customerNo != order.CustomerNo {
-			h.orderRepo.UpdateCustomerNo(ctx, orderID, customerNo)
-		}
-
-		auditDetails["result"] = "success"
-		auditDetails["sn"] = resp.Data.SN
-		h.securityRepo.CreateAuditLog(ctx, "manual_topup", orderID, getClientIP(r), auditDetails, true, "")
-
+	switch order.Status {
+	case model.OrderStatusSuccess:
 		Success(w, "Manual topup berhasil!", map[string]interface{}{
 			"order_id":      orderID,
 			"status":        "success",
-			"serial_number": resp.Data.SN,
+			"serial_number": order.SerialNumber,
 			"customer_no":   customerNo,
 		})
-	} else if resp.Data.Status == "Pending" {
-		// Still pending
-		err = h.orderRepo.UpdateDigiflazzResponse(ctx, orderID, model.OrderStatusProcessing, resp.Data.Status, resp.Data.RC, "", resp.Data.Message)
-
-		// Update customer_no if changed
-		if customerNo != order.CustomerNo {
-			h.orderRepo.UpdateCustomerNo(ctx, orderID, customerNo)
-		}
-
-		auditDetails["result"] = "pending"
-		h.securityRepo.CreateAuditLog(ctx, "manual_topup", orderID, getClientIP(r), auditDetails, true, "")
-
+	case model.OrderStatusProcessing:
 		Success(w, "Topup sedang diproses", map[string]interface{}{
 			"order_id": orderID,
 			"status":   "processing",
-			"message":  resp.Data.Message,
+			"message":  order.DigiflazzMsg,
 		})
-	} else {
+	default:
 		// Failed again
-		auditDetails["result"] = "failed"
-		auditDetails["error"] = resp.Data.Message
-		h.securityRepo.CreateAuditLog(ctx, "manual_topup", orderID, getClientIP(r), auditDetails, false, resp.Data.Message)
-
-		BadRequest(w, fmt.Sprintf("Topup gagal: %s", resp.Data.Message))
+		BadRequest(w, fmt.Sprintf("Topup gagal: %s", order.DigiflazzMsg))
 	}
 }
 
@@ -373,10 +326,8 @@ func (h *TOTPHandler) CustomTopup(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		SKU        string `json:"sku"`
 		CustomerNo string `json:"customer_no"`
-		Source     string `json:"source"`    // "cash" or "gift"
-		Notes      string `json:"notes"`     // Optional notes
-		Password   string `json:"password"`  // Admin password
-		TOTPCode   string `json:"totp_code"` // TOTP code
+		Source     string `json:"source"` // "cash" or "gift"
+		Notes      string `json:"notes"`  // Optional notes
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		BadRequest(w, "Format request tidak valid")
@@ -397,32 +348,7 @@ func (h *TOTPHandler) CustomTopup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// ============ AUTHENTICATION ============
-
-	// 1. Verify Admin Password
-	if req.Password != h.config.AdminPassword {
-		h.securityRepo.CreateAuditLog(ctx, "custom_topup", "", getClientIP(r),
-			map[string]interface{}{"reason": "invalid_password", "sku": req.SKU}, false, "Invalid password")
-		Unauthorized(w, "Password admin tidak valid")
-		return
-	}
-
-	// 2. Verify TOTP if enabled
-	security, err := h.securityRepo.GetPrimary(ctx)
-	if err == nil && security.TOTPEnabled {
-		if req.TOTPCode == "" || len(req.TOTPCode) != 6 {
-			BadRequest(w, "Kode TOTP diperlukan (6 digit)")
-			return
-		}
-
-		valid := totp.Validate(req.TOTPCode, security.TOTPSecret)
-		if !valid {
-			h.securityRepo.CreateAuditLog(ctx, "custom_topup", "", getClientIP(r),
-				map[string]interface{}{"reason": "invalid_totp", "sku": req.SKU}, false, "Invalid TOTP code")
-			Unauthorized(w, "Kode TOTP tidak valid")
-			return
-		}
-	}
+	// Password and TOTP are checked by the step-up re-authentication (middleware.StepUp)
 
 	// ============ RATE LIMITING ============
 	recentCount, _ := h.securityRepo.CountRecentManualTopups(ctx)
@@ -449,55 +375,37 @@ func (h *TOTPHandler) CustomTopup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// ============ CALL DIGIFLAZZ ============
-	resp, err := h.digiflazzSvc.CreateTransaction(digiflazz.TopupRequest{
-		BuyerSKUCode: req.SKU,
-		CustomerNo:   req.CustomerNo,
-		RefID:        refID,
-		Testing:      false,
-	})
-
-	auditDetails := map[string]interface{}{
-		"ref_id":      refID,
-		"sku":         req.SKU,
-		"customer_no": req.CustomerNo,
-		"source":      orderSource,
-		"notes":       req.Notes,
-	}
-
+	order, err := h.orderRepo.GetByID(ctx, orderID)
 	if err != nil {
-		// Update order as failed
-		h.orderRepo.UpdateDigiflazzResponse(ctx, orderID, model.OrderStatusFailed, "", "", "", err.Error())
-		h.securityRepo.CreateAuditLog(ctx, "custom_topup", orderID, getClientIP(r), auditDetails, false, err.Error())
-		InternalError(w, fmt.Sprintf("Gagal topup: %v", err))
+		InternalError(w, "Gagal membuat order record")
 		return
 	}
 
-	// ============ UPDATE ORDER BASED ON RESPONSE ============
-	if resp.Data.Status == "Sukses" {
-		h.orderRepo.UpdateDigiflazzResponse(ctx, orderID, model.OrderStatusSuccess,
-			resp.Data.Status, resp.Data.RC, resp.Data.SN, resp.Data.Message)
+	middleware.AuditDetail(ctx, "order_id", orderID)
+	middleware.AuditDetail(ctx, "ref_id", refID)
+	middleware.AuditDetail(ctx, "sku", req.SKU)
+	middleware.AuditDetail(ctx, "customer_no", req.CustomerNo)
+	middleware.AuditDetail(ctx, "source", orderSource)
 
-		auditDetails["result"] = "success"
-		auditDetails["sn"] = resp.Data.SN
-		h.securityRepo.CreateAuditLog(ctx, "custom_topup", orderID, getClientIP(r), auditDetails, true, "")
+	// ============ CALL DIGIFLAZZ ============
+	if err := h.fulfillment.Submit(ctx, order); err != nil {
+		InternalError(w, fmt.Sprintf("Gagal topup: %v", err))
+		return
+	}
 
+	// ============ RESPOND BASED ON RESULT ============
+	switch order.Status {
+	case model.OrderStatusSuccess:
 		Success(w, "Custom topup berhasil!", map[string]interface{}{
 			"order_id":      orderID,
 			"ref_id":        refID,
 			"status":        "success",
-			"serial_number": resp.Data.SN,
+			"serial_number": order.SerialNumber,
 			"product":       product.ProductName,
 			"customer_no":   req.CustomerNo,
 			"source":        orderSource,
 		})
-	} else if resp.Data.Status == "Pending" {
-		h.orderRepo.UpdateDigiflazzResponse(ctx, orderID, model.OrderStatusProcessing,
-			resp.Data.Status, resp.Data.RC, "", resp.Data.Message)
-
-		auditDetails["result"] = "pending"
-		h.securityRepo.CreateAuditLog(ctx, "custom_topup", orderID, getClientIP(r), auditDetails, true, "")
-
+	case model.OrderStatusProcessing:
 		Success(w, "Topup sedang diproses", map[string]interface{}{
 			"order_id":    orderID,
 			"ref_id":      refID,
@@ -506,15 +414,8 @@ func (h *TOTPHandler) CustomTopup(w http.ResponseWriter, r *http.Request) {
 			"customer_no": req.CustomerNo,
 			"source":      orderSource,
 		})
-	} else {
-		h.orderRepo.UpdateDigiflazzResponse(ctx, orderID, model.OrderStatusFailed,
-			resp.Data.Status, resp.Data.RC, "", resp.Data.Message)
-
-		auditDetails["result"] = "failed"
-		auditDetails["error"] = resp.Data.Message
-		h.securityRepo.CreateAuditLog(ctx, "custom_topup", orderID, getClientIP(r), auditDetails, false, resp.Data.Message)
-
-		BadRequest(w, fmt.Sprintf("Topup gagal: %s", resp.Data.Message))
+	default:
+		BadRequest(w, fmt.Sprintf("Topup gagal: %s", order.DigiflazzMsg))
 	}
 }
 
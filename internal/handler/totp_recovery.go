@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"govershop-api/internal/middleware"
+	"govershop-api/internal/repository"
+)
+
+// Admin TOTP recovery settings
+const (
+	recoveryCodeCount = 10
+	totpResetTTL      = 30 * time.Minute
+)
+
+// verifyAdminCode accepts a 6-digit TOTP code or one of the logged in admin's
+// unused recovery codes. A recovery code is used up when it matches.
+func verifyAdminCode(r *http.Request, repo *repository.AdminSecurityRepository, secret, code string) bool {
+	ctx := r.Context()
+	userID := ctx.Value("user_id").(int)
+
+	if validTOTPCode(code, secret) {
+		return true
+	}
+
+	normalized := normalizeRecoveryCode(code)
+	if len(normalized) != 10 {
+		return false
+	}
+
+	used, err := repo.UseRecoveryCode(ctx, userID, hashToken(normalized))
+	if err != nil {
+		log.Printf("Error using recovery code: %v", err)
+		return false
+	}
+	if used {
+		log.Printf("[TOTP] Admin id=%d used a recovery code", userID)
+		middleware.AuditDetail(ctx, "recovery_code_used", true)
+	}
+	return used
+}
+
+// normalizeRecoveryCode upper-cases a recovery code and drops separators
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+// generateRecoveryCodes returns new recovery codes (XXXXX-XXXXX) and their hashes
+func generateRecoveryCodes() (codes, hashes []string, err error) {
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 6)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b)[:10]
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, hashToken(raw))
+	}
+	return codes, hashes, nil
+}
+
+// issueRecoveryCodes replaces the admin's recovery codes and returns the new ones
+func (h *TOTPHandler) issueRecoveryCodes(ctx context.Context, userID int) ([]string, error) {
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := h.securityRepo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes handles POST /api/v1/admin/totp/recovery-codes
+// Replaces all recovery codes; requires a valid TOTP code
+func (h *TOTPHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value("user_id").(int)
+
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		BadRequest(w, "Format request tidak valid")
+		return
+	}
+
+	security, err := h.securityRepo.GetByUserID(ctx, userID)
+	if err != nil || !security.TOTPEnabled {
+		BadRequest(w, "TOTP tidak aktif")
+		return
+	}
+
+	if !validTOTPCode(req.Code, security.TOTPSecret) {
+		Unauthorized(w, "Kode TOTP tidak valid")
+		return
+	}
+
+	codes, err := h.issueRecoveryCodes(ctx, userID)
+	if err != nil {
+		log.Printf("Error generating recovery codes: %v", err)
+		InternalError(w, "Gagal membuat kode pemulihan")
+		return
+	}
+
+	Success(w, "Kode pemulihan baru dibuat. Kode lama tidak berlaku lagi.", map[string]interface{}{
+		"recovery_codes": codes,
+	})
+}
+
+// RequestTOTPReset handles POST /api/v1/admin/totp/reset
+// Break-glass for a lost device: checks the admin password and emails a one-time confirmation link
+func (h *TOTPHandler) RequestTOTPReset(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value("user_id").(int)
+
+	var req struct {
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		BadRequest(w, "Format request tidak valid")
+		return
+	}
+
+	user, err := h.userRepo.GetByID(ctx, userID)
+	if err != nil || user == nil {
+		InternalError(w, "Gagal mengambil data admin")
+		return
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)) != nil {
+		Unauthorized(w, "Password tidak valid")
+		return
+	}
+
+	security, err := h.securityRepo.GetByUserID(ctx, userID)
+	if err != nil || !security.TOTPEnabled {
+		BadRequest(w, "TOTP tidak aktif")
+		return
+	}
+
+	if user.Email == nil || *user.Email == "" {
+		BadRequest(w, "Akun admin ini belum punya email. Minta owner lain untuk reset 2FA.")
+		return
+	}
+
+	token, err := randomHex(32)
+	if err != nil {
+		InternalError(w, "Gagal membuat token reset")
+		return
+	}
+	if err := h.securityRepo.CreateTOTPReset(ctx, userID, hashToken(token), trustedClientIP(r, h.config.TrustedProxyHops), totpResetTTL); err != nil {
+		log.Printf("Error creating TOTP reset: %v", err)
+		InternalError(w, "Gagal membuat token reset")
+		return
+	}
+
+	resetLink := fmt.Sprintf("%s/admin/totp-reset?token=%s", h.config.FrontendURL, url.QueryEscape(token))
+	if err := h.emailSvc.SendAdminTOTPResetEmail(*user.Email, user.FullName, resetLink, trustedClientIP(r, h.config.TrustedProxyHops)); err != nil {
+		log.Printf("Error sending TOTP reset email: %v", err)
+		middleware.AuditDetail(ctx, "email_error", err.Error())
+		InternalError(w, "Gagal mengirim email konfirmasi")
+		return
+	}
+
+	Success(w, "Link konfirmasi reset 2FA telah dikirim ke email admin", nil)
+}
+
+// ConfirmTOTPReset handles POST /api/v1/admin/totp/reset/confirm
+// Turns off TOTP with the token from the confirmation email
+func (h *TOTPHandler) ConfirmTOTPReset(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value("user_id").(int)
+
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		BadRequest(w, "Format request tidak valid")
+		return
+	}
+
+	reset, err := h.securityRepo.ResetTOTP(ctx, userID, hashToken(req.Token))
+	if err != nil {
+		log.Printf("Error resetting TOTP: %v", err)
+		InternalError(w, "Gagal mereset 2FA")
+		return
+	}
+	if !reset {
+		BadRequest(w, "Token tidak valid atau sudah kadaluarsa")
+		return
+	}
+
+	log.Printf("[TOTP] Admin id=%d reset TOTP via break-glass", userID)
+	Success(w, "2FA berhasil direset. Silakan setup ulang TOTP.", nil)
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{code: "ABCDE-FGHIJ", want: "ABCDEFGHIJ"},
+		{code: "abcde-fghij", want: "ABCDEFGHIJ"},
+		{code: "ABCDE FGHIJ", want: "ABCDEFGHIJ"},
+		{code: " ab-cd e-fg hij ", want: "ABCDEFGHIJ"},
+		{code: "ABCDEFGHIJ", want: "ABCDEFGHIJ"},
+		{code: "", want: ""},
+		{code: "123456", want: "123456"},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeRecoveryCode(tt.code); got != tt.want {
+			t.Errorf("normalizeRecoveryCode(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		t.Fatalf("generateRecoveryCodes: %v", err)
+	}
+	if len(codes) != recoveryCodeCount || len(hashes) != recoveryCodeCount {
+		t.Fatalf("got %d codes and %d hashes, want %d each", len(codes), len(hashes), recoveryCodeCount)
+	}
+
+	format := regexp.MustCompile(`^[A-Z2-7]{5}-[A-Z2-7]{5}$`)
+	seen := map[string]bool{}
+	for i, code := range codes {
+		if !format.MatchString(code) {
+			t.Errorf("code %q is not XXXXX-XXXXX base32", code)
+		}
+		if seen[code] {
+			t.Errorf("code %q generated twice", code)
+		}
+		seen[code] = true
+
+		// What the admin types must hash to the stored value, however it is formatted
+		if got := hashToken(normalizeRecoveryCode(code)); got != hashes[i] {
+			t.Errorf("hash of normalized %q does not match the stored hash", code)
+		}
+		if normalized := normalizeRecoveryCode(code); len(normalized) != 10 {
+			t.Errorf("normalized code %q has length %d, want 10", normalized, len(normalized))
+		}
+	}
+}
@@ -19,6 +19,7 @@ type ValidationHandler struct {
 	config       *config.Config
 	productRepo  *repository.ProductRepository
 	orderRepo    *repository.OrderRepository
+	promoRepo    *repository.PromoRepository
 	digiflazzSvc *digiflazz.Service
 }
 
@@ -27,12 +28,14 @@ func NewValidationHandler(
 	cfg *config.Config,
 	productRepo *repository.ProductRepository,
 	orderRepo *repository.OrderRepository,
+	promoRepo *repository.PromoRepository,
 	digiflazzSvc *digiflazz.Service,
 ) *ValidationHandler {
 	return &ValidationHandler{
 		config:       cfg,
 		productRepo:  productRepo,
 		orderRepo:    orderRepo,
+		promoRepo:    promoRepo,
 		digiflazzSvc: digiflazzSvc,
 	}
 }
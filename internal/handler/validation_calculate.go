@@ -2,8 +2,10 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"math"
 	"net/http"
+	"strings"
 )
 
 // CalculatePriceRequest is the request for price calculation
@@ -11,11 +13,14 @@ type CalculatePriceRequest struct {
 	BuyerSKUCode  string `json:"buyer_sku_code"` // SKU produk yang dibeli
 	PaymentMethod string `json:"payment_method"` // qris, bni_va, dll
 	Brand         string `json:"brand"`          // Brand untuk check username
+	PromoCode     string `json:"promo_code"`     // Kode promo (opsional)
 }
 
 // CalculatePriceResponse is the response for price calculation
 type CalculatePriceResponse struct {
 	ProductPrice       float64        `json:"product_price"`        // Harga produk
+	Discount           float64        `json:"discount"`             // Potongan dari kode promo
+	PromoCode          string         `json:"promo_code,omitempty"` // Kode promo yang dipakai
 	AdminFee           float64        `json:"admin_fee"`            // Biaya check username
 	PaymentFee         float64        `json:"payment_fee"`          // Biaya payment gateway
 	TotalPrice         float64        `json:"total_price"`          // Total yang harus dibayar
@@ -77,6 +82,24 @@ func (h *ValidationHandler) CalculatePrice(w http.ResponseWriter, r *http.Reques
 		sellingPrice = *product.DiscountPrice
 	}
 
+	// Apply promo code if provided
+	var discount float64
+	var promoCode string
+	if strings.TrimSpace(req.PromoCode) != "" {
+		promo, promoDiscount, err := applyPromoCode(ctx, h.promoRepo, h.orderRepo, req.PromoCode, product, sellingPrice, nil)
+		if err != nil {
+			var pErr *promoError
+			if errors.As(err, &pErr) {
+				BadRequest(w, pErr.Error())
+				return
+			}
+			InternalError(w, "Gagal memeriksa kode promo")
+			return
+		}
+		discount = promoDiscount
+		promoCode = *promo.Code
+	}
+
 	// Flat admin fee as per requirement
 	var adminFee float64 = 10
 
@@ -90,7 +113,7 @@ func (h *ValidationHandler) CalculatePrice(w http.ResponseWriter, r *http.Reques
 		paymentFee = 0
 	case method == "paypal":
 		// Paypal: 1%
-		paymentFee = sellingPrice * 0.01
+		paymentFee = (sellingPrice - discount) * 0.01
 	case method == "artha_va" || method == "sampoerna_va":
 		// Specific VAs: 2000
 		paymentFee = 2000
@@ -112,19 +135,23 @@ func (h *ValidationHandler) CalculatePrice(w http.ResponseWriter, r *http.Reques
 	paymentFee = math.Ceil(paymentFee)
 
 	// Calculate total
-	totalPrice := sellingPrice + adminFee + paymentFee
+	totalPrice := sellingPrice - discount + adminFee + paymentFee
 
 	// Build breakdown
-	breakdown := PriceBreakdown{
-		Items: []PriceItem{
-			{Label: product.ProductName, Amount: sellingPrice},
-			{Label: "Biaya Admin", Amount: adminFee},
-			{Label: "Biaya Transaksi", Amount: paymentFee},
-		},
+	items := []PriceItem{{Label: product.ProductName, Amount: sellingPrice}}
+	if discount > 0 {
+		items = append(items, PriceItem{Label: "Diskon Promo (" + promoCode + ")", Amount: -discount})
 	}
+	items = append(items,
+		PriceItem{Label: "Biaya Admin", Amount: adminFee},
+		PriceItem{Label: "Biaya Transaksi", Amount: paymentFee},
+	)
+	breakdown := PriceBreakdown{Items: items}
 
 	Success(w, "Kalkulasi harga berhasil", CalculatePriceResponse{
 		ProductPrice:       sellingPrice,
+		Discount:           discount,
+		PromoCode:          promoCode,
 		AdminFee:           adminFee,
 		PaymentFee:         paymentFee,
 		TotalPrice:         totalPrice,
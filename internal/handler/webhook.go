@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -19,12 +18,11 @@ import (
 
 // WebhookHandler handles webhook callbacks from external services
 type WebhookHandler struct {
-	config       *config.Config
-	orderRepo    *repository.OrderRepository
-	paymentRepo  *repository.PaymentRepository
-	webhookRepo  *repository.WebhookLogRepository
-	userRepo     *repository.UserRepository
-	digiflazzSvc *digiflazz.Service
+	config      *config.Config
+	orderRepo   *repository.OrderRepository
+	paymentRepo *repository.PaymentRepository
+	webhookRepo *repository.WebhookLogRepository
+	fulfillment *OrderFulfillment
 }
 
 // NewWebhookHandler creates a new WebhookHandler
@@ -33,16 +31,14 @@ func NewWebhookHandler(
 	orderRepo *repository.OrderRepository,
 	paymentRepo *repository.PaymentRepository,
 	webhookRepo *repository.WebhookLogRepository,
-	userRepo *repository.UserRepository,
-	digiflazzSvc *digiflazz.Service,
+	fulfillment *OrderFulfillment,
 ) *WebhookHandler {
 	return &WebhookHandler{
-		config:       cfg,
-		orderRepo:    orderRepo,
-		paymentRepo:  paymentRepo,
-		webhookRepo:  webhookRepo,
-		userRepo:     userRepo,
-		digiflazzSvc: digiflazzSvc,
+		config:      cfg,
+		orderRepo:   orderRepo,
+		paymentRepo: paymentRepo,
+		webhookRepo: webhookRepo,
+		fulfillment: fulfillment,
 	}
 }
 
@@ -112,15 +108,21 @@ func (h *WebhookHandler) HandlePakasirWebhook(w http.ResponseWriter, r *http.Req
 	}
 
 	// Update order status to paid
-	if err := h.orderRepo.UpdateStatus(ctx, order.ID, model.OrderStatusPaid); err != nil {
+	paid, err := h.orderRepo.MarkPaid(ctx, order.ID)
+	if err != nil {
 		log.Printf("[Webhook] Failed to update order status: %v", err)
 		h.webhookRepo.MarkProcessed(ctx, logID, err.Error())
 		http.Error(w, "Internal Error", http.StatusInternalServerError)
 		return
 	}
+	if !paid {
+		h.webhookRepo.MarkProcessed(ctx, logID, unfulfilledPaymentNote(order))
+		w.WriteHeader(http.StatusOK)
+		return
+	}
 
 	// Process topup to Digiflazz
-	go h.processTopup(order)
+	go h.fulfillment.ProcessPaid(order)
 
 	h.webhookRepo.MarkProcessed(ctx, logID, "")
 	w.WriteHeader(http.StatusOK)
@@ -198,15 +200,20 @@ func (h *WebhookHandler) HandleQrisPWWebhook(w http.ResponseWriter, r *http.Requ
 		}
 
 		// Update order status to paid
-		if err := h.orderRepo.UpdateStatus(ctx, order.ID, model.OrderStatusPaid); err != nil {
+		paid, err := h.orderRepo.MarkPaid(ctx, order.ID)
+		if err != nil {
 			log.Printf("[Webhook] QrisPW failed to update order status: %v", err)
 			h.webhookRepo.MarkProcessed(ctx, logID, err.Error())
 			http.Error(w, "Internal Error", http.StatusInternalServerError)
 			return
 		}
+		if !paid {
+			h.webhookRepo.MarkProcessed(ctx, logID, unfulfilledPaymentNote(order))
+			break
+		}
 
 		// Process topup to Digiflazz
-		go h.processTopup(order)
+		go h.fulfillment.ProcessPaid(order)
 
 		h.webhookRepo.MarkProcessed(ctx, logID, "")
 		log.Printf("[Webhook] QrisPW order %s processed successfully → topup triggered", order.ID)
@@ -219,6 +226,9 @@ func (h *WebhookHandler) HandleQrisPWWebhook(w http.ResponseWriter, r *http.Requ
 			log.Printf("[Webhook] QrisPW failed to update payment to expired: %v", err)
 		}
 
+		// Close the unpaid order so it stops holding flash sale quota and promo usage
+		expireUnpaidOrder(ctx, h.orderRepo, h.fulfillment.promoRepo, order.ID)
+
 		h.webhookRepo.MarkProcessed(ctx, logID, "")
 
 	case "pending":
@@ -234,88 +244,16 @@ func (h *WebhookHandler) HandleQrisPWWebhook(w http.ResponseWriter, r *http.Requ
 	w.Write([]byte("OK"))
 }
 
-// processTopup processes the topup transaction with Digiflazz
-func (h *WebhookHandler) processTopup(order *model.Order) {
-	// Use background context for goroutine operations
-	ctx := context.Background()
-
-	log.Printf("[Topup] Processing topup for order %s", order.ID)
-
-	// Update status to processing
-	_ = h.orderRepo.UpdateStatus(ctx, order.ID, model.OrderStatusProcessing)
-
-	// Create transaction with Digiflazz
-	// Force Testing: false because user wants real transactions even if ENV is not explicitly set to production
-	req := digiflazz.TopupRequest{
-		BuyerSKUCode: order.BuyerSKUCode,
-		CustomerNo:   order.CustomerNo,
-		RefID:        order.RefID,
-		Testing:      false,
+// unfulfilledPaymentNote logs why a completed payment did not trigger a topup and
+// returns the note for the webhook log. A payment for an expired order released
+// its flash sale quota and promo usage already, so it is refunded by hand instead.
+func unfulfilledPaymentNote(order *model.Order) string {
+	if order.Status == model.OrderStatusExpired {
+		log.Printf("CRITICAL: [Webhook] Payment completed for expired order %s (%s), needs manual refund", order.ID, order.RefID)
+		return "order expired: payment needs manual refund"
 	}
-
-	resp, err := h.digiflazzSvc.CreateTransaction(req)
-
-	if err != nil {
-		log.Printf("[Topup] Failed to create transaction: %v", err)
-		// Check if it's a "Signature Anda salah" error or IP error
-		_ = h.orderRepo.UpdateDigiflazzResponse(ctx, order.ID, model.OrderStatusFailed, "", "", "", err.Error())
-
-		// REFUND IF MEMBER
-		if order.MemberID != nil {
-			amount := order.MemberPrice
-			if amount == nil {
-				amount = &order.SellingPrice
-			}
-			desc := fmt.Sprintf("Refund Gagal Transaksi (Initial) %s", order.RefID)
-			if err := h.userRepo.TopupBalance(ctx, *order.MemberID, *amount, desc, "SYSTEM"); err != nil {
-				log.Printf("CRITICAL: Failed to refund member balance for order %s: %v", order.ID, err)
-			}
-		}
-
-		return
-	}
-
-	// Log Raw Response for debugging
-	respJSON, _ := json.Marshal(resp)
-	log.Printf("[Topup] Digiflazz Raw Response: %s", string(respJSON))
-
-	log.Printf("[Topup] Digiflazz response: status=%s, message=%s", resp.Data.Status, resp.Data.Message)
-
-	// Map Digiflazz status to order status
-	var orderStatus model.OrderStatus
-	switch resp.Data.Status {
-	case "Sukses":
-		orderStatus = model.OrderStatusSuccess
-	case "Gagal":
-		orderStatus = model.OrderStatusFailed
-	default:
-		orderStatus = model.OrderStatusProcessing
-	}
-
-	// Update order with Digiflazz response
-	_ = h.orderRepo.UpdateDigiflazzResponse(
-		ctx,
-		order.ID,
-		orderStatus,
-		resp.Data.Status,
-		resp.Data.RC,
-		resp.Data.SN,
-		resp.Data.Message,
-	)
-
-	// REFUND IF MEMBER AND FAILED
-	if orderStatus == model.OrderStatusFailed && order.MemberID != nil {
-		amount := order.MemberPrice
-		if amount == nil {
-			amount = &order.SellingPrice
-		}
-		desc := fmt.Sprintf("Refund Gagal Transaksi %s", order.RefID)
-		if err := h.userRepo.TopupBalance(ctx, *order.MemberID, *amount, desc, "SYSTEM"); err != nil {
-			log.Printf("CRITICAL: Failed to refund member balance for order %s: %v", order.ID, err)
-		}
-	}
-
-	log.Printf("[Topup] Order %s updated to status %s", order.ID, orderStatus)
+	log.Printf("[Webhook] Order %s already %s, ignoring duplicate payment notification", order.ID, order.Status)
+	return "ignored: order already paid"
 }
 
 // HandleDigiflazzWebhook handles POST /api/v1/webhook/digiflazz
@@ -374,28 +312,16 @@ func (h *WebhookHandler) HandleDigiflazzWebhook(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	// Map Digiflazz status to order status
-	var orderStatus model.OrderStatus
-	switch payload.Data.Status {
-	case "Sukses":
-		orderStatus = model.OrderStatusSuccess
-	case "Gagal":
-		orderStatus = model.OrderStatusFailed
-	default:
-		orderStatus = model.OrderStatusProcessing
+	// A result for the original ref of a manually retried order is stale
+	if payload.Data.RefID != order.DigiflazzRefID() {
+		log.Printf("[Webhook] Ignored stale RefID %s for order %s (retried as %s)", payload.Data.RefID, order.ID, order.DigiflazzRefID())
+		h.webhookRepo.MarkProcessed(ctx, logID, "ignored: superseded ref_id")
+		w.WriteHeader(http.StatusOK)
+		return
 	}
 
-	// Update order with Digiflazz response
-	err = h.orderRepo.UpdateDigiflazzResponse(
-		ctx,
-		order.ID,
-		orderStatus,
-		payload.Data.Status,
-		payload.Data.RC,
-		payload.Data.SN,
-		payload.Data.Message,
-	)
-
+	// Record the result and refund, reward and notify as needed
+	_, err = h.fulfillment.ApplyResult(ctx, order, payload.Data.Status, payload.Data.RC, payload.Data.SN, payload.Data.Message)
 	if err != nil {
 		log.Printf("[Webhook] Failed to update order: %v", err)
 		h.webhookRepo.MarkProcessed(ctx, logID, err.Error())
@@ -403,20 +329,6 @@ func (h *WebhookHandler) HandleDigiflazzWebhook(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	log.Printf("[Webhook] Order %s updated to status %s", order.ID, orderStatus)
-
-	// REFUND IF MEMBER AND FAILED
-	if orderStatus == model.OrderStatusFailed && order.MemberID != nil {
-		amount := order.MemberPrice
-		if amount == nil {
-			amount = &order.SellingPrice
-		}
-		desc := fmt.Sprintf("Refund Gagal Transaksi %s", order.RefID)
-		if err := h.userRepo.TopupBalance(ctx, *order.MemberID, *amount, desc, "SYSTEM"); err != nil {
-			log.Printf("CRITICAL: Failed to refund member balance for order %s: %v", order.ID, err)
-		}
-	}
-
 	h.webhookRepo.MarkProcessed(ctx, logID, "")
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("OK"))
@@ -0,0 +1,189 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+
+	"govershop-api/internal/model"
+	"govershop-api/internal/repository"
+)
+
+// auditIgnoredFields are left out of diffs because they change on every write
+var auditIgnoredFields = map[string]bool{
+	"updated_at": true,
+}
+
+// Audit records every admin write in the audit trail: who did it, what entity
+// it touched, the entity before and after, and where the request came from.
+type Audit struct {
+	repo *repository.AuditRepository
+}
+
+// NewAudit creates the audit middleware
+func NewAudit(repo *repository.AuditRepository) *Audit {
+	return &Audit{repo: repo}
+}
+
+// Record wraps an admin write route. idParam is the path value holding the
+// entity ID ("" for creates, in which case the ID is taken from data.id of the
+// response). Must run inside AdminAuth so the actor is in the context.
+func (a *Audit) Record(action, entityType, idParam string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		entityID := ""
+		if idParam != "" {
+			entityID = r.PathValue(idParam)
+		}
+
+		before := a.snapshot(ctx, entityType, entityID)
+
+		details := map[string]interface{}{}
+		rw := &recordingWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rw, r.WithContext(context.WithValue(ctx, "audit_details", details)))
+
+		if entityID == "" {
+			entityID = responseEntityID(rw.body.Bytes())
+		}
+		// Detached so a client disconnect does not drop the entry
+		logCtx := context.WithoutCancel(ctx)
+		after := a.snapshot(logCtx, entityType, entityID)
+
+		entry := model.AuditLog{
+			Action:     action,
+			Method:     &r.Method,
+			Path:       &r.URL.Path,
+			StatusCode: &rw.status,
+			Success:    rw.status < http.StatusBadRequest,
+			Before:     marshalSnapshot(before),
+			After:      marshalSnapshot(after),
+			Diff:       auditDiff(before, after),
+		}
+		if len(details) > 0 {
+			entry.Details = details
+		}
+		if id, ok := ctx.Value("user_id").(int); ok {
+			entry.AdminUserID = &id
+		}
+		if name, ok := ctx.Value("user").(string); ok {
+			entry.AdminUsername = &name
+		}
+		if entityType != "" {
+			entry.EntityType = &entityType
+		}
+		if entityID != "" {
+			entry.EntityID = &entityID
+		}
+		ip := getClientIP(r)
+		entry.IPAddress = &ip
+		if ua := r.UserAgent(); ua != "" {
+			entry.UserAgent = &ua
+		}
+		if !entry.Success {
+			msg := responseError(rw.body.Bytes())
+			entry.ErrorMessage = &msg
+		}
+
+		if err := a.repo.Create(logCtx, &entry); err != nil {
+			log.Printf("[Audit] Failed to record %s: %v", action, err)
+		}
+	}
+}
+
+// AuditDetail adds a detail the entity snapshots do not show (e.g. the Digiflazz
+// ref of a manual topup) to the audit entry of the current request. It is a no-op
+// outside audit.Record.
+func AuditDetail(ctx context.Context, key string, value interface{}) {
+	if details, ok := ctx.Value("audit_details").(map[string]interface{}); ok {
+		details[key] = value
+	}
+}
+
+// snapshot loads an entity for the audit trail, logging failures
+func (a *Audit) snapshot(ctx context.Context, entityType, entityID string) map[string]interface{} {
+	data, err := a.repo.Snapshot(ctx, entityType, entityID)
+	if err != nil {
+		log.Printf("[Audit] %v", err)
+		return nil
+	}
+	return data
+}
+
+func marshalSnapshot(data map[string]interface{}) json.RawMessage {
+	if data == nil {
+		return nil
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil
+	}
+	return raw
+}
+
+// auditDiff lists the fields that differ between two snapshots
+func auditDiff(before, after map[string]interface{}) map[string]model.AuditFieldChange {
+	if before == nil && after == nil {
+		return nil
+	}
+
+	diff := map[string]model.AuditFieldChange{}
+	for k, from := range before {
+		if auditIgnoredFields[k] {
+			continue
+		}
+		to, ok := after[k]
+		if !ok || !reflect.DeepEqual(from, to) {
+			diff[k] = model.AuditFieldChange{From: from, To: to}
+		}
+	}
+	for k, to := range after {
+		if _, ok := before[k]; ok || auditIgnoredFields[k] {
+			continue
+		}
+		diff[k] = model.AuditFieldChange{From: nil, To: to}
+	}
+	return diff
+}
+
+// responseEntityID reads data.id (or data.sku) from a JSON response
+func responseEntityID(body []byte) string {
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if json.Unmarshal(body, &resp) != nil || resp.Data == nil {
+		return ""
+	}
+	for _, key := range []string{"id", "sku", "order_id"} {
+		switch v := resp.Data[key].(type) {
+		case string:
+			return v
+		case float64:
+			return fmt.Sprintf("%.0f", v)
+		}
+	}
+	return ""
+}
+
+// responseError reads the error message of a failed response
+func responseError(body []byte) string {
+	var resp struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &resp) == nil {
+		if resp.Error != "" {
+			return resp.Error
+		}
+		if resp.Message != "" {
+			return resp.Message
+		}
+	}
+	if len(body) > 200 {
+		body = body[:200]
+	}
+	return string(body)
+}
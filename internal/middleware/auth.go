@@ -2,28 +2,57 @@ package middleware
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
 	"strings"
 	"time"
 
 	"govershop-api/internal/config"
+	"govershop-api/internal/model"
+	"govershop-api/internal/repository"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
 type AuthMiddleware struct {
-	config *config.Config
+	config      *config.Config
+	sessionRepo *repository.SessionRepository
+
+	adminRepo *repository.AdminRepository
 }
 
-func NewAuthMiddleware(cfg *config.Config) *AuthMiddleware {
+func NewAuthMiddleware(cfg *config.Config, sessionRepo *repository.SessionRepository, adminRepo *repository.AdminRepository) *AuthMiddleware {
 	return &AuthMiddleware{
-		config: cfg,
+		config:      cfg,
+		sessionRepo: sessionRepo,
+
+		adminRepo: adminRepo,
+	}
+}
+
+// sessionValid checks that the token's session is still active and the token was not
+// issued before a password change, suspension or logout-all
+func (m *AuthMiddleware) sessionValid(r *http.Request, claims jwt.MapClaims, userID int) (string, bool) {
+	sessionID, _ := claims["sid"].(string)
+	iat, ok := claims["iat"].(float64)
+	if sessionID == "" || !ok {
+		return "", false
+	}
+
+	valid, err := m.sessionRepo.IsAccessValid(r.Context(), sessionID, userID, time.Unix(int64(iat), 0))
+	if err != nil {
+		log.Printf("[Auth] Failed to validate session: %v", err)
+		return "", false
 	}
+	return sessionID, valid
 }
 
-// AdminAuth validates JWT token for admin routes
-func (m *AuthMiddleware) AdminAuth(next http.HandlerFunc) http.HandlerFunc {
+// AdminAuth validates JWT token for admin routes and checks that the admin's
+// role grants permission (see model.AdminPermissions). The role is read from
+// the database on every request so role changes apply immediately.
+func (m *AuthMiddleware) AdminAuth(permission string, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var tokenString string
 
@@ -75,11 +104,34 @@ func (m *AuthMiddleware) AdminAuth(next http.HandlerFunc) http.HandlerFunc {
 				return
 			}
 
-			// Add user info to context
 			userID := int(claims["user_id"].(float64))
+			sessionID, valid := m.sessionValid(r, claims, userID)
+			if !valid {
+				http.Error(w, "Unauthorized: Session revoked", http.StatusUnauthorized)
+				return
+			}
+
+			adminRole, status, err := m.adminRepo.GetRole(r.Context(), userID)
+			if err != nil {
+				log.Printf("[Auth] Failed to get admin role: %v", err)
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			if adminRole == "" || status != model.UserStatusActive {
+				http.Error(w, "Unauthorized: Admin access required", http.StatusUnauthorized)
+				return
+			}
+			if !model.AdminHasPermission(adminRole, permission) {
+				http.Error(w, "Forbidden: Role "+adminRole+" lacks permission "+permission, http.StatusForbidden)
+				return
+			}
+
+			// Add user info to context
 			ctx := context.WithValue(r.Context(), "user", claims["sub"])
 			ctx = context.WithValue(ctx, "user_id", userID)
 			ctx = context.WithValue(ctx, "role", role)
+			ctx = context.WithValue(ctx, "admin_role", adminRole)
+			ctx = context.WithValue(ctx, "session_id", sessionID)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		} else {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
@@ -87,6 +139,59 @@ func (m *AuthMiddleware) AdminAuth(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// StepUp requires the X-Step-Up-Token issued by POST /api/v1/admin/reauth for
+// the same admin and session. Must run inside AdminAuth. Puts "step_up_totp"
+// (whether TOTP was checked) in the context.
+func (m *AuthMiddleware) StepUp(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tokenString := r.Header.Get("X-Step-Up-Token")
+		if tokenString == "" {
+			writeReauthRequired(w, "Konfirmasi password diperlukan untuk aksi ini")
+			return
+		}
+
+		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+			return []byte(m.config.JWTSecretGovershop), nil
+		})
+		if err != nil || !token.Valid {
+			writeReauthRequired(w, "Konfirmasi password sudah kadaluarsa, silakan ulangi")
+			return
+		}
+
+		claims, ok := token.Claims.(jwt.MapClaims)
+		if !ok || claims["type"] != "admin_step_up" {
+			writeReauthRequired(w, "Token konfirmasi tidak valid")
+			return
+		}
+
+		userID, _ := claims["user_id"].(float64)
+		sessionID, _ := claims["sid"].(string)
+		ctxUserID, _ := r.Context().Value("user_id").(int)
+		ctxSessionID, _ := r.Context().Value("session_id").(string)
+		if int(userID) != ctxUserID || sessionID == "" || sessionID != ctxSessionID {
+			writeReauthRequired(w, "Token konfirmasi tidak valid")
+			return
+		}
+
+		totpVerified, _ := claims["totp"].(bool)
+		ctx := context.WithValue(r.Context(), "step_up_totp", totpVerified)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+}
+
+func writeReauthRequired(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":         false,
+		"error":           message,
+		"reauth_required": true,
+	})
+}
+
 // MemberAuth validates JWT token for member routes
 func (m *AuthMiddleware) MemberAuth(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -138,18 +243,25 @@ func (m *AuthMiddleware) MemberAuth(next http.HandlerFunc) http.HandlerFunc {
 				return
 			}
 
-			// Verify role is member
-			role := claims["role"].(string)
+			// Verify role is member (pre-auth and email tokens carry no role)
+			role, _ := claims["role"].(string)
 			if role != "member" {
 				http.Error(w, `{"success":false,"error":"Unauthorized: Invalid role"}`, http.StatusUnauthorized)
 				return
 			}
 
-			// Add user info to context
 			userID := int(claims["user_id"].(float64))
+			sessionID, valid := m.sessionValid(r, claims, userID)
+			if !valid {
+				http.Error(w, `{"success":false,"error":"Unauthorized: Session revoked"}`, http.StatusUnauthorized)
+				return
+			}
+
+			// Add user info to context
 			ctx := context.WithValue(r.Context(), "user", claims["sub"])
 			ctx = context.WithValue(ctx, "user_id", userID)
 			ctx = context.WithValue(ctx, "role", role)
+			ctx = context.WithValue(ctx, "session_id", sessionID)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		} else {
 			http.Error(w, `{"success":false,"error":"Unauthorized"}`, http.StatusUnauthorized)
@@ -0,0 +1,234 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"govershop-api/internal/model"
+	"govershop-api/internal/repository"
+)
+
+// Idempotency key limits
+const (
+	idempotencyMaxKeyLength = 255
+	idempotencyMaxBody      = 1 << 20 // 1 MB
+
+	// idempotencyLease is how long a key stays "processing" before a retry may take
+	// it over (e.g. after a crash). Well above the server's write timeout.
+	idempotencyLease = 5 * time.Minute
+)
+
+// Idempotency makes order creation safe to retry. A request sent again with the
+// same key returns the stored response instead of running the handler twice.
+type Idempotency struct {
+	repo *repository.IdempotencyRepository
+	ttl  time.Duration
+}
+
+// NewIdempotency creates the idempotency middleware with the given retention window.
+// It starts a background goroutine to delete expired keys every hour.
+func NewIdempotency(repo *repository.IdempotencyRepository, ttl time.Duration) *Idempotency {
+	m := &Idempotency{
+		repo: repo,
+		ttl:  ttl,
+	}
+
+	go m.cleanup()
+
+	return m
+}
+
+// cleanup removes keys past the retention window
+func (m *Idempotency) cleanup() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		deleted, err := m.repo.DeleteExpired(context.Background())
+		if err != nil {
+			log.Printf("[Idempotency] Failed to delete expired keys: %v", err)
+			continue
+		}
+		if deleted > 0 {
+			log.Printf("[Idempotency] Deleted %d expired keys", deleted)
+		}
+	}
+}
+
+// Member wraps a member route. The key comes from the Idempotency-Key header or,
+// when absent, the client_ref_id field of the JSON body. Must run inside MemberAuth.
+func (m *Idempotency) Member(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("user_id").(int)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, ok := readBody(w, r)
+		if !ok {
+			return
+		}
+
+		key := strings.TrimSpace(r.Header.Get("Idempotency-Key"))
+		if key == "" {
+			var payload struct {
+				ClientRefID string `json:"client_ref_id"`
+			}
+			if json.Unmarshal(body, &payload) == nil && strings.TrimSpace(payload.ClientRefID) != "" {
+				key = "ref:" + strings.TrimSpace(payload.ClientRefID)
+			}
+		}
+
+		m.handle(w, r, fmt.Sprintf("member:%d", userID), key, body, next)
+	}
+}
+
+// Guest wraps a public route. The key comes from the Idempotency-Key header only
+// and is scoped to the client IP.
+func (m *Idempotency) Guest(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimSpace(r.Header.Get("Idempotency-Key"))
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, ok := readBody(w, r)
+		if !ok {
+			return
+		}
+
+		m.handle(w, r, "guest:"+getClientIP(r), key, body, next)
+	}
+}
+
+func (m *Idempotency) handle(w http.ResponseWriter, r *http.Request, scope, key string, body []byte, next http.HandlerFunc) {
+	if key == "" {
+		next.ServeHTTP(w, r)
+		return
+	}
+	if len(key) > idempotencyMaxKeyLength {
+		writeIdempotencyError(w, http.StatusBadRequest, "Idempotency-Key terlalu panjang", "IDEMPOTENCY_KEY_INVALID")
+		return
+	}
+
+	hash := requestHash(r, body)
+	existing, err := m.repo.Reserve(r.Context(), scope, key, hash, m.ttl, idempotencyLease)
+	if err != nil {
+		log.Printf("[Idempotency] %v", err)
+		writeIdempotencyError(w, http.StatusInternalServerError, "Gagal memproses permintaan", "INTERNAL_ERROR")
+		return
+	}
+
+	if existing != nil {
+		if existing.RequestHash != hash {
+			writeIdempotencyError(w, http.StatusUnprocessableEntity,
+				"Idempotency-Key sudah digunakan untuk permintaan yang berbeda", "IDEMPOTENCY_KEY_REUSED")
+			return
+		}
+		if existing.Status != model.IdempotencyStatusCompleted {
+			w.Header().Set("Retry-After", "2")
+			writeIdempotencyError(w, http.StatusConflict,
+				"Permintaan dengan Idempotency-Key ini sedang diproses", "IDEMPOTENCY_IN_PROGRESS")
+			return
+		}
+
+		if existing.ContentType != "" {
+			w.Header().Set("Content-Type", existing.ContentType)
+		}
+		w.Header().Set("Idempotent-Replayed", "true")
+		w.WriteHeader(existing.ResponseStatus)
+		w.Write(existing.ResponseBody)
+		return
+	}
+
+	rec := &recordingWriter{ResponseWriter: w, status: http.StatusOK}
+	next.ServeHTTP(rec, r)
+
+	// Use a fresh context so a disconnected client still gets its response stored
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Server errors are not final; let the client retry with the same key
+	if rec.status >= http.StatusInternalServerError {
+		if err := m.repo.Release(ctx, scope, key); err != nil {
+			log.Printf("[Idempotency] %v", err)
+		}
+		return
+	}
+
+	if err := m.repo.Complete(ctx, scope, key, rec.status, w.Header().Get("Content-Type"), rec.body.Bytes()); err != nil {
+		log.Printf("[Idempotency] %v", err)
+	}
+}
+
+// readBody buffers the request body so it can be hashed and read again by the handler.
+// Bodies over idempotencyMaxBody are rejected rather than truncated.
+func readBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, idempotencyMaxBody+1))
+	if err != nil {
+		writeIdempotencyError(w, http.StatusBadRequest, "Format request tidak valid", "INVALID_REQUEST")
+		return nil, false
+	}
+	if len(body) > idempotencyMaxBody {
+		writeIdempotencyError(w, http.StatusRequestEntityTooLarge, "Ukuran request terlalu besar", "REQUEST_TOO_LARGE")
+		return nil, false
+	}
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body, true
+}
+
+// requestHash fingerprints a request. JSON bodies are re-encoded first so key
+// order and whitespace do not count as a different payload.
+func requestHash(r *http.Request, body []byte) string {
+	payload := body
+	var v interface{}
+	if json.Unmarshal(body, &v) == nil {
+		if canonical, err := json.Marshal(v); err == nil {
+			payload = canonical
+		}
+	}
+
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	h.Write(payload)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func writeIdempotencyError(w http.ResponseWriter, status int, message, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": false,
+		"error":   message,
+		"code":    code,
+	})
+}
+
+// recordingWriter passes the response through while keeping a copy of it
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rw *recordingWriter) WriteHeader(code int) {
+	rw.status = code
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recordingWriter) Write(b []byte) (int, error) {
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
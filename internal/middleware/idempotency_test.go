@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestHash(t *testing.T) {
+	base := requestHash(httptest.NewRequest("POST", "/api/v1/member/orders", nil), []byte(`{"sku":"ML5","customer_no":"123"}`))
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		wantSame bool
+	}{
+		{name: "identical", method: "POST", path: "/api/v1/member/orders", body: `{"sku":"ML5","customer_no":"123"}`, wantSame: true},
+		{name: "key order", method: "POST", path: "/api/v1/member/orders", body: `{"customer_no":"123","sku":"ML5"}`, wantSame: true},
+		{name: "whitespace", method: "POST", path: "/api/v1/member/orders", body: "{\n  \"sku\": \"ML5\",\n  \"customer_no\": \"123\"\n}", wantSame: true},
+		{name: "different value", method: "POST", path: "/api/v1/member/orders", body: `{"sku":"ML5","customer_no":"124"}`, wantSame: false},
+		{name: "extra field", method: "POST", path: "/api/v1/member/orders", body: `{"sku":"ML5","customer_no":"123","promo_code":"X"}`, wantSame: false},
+		{name: "number vs string", method: "POST", path: "/api/v1/member/orders", body: `{"sku":"ML5","customer_no":123}`, wantSame: false},
+		{name: "different path", method: "POST", path: "/api/v1/orders", body: `{"sku":"ML5","customer_no":"123"}`, wantSame: false},
+		{name: "different method", method: "PUT", path: "/api/v1/member/orders", body: `{"sku":"ML5","customer_no":"123"}`, wantSame: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := requestHash(httptest.NewRequest(tt.method, tt.path, nil), []byte(tt.body))
+			if len(got) != 64 {
+				t.Fatalf("requestHash() = %q, want a hex sha256", got)
+			}
+			if (got == base) != tt.wantSame {
+				t.Errorf("requestHash() same as base = %v, want %v", got == base, tt.wantSame)
+			}
+		})
+	}
+}
+
+func TestRequestHashNonJSON(t *testing.T) {
+	r := httptest.NewRequest("POST", "/api/v1/orders", nil)
+
+	a := requestHash(r, []byte("not json"))
+	if a != requestHash(r, []byte("not json")) {
+		t.Error("requestHash is not deterministic for a non-JSON body")
+	}
+	if a == requestHash(r, []byte("not  json")) {
+		t.Error("non-JSON bodies are hashed as they are, whitespace included")
+	}
+	if requestHash(r, nil) == requestHash(r, []byte("{}")) {
+		t.Error("empty body and {} hash the same")
+	}
+}
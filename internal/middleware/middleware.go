@@ -19,8 +19,8 @@ func CORS(next http.Handler, allowedOrigin string) http.Handler {
 		}
 
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type, X-CSRF-Token")
-		w.Header().Set("Access-Control-Expose-Headers", "Link")
+		w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type, X-CSRF-Token, Idempotency-Key, X-Step-Up-Token")
+		w.Header().Set("Access-Control-Expose-Headers", "Link, Idempotent-Replayed")
 		w.Header().Set("Access-Control-Max-Age", "300")
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 
@@ -0,0 +1,97 @@
+package model
+
+// Admin roles (users.admin_role of users with role = admin)
+const (
+	AdminRoleOwner   = "owner"
+	AdminRoleFinance = "finance"
+	AdminRoleCS      = "cs"
+	AdminRoleContent = "content"
+)
+
+// AdminRoles are the accepted admin roles
+var AdminRoles = []string{AdminRoleOwner, AdminRoleFinance, AdminRoleCS, AdminRoleContent}
+
+// IsAdminRole reports whether role is an accepted admin role
+func IsAdminRole(role string) bool {
+	_, ok := AdminPermissions[role]
+	return ok
+}
+
+// Admin permissions. Every /api/v1/admin/* route requires exactly one.
+const (
+	PermSelf           = "self"            // Own session and TOTP management
+	PermDashboardView  = "dashboard.view"  // Dashboard and provider balance
+	PermOrdersView     = "orders.view"     // Order list
+	PermOrdersManage   = "orders.manage"   // Re-check order status with the provider
+	PermOrdersTopup    = "orders.topup"    // Manual topup of failed orders, custom topup
+	PermCatalogView    = "catalog.view"    // Products, content, brands, promos (read only)
+	PermCatalogManage  = "catalog.manage"  // Products, sync, homepage content, brands
+	PermPromosManage   = "promos.manage"   // Promos, flash sales, point rules
+	PermMembersView    = "members.view"    // Member list and details
+	PermMembersManage  = "members.manage"  // Create, update and delete members
+	PermMembersBalance = "members.balance" // Top-ups, adjustments, spending limits
+	PermLedgerManage   = "ledger.manage"   // Ledger reconciliation
+	PermLogsView       = "logs.view"       // Sync and webhook logs
+	PermSettingsManage = "settings.manage" // Registration settings
+	PermAdminsManage   = "admins.manage"   // Admin accounts and roles
+	PermAuditView      = "audit.view"      // Admin audit trail
+)
+
+// AdminPermissions is the permission matrix of each admin role
+var AdminPermissions = map[string][]string{
+	AdminRoleOwner: {
+		PermSelf, PermDashboardView, PermOrdersView, PermOrdersManage, PermOrdersTopup,
+		PermCatalogView, PermCatalogManage, PermPromosManage, PermMembersView, PermMembersManage, PermMembersBalance,
+		PermLedgerManage, PermLogsView, PermSettingsManage, PermAdminsManage, PermAuditView,
+	},
+	AdminRoleFinance: {
+		PermSelf, PermDashboardView, PermOrdersView, PermOrdersManage, PermOrdersTopup,
+		PermCatalogView, PermMembersView, PermMembersBalance, PermLedgerManage, PermLogsView, PermAuditView,
+	},
+	AdminRoleCS: {
+		PermSelf, PermDashboardView, PermOrdersView, PermOrdersManage,
+		PermCatalogView, PermMembersView, PermMembersManage,
+	},
+	AdminRoleContent: {
+		PermSelf, PermDashboardView, PermCatalogView, PermCatalogManage, PermPromosManage,
+	},
+}
+
+// AdminHasPermission reports whether an admin role grants a permission
+func AdminHasPermission(role, permission string) bool {
+	for _, p := range AdminPermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// AdminAccount is an admin user with their role
+type AdminAccount struct {
+	ID          int      `json:"id"`
+	Username    string   `json:"username"`
+	Email       *string  `json:"email,omitempty"`
+	FullName    string   `json:"full_name"`
+	AdminRole   string   `json:"admin_role"`
+	Status      string   `json:"status"`
+	TOTPEnabled bool     `json:"totp_enabled"`
+	Permissions []string `json:"permissions"`
+}
+
+// CreateAdminRequest is the body of POST /api/v1/admin/admins
+type CreateAdminRequest struct {
+	Username  string  `json:"username"`
+	Password  string  `json:"password"`
+	Email     *string `json:"email,omitempty"`
+	FullName  string  `json:"full_name"`
+	AdminRole string  `json:"admin_role"`
+}
+
+// UpdateAdminRequest is the body of PUT /api/v1/admin/admins/{id}
+type UpdateAdminRequest struct {
+	FullName  string `json:"full_name,omitempty"`
+	AdminRole string `json:"admin_role,omitempty"`
+	Status    string `json:"status,omitempty"` // active, suspended
+	Password  string `json:"password,omitempty"`
+}
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Audit entity types (admin_audit_logs.entity_type)
+const (
+	AuditEntityAdmin        = "admin"
+	AuditEntityMember       = "member"
+	AuditEntityMemberLimit  = "member_limit"
+	AuditEntityMemberLevel  = "member_level"
+	AuditEntityOrder        = "order"
+	AuditEntityProduct      = "product"
+	AuditEntityContent      = "content"
+	AuditEntityPromo        = "promo"
+	AuditEntityFlashSale    = "flash_sale"
+	AuditEntityPointRule    = "point_rule"
+	AuditEntityBrand        = "brand"
+	AuditEntitySetting      = "setting"
+	AuditEntitySession      = "session"
+	AuditEntityTOTP         = "totp"
+	AuditEntityLedger       = "ledger"
+	AuditEntityLoginLockout = "login_lockout"
+)
+
+// AuditFieldChange is one changed field in an audit diff
+type AuditFieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// AuditLog is an admin audit trail entry
+type AuditLog struct {
+	ID            int                         `json:"id"`
+	Action        string                      `json:"action"`
+	AdminUserID   *int                        `json:"admin_user_id,omitempty"`
+	AdminUsername *string                     `json:"admin_username,omitempty"`
+	EntityType    *string                     `json:"entity_type,omitempty"`
+	EntityID      *string                     `json:"entity_id,omitempty"`
+	OrderID       *string                     `json:"order_id,omitempty"`
+	Before        json.RawMessage             `json:"before,omitempty"`
+	After         json.RawMessage             `json:"after,omitempty"`
+	Diff          map[string]AuditFieldChange `json:"diff,omitempty"`
+	Details       map[string]interface{}      `json:"details,omitempty"`
+	Method        *string                     `json:"method,omitempty"`
+	Path          *string                     `json:"path,omitempty"`
+	StatusCode    *int                        `json:"status_code,omitempty"`
+	IPAddress     *string                     `json:"ip_address,omitempty"`
+	UserAgent     *string                     `json:"user_agent,omitempty"`
+	Success       bool                        `json:"success"`
+	ErrorMessage  *string                     `json:"error_message,omitempty"`
+	CreatedAt     time.Time                   `json:"created_at"`
+}
+
+// AuditLogFilter filters GET /api/v1/admin/audit-logs
+type AuditLogFilter struct {
+	Actor      string // admin username
+	Action     string // exact action, or a prefix ending in "." (e.g. "member.")
+	EntityType string
+	EntityID   string
+	DateFrom   string // YYYY-MM-DD
+	DateTo     string // YYYY-MM-DD, inclusive
+	Limit      int
+	Offset     int
+}
@@ -0,0 +1,66 @@
+package model
+
+import "time"
+
+// Balance adjustment kinds
+const (
+	AdjustmentKindCredit   = "credit"
+	AdjustmentKindDebit    = "debit"
+	AdjustmentKindReversal = "reversal" // Undoes a specific deposit entry
+)
+
+// Balance adjustment reason codes
+const (
+	AdjustmentReasonOverCredit      = "over_credit"      // Member was credited too much
+	AdjustmentReasonMistakenRefund  = "mistaken_refund"  // Refund for an order that actually succeeded
+	AdjustmentReasonMissedTopup     = "missed_topup"     // Payment received but never credited
+	AdjustmentReasonProviderDispute = "provider_dispute" // Outcome corrected after a provider dispute
+	AdjustmentReasonGoodwill        = "goodwill"         // Compensation
+	AdjustmentReasonOther           = "other"
+)
+
+// AdjustmentReasonCodes are the accepted reason codes
+var AdjustmentReasonCodes = []string{
+	AdjustmentReasonOverCredit,
+	AdjustmentReasonMistakenRefund,
+	AdjustmentReasonMissedTopup,
+	AdjustmentReasonProviderDispute,
+	AdjustmentReasonGoodwill,
+	AdjustmentReasonOther,
+}
+
+// IsAdjustmentReasonCode reports whether code is an accepted reason code
+func IsAdjustmentReasonCode(code string) bool {
+	for _, c := range AdjustmentReasonCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// BalanceAdjustment is a manual balance correction made by an admin
+type BalanceAdjustment struct {
+	ID                int       `json:"id" db:"id"`
+	UserID            int       `json:"user_id" db:"user_id"`
+	Kind              string    `json:"kind" db:"kind"`
+	Amount            float64   `json:"amount" db:"amount"`
+	ReasonCode        string    `json:"reason_code" db:"reason_code"`
+	Note              string    `json:"note" db:"note"`
+	DepositID         *int      `json:"deposit_id,omitempty" db:"deposit_id"`
+	ReversedDepositID *int      `json:"reversed_deposit_id,omitempty" db:"reversed_deposit_id"`
+	TOTPVerified      bool      `json:"totp_verified" db:"totp_verified"`
+	CreatedBy         string    `json:"created_by" db:"created_by"`
+	CreatedAt         time.Time `json:"created_at" db:"created_at"`
+
+	BalanceAfter *float64 `json:"balance_after,omitempty"`
+}
+
+// BalanceAdjustmentRequest is the body of POST /api/v1/admin/members/{id}/adjustments
+type BalanceAdjustmentRequest struct {
+	Kind       string  `json:"kind"`
+	Amount     float64 `json:"amount,omitempty"`     // Required for credit and debit
+	DepositID  int     `json:"deposit_id,omitempty"` // Required for reversal
+	ReasonCode string  `json:"reason_code"`
+	Note       string  `json:"note"`
+}
@@ -0,0 +1,60 @@
+package model
+
+import "time"
+
+// FlashSaleStatus represents the lifecycle of a flash sale campaign
+type FlashSaleStatus string
+
+const (
+	FlashSaleStatusScheduled FlashSaleStatus = "scheduled" // Waiting for start_at
+	FlashSaleStatusActive    FlashSaleStatus = "active"    // Prices applied to products
+	FlashSaleStatusEnded     FlashSaleStatus = "ended"     // Finished, prices reverted
+	FlashSaleStatusCancelled FlashSaleStatus = "cancelled" // Stopped early by admin
+)
+
+// FlashSaleDiscountType constants
+const (
+	FlashSaleDiscountPrice   = "price"   // discount_value is the flash price itself
+	FlashSaleDiscountPercent = "percent" // discount_value is a percentage off selling_price
+)
+
+// FlashSale represents a scheduled flash sale campaign
+type FlashSale struct {
+	ID            int64           `json:"id" db:"id"`
+	Name          string          `json:"name" db:"name"`
+	DiscountType  string          `json:"discount_type" db:"discount_type"`
+	DiscountValue float64         `json:"discount_value" db:"discount_value"`
+	Category      *string         `json:"category,omitempty" db:"category"`
+	Brand         *string         `json:"brand,omitempty" db:"brand"`
+	QuotaPerSKU   *int            `json:"quota_per_sku,omitempty" db:"quota_per_sku"`
+	StartAt       time.Time       `json:"start_at" db:"start_at"`
+	EndAt         time.Time       `json:"end_at" db:"end_at"`
+	Status        FlashSaleStatus `json:"status" db:"status"`
+	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at" db:"updated_at"`
+
+	Items []FlashSaleItem `json:"items,omitempty"`
+}
+
+// FlashSaleItem represents a single SKU in a flash sale
+type FlashSaleItem struct {
+	ID                    int64    `json:"id" db:"id"`
+	FlashSaleID           int64    `json:"flash_sale_id" db:"flash_sale_id"`
+	BuyerSKUCode          string   `json:"buyer_sku_code" db:"buyer_sku_code"`
+	FlashPrice            *float64 `json:"flash_price,omitempty" db:"flash_price"`
+	Quota                 *int     `json:"quota,omitempty" db:"quota"`
+	Sold                  int      `json:"sold" db:"-"`
+	OriginalDiscountPrice *float64 `json:"original_discount_price,omitempty" db:"original_discount_price"`
+	IsApplied             bool     `json:"is_applied" db:"is_applied"`
+}
+
+// FlashSaleInfo is the public flash sale info shown on the catalog (for countdown)
+type FlashSaleInfo struct {
+	FlashSaleID    int64     `json:"flash_sale_id"`
+	Name           string    `json:"name"`
+	ItemID         int64     `json:"-"`
+	FlashPrice     float64   `json:"-"`
+	EndAt          time.Time `json:"end_at"`
+	Quota          *int      `json:"quota,omitempty"`
+	RemainingQuota *int      `json:"remaining_quota,omitempty"`
+}
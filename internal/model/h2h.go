@@ -0,0 +1,129 @@
+package model
+
+import (
+	"time"
+)
+
+// H2HVersion is the version of the H2H API response format
+const H2HVersion = "v1"
+
+// H2H response codes (stable, part of the v1 contract)
+const (
+	H2HRCSuccess             = "00" // Sukses
+	H2HRCPending             = "03" // Sedang diproses
+	H2HRCFailed              = "10" // Transaksi gagal
+	H2HRCInvalidRequest      = "40" // Request tidak valid
+	H2HRCInvalidSignature    = "41" // API key / signature salah
+	H2HRCIPNotAllowed        = "42" // IP tidak terdaftar
+	H2HRCProductUnavailable  = "43" // Produk tidak tersedia
+	H2HRCInsufficientBalance = "44" // Saldo tidak mencukupi
+	H2HRCDuplicateRefID      = "45" // ref_id sudah digunakan
+	H2HRCNotFound            = "46" // Transaksi tidak ditemukan
+	H2HRCPromoInvalid        = "47" // Kode promo tidak valid
+	H2HRCFlashSaleSoldOut    = "48" // Kuota flash sale habis
+	H2HRCLimitOrderAmount    = "49" // Melebihi batas nominal per transaksi
+	H2HRCProviderError       = "50" // Gagal diteruskan ke provider
+	H2HRCLimitDailySpend     = "51" // Batas belanja harian tercapai
+	H2HRCLimitHourlyOrders   = "52" // Batas transaksi per jam tercapai
+	H2HRCBrandNotAllowed     = "53" // Produk tidak diizinkan untuk akun
+	H2HRCInternalError       = "99" // Kesalahan sistem
+)
+
+// MemberAPIKey holds H2H credentials for a member
+type MemberAPIKey struct {
+	ID          int        `json:"id" db:"id"`
+	UserID      int        `json:"user_id" db:"user_id"`
+	APIKey      string     `json:"api_key" db:"api_key"`
+	APISecret   string     `json:"-" db:"api_secret"` // Never expose after creation
+	IPAllowlist []string   `json:"ip_allowlist" db:"ip_allowlist"`
+	IsActive    bool       `json:"is_active" db:"is_active"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	LastUsedIP  *string    `json:"last_used_ip,omitempty" db:"last_used_ip"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// AllowsIP checks the IP allowlist (empty allowlist allows every IP)
+func (k *MemberAPIKey) AllowsIP(ip string) bool {
+	if len(k.IPAllowlist) == 0 {
+		return true
+	}
+	for _, allowed := range k.IPAllowlist {
+		if allowed == ip {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdateAPIKeyRequest for updating H2H key settings
+type UpdateAPIKeyRequest struct {
+	IPAllowlist []string `json:"ip_allowlist"`
+	IsActive    *bool    `json:"is_active,omitempty"`
+}
+
+// H2HRequest is the request body for every H2H endpoint.
+// sign = md5(api_key + api_secret + <balance|pricelist|ref_id>), or an
+// X-Signature header with hex(HMAC-SHA256(api_secret, raw body)).
+type H2HRequest struct {
+	APIKey       string `json:"api_key"`
+	Sign         string `json:"sign"`
+	RefID        string `json:"ref_id,omitempty"`         // Reseller's own ref ID (transaction, status)
+	BuyerSKUCode string `json:"buyer_sku_code,omitempty"` // transaction
+	CustomerNo   string `json:"customer_no,omitempty"`    // transaction
+	PromoCode    string `json:"promo_code,omitempty"`     // transaction (optional)
+	Category     string `json:"category,omitempty"`       // price-list filter
+	Brand        string `json:"brand,omitempty"`          // price-list filter
+}
+
+// H2HResponse is the versioned response envelope of the H2H API
+type H2HResponse struct {
+	Version string      `json:"version"`
+	RC      string      `json:"rc"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// H2HBalance is the balance payload
+type H2HBalance struct {
+	Balance float64 `json:"balance"`
+}
+
+// H2HProduct is a price list entry
+type H2HProduct struct {
+	BuyerSKUCode string  `json:"buyer_sku_code"`
+	ProductName  string  `json:"product_name"`
+	Category     string  `json:"category"`
+	Brand        string  `json:"brand"`
+	Type         string  `json:"type"`
+	Price        float64 `json:"price"`
+	Available    bool    `json:"available"`
+}
+
+// H2HTransaction is the transaction / status payload
+type H2HTransaction struct {
+	RefID        string     `json:"ref_id"` // Reseller's ref ID
+	TrxID        string     `json:"trx_id"` // Our ref ID (INV-...)
+	Status       string     `json:"status"` // pending, success, failed
+	BuyerSKUCode string     `json:"buyer_sku_code"`
+	ProductName  string     `json:"product_name"`
+	CustomerNo   string     `json:"customer_no"`
+	Price        float64    `json:"price"`
+	SN           string     `json:"sn"`
+	Message      string     `json:"message"`
+	Balance      *float64   `json:"balance,omitempty"` // Balance after transaction (transaction endpoint only)
+	CreatedAt    time.Time  `json:"created_at"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+}
+
+// H2HStatus maps an order status to the stable H2H status and response code
+func H2HStatus(status OrderStatus) (string, string) {
+	switch status {
+	case OrderStatusSuccess:
+		return "success", H2HRCSuccess
+	case OrderStatusFailed, OrderStatusCancelled, OrderStatusExpired, OrderStatusRefunded:
+		return "failed", H2HRCFailed
+	default:
+		return "pending", H2HRCPending
+	}
+}
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// Idempotency key statuses
+const (
+	IdempotencyStatusProcessing = "processing"
+	IdempotencyStatusCompleted  = "completed"
+)
+
+// IdempotencyRecord is the stored result of a request sent with an idempotency key
+type IdempotencyRecord struct {
+	Scope          string    `json:"scope"`
+	Key            string    `json:"key"`
+	RequestHash    string    `json:"request_hash"`
+	Status         string    `json:"status"`
+	ResponseStatus int       `json:"response_status"`
+	ContentType    string    `json:"content_type"`
+	ResponseBody   []byte    `json:"-"`
+	CreatedAt      time.Time `json:"created_at"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
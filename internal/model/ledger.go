@@ -0,0 +1,66 @@
+package model
+
+import "time"
+
+// Balance adjustment deposit types (admin or system corrections)
+const (
+	DepositTypeAdjustmentCredit = "adjustment_credit"
+	DepositTypeAdjustmentDebit  = "adjustment_debit"
+)
+
+// DebitDepositTypes are the deposit types that reduce balance; every other type adds to it
+var DebitDepositTypes = []string{
+	DepositTypeDebit,
+	DepositTypeReferralReversal,
+	DepositTypeAdjustmentDebit,
+	DepositTypeTransferOut,
+	DepositTypeBatchReserve,
+}
+
+// IsDebitDepositType reports whether a deposit type reduces balance
+func IsDebitDepositType(depositType string) bool {
+	for _, t := range DebitDepositTypes {
+		if t == depositType {
+			return true
+		}
+	}
+	return false
+}
+
+// LedgerEntry is a single balance mutation posted to the deposits ledger
+type LedgerEntry struct {
+	UserID      int
+	Amount      float64 // Always positive; direction comes from Type
+	Type        string
+	Description string
+	ReferenceID string
+	CreatedBy   string
+
+	// LedgerRef is unique across the ledger. Defaults to "<type>:<reference_id>",
+	// or a random ref when there is no reference.
+	LedgerRef string
+
+	// AllowNegative lets a debit take the balance below zero (clawbacks)
+	AllowNegative bool
+}
+
+// LedgerMismatch is a member whose balance differs from the sum of their ledger
+type LedgerMismatch struct {
+	UserID           int      `json:"user_id"`
+	Username         string   `json:"username"`
+	Balance          float64  `json:"balance"`
+	LedgerBalance    float64  `json:"ledger_balance"`
+	Difference       float64  `json:"difference"`
+	Entries          int      `json:"entries"`
+	LastBalanceAfter *float64 `json:"last_balance_after,omitempty"`
+}
+
+// LedgerReconciliation is one run of the reconciliation job
+type LedgerReconciliation struct {
+	ID            int              `json:"id"`
+	CheckedUsers  int              `json:"checked_users"`
+	MismatchCount int              `json:"mismatch_count"`
+	Mismatches    []LedgerMismatch `json:"mismatches"`
+	TriggeredBy   string           `json:"triggered_by"`
+	CreatedAt     time.Time        `json:"created_at"`
+}
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"time"
+)
+
+// Login attempt outcomes (login_events.outcome)
+const (
+	LoginOutcomeSuccess         = "success"
+	LoginOutcomeInvalidPassword = "invalid_password"
+	LoginOutcomeInvalidTOTP     = "invalid_totp"
+	LoginOutcomeUnknownUser     = "unknown_user"
+	LoginOutcomeInactive        = "inactive"
+	LoginOutcomeLocked          = "locked"
+)
+
+// LoginLockout is the failed login state of an account
+type LoginLockout struct {
+	UserID         int        `json:"user_id" db:"user_id"`
+	Username       string     `json:"username" db:"username"`
+	Role           string     `json:"role" db:"role"`
+	FailedAttempts int        `json:"failed_attempts" db:"failed_attempts"`
+	LockoutCount   int        `json:"lockout_count" db:"lockout_count"`
+	LockedUntil    *time.Time `json:"locked_until,omitempty" db:"locked_until"`
+	LastFailedAt   *time.Time `json:"last_failed_at,omitempty" db:"last_failed_at"`
+	LastFailedIP   *string    `json:"last_failed_ip,omitempty" db:"last_failed_ip"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// Locked reports whether the account is locked at the given time
+func (l *LoginLockout) Locked(now time.Time) bool {
+	return l != nil && l.LockedUntil != nil && l.LockedUntil.After(now)
+}
+
+// LoginEvent is one login attempt
+type LoginEvent struct {
+	ID        int64     `json:"id" db:"id"`
+	UserID    *int      `json:"user_id,omitempty" db:"user_id"`
+	Username  string    `json:"username" db:"username"`
+	Channel   string    `json:"channel" db:"channel"`
+	Outcome   string    `json:"outcome" db:"outcome"`
+	IPAddress string    `json:"ip_address" db:"ip_address"`
+	UserAgent string    `json:"user_agent" db:"user_agent"`
+	Device    string    `json:"device" db:"device"`
+	NewDevice bool      `json:"new_device" db:"new_device"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
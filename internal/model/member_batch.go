@@ -0,0 +1,85 @@
+package model
+
+import "time"
+
+// Bulk order deposit types
+const (
+	DepositTypeBatchReserve = "batch_reserve" // Total of a batch held from balance
+	DepositTypeBatchRelease = "batch_release" // Reserved amount handed back (per line, or the unused rest)
+)
+
+// Order batch statuses
+const (
+	BatchStatusProcessing = "processing"
+	BatchStatusCompleted  = "completed"
+)
+
+// Order batch line statuses
+const (
+	BatchLinePending    = "pending"    // Waiting for the worker
+	BatchLineProcessing = "processing" // Being placed right now
+	BatchLineSubmitted  = "submitted"  // Order created; see order_status
+	BatchLineFailed     = "failed"     // Order could not be placed; see error
+)
+
+// OrderBatch is a bulk order submitted by a member
+type OrderBatch struct {
+	ID             int        `json:"-" db:"id"`
+	RefID          string     `json:"batch_id" db:"ref_id"`
+	UserID         int        `json:"-" db:"user_id"`
+	Status         string     `json:"status" db:"status"`
+	TotalLines     int        `json:"total_lines" db:"total_lines"`
+	ReservedAmount float64    `json:"reserved_amount" db:"reserved_amount"`
+	RefundedAmount float64    `json:"refunded_amount" db:"refunded_amount"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+
+	PendingLines   int `json:"pending_lines"`
+	SubmittedLines int `json:"submitted_lines"`
+	FailedLines    int `json:"failed_lines"`
+
+	Lines []OrderBatchLine `json:"lines,omitempty"`
+}
+
+// OrderBatchLine is one order of a batch
+type OrderBatchLine struct {
+	ID                int        `json:"-" db:"id"`
+	BatchID           int        `json:"-" db:"batch_id"`
+	LineNo            int        `json:"line_no" db:"line_no"`
+	BuyerSKUCode      string     `json:"buyer_sku_code" db:"buyer_sku_code"`
+	DestinationNumber string     `json:"destination_number" db:"destination_number"`
+	ClientRefID       string     `json:"client_ref_id,omitempty" db:"client_ref_id"`
+	Price             float64    `json:"price" db:"price"`
+	Status            string     `json:"status" db:"status"`
+	OrderRef          *string    `json:"order_ref,omitempty" db:"order_ref"`
+	ErrorCode         string     `json:"error_code,omitempty" db:"error_code"`
+	ErrorMessage      string     `json:"error_message,omitempty" db:"error_message"`
+	ProcessedAt       *time.Time `json:"processed_at,omitempty" db:"processed_at"`
+
+	// From the linked order
+	OrderStatus  *string `json:"order_status,omitempty"`
+	SerialNumber string  `json:"serial_number,omitempty"`
+
+	// Set by the worker when claiming
+	UserID     int    `json:"-"`
+	BatchRefID string `json:"-"`
+}
+
+// OrderBatchLineRequest is one line of a bulk order
+type OrderBatchLineRequest struct {
+	BuyerSKUCode      string `json:"buyer_sku_code"`
+	DestinationNumber string `json:"destination_number"`
+	ClientRefID       string `json:"client_ref_id,omitempty"` // Optional own ref ID, unique per member
+}
+
+// OrderBatchRequest is the JSON body of POST /api/v1/member/batches
+type OrderBatchRequest struct {
+	Lines    []OrderBatchLineRequest `json:"lines"`
+	TOTPCode string                  `json:"totp_code,omitempty"` // Required when the batch total needs TOTP
+}
+
+// OrderBatchLineError explains why a submitted line was rejected
+type OrderBatchLineError struct {
+	LineNo int    `json:"line_no"`
+	Error  string `json:"error"`
+}
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// MemberFavorite is a saved destination number of a member
+type MemberFavorite struct {
+	ID          int        `json:"id" db:"id"`
+	UserID      int        `json:"-" db:"user_id"`
+	Label       string     `json:"label" db:"label"`
+	Brand       string     `json:"brand" db:"brand"`
+	CustomerNo  string     `json:"customer_no" db:"customer_no"`
+	AccountName string     `json:"account_name,omitempty" db:"account_name"`
+	UseCount    int        `json:"use_count" db:"use_count"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// MemberFavoriteRequest is the body of POST/PUT /api/v1/member/favorites
+type MemberFavoriteRequest struct {
+	Label       string `json:"label"`
+	Brand       string `json:"brand"`
+	CustomerNo  string `json:"customer_no"`
+	AccountName string `json:"account_name,omitempty"`
+}
@@ -0,0 +1,51 @@
+package model
+
+import (
+	"time"
+)
+
+// MemberSecurity holds a member's TOTP (2FA) settings
+type MemberSecurity struct {
+	UserID              int        `json:"user_id" db:"user_id"`
+	TOTPSecret          string     `json:"-" db:"totp_secret"`
+	TOTPEnabled         bool       `json:"totp_enabled" db:"totp_enabled"`
+	TOTPEnabledAt       *time.Time `json:"totp_enabled_at,omitempty" db:"totp_enabled_at"`
+	RequireTOTPPassword bool       `json:"require_totp_password" db:"require_totp_password"`
+	RequireTOTPOrders   bool       `json:"require_totp_orders" db:"require_totp_orders"`
+	OrderTOTPThreshold  float64    `json:"order_totp_threshold" db:"order_totp_threshold"`
+	CreatedAt           time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// RequiresTOTPForOrder checks if an order of this amount needs a TOTP code
+func (s *MemberSecurity) RequiresTOTPForOrder(amount float64) bool {
+	if s == nil || !s.TOTPEnabled || !s.RequireTOTPOrders {
+		return false
+	}
+	return amount >= s.OrderTOTPThreshold
+}
+
+// RequiresTOTPForPassword checks if changing the password needs a TOTP code
+func (s *MemberSecurity) RequiresTOTPForPassword() bool {
+	return s != nil && s.TOTPEnabled && s.RequireTOTPPassword
+}
+
+// MemberTOTPCodeRequest carries a TOTP code (enable/disable)
+type MemberTOTPCodeRequest struct {
+	Code     string `json:"code"`
+	Password string `json:"password,omitempty"` // Required to disable
+}
+
+// MemberTOTPSettingsRequest for choosing when a TOTP code is required
+type MemberTOTPSettingsRequest struct {
+	Code                string  `json:"code"`
+	RequireTOTPPassword bool    `json:"require_totp_password"`
+	RequireTOTPOrders   bool    `json:"require_totp_orders"`
+	OrderTOTPThreshold  float64 `json:"order_totp_threshold"`
+}
+
+// MemberLoginTOTPRequest is the second login step for members with TOTP enabled
+type MemberLoginTOTPRequest struct {
+	PreAuthToken string `json:"pre_auth_token"`
+	Code         string `json:"code"`
+}
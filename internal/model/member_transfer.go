@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// Transfer deposit types
+const (
+	DepositTypeTransferOut = "transfer_out" // Balance sent to another member
+	DepositTypeTransferIn  = "transfer_in"  // Balance received from another member
+)
+
+// MemberTransfer is a balance transfer between two members
+type MemberTransfer struct {
+	ID           int       `json:"id" db:"id"`
+	RefID        string    `json:"ref_id" db:"ref_id"`
+	FromUserID   int       `json:"from_user_id" db:"from_user_id"`
+	ToUserID     int       `json:"to_user_id" db:"to_user_id"`
+	FromUsername string    `json:"from_username"`
+	ToUsername   string    `json:"to_username"`
+	Amount       float64   `json:"amount" db:"amount"`
+	Note         string    `json:"note,omitempty" db:"note"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// TransferRequest is the body of POST /api/v1/member/transfers
+type TransferRequest struct {
+	ToUsername string  `json:"to_username"`
+	Amount     float64 `json:"amount"`
+	Note       string  `json:"note,omitempty"`
+	TOTPCode   string  `json:"totp_code,omitempty"` // Required when the sender has TOTP enabled
+	Password   string  `json:"password,omitempty"`  // Required when the sender has no TOTP
+}
@@ -0,0 +1,71 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Member webhook events
+const (
+	MemberWebhookEventOrderStatus = "order.status"
+)
+
+// Member webhook delivery status constants
+const (
+	WebhookDeliveryPending = "pending"
+	WebhookDeliverySuccess = "success"
+	WebhookDeliveryFailed  = "failed"
+)
+
+// MemberWebhook is a member's callback URL registration
+type MemberWebhook struct {
+	UserID    int       `json:"user_id" db:"user_id"`
+	URL       string    `json:"url" db:"url"`
+	Secret    string    `json:"-" db:"secret"` // Only shown when generated
+	IsActive  bool      `json:"is_active" db:"is_active"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// MemberWebhookRequest for registering/updating the callback URL
+type MemberWebhookRequest struct {
+	URL      string `json:"url"`
+	IsActive *bool  `json:"is_active,omitempty"`
+}
+
+// MemberWebhookDelivery is a delivery log entry
+type MemberWebhookDelivery struct {
+	ID             int             `json:"id" db:"id"`
+	UserID         int             `json:"user_id" db:"user_id"`
+	OrderID        *string         `json:"order_id,omitempty" db:"order_id"`
+	Event          string          `json:"event" db:"event"`
+	URL            string          `json:"url" db:"url"`
+	Payload        json.RawMessage `json:"payload" db:"payload"`
+	Status         string          `json:"status" db:"status"`
+	Attempts       int             `json:"attempts" db:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty" db:"next_attempt_at"`
+	LastStatusCode *int            `json:"last_status_code,omitempty" db:"last_status_code"`
+	LastError      *string         `json:"last_error,omitempty" db:"last_error"`
+	LastResponse   *string         `json:"last_response,omitempty" db:"last_response"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at" db:"updated_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty" db:"delivered_at"`
+
+	Secret string `json:"-"` // Loaded when claiming for delivery
+}
+
+// OrderStatusPayload is the JSON body posted to a member's callback URL
+type OrderStatusPayload struct {
+	Event        string      `json:"event"`
+	OrderID      string      `json:"order_id"`
+	RefID        string      `json:"ref_id"`                  // Our ref ID (INV-...)
+	ClientRefID  string      `json:"client_ref_id,omitempty"` // Reseller's ref ID (H2H)
+	Status       OrderStatus `json:"status"`
+	BuyerSKUCode string      `json:"buyer_sku_code"`
+	ProductName  string      `json:"product_name"`
+	CustomerNo   string      `json:"customer_no"`
+	Price        float64     `json:"price"`
+	SN           string      `json:"sn"`
+	Message      string      `json:"message"`
+	UpdatedAt    time.Time   `json:"updated_at"`
+}
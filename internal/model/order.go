@@ -43,6 +43,22 @@ type Order struct {
 	AdminNotes      string      `json:"admin_notes,omitempty" db:"admin_notes"`
 	MemberID        *int        `json:"member_id,omitempty" db:"member_id"`
 	MemberPrice     *float64    `json:"member_price,omitempty" db:"member_price"`
+	PromoID         *int64      `json:"promo_id,omitempty" db:"promo_id"`
+	PromoCode       string      `json:"promo_code,omitempty" db:"promo_code"`
+	DiscountAmount  float64     `json:"discount_amount,omitempty" db:"discount_amount"`
+	FlashSaleItemID *int64      `json:"flash_sale_item_id,omitempty" db:"flash_sale_item_id"`
+
+	ClientRefID string `json:"client_ref_id,omitempty" db:"client_ref_id"` // Reseller's own ref ID (H2H)
+
+	RetryRefID string `json:"retry_ref_id,omitempty" db:"retry_ref_id"` // Ref ID of the latest admin manual topup retry
+}
+
+// DigiflazzRefID returns the ref ID the order is sent to Digiflazz with
+func (o *Order) DigiflazzRefID() string {
+	if o.RetryRefID != "" {
+		return o.RetryRefID
+	}
+	return o.RefID
 }
 
 // CreateOrderRequest is the request body for creating an order
@@ -52,6 +68,7 @@ type CreateOrderRequest struct {
 	CustomerEmail string `json:"customer_email,omitempty"`
 	CustomerPhone string `json:"customer_phone,omitempty"`
 	CustomerName  string `json:"customer_name,omitempty"`
+	PromoCode     string `json:"promo_code,omitempty"`
 }
 
 // OrderResponse is the response format for FE
@@ -61,6 +78,8 @@ type OrderResponse struct {
 	ProductName  string      `json:"product_name"`
 	CustomerNo   string      `json:"customer_no"`
 	Price        float64     `json:"price"`
+	Discount     float64     `json:"discount,omitempty"`
+	PromoCode    string      `json:"promo_code,omitempty"`
 	Status       OrderStatus `json:"status"`
 	StatusLabel  string      `json:"status_label"`
 	SerialNumber string      `json:"serial_number,omitempty"`
@@ -98,6 +117,8 @@ func (o *Order) ToResponse(payment *Payment) OrderResponse {
 		ProductName:  o.ProductName,
 		CustomerNo:   o.CustomerNo,
 		Price:        o.SellingPrice,
+		Discount:     o.DiscountAmount,
+		PromoCode:    o.PromoCode,
 		Status:       o.Status,
 		StatusLabel:  o.GetStatusLabel(),
 		SerialNumber: o.SerialNumber,
@@ -0,0 +1,105 @@
+package model
+
+import (
+	"math"
+	"time"
+)
+
+// DepositTypePointsRedeem is the deposit type for points redeemed into balance
+const DepositTypePointsRedeem = "points_redeem"
+
+// Point earn types
+const (
+	PointEarnPercent = "percent" // Percent of the paid amount (1 point = Rp 1)
+	PointEarnFixed   = "fixed"   // Fixed points per order
+)
+
+// Point ledger entry types
+const (
+	PointTypeEarn     = "earn"
+	PointTypeRedeem   = "redeem"
+	PointTypeClawback = "clawback"
+	PointTypeExpire   = "expire"
+)
+
+// PointRule defines how many points a member earns for a brand or category
+type PointRule struct {
+	ID             int64     `json:"id" db:"id"`
+	Name           string    `json:"name" db:"name"`
+	Brand          *string   `json:"brand,omitempty" db:"brand"`
+	Category       *string   `json:"category,omitempty" db:"category"`
+	EarnType       string    `json:"earn_type" db:"earn_type"`
+	EarnValue      float64   `json:"earn_value" db:"earn_value"`
+	MinOrderAmount float64   `json:"min_order_amount" db:"min_order_amount"`
+	ExpiryDays     int       `json:"expiry_days" db:"expiry_days"`
+	IsActive       bool      `json:"is_active" db:"is_active"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// AppliesTo checks whether the rule targets the product's brand/category
+func (r *PointRule) AppliesTo(brand, category string) bool {
+	if r.Brand != nil && *r.Brand != brand {
+		return false
+	}
+	if r.Category != nil && *r.Category != category {
+		return false
+	}
+	return true
+}
+
+// Specificity ranks rules: brand+category > brand > category > all products
+func (r *PointRule) Specificity() int {
+	score := 0
+	if r.Brand != nil {
+		score += 2
+	}
+	if r.Category != nil {
+		score++
+	}
+	return score
+}
+
+// CalculatePoints returns the points earned for a paid amount
+func (r *PointRule) CalculatePoints(paid float64) int {
+	if !r.IsActive || paid < r.MinOrderAmount {
+		return 0
+	}
+
+	switch r.EarnType {
+	case PointEarnPercent:
+		return int(math.Floor(paid * r.EarnValue / 100))
+	case PointEarnFixed:
+		return int(math.Floor(r.EarnValue))
+	}
+	return 0
+}
+
+// PointEntry represents a points ledger entry
+type PointEntry struct {
+	ID          int        `json:"id" db:"id"`
+	UserID      int        `json:"user_id" db:"user_id"`
+	Type        string     `json:"type" db:"type"` // earn, redeem, clawback, expire
+	Points      int        `json:"points" db:"points"`
+	Remaining   int        `json:"remaining,omitempty" db:"remaining"`
+	OrderID     *string    `json:"order_id,omitempty" db:"order_id"`
+	RuleID      *int64     `json:"rule_id,omitempty" db:"rule_id"`
+	ReferenceID *string    `json:"reference_id,omitempty" db:"reference_id"`
+	Description *string    `json:"description,omitempty" db:"description"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+}
+
+// PointsSummary is the points overview shown to members
+type PointsSummary struct {
+	Balance        int        `json:"balance"`
+	ExpiringPoints int        `json:"expiring_points"` // Points expiring within 7 days
+	NextExpiry     *time.Time `json:"next_expiry,omitempty"`
+	RedeemRate     float64    `json:"redeem_rate"` // Rupiah per point
+	MinRedeem      int        `json:"min_redeem"`
+}
+
+// RedeemPointsRequest for converting points into balance
+type RedeemPointsRequest struct {
+	Points int `json:"points"`
+}
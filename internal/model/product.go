@@ -56,6 +56,8 @@ type ProductResponse struct {
 	IsBestSeller   bool     `json:"is_best_seller"`      // Best seller flag
 	Tags           []string `json:"tags,omitempty"`      // Product tags
 	ImageURL       *string  `json:"image_url,omitempty"` // Brand/game logo URL
+
+	FlashSale *FlashSaleInfo `json:"flash_sale,omitempty"` // Running flash sale (for countdown)
 }
 
 // ToResponse converts Product to ProductResponse for FE
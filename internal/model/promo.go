@@ -1,6 +1,9 @@
 package model
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 // Promo represents a discount/promo event
 type Promo struct {
@@ -22,6 +25,10 @@ type Promo struct {
 	IsActive      bool      `json:"is_active" db:"is_active"`
 	CreatedAt     time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+
+	// Per-customer limits (keyed by member ID, or normalized phone/email for guests)
+	PerCustomerLimit *int `json:"per_customer_limit,omitempty" db:"per_customer_limit"`
+	FirstOrderOnly   bool `json:"first_order_only" db:"first_order_only"`
 }
 
 // IsValid checks if the promo is currently valid
@@ -54,7 +61,7 @@ func (p *Promo) CalculateDiscount(price float64) float64 {
 
 	var discount float64
 
-	if p.DiscountType == "percent" {
+	if p.DiscountType == PromoDiscountPercent {
 		discount = price * (p.DiscountValue / 100)
 
 		// Apply max discount cap
@@ -74,6 +81,81 @@ func (p *Promo) CalculateDiscount(price float64) float64 {
 	return discount
 }
 
+// AppliesTo checks if the promo targeting (category, brand, SKU) matches the product.
+// A nil target means the promo applies to all products.
+func (p *Promo) AppliesTo(product *Product) bool {
+	if p.Category != nil && *p.Category != "" && !strings.EqualFold(*p.Category, product.Category) {
+		return false
+	}
+	if p.Brand != nil && *p.Brand != "" && !strings.EqualFold(*p.Brand, product.Brand) {
+		return false
+	}
+	if p.BuyerSKUCode != nil && *p.BuyerSKUCode != "" && !strings.EqualFold(*p.BuyerSKUCode, product.BuyerSKUCode) {
+		return false
+	}
+	return true
+}
+
+// RequiresCustomerKey returns true if the promo needs to identify the customer
+func (p *Promo) RequiresCustomerKey() bool {
+	return p.PerCustomerLimit != nil || p.FirstOrderOnly
+}
+
+// NormalizePhone converts an Indonesian phone number to 62xxxxxxxx format
+func NormalizePhone(phone string) string {
+	var digits strings.Builder
+	for _, c := range phone {
+		if c >= '0' && c <= '9' {
+			digits.WriteRune(c)
+		}
+	}
+
+	n := digits.String()
+	switch {
+	case strings.HasPrefix(n, "62"):
+		return n
+	case strings.HasPrefix(n, "0"):
+		return "62" + n[1:]
+	case strings.HasPrefix(n, "8"):
+		return "62" + n
+	}
+	return n
+}
+
+// PhoneVariants returns the common ways a phone number may have been stored on orders
+func PhoneVariants(phone string) []string {
+	normalized := NormalizePhone(phone)
+	if normalized == "" {
+		return nil
+	}
+
+	candidates := []string{normalized, strings.TrimSpace(phone)}
+	if strings.HasPrefix(normalized, "62") {
+		candidates = append(candidates, "0"+normalized[2:], "+"+normalized)
+	}
+
+	seen := make(map[string]bool)
+	var variants []string
+	for _, v := range candidates {
+		if v != "" && !seen[v] {
+			seen[v] = true
+			variants = append(variants, v)
+		}
+	}
+	return variants
+}
+
+// NormalizeEmail lowercases and trims an email address
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// PromoDiscountType constants
+const (
+	PromoDiscountPercent = "percent"
+	PromoDiscountFixed   = "fixed"
+)
+
 // WebhookLog represents a webhook request log
 type WebhookLog struct {
 	ID           int64     `json:"id" db:"id"`
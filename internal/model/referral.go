@@ -0,0 +1,83 @@
+package model
+
+import (
+	"math"
+	"time"
+)
+
+// Referral deposit types (balance ledger)
+const (
+	DepositTypeReferral         = "referral"          // Commission credited to the referrer
+	DepositTypeReferralReversal = "referral_reversal" // Commission clawed back after refund
+)
+
+// Referral commission types
+const (
+	ReferralCommissionPercent = "percent" // Percent of order margin
+	ReferralCommissionFlat    = "flat"    // Flat amount per order
+)
+
+// ReferralCommission status constants
+const (
+	ReferralStatusPending  = "pending"
+	ReferralStatusCredited = "credited"
+	ReferralStatusReversed = "reversed"
+)
+
+// ReferralCommission represents a commission earned from a referred member's order
+type ReferralCommission struct {
+	ID              int        `json:"id" db:"id"`
+	ReferrerID      int        `json:"referrer_id" db:"referrer_id"`
+	ReferredID      int        `json:"referred_id" db:"referred_id"`
+	ReferredName    string     `json:"referred_username" db:"referred_username"`
+	OrderID         string     `json:"order_id" db:"order_id"`
+	RefID           string     `json:"ref_id" db:"ref_id"`
+	CommissionType  string     `json:"commission_type" db:"commission_type"`
+	CommissionValue float64    `json:"commission_value" db:"commission_value"`
+	OrderMargin     float64    `json:"-" db:"order_margin"` // Hidden from FE
+	Amount          float64    `json:"amount" db:"amount"`
+	Status          string     `json:"status" db:"status"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	CreditedAt      *time.Time `json:"credited_at,omitempty" db:"credited_at"`
+	ReversedAt      *time.Time `json:"reversed_at,omitempty" db:"reversed_at"`
+}
+
+// ReferredMember is a member who signed up with someone's referral code
+type ReferredMember struct {
+	ID          int       `json:"id"`
+	Username    string    `json:"username"`
+	FullName    string    `json:"full_name"`
+	JoinedAt    time.Time `json:"joined_at"`
+	TotalOrders int       `json:"total_orders"` // Orders that earned a commission
+	Earned      float64   `json:"earned"`
+}
+
+// ReferralSummary is the referral overview for a member
+type ReferralSummary struct {
+	ReferralCode   string  `json:"referral_code"`
+	ReferralLink   string  `json:"referral_link"`
+	CommissionType string  `json:"commission_type"`
+	CommissionRate float64 `json:"commission_value"`
+	TotalReferrals int     `json:"total_referrals"`
+	TotalEarned    float64 `json:"total_earned"`
+	TotalReversed  float64 `json:"total_reversed"`
+}
+
+// CalculateReferralCommission returns the commission for an order margin.
+// Percent commissions are taken from the margin and rounded down to whole rupiah.
+func CalculateReferralCommission(commissionType string, value, margin float64) float64 {
+	if value <= 0 {
+		return 0
+	}
+
+	switch commissionType {
+	case ReferralCommissionFlat:
+		return math.Floor(value)
+	case ReferralCommissionPercent:
+		if margin <= 0 {
+			return 0
+		}
+		return math.Floor(margin * value / 100)
+	}
+	return 0
+}
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"time"
+)
+
+// UserStatusPendingVerification is the status of a self-registered member until the email is verified
+const UserStatusPendingVerification = "pending_verification"
+
+// Registration modes
+const (
+	RegistrationModeOpen   = "open"   // Anyone can register
+	RegistrationModeInvite = "invite" // A valid referral (invite) code is required
+	RegistrationModeClosed = "closed" // Members are only created by admin
+)
+
+// SettingRegistrationMode is the app_settings key for the registration mode
+const SettingRegistrationMode = "registration_mode"
+
+// ValidRegistrationMode checks if mode is a known registration mode
+func ValidRegistrationMode(mode string) bool {
+	switch mode {
+	case RegistrationModeOpen, RegistrationModeInvite, RegistrationModeClosed:
+		return true
+	}
+	return false
+}
+
+// AppSetting is a key/value application setting
+type AppSetting struct {
+	Key       string    `json:"key" db:"key"`
+	Value     string    `json:"value" db:"value"`
+	UpdatedBy *string   `json:"updated_by,omitempty" db:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// RegisterRequest for public member self-registration
+type RegisterRequest struct {
+	Username     string `json:"username" validate:"required,min=3,max=50"`
+	Password     string `json:"password" validate:"required,min=6"`
+	Email        string `json:"email" validate:"required,email"`
+	FullName     string `json:"full_name" validate:"required"`
+	WhatsApp     string `json:"whatsapp,omitempty"`
+	ReferralCode string `json:"referral_code,omitempty"` // Required in invite mode
+}
+
+// VerifyEmailRequest for confirming the email verification link
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// ResendVerificationRequest for requesting a new verification link
+type ResendVerificationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// RegistrationSettings is the registration configuration exposed to admin and FE
+type RegistrationSettings struct {
+	Mode string `json:"mode"` // open, invite, closed
+}
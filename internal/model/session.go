@@ -0,0 +1,43 @@
+package model
+
+import (
+	"time"
+)
+
+// Session channels (which login endpoint created the session)
+const (
+	SessionChannelAdmin  = "admin"  // POST /api/v1/admin/login (unified)
+	SessionChannelMember = "member" // POST /api/v1/member/login
+)
+
+// Session revoke reasons
+const (
+	SessionRevokeLogout         = "logout"
+	SessionRevokeLogoutAll      = "logout_all"
+	SessionRevokePasswordChange = "password_change"
+	SessionRevokeSuspended      = "suspended"
+	SessionRevokeRefreshReuse   = "refresh_reuse"
+	SessionRevokeRoleChange     = "role_change"
+)
+
+// Session is a login session with a rotating refresh token
+type Session struct {
+	ID            string     `json:"id" db:"id"`
+	UserID        int        `json:"user_id" db:"user_id"`
+	Channel       string     `json:"channel" db:"channel"`
+	Device        string     `json:"device" db:"device"`
+	IPAddress     string     `json:"ip_address" db:"ip_address"`
+	UserAgent     string     `json:"user_agent" db:"user_agent"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	LastUsedAt    time.Time  `json:"last_used_at" db:"last_used_at"`
+	ExpiresAt     time.Time  `json:"expires_at" db:"expires_at"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	RevokedReason *string    `json:"revoked_reason,omitempty" db:"revoked_reason"`
+
+	Current bool `json:"current"` // Session of the calling token
+}
+
+// RefreshTokenRequest for rotating a refresh token (body is optional when the cookie is sent)
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
@@ -0,0 +1,69 @@
+package model
+
+import "time"
+
+// DefaultMemberLevel is the level of new members
+const DefaultMemberLevel = "regular"
+
+// Spending limit error codes (stable, exposed to members and the H2H API)
+const (
+	LimitCodeOrderAmount = "limit_order_amount"
+	LimitCodeDailySpend  = "limit_daily_spend"
+	LimitCodeHourlyOrder = "limit_hourly_orders"
+	LimitCodeBrand       = "limit_brand_not_allowed"
+)
+
+// SpendingLimits are the effective limits of a member. nil means unlimited;
+// an empty AllowedBrands allows every brand.
+type SpendingLimits struct {
+	MaxOrderAmount   *float64 `json:"max_order_amount"`
+	MaxDailySpend    *float64 `json:"max_daily_spend"`
+	MaxOrdersPerHour *int     `json:"max_orders_per_hour"`
+	AllowedBrands    []string `json:"allowed_brands"`
+}
+
+// MemberLevel holds the default limits of a group of members
+type MemberLevel struct {
+	Level     string    `json:"level"`
+	Name      string    `json:"name"`
+	UpdatedBy string    `json:"updated_by,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	SpendingLimits
+}
+
+// MemberLimitOverride replaces level limits for one member. A nil field inherits
+// the level value, 0 lifts the limit, and an empty AllowedBrands allows every brand.
+type MemberLimitOverride struct {
+	UserID    int       `json:"user_id"`
+	Note      string    `json:"note,omitempty"`
+	UpdatedBy string    `json:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	SpendingLimits
+}
+
+// SpendingUsage is a member's usage against their effective limits
+type SpendingUsage struct {
+	Level          string               `json:"level"`
+	Limits         SpendingLimits       `json:"limits"`
+	SpentToday     float64              `json:"spent_today"`
+	OrdersLastHour int                  `json:"orders_last_hour"`
+	RemainingToday *float64             `json:"remaining_today"` // nil when there is no daily limit
+	Override       *MemberLimitOverride `json:"override,omitempty"`
+}
+
+// MemberLevelRequest is the body of PUT /api/v1/admin/member-levels/{level}
+type MemberLevelRequest struct {
+	Name string `json:"name"`
+
+	SpendingLimits
+}
+
+// MemberLimitRequest is the body of PUT /api/v1/admin/members/{id}/limits
+type MemberLimitRequest struct {
+	Level string `json:"level,omitempty"` // Moves the member to another level
+	Note  string `json:"note,omitempty"`
+
+	SpendingLimits
+}
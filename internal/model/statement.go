@@ -0,0 +1,69 @@
+package model
+
+import "time"
+
+// Statement formats
+const (
+	StatementFormatCSV = "csv"
+	StatementFormatPDF = "pdf"
+)
+
+// Statement job statuses
+const (
+	StatementStatusPending    = "pending"
+	StatementStatusProcessing = "processing"
+	StatementStatusReady      = "ready"
+	StatementStatusFailed     = "failed"
+)
+
+// Statement is a member's balance ledger for a date range, joined with orders
+type Statement struct {
+	UserID         int              `json:"user_id"`
+	Username       string           `json:"username"`
+	FullName       string           `json:"full_name"`
+	DateFrom       string           `json:"date_from"`
+	DateTo         string           `json:"date_to"`
+	OpeningBalance float64          `json:"opening_balance"`
+	ClosingBalance float64          `json:"closing_balance"`
+	TotalCredit    float64          `json:"total_credit"`
+	TotalDebit     float64          `json:"total_debit"`
+	Entries        []StatementEntry `json:"entries"`
+	GeneratedAt    time.Time        `json:"generated_at"`
+}
+
+// StatementEntry is one ledger row of a statement
+type StatementEntry struct {
+	Date         time.Time `json:"date"`
+	Type         string    `json:"type"`
+	Description  string    `json:"description"`
+	ReferenceID  string    `json:"reference_id"`
+	ProductName  string    `json:"product_name,omitempty"`
+	Destination  string    `json:"destination,omitempty"`
+	SerialNumber string    `json:"serial_number,omitempty"`
+	OrderStatus  string    `json:"order_status,omitempty"`
+	Credit       float64   `json:"credit"`
+	Debit        float64   `json:"debit"`
+	Balance      float64   `json:"balance"` // Running balance after this entry
+}
+
+// StatementJob is a statement export generated in the background
+type StatementJob struct {
+	ID           string     `json:"id" db:"id"`
+	UserID       int        `json:"-" db:"user_id"`
+	DateFrom     string     `json:"date_from" db:"date_from"`
+	DateTo       string     `json:"date_to" db:"date_to"`
+	Format       string     `json:"format" db:"format"`
+	Status       string     `json:"status" db:"status"`
+	FileName     string     `json:"file_name,omitempty" db:"file_name"`
+	ErrorMessage string     `json:"error_message,omitempty" db:"error_message"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+	ExpiresAt    time.Time  `json:"expires_at" db:"expires_at"`
+}
+
+// StatementRequest is the body of POST /api/v1/member/statements
+type StatementRequest struct {
+	DateFrom string `json:"date_from"` // YYYY-MM-DD
+	DateTo   string `json:"date_to"`   // YYYY-MM-DD, inclusive
+	Format   string `json:"format"`    // csv or pdf
+}
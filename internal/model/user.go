@@ -17,6 +17,9 @@ type User struct {
 	WhatsApp  *string   `json:"whatsapp,omitempty" db:"whatsapp"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+
+	ReferralCode *string `json:"referral_code,omitempty" db:"referral_code"`
+	ReferredBy   *int    `json:"referred_by,omitempty" db:"referred_by"`
 }
 
 // Deposit represents a balance transaction (topup, debit, refund)
@@ -30,6 +33,9 @@ type Deposit struct {
 	Status      string    `json:"status" db:"status"` // success, pending, failed
 	CreatedBy   string    `json:"created_by" db:"created_by"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+
+	BalanceBefore *float64 `json:"balance_before,omitempty" db:"balance_before"` // NULL for entries before the ledger migration
+	BalanceAfter  *float64 `json:"balance_after,omitempty" db:"balance_after"`
 }
 
 // DepositType constants
@@ -58,6 +64,8 @@ type CreateUserRequest struct {
 	Email    string `json:"email,omitempty" validate:"omitempty,email"`
 	FullName string `json:"full_name" validate:"required"`
 	WhatsApp string `json:"whatsapp,omitempty"`
+
+	ReferralCode string `json:"referral_code,omitempty"` // Referral code of the referring member
 }
 
 // UpdateUserRequest for updating user data
@@ -90,7 +98,13 @@ type MemberLoginResponse struct {
 type MemberOrderRequest struct {
 	BuyerSKUCode      string `json:"buyer_sku_code"`
 	DestinationNumber string `json:"destination_number"`
-	Pin               string `json:"pin,omitempty"` // Optional security pin
+	Pin               string `json:"pin,omitempty"`        // Optional security pin
+	PromoCode         string `json:"promo_code,omitempty"` // Optional promo code
+
+	TOTPCode string `json:"totp_code,omitempty"` // Required for large orders when enabled
+
+	ClientRefID string `json:"client_ref_id,omitempty"` // Member's own ref ID, doubles as idempotency key
+	FavoriteID  int    `json:"favorite_id,omitempty"`   // Saved favorite used in place of destination_number
 }
 
 // ForgotPasswordRequest for password reset request
@@ -111,6 +125,11 @@ type MemberDashboardResponse struct {
 	SuccessOrders int     `json:"success_orders"`
 	PendingOrders int     `json:"pending_orders"`
 	TodayOrders   int     `json:"today_orders"`
+
+	Points        *PointsSummary `json:"points"`
+	PointsHistory []PointEntry   `json:"points_history"` // Latest entries
+
+	Limits *SpendingUsage `json:"limits,omitempty"` // Usage against spending limits
 }
 
 // UserResponse is a safe user response without password
@@ -124,6 +143,9 @@ type UserResponse struct {
 	Status    string    `json:"status"`
 	WhatsApp  *string   `json:"whatsapp,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
+
+	ReferralCode *string `json:"referral_code,omitempty"`
+	ReferredBy   *int    `json:"referred_by,omitempty"`
 }
 
 // ToResponse converts User to UserResponse (safe for frontend)
@@ -138,5 +160,8 @@ func (u *User) ToResponse() UserResponse {
 		Status:    u.Status,
 		WhatsApp:  u.WhatsApp,
 		CreatedAt: u.CreatedAt,
+
+		ReferralCode: u.ReferralCode,
+		ReferredBy:   u.ReferredBy,
 	}
 }
@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"govershop-api/internal/model"
+)
+
+// Adjustment errors
+var (
+	ErrDepositNotFound        = errors.New("deposit not found")
+	ErrDepositNotReversible   = errors.New("deposit cannot be reversed")
+	ErrDepositAlreadyReversed = errors.New("deposit already reversed")
+)
+
+// AdjustmentRepository handles admin balance adjustments
+type AdjustmentRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewAdjustmentRepository creates a new AdjustmentRepository
+func NewAdjustmentRepository(db *pgxpool.Pool) *AdjustmentRepository {
+	return &AdjustmentRepository{db: db}
+}
+
+// GetDeposit returns a member's ledger entry (nil if not found)
+func (r *AdjustmentRepository) GetDeposit(ctx context.Context, userID, depositID int) (*model.Deposit, error) {
+	query := `
+		SELECT id, user_id, amount, type, description, reference_id, status, created_by, created_at
+		FROM deposits
+		WHERE id = $1 AND user_id = $2
+	`
+
+	var d model.Deposit
+	err := r.db.QueryRow(ctx, query, depositID, userID).Scan(
+		&d.ID, &d.UserID, &d.Amount, &d.Type, &d.Description, &d.ReferenceID, &d.Status, &d.CreatedBy, &d.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deposit: %w", err)
+	}
+	return &d, nil
+}
+
+// Create records an adjustment and posts it to the ledger in one transaction.
+// A reversal takes its amount from the reversed entry and posts the opposite type;
+// it may take the balance below zero, a plain debit may not.
+func (r *AdjustmentRepository) Create(ctx context.Context, a *model.BalanceAdjustment) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	entryType := model.DepositTypeAdjustmentCredit
+	description := fmt.Sprintf("Penyesuaian saldo (%s): %s", a.ReasonCode, a.Note)
+	allowNegative := false
+
+	switch a.Kind {
+	case model.AdjustmentKindDebit:
+		entryType = model.DepositTypeAdjustmentDebit
+	case model.AdjustmentKindReversal:
+		if a.ReversedDepositID == nil {
+			return ErrDepositNotFound
+		}
+
+		var reversedType, status string
+		err := tx.QueryRow(ctx, `
+			SELECT amount, type, status FROM deposits
+			WHERE id = $1 AND user_id = $2
+			FOR UPDATE
+		`, *a.ReversedDepositID, a.UserID).Scan(&a.Amount, &reversedType, &status)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrDepositNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get reversed deposit: %w", err)
+		}
+		if status != "success" {
+			return ErrDepositNotReversible
+		}
+
+		if !model.IsDebitDepositType(reversedType) {
+			entryType = model.DepositTypeAdjustmentDebit
+		}
+		description = fmt.Sprintf("Pembatalan mutasi #%d (%s): %s", *a.ReversedDepositID, a.ReasonCode, a.Note)
+		allowNegative = true
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO balance_adjustments (user_id, kind, amount, reason_code, note, reversed_deposit_id, totp_verified, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at
+	`, a.UserID, a.Kind, a.Amount, a.ReasonCode, a.Note, a.ReversedDepositID, a.TOTPVerified, a.CreatedBy,
+	).Scan(&a.ID, &a.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "idx_balance_adjustments_reversed" {
+			return ErrDepositAlreadyReversed
+		}
+		return fmt.Errorf("failed to create balance adjustment: %w", err)
+	}
+
+	entry := model.LedgerEntry{
+		UserID:        a.UserID,
+		Amount:        a.Amount,
+		Type:          entryType,
+		Description:   description,
+		ReferenceID:   fmt.Sprintf("ADJ-%d", a.ID),
+		CreatedBy:     a.CreatedBy,
+		AllowNegative: allowNegative,
+	}
+	if a.ReversedDepositID != nil {
+		entry.LedgerRef = fmt.Sprintf("reversal:%d", *a.ReversedDepositID)
+	}
+
+	d, err := postLedgerTx(ctx, tx, entry)
+	if errors.Is(err, ErrDuplicateLedgerEntry) {
+		return ErrDepositAlreadyReversed
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE balance_adjustments SET deposit_id = $1 WHERE id = $2`, d.ID, a.ID); err != nil {
+		return fmt.Errorf("failed to link balance adjustment: %w", err)
+	}
+	a.DepositID = &d.ID
+	a.BalanceAfter = d.BalanceAfter
+
+	return tx.Commit(ctx)
+}
+
+// ListByUser returns a member's adjustments, newest first
+func (r *AdjustmentRepository) ListByUser(ctx context.Context, userID, limit, offset int) ([]model.BalanceAdjustment, int, error) {
+	var total int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM balance_adjustments WHERE user_id = $1`, userID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count balance adjustments: %w", err)
+	}
+
+	query := `
+		SELECT id, user_id, kind, amount, reason_code, note, deposit_id, reversed_deposit_id,
+			totp_verified, created_by, created_at
+		FROM balance_adjustments
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.Query(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get balance adjustments: %w", err)
+	}
+	defer rows.Close()
+
+	var adjustments []model.BalanceAdjustment
+	for rows.Next() {
+		var a model.BalanceAdjustment
+		if err := rows.Scan(
+			&a.ID, &a.UserID, &a.Kind, &a.Amount, &a.ReasonCode, &a.Note, &a.DepositID, &a.ReversedDepositID,
+			&a.TOTPVerified, &a.CreatedBy, &a.CreatedAt,
+		); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan balance adjustment: %w", err)
+		}
+		adjustments = append(adjustments, a)
+	}
+
+	return adjustments, total, nil
+}
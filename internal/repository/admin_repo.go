@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"govershop-api/internal/model"
+)
+
+// ErrLastOwner is returned when a change would leave no active owner
+var ErrLastOwner = errors.New("at least one active owner is required")
+
+// AdminRepository handles admin accounts and their roles
+type AdminRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewAdminRepository creates a new AdminRepository
+func NewAdminRepository(db *pgxpool.Pool) *AdminRepository {
+	return &AdminRepository{db: db}
+}
+
+const adminColumns = `
+	u.id, u.username, u.email, u.full_name, COALESCE(u.admin_role, ''), u.status,
+	COALESCE(s.totp_enabled, false)
+`
+
+func scanAdmin(row pgx.Row) (*model.AdminAccount, error) {
+	var a model.AdminAccount
+	err := row.Scan(&a.ID, &a.Username, &a.Email, &a.FullName, &a.AdminRole, &a.Status, &a.TOTPEnabled)
+	if err != nil {
+		return nil, err
+	}
+	a.Permissions = model.AdminPermissions[a.AdminRole]
+	return &a, nil
+}
+
+// GetRole returns the admin role and status of an admin user ("" if not an admin)
+func (r *AdminRepository) GetRole(ctx context.Context, userID int) (role, status string, err error) {
+	query := `SELECT COALESCE(admin_role, ''), status FROM users WHERE id = $1 AND role = 'admin'`
+
+	err = r.db.QueryRow(ctx, query, userID).Scan(&role, &status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get admin role: %w", err)
+	}
+	return role, status, nil
+}
+
+// GetByID returns an admin account (nil if not found)
+func (r *AdminRepository) GetByID(ctx context.Context, id int) (*model.AdminAccount, error) {
+	query := `
+		SELECT ` + adminColumns + `
+		FROM users u
+		LEFT JOIN admin_security s ON s.user_id = u.id
+		WHERE u.id = $1 AND u.role = 'admin'
+	`
+
+	a, err := scanAdmin(r.db.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get admin: %w", err)
+	}
+	return a, nil
+}
+
+// List returns all admin accounts
+func (r *AdminRepository) List(ctx context.Context) ([]model.AdminAccount, error) {
+	query := `
+		SELECT ` + adminColumns + `
+		FROM users u
+		LEFT JOIN admin_security s ON s.user_id = u.id
+		WHERE u.role = 'admin'
+		ORDER BY u.id
+	`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get admins: %w", err)
+	}
+	defer rows.Close()
+
+	var admins []model.AdminAccount
+	for rows.Next() {
+		a, err := scanAdmin(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan admin: %w", err)
+		}
+		admins = append(admins, *a)
+	}
+
+	return admins, nil
+}
+
+// Create creates a new admin user with a role
+func (r *AdminRepository) Create(ctx context.Context, user *model.User, adminRole string) error {
+	query := `
+		INSERT INTO users (username, password, email, full_name, role, balance, status, admin_role)
+		VALUES ($1, $2, $3, $4, 'admin', 0, 'active', $5)
+		RETURNING id, role, status, created_at, updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query, user.Username, user.Password, user.Email, user.FullName, adminRole).
+		Scan(&user.ID, &user.Role, &user.Status, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		if uErr := uniqueUserError(err); uErr != nil {
+			return uErr
+		}
+		return fmt.Errorf("failed to create admin: %w", err)
+	}
+	return nil
+}
+
+// Update changes the role, status, name and/or password hash of an admin.
+// Empty values are left unchanged. Sessions are revoked when the role, status
+// or password changes. Returns ErrLastOwner if no active owner would remain.
+func (r *AdminRepository) Update(ctx context.Context, id int, fullName, adminRole, status, passwordHash string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Serialize owner changes so two admins cannot demote each other at once
+	if _, err := tx.Exec(ctx, `SELECT id FROM users WHERE role = 'admin' AND admin_role = 'owner' FOR UPDATE`); err != nil {
+		return fmt.Errorf("failed to lock owners: %w", err)
+	}
+
+	var curRole, curStatus string
+	err = tx.QueryRow(ctx, `SELECT COALESCE(admin_role, ''), status FROM users WHERE id = $1 AND role = 'admin' FOR UPDATE`, id).
+		Scan(&curRole, &curStatus)
+	if err != nil {
+		return fmt.Errorf("failed to lock admin: %w", err)
+	}
+
+	newRole, newStatus := curRole, curStatus
+	if adminRole != "" {
+		newRole = adminRole
+	}
+	if status != "" {
+		newStatus = status
+	}
+
+	if curRole == model.AdminRoleOwner && curStatus == model.UserStatusActive &&
+		(newRole != model.AdminRoleOwner || newStatus != model.UserStatusActive) {
+		var owners int
+		err := tx.QueryRow(ctx, `
+			SELECT COUNT(*) FROM users
+			WHERE role = 'admin' AND admin_role = 'owner' AND status = 'active' AND id <> $1
+		`, id).Scan(&owners)
+		if err != nil {
+			return fmt.Errorf("failed to count owners: %w", err)
+		}
+		if owners == 0 {
+			return ErrLastOwner
+		}
+	}
+
+	query := `
+		UPDATE users
+		SET full_name = COALESCE(NULLIF($2, ''), full_name),
+		    admin_role = $3,
+		    status = $4,
+		    password = COALESCE(NULLIF($5, ''), password),
+		    updated_at = NOW()
+		WHERE id = $1
+	`
+	if _, err := tx.Exec(ctx, query, id, fullName, newRole, newStatus, passwordHash); err != nil {
+		return fmt.Errorf("failed to update admin: %w", err)
+	}
+
+	reason := ""
+	switch {
+	case newStatus != model.UserStatusActive && curStatus == model.UserStatusActive:
+		reason = model.SessionRevokeSuspended
+	case newRole != curRole:
+		reason = model.SessionRevokeRoleChange
+	case passwordHash != "":
+		reason = model.SessionRevokePasswordChange
+	}
+	if reason != "" {
+		if err := revokeUserSessionsTx(ctx, tx, id, reason); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"govershop-api/internal/service/envelope"
 )
 
 // AdminSecurity represents the admin security settings
@@ -28,29 +30,42 @@ type AdminAuditLog struct {
 	Success      bool
 	ErrorMessage string
 	CreatedAt    time.Time
+
+	AdminUserID   *int
+	AdminUsername *string
 }
 
 // AdminSecurityRepository handles admin security operations
 type AdminSecurityRepository struct {
 	db *pgxpool.Pool
+
+	keyring *envelope.Keyring // TOTP secrets are stored encrypted
 }
 
 // NewAdminSecurityRepository creates a new AdminSecurityRepository
-func NewAdminSecurityRepository(db *pgxpool.Pool) *AdminSecurityRepository {
-	return &AdminSecurityRepository{db: db}
+func NewAdminSecurityRepository(db *pgxpool.Pool, keyring *envelope.Keyring) *AdminSecurityRepository {
+	return &AdminSecurityRepository{db: db, keyring: keyring}
 }
 
-// GetPrimary gets the primary admin security settings
-func (r *AdminSecurityRepository) GetPrimary(ctx context.Context) (*AdminSecurity, error) {
+// GetByUserID gets the security settings of an admin, creating an empty row on first use
+func (r *AdminSecurityRepository) GetByUserID(ctx context.Context, userID int) (*AdminSecurity, error) {
+	insert := `
+		INSERT INTO admin_security (admin_identifier, user_id, totp_enabled)
+		VALUES ($1, $2, false)
+		ON CONFLICT DO NOTHING
+	`
+	if _, err := r.db.Exec(ctx, insert, fmt.Sprintf("user:%d", userID), userID); err != nil {
+		return nil, fmt.Errorf("failed to init admin security: %w", err)
+	}
+
 	query := `
-		SELECT id, COALESCE(totp_secret, ''), totp_enabled, admin_identifier, created_at, updated_at
+		SELECT id, COALESCE(totp_secret, ''), COALESCE(totp_enabled, false), admin_identifier, created_at, updated_at
 		FROM admin_security
-		WHERE admin_identifier = 'primary'
-		LIMIT 1
+		WHERE user_id = $1
 	`
 
 	var sec AdminSecurity
-	err := r.db.QueryRow(ctx, query).Scan(
+	err := r.db.QueryRow(ctx, query, userID).Scan(
 		&sec.ID,
 		&sec.TOTPSecret,
 		&sec.TOTPEnabled,
@@ -62,18 +77,27 @@ func (r *AdminSecurityRepository) GetPrimary(ctx context.Context) (*AdminSecurit
 		return nil, fmt.Errorf("failed to get admin security: %w", err)
 	}
 
+	if sec.TOTPSecret, err = r.keyring.Decrypt(sec.TOTPSecret); err != nil {
+		return nil, fmt.Errorf("failed to decrypt TOTP secret: %w", err)
+	}
+
 	return &sec, nil
 }
 
-// SetTOTPSecret sets the TOTP secret for primary admin
-func (r *AdminSecurityRepository) SetTOTPSecret(ctx context.Context, secret string) error {
+// SetTOTPSecret sets the TOTP secret of an admin
+func (r *AdminSecurityRepository) SetTOTPSecret(ctx context.Context, userID int, secret string) error {
 	query := `
 		UPDATE admin_security 
-		SET totp_secret = $1, updated_at = NOW()
-		WHERE admin_identifier = 'primary'
+		SET totp_secret = NULLIF($2, ''), updated_at = NOW()
+		WHERE user_id = $1
 	`
 
-	_, err := r.db.Exec(ctx, query, secret)
+	encrypted, err := r.keyring.Encrypt(secret)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt TOTP secret: %w", err)
+	}
+
+	_, err = r.db.Exec(ctx, query, userID, encrypted)
 	if err != nil {
 		return fmt.Errorf("failed to set TOTP secret: %w", err)
 	}
@@ -81,15 +105,15 @@ func (r *AdminSecurityRepository) SetTOTPSecret(ctx context.Context, secret stri
 	return nil
 }
 
-// EnableTOTP enables TOTP for primary admin
-func (r *AdminSecurityRepository) EnableTOTP(ctx context.Context, enabled bool) error {
+// EnableTOTP enables or disables TOTP of an admin
+func (r *AdminSecurityRepository) EnableTOTP(ctx context.Context, userID int, enabled bool) error {
 	query := `
 		UPDATE admin_security 
-		SET totp_enabled = $1, updated_at = NOW()
-		WHERE admin_identifier = 'primary'
+		SET totp_enabled = $2, updated_at = NOW()
+		WHERE user_id = $1
 	`
 
-	_, err := r.db.Exec(ctx, query, enabled)
+	_, err := r.db.Exec(ctx, query, userID, enabled)
 	if err != nil {
 		return fmt.Errorf("failed to enable TOTP: %w", err)
 	}
@@ -97,41 +121,128 @@ func (r *AdminSecurityRepository) EnableTOTP(ctx context.Context, enabled bool)
 	return nil
 }
 
-// CreateAuditLog creates an audit log entry
-func (r *AdminSecurityRepository) CreateAuditLog(ctx context.Context, action, orderID, ipAddress string, details map[string]interface{}, success bool, errMsg string) error {
+// CountRecentManualTopups counts manual topups in the last hour for rate limiting
+func (r *AdminSecurityRepository) CountRecentManualTopups(ctx context.Context) (int, error) {
 	query := `
-		INSERT INTO admin_audit_logs (action, order_id, details, ip_address, success, error_message)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		SELECT COUNT(*) 
+		FROM admin_audit_logs 
+		WHERE action = 'order.manual_topup'
+		AND success = true
+		AND created_at > NOW() - INTERVAL '1 hour'
 	`
 
-	var orderIDPtr *string
-	if orderID != "" {
-		orderIDPtr = &orderID
+	var count int
+	err := r.db.QueryRow(ctx, query).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count manual topups: %w", err)
 	}
 
-	_, err := r.db.Exec(ctx, query, action, orderIDPtr, details, ipAddress, success, errMsg)
+	return count, nil
+}
+
+// ReplaceRecoveryCodes swaps an admin's TOTP recovery codes for new ones (SHA-256 hashes).
+// Passing no hashes deletes all codes.
+func (r *AdminSecurityRepository) ReplaceRecoveryCodes(ctx context.Context, userID int, hashes []string) error {
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to create audit log: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback(ctx)
 
-	return nil
+	if _, err := tx.Exec(ctx, `DELETE FROM admin_totp_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	for _, hash := range hashes {
+		_, err := tx.Exec(ctx, `INSERT INTO admin_totp_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hash)
+		if err != nil {
+			return fmt.Errorf("failed to insert recovery code: %w", err)
+		}
+	}
+
+	return tx.Commit(ctx)
 }
 
-// CountRecentManualTopups counts manual topups in the last hour for rate limiting
-func (r *AdminSecurityRepository) CountRecentManualTopups(ctx context.Context) (int, error) {
+// UseRecoveryCode consumes an unused recovery code. Returns false if it does not match.
+func (r *AdminSecurityRepository) UseRecoveryCode(ctx context.Context, userID int, hash string) (bool, error) {
 	query := `
-		SELECT COUNT(*) 
-		FROM admin_audit_logs 
-		WHERE action = 'manual_topup' 
-		AND success = true
-		AND created_at > NOW() - INTERVAL '1 hour'
+		UPDATE admin_totp_recovery_codes
+		SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
 	`
 
-	var count int
-	err := r.db.QueryRow(ctx, query).Scan(&count)
+	result, err := r.db.Exec(ctx, query, userID, hash)
 	if err != nil {
-		return 0, fmt.Errorf("failed to count manual topups: %w", err)
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
 	}
+	return result.RowsAffected() > 0, nil
+}
 
+// CountRecoveryCodes counts an admin's unused recovery codes
+func (r *AdminSecurityRepository) CountRecoveryCodes(ctx context.Context, userID int) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM admin_totp_recovery_codes WHERE user_id = $1 AND used_at IS NULL`, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
 	return count, nil
 }
+
+// CreateTOTPReset stores a break-glass reset token (SHA-256 hash) valid for ttl.
+// Earlier unused tokens of the admin are invalidated.
+func (r *AdminSecurityRepository) CreateTOTPReset(ctx context.Context, userID int, tokenHash, ip string, ttl time.Duration) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `UPDATE admin_totp_resets SET expires_at = NOW() WHERE user_id = $1 AND used_at IS NULL AND expires_at > NOW()`, userID); err != nil {
+		return fmt.Errorf("failed to invalidate TOTP resets: %w", err)
+	}
+
+	query := `
+		INSERT INTO admin_totp_resets (user_id, token_hash, requested_ip, expires_at)
+		VALUES ($1, $2, $3, NOW() + make_interval(secs => $4))
+	`
+	if _, err := tx.Exec(ctx, query, userID, tokenHash, ip, ttl.Seconds()); err != nil {
+		return fmt.Errorf("failed to create TOTP reset: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ResetTOTP consumes a break-glass token and turns off the admin's TOTP,
+// clearing the secret and recovery codes. Returns false if the token is
+// unknown, expired, used or belongs to another admin.
+func (r *AdminSecurityRepository) ResetTOTP(ctx context.Context, userID int, tokenHash string) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `
+		UPDATE admin_totp_resets
+		SET used_at = NOW()
+		WHERE token_hash = $1 AND user_id = $2 AND used_at IS NULL AND expires_at > NOW()
+	`, tokenHash, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to use TOTP reset: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE admin_security SET totp_enabled = false, totp_secret = NULL, updated_at = NOW() WHERE user_id = $1`, userID); err != nil {
+		return false, fmt.Errorf("failed to reset TOTP: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM admin_totp_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return false, fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit TOTP reset: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"govershop-api/internal/model"
+	"govershop-api/internal/service/envelope"
+)
+
+// APIKeyRepository handles database operations for member H2H API keys
+type APIKeyRepository struct {
+	db *pgxpool.Pool
+
+	keyring *envelope.Keyring // API secrets are stored encrypted
+}
+
+// NewAPIKeyRepository creates a new APIKeyRepository
+func NewAPIKeyRepository(db *pgxpool.Pool, keyring *envelope.Keyring) *APIKeyRepository {
+	return &APIKeyRepository{db: db, keyring: keyring}
+}
+
+const apiKeyColumns = `
+	id, user_id, api_key, api_secret, COALESCE(ip_allowlist, '{}'), COALESCE(is_active, false),
+	last_used_at, last_used_ip, created_at, updated_at
+`
+
+func (r *APIKeyRepository) scanAPIKey(row pgx.Row) (*model.MemberAPIKey, error) {
+	var k model.MemberAPIKey
+	err := row.Scan(
+		&k.ID, &k.UserID, &k.APIKey, &k.APISecret, &k.IPAllowlist, &k.IsActive,
+		&k.LastUsedAt, &k.LastUsedIP, &k.CreatedAt, &k.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if k.APISecret, err = r.keyring.Decrypt(k.APISecret); err != nil {
+		return nil, fmt.Errorf("failed to decrypt api secret: %w", err)
+	}
+	return &k, nil
+}
+
+// GetByUserID retrieves the API key of a member (nil if none)
+func (r *APIKeyRepository) GetByUserID(ctx context.Context, userID int) (*model.MemberAPIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM member_api_keys WHERE user_id = $1`
+
+	k, err := r.scanAPIKey(r.db.QueryRow(ctx, query, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	return k, nil
+}
+
+// GetByKey retrieves an API key by its public key (nil if none)
+func (r *APIKeyRepository) GetByKey(ctx context.Context, apiKey string) (*model.MemberAPIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM member_api_keys WHERE api_key = $1`
+
+	k, err := r.scanAPIKey(r.db.QueryRow(ctx, query, apiKey))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	return k, nil
+}
+
+// Upsert creates the member's API key or replaces it with new credentials (regenerate)
+func (r *APIKeyRepository) Upsert(ctx context.Context, userID int, apiKey, apiSecret string) (*model.MemberAPIKey, error) {
+	query := `
+		INSERT INTO member_api_keys (user_id, api_key, api_secret, is_active)
+		VALUES ($1, $2, $3, true)
+		ON CONFLICT (user_id) DO UPDATE SET
+			api_key = EXCLUDED.api_key,
+			api_secret = EXCLUDED.api_secret,
+			is_active = true,
+			updated_at = NOW()
+		RETURNING ` + apiKeyColumns
+
+	encrypted, err := r.keyring.Encrypt(apiSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt api secret: %w", err)
+	}
+
+	k, err := r.scanAPIKey(r.db.QueryRow(ctx, query, userID, apiKey, encrypted))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save api key: %w", err)
+	}
+
+	return k, nil
+}
+
+// UpdateSettings updates the IP allowlist and active flag of a member's API key
+func (r *APIKeyRepository) UpdateSettings(ctx context.Context, userID int, ipAllowlist []string, isActive bool) error {
+	query := `
+		UPDATE member_api_keys
+		SET ip_allowlist = $2, is_active = $3, updated_at = NOW()
+		WHERE user_id = $1
+	`
+
+	result, err := r.db.Exec(ctx, query, userID, ipAllowlist, isActive)
+	if err != nil {
+		return fmt.Errorf("failed to update api key: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("api key not found")
+	}
+
+	return nil
+}
+
+// TouchLastUsed records when and from where a key was last used
+func (r *APIKeyRepository) TouchLastUsed(ctx context.Context, id int, ip string) error {
+	_, err := r.db.Exec(ctx, `UPDATE member_api_keys SET last_used_at = NOW(), last_used_ip = $2 WHERE id = $1`, id, ip)
+	if err != nil {
+		return fmt.Errorf("failed to update api key usage: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"govershop-api/internal/model"
+)
+
+// AuditRepository handles the admin audit trail
+type AuditRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewAuditRepository creates a new AuditRepository
+func NewAuditRepository(db *pgxpool.Pool) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// auditSnapshots loads the current state of an entity as JSON.
+// Queries with $1 take the entity ID; entities not listed are not snapshotted.
+var auditSnapshots = map[string]string{
+	model.AuditEntityAdmin:        `SELECT row_to_json(t) FROM users t WHERE id = $1::text::int AND role = 'admin'`,
+	model.AuditEntityMember:       `SELECT row_to_json(t) FROM users t WHERE id = $1::text::int AND role = 'member'`,
+	model.AuditEntityMemberLimit:  `SELECT row_to_json(t) FROM member_limits t WHERE user_id = $1::text::int`,
+	model.AuditEntityMemberLevel:  `SELECT row_to_json(t) FROM member_levels t WHERE level = $1`,
+	model.AuditEntityOrder:        `SELECT row_to_json(t) FROM orders t WHERE id = $1::text::uuid`,
+	model.AuditEntityProduct:      `SELECT row_to_json(t) FROM products t WHERE buyer_sku_code = $1`,
+	model.AuditEntityContent:      `SELECT row_to_json(t) FROM homepage_content t WHERE id = $1::text::int`,
+	model.AuditEntityPromo:        `SELECT row_to_json(t) FROM promos t WHERE id = $1::text::int`,
+	model.AuditEntityFlashSale:    `SELECT row_to_json(t) FROM flash_sales t WHERE id = $1::text::int`,
+	model.AuditEntityPointRule:    `SELECT row_to_json(t) FROM point_rules t WHERE id = $1::text::int`,
+	model.AuditEntityBrand:        `SELECT row_to_json(t) FROM brand_settings t WHERE brand_name = $1`,
+	model.AuditEntitySetting:      `SELECT json_object_agg(key, value) FROM app_settings`,
+	model.AuditEntityLoginLockout: `SELECT row_to_json(t) FROM login_lockouts t WHERE user_id = $1::text::int`,
+}
+
+// Snapshot returns the current state of an entity with secrets redacted
+// (nil if the entity type is not snapshotted or the row does not exist)
+func (r *AuditRepository) Snapshot(ctx context.Context, entityType, entityID string) (map[string]interface{}, error) {
+	query, ok := auditSnapshots[entityType]
+	if !ok {
+		return nil, nil
+	}
+
+	var args []interface{}
+	if strings.Contains(query, "$1") {
+		if entityID == "" {
+			return nil, nil
+		}
+		args = append(args, entityID)
+	}
+
+	var raw []byte
+	err := r.db.QueryRow(ctx, query, args...).Scan(&raw)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && raw == nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot %s: %w", entityType, err)
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("failed to decode %s snapshot: %w", entityType, err)
+	}
+	for k := range data {
+		if auditSensitiveField(k) {
+			data[k] = "[redacted]"
+		}
+	}
+	return data, nil
+}
+
+// auditSensitiveField reports whether a column holds a credential
+func auditSensitiveField(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range []string{"password", "secret", "token", "hash"} {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Create stores an audit trail entry
+func (r *AuditRepository) Create(ctx context.Context, l *model.AuditLog) error {
+	query := `
+		INSERT INTO admin_audit_logs (
+			action, admin_user_id, admin_username, entity_type, entity_id, before_data, after_data, diff,
+			method, path, status_code, ip_address, user_agent, success, error_message, details
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		RETURNING id, created_at
+	`
+
+	var diff, details interface{}
+	if len(l.Diff) > 0 {
+		diff = l.Diff
+	}
+	if len(l.Details) > 0 {
+		details = l.Details
+	}
+
+	err := r.db.QueryRow(ctx, query,
+		l.Action, l.AdminUserID, l.AdminUsername, l.EntityType, l.EntityID,
+		nullJSON(l.Before), nullJSON(l.After), diff,
+		l.Method, l.Path, l.StatusCode, l.IPAddress, l.UserAgent, l.Success, l.ErrorMessage, details,
+	).Scan(&l.ID, &l.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create audit log: %w", err)
+	}
+	return nil
+}
+
+// nullJSON maps an empty raw message to SQL NULL
+func nullJSON(raw json.RawMessage) interface{} {
+	if len(raw) == 0 {
+		return nil
+	}
+	return string(raw)
+}
+
+// List returns audit trail entries, newest first, and the total count
+func (r *AuditRepository) List(ctx context.Context, f model.AuditLogFilter) ([]model.AuditLog, int, error) {
+	whereClause := "WHERE 1=1"
+	args := []interface{}{}
+	paramIdx := 1
+
+	if f.Actor != "" {
+		whereClause += fmt.Sprintf(" AND admin_username = $%d", paramIdx)
+		args = append(args, f.Actor)
+		paramIdx++
+	}
+	if f.Action != "" {
+		if strings.HasSuffix(f.Action, ".") {
+			whereClause += fmt.Sprintf(" AND action LIKE $%d || '%%'", paramIdx)
+		} else {
+			whereClause += fmt.Sprintf(" AND action = $%d", paramIdx)
+		}
+		args = append(args, f.Action)
+		paramIdx++
+	}
+	if f.EntityType != "" {
+		whereClause += fmt.Sprintf(" AND entity_type = $%d", paramIdx)
+		args = append(args, f.EntityType)
+		paramIdx++
+	}
+	if f.EntityID != "" {
+		whereClause += fmt.Sprintf(" AND entity_id = $%d", paramIdx)
+		args = append(args, f.EntityID)
+		paramIdx++
+	}
+	if f.DateFrom != "" {
+		whereClause += fmt.Sprintf(" AND created_at >= $%d::date", paramIdx)
+		args = append(args, f.DateFrom)
+		paramIdx++
+	}
+	if f.DateTo != "" {
+		whereClause += fmt.Sprintf(" AND created_at < ($%d::date + interval '1 day')", paramIdx)
+		args = append(args, f.DateTo)
+		paramIdx++
+	}
+
+	var total int
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM admin_audit_logs %s", whereClause)
+	if err := r.db.QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit logs: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, action, admin_user_id, admin_username, entity_type, entity_id, order_id::text,
+			before_data, after_data, diff, details, method, path, status_code, ip_address, user_agent,
+			COALESCE(success, false), NULLIF(error_message, ''), created_at
+		FROM admin_audit_logs %s
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d OFFSET $%d
+	`, whereClause, paramIdx, paramIdx+1)
+	args = append(args, f.Limit, f.Offset)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get audit logs: %w", err)
+	}
+	defer rows.Close()
+
+	var logs []model.AuditLog
+	for rows.Next() {
+		var l model.AuditLog
+		var before, after, diff []byte
+		err := rows.Scan(
+			&l.ID, &l.Action, &l.AdminUserID, &l.AdminUsername, &l.EntityType, &l.EntityID, &l.OrderID,
+			&before, &after, &diff, &l.Details, &l.Method, &l.Path, &l.StatusCode, &l.IPAddress, &l.UserAgent,
+			&l.Success, &l.ErrorMessage, &l.CreatedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan audit log: %w", err)
+		}
+		l.Before = before
+		l.After = after
+		if diff != nil {
+			if err := json.Unmarshal(diff, &l.Diff); err != nil {
+				return nil, 0, fmt.Errorf("failed to decode audit diff: %w", err)
+			}
+		}
+		logs = append(logs, l)
+	}
+
+	return logs, total, nil
+}
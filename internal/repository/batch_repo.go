@@ -0,0 +1,482 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"govershop-api/internal/model"
+)
+
+// BatchRepository handles member bulk orders
+type BatchRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewBatchRepository creates a new BatchRepository
+func NewBatchRepository(db *pgxpool.Pool) *BatchRepository {
+	return &BatchRepository{db: db}
+}
+
+// Create stores a batch with its lines and reserves the batch total from balance.
+// Returns ErrInsufficientBalance if the member can't cover the whole batch.
+func (r *BatchRepository) Create(ctx context.Context, b *model.OrderBatch, lines []model.OrderBatchLine, createdBy string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	b.Status = model.BatchStatusProcessing
+	b.TotalLines = len(lines)
+	b.ReservedAmount = 0
+	for _, l := range lines {
+		b.ReservedAmount += l.Price
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO member_order_batches (ref_id, user_id, status, total_lines, reserved_amount)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, b.RefID, b.UserID, b.Status, b.TotalLines, b.ReservedAmount).Scan(&b.ID, &b.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create batch: %w", err)
+	}
+
+	for _, l := range lines {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO member_order_batch_lines (batch_id, line_no, buyer_sku_code, destination_number, client_ref_id, price)
+			VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)
+		`, b.ID, l.LineNo, l.BuyerSKUCode, l.DestinationNumber, l.ClientRefID, l.Price)
+		if err != nil {
+			return fmt.Errorf("failed to create batch line: %w", err)
+		}
+	}
+
+	_, err = postLedgerTx(ctx, tx, model.LedgerEntry{
+		UserID:      b.UserID,
+		Amount:      b.ReservedAmount,
+		Type:        model.DepositTypeBatchReserve,
+		Description: fmt.Sprintf("Reservasi saldo batch %s (%d order)", b.RefID, b.TotalLines),
+		ReferenceID: b.RefID,
+		CreatedBy:   createdBy,
+	})
+	if err != nil {
+		return err
+	}
+
+	b.PendingLines = b.TotalLines
+	return tx.Commit(ctx)
+}
+
+const batchLineColumns = `
+	l.id, l.batch_id, l.line_no, l.buyer_sku_code, l.destination_number, COALESCE(l.client_ref_id, ''),
+	l.price, l.status, l.order_ref, COALESCE(l.error_code, ''), COALESCE(l.error_message, ''), l.processed_at
+`
+
+func scanBatchLine(row pgx.Row, extra ...interface{}) (*model.OrderBatchLine, error) {
+	var l model.OrderBatchLine
+	dest := []interface{}{
+		&l.ID, &l.BatchID, &l.LineNo, &l.BuyerSKUCode, &l.DestinationNumber, &l.ClientRefID,
+		&l.Price, &l.Status, &l.OrderRef, &l.ErrorCode, &l.ErrorMessage, &l.ProcessedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// ClaimLines locks up to limit pending lines (or lines whose worker died before
+// charging) for 5 minutes
+func (r *BatchRepository) ClaimLines(ctx context.Context, limit int) ([]model.OrderBatchLine, error) {
+	query := `
+		WITH due AS (
+			SELECT id FROM member_order_batch_lines
+			WHERE status = 'pending'
+				OR (status = 'processing' AND locked_until < NOW() AND order_ref IS NULL)
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE member_order_batch_lines l
+		SET status = 'processing', locked_until = NOW() + INTERVAL '5 minutes', attempts = l.attempts + 1
+		FROM due, member_order_batches b
+		WHERE l.id = due.id AND b.id = l.batch_id
+		RETURNING ` + batchLineColumns + `, b.user_id, b.ref_id
+	`
+
+	rows, err := r.db.Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim batch lines: %w", err)
+	}
+	defer rows.Close()
+
+	var lines []model.OrderBatchLine
+	for rows.Next() {
+		var userID int
+		var batchRef string
+		l, err := scanBatchLine(rows, &userID, &batchRef)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan batch line: %w", err)
+		}
+		l.UserID = userID
+		l.BatchRefID = batchRef
+		lines = append(lines, *l)
+	}
+
+	return lines, rows.Err()
+}
+
+// ClaimInterrupted locks lines whose worker died after the line was charged.
+// OrderStatus is set when the line's order was created.
+func (r *BatchRepository) ClaimInterrupted(ctx context.Context) ([]model.OrderBatchLine, error) {
+	query := `
+		UPDATE member_order_batch_lines l
+		SET locked_until = NOW() + INTERVAL '5 minutes'
+		FROM member_order_batches b
+		WHERE b.id = l.batch_id AND l.status = 'processing'
+			AND l.locked_until < NOW() AND l.order_ref IS NOT NULL
+		RETURNING ` + batchLineColumns + `, b.user_id, b.ref_id,
+			(SELECT o.status FROM orders o WHERE o.ref_id = l.order_ref)
+	`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim interrupted batch lines: %w", err)
+	}
+	defer rows.Close()
+
+	var lines []model.OrderBatchLine
+	for rows.Next() {
+		var userID int
+		var batchRef string
+		var orderStatus *string
+		l, err := scanBatchLine(rows, &userID, &batchRef, &orderStatus)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan batch line: %w", err)
+		}
+		l.UserID = userID
+		l.BatchRefID = batchRef
+		l.OrderStatus = orderStatus
+		lines = append(lines, *l)
+	}
+
+	return lines, rows.Err()
+}
+
+// PayLine charges one line's order: in a single transaction the member's spending limits
+// are checked, the line's reserved amount is released and the order amount is debited,
+// and the order ref is recorded on the line
+func (r *BatchRepository) PayLine(ctx context.Context, line model.OrderBatchLine, amount float64, brand, description, orderRef string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := checkSpendingLimitsTx(ctx, tx, line.UserID, amount, brand); err != nil {
+		return err
+	}
+
+	_, err = postLedgerTx(ctx, tx, model.LedgerEntry{
+		UserID:      line.UserID,
+		Amount:      line.Price,
+		Type:        model.DepositTypeBatchRelease,
+		Description: fmt.Sprintf("Pelepasan reservasi batch %s baris %d", line.BatchRefID, line.LineNo),
+		ReferenceID: line.BatchRefID,
+		LedgerRef:   fmt.Sprintf("%s:%s:%d", model.DepositTypeBatchRelease, line.BatchRefID, line.LineNo),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = postLedgerTx(ctx, tx, model.LedgerEntry{
+		UserID:      line.UserID,
+		Amount:      amount,
+		Type:        model.DepositTypeDebit,
+		Description: description,
+		ReferenceID: orderRef,
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE member_order_batch_lines SET order_ref = $2 WHERE id = $1`, line.ID, orderRef); err != nil {
+		return fmt.Errorf("failed to update batch line: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// MarkSubmitted marks a line whose order was created
+func (r *BatchRepository) MarkSubmitted(ctx context.Context, lineID int, orderRef string) error {
+	query := `
+		UPDATE member_order_batch_lines
+		SET status = 'submitted', order_ref = $2, locked_until = NULL, processed_at = NOW()
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Exec(ctx, query, lineID, orderRef); err != nil {
+		return fmt.Errorf("failed to mark batch line submitted: %w", err)
+	}
+	return nil
+}
+
+// MarkFailed marks a line that could not be placed
+func (r *BatchRepository) MarkFailed(ctx context.Context, lineID int, code, message string) error {
+	query := `
+		UPDATE member_order_batch_lines
+		SET status = 'failed', error_code = $2, error_message = $3, locked_until = NULL, processed_at = NOW()
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Exec(ctx, query, lineID, code, message); err != nil {
+		return fmt.Errorf("failed to mark batch line failed: %w", err)
+	}
+	return nil
+}
+
+// CompleteReady completes every processing batch with no open lines, releasing the
+// part of the reservation no line used back to the member. Returns the completed batches.
+func (r *BatchRepository) CompleteReady(ctx context.Context) ([]model.OrderBatch, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT b.id FROM member_order_batches b
+		WHERE b.status = 'processing'
+			AND NOT EXISTS (
+				SELECT 1 FROM member_order_batch_lines l
+				WHERE l.batch_id = b.id AND l.status IN ('pending', 'processing')
+			)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ready batches: %w", err)
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan batch: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	var completed []model.OrderBatch
+	for _, id := range ids {
+		b, err := r.complete(ctx, id)
+		if err != nil {
+			return completed, err
+		}
+		if b != nil {
+			completed = append(completed, *b)
+		}
+	}
+	return completed, nil
+}
+
+func (r *BatchRepository) complete(ctx context.Context, batchID int) (*model.OrderBatch, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var b model.OrderBatch
+	err = tx.QueryRow(ctx, `
+		SELECT id, ref_id, user_id, reserved_amount FROM member_order_batches
+		WHERE id = $1 AND status = 'processing'
+			AND NOT EXISTS (
+				SELECT 1 FROM member_order_batch_lines
+				WHERE batch_id = $1 AND status IN ('pending', 'processing')
+			)
+		FOR UPDATE
+	`, batchID).Scan(&b.ID, &b.RefID, &b.UserID, &b.ReservedAmount)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batch: %w", err)
+	}
+
+	var released float64
+	err = tx.QueryRow(ctx, `
+		SELECT COALESCE(SUM(amount), 0) FROM deposits
+		WHERE user_id = $1 AND type = $2 AND reference_id = $3
+	`, b.UserID, model.DepositTypeBatchRelease, b.RefID).Scan(&released)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum released amount: %w", err)
+	}
+
+	b.RefundedAmount = b.ReservedAmount - released
+	if b.RefundedAmount > 0 {
+		_, err = postLedgerTx(ctx, tx, model.LedgerEntry{
+			UserID:      b.UserID,
+			Amount:      b.RefundedAmount,
+			Type:        model.DepositTypeBatchRelease,
+			Description: fmt.Sprintf("Pengembalian sisa reservasi batch %s", b.RefID),
+			ReferenceID: b.RefID,
+			LedgerRef:   fmt.Sprintf("%s:%s:final", model.DepositTypeBatchRelease, b.RefID),
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		b.RefundedAmount = 0
+	}
+
+	b.Status = model.BatchStatusCompleted
+	_, err = tx.Exec(ctx, `
+		UPDATE member_order_batches
+		SET status = 'completed', refunded_amount = $2, completed_at = NOW()
+		WHERE id = $1
+	`, b.ID, b.RefundedAmount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete batch: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return &b, nil
+}
+
+const batchColumns = `
+	b.id, b.ref_id, b.user_id, b.status, b.total_lines, b.reserved_amount, b.refunded_amount,
+	b.created_at, b.completed_at,
+	(SELECT COUNT(*) FROM member_order_batch_lines l WHERE l.batch_id = b.id AND l.status IN ('pending', 'processing')),
+	(SELECT COUNT(*) FROM member_order_batch_lines l WHERE l.batch_id = b.id AND l.status = 'submitted'),
+	(SELECT COUNT(*) FROM member_order_batch_lines l WHERE l.batch_id = b.id AND l.status = 'failed')
+`
+
+func scanBatch(row pgx.Row) (*model.OrderBatch, error) {
+	var b model.OrderBatch
+	err := row.Scan(
+		&b.ID, &b.RefID, &b.UserID, &b.Status, &b.TotalLines, &b.ReservedAmount, &b.RefundedAmount,
+		&b.CreatedAt, &b.CompletedAt,
+		&b.PendingLines, &b.SubmittedLines, &b.FailedLines,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// GetByRefID gets a member's batch by its ref ID (nil if not found)
+func (r *BatchRepository) GetByRefID(ctx context.Context, userID int, refID string) (*model.OrderBatch, error) {
+	query := `SELECT ` + batchColumns + ` FROM member_order_batches b WHERE b.user_id = $1 AND b.ref_id = $2`
+
+	b, err := scanBatch(r.db.QueryRow(ctx, query, userID, refID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batch: %w", err)
+	}
+	return b, nil
+}
+
+// ListByUser returns a member's batches, newest first
+func (r *BatchRepository) ListByUser(ctx context.Context, userID, limit, offset int) ([]model.OrderBatch, int, error) {
+	var total int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM member_order_batches WHERE user_id = $1`, userID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count batches: %w", err)
+	}
+
+	query := `
+		SELECT ` + batchColumns + `
+		FROM member_order_batches b
+		WHERE b.user_id = $1
+		ORDER BY b.created_at DESC, b.id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.Query(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get batches: %w", err)
+	}
+	defer rows.Close()
+
+	var batches []model.OrderBatch
+	for rows.Next() {
+		b, err := scanBatch(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan batch: %w", err)
+		}
+		batches = append(batches, *b)
+	}
+
+	return batches, total, nil
+}
+
+// GetLines returns the lines of a batch with the status of their orders
+func (r *BatchRepository) GetLines(ctx context.Context, batchID int) ([]model.OrderBatchLine, error) {
+	query := `
+		SELECT ` + batchLineColumns + `, o.status, COALESCE(o.serial_number, '')
+		FROM member_order_batch_lines l
+		LEFT JOIN orders o ON o.ref_id = l.order_ref
+		WHERE l.batch_id = $1
+		ORDER BY l.line_no
+	`
+
+	rows, err := r.db.Query(ctx, query, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batch lines: %w", err)
+	}
+	defer rows.Close()
+
+	var lines []model.OrderBatchLine
+	for rows.Next() {
+		var orderStatus *string
+		var sn string
+		l, err := scanBatchLine(rows, &orderStatus, &sn)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan batch line: %w", err)
+		}
+		l.OrderStatus = orderStatus
+		l.SerialNumber = sn
+		lines = append(lines, *l)
+	}
+
+	return lines, nil
+}
+
+// RefundLine refunds the order debit of a charged line whose order was never created.
+// Returns ErrDuplicateLedgerEntry if the order was already refunded.
+func (r *BatchRepository) RefundLine(ctx context.Context, line model.OrderBatchLine) error {
+	if line.OrderRef == nil {
+		return nil
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var amount float64
+	err = tx.QueryRow(ctx, `
+		SELECT amount FROM deposits WHERE user_id = $1 AND type = $2 AND reference_id = $3
+	`, line.UserID, model.DepositTypeDebit, *line.OrderRef).Scan(&amount)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get line debit: %w", err)
+	}
+
+	_, err = postLedgerTx(ctx, tx, model.LedgerEntry{
+		UserID:      line.UserID,
+		Amount:      amount,
+		Type:        model.DepositTypeRefund,
+		Description: fmt.Sprintf("Refund Gagal System %s", *line.OrderRef),
+		ReferenceID: *line.OrderRef,
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
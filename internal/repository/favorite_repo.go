@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"govershop-api/internal/model"
+)
+
+// ErrDuplicateFavorite is returned when a member already saved the same brand and number
+var ErrDuplicateFavorite = errors.New("favorite already exists")
+
+// FavoriteRepository handles member saved destination numbers
+type FavoriteRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewFavoriteRepository creates a new FavoriteRepository
+func NewFavoriteRepository(db *pgxpool.Pool) *FavoriteRepository {
+	return &FavoriteRepository{db: db}
+}
+
+const favoriteColumns = `
+	id, user_id, label, brand, customer_no, COALESCE(account_name, ''), use_count, last_used_at, created_at, updated_at
+`
+
+func scanFavorite(row pgx.Row) (*model.MemberFavorite, error) {
+	var f model.MemberFavorite
+	err := row.Scan(
+		&f.ID, &f.UserID, &f.Label, &f.Brand, &f.CustomerNo, &f.AccountName,
+		&f.UseCount, &f.LastUsedAt, &f.CreatedAt, &f.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func favoriteWriteError(err error, action string) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "idx_member_favorites_unique" {
+		return ErrDuplicateFavorite
+	}
+	return fmt.Errorf("failed to %s favorite: %w", action, err)
+}
+
+// Create saves a new favorite
+func (r *FavoriteRepository) Create(ctx context.Context, f *model.MemberFavorite) error {
+	query := `
+		INSERT INTO member_favorites (user_id, label, brand, customer_no, account_name)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+		RETURNING ` + favoriteColumns
+
+	created, err := scanFavorite(r.db.QueryRow(ctx, query, f.UserID, f.Label, f.Brand, f.CustomerNo, f.AccountName))
+	if err != nil {
+		return favoriteWriteError(err, "create")
+	}
+	*f = *created
+	return nil
+}
+
+// Update changes a member's favorite. Returns nil when it does not exist.
+func (r *FavoriteRepository) Update(ctx context.Context, f *model.MemberFavorite) (*model.MemberFavorite, error) {
+	query := `
+		UPDATE member_favorites
+		SET label = $3, brand = $4, customer_no = $5, account_name = NULLIF($6, ''), updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+		RETURNING ` + favoriteColumns
+
+	updated, err := scanFavorite(r.db.QueryRow(ctx, query, f.ID, f.UserID, f.Label, f.Brand, f.CustomerNo, f.AccountName))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, favoriteWriteError(err, "update")
+	}
+	return updated, nil
+}
+
+// Delete removes a member's favorite. Returns false when it does not exist.
+func (r *FavoriteRepository) Delete(ctx context.Context, userID, id int) (bool, error) {
+	result, err := r.db.Exec(ctx, `DELETE FROM member_favorites WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete favorite: %w", err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// GetByID returns a member's favorite (nil if not found)
+func (r *FavoriteRepository) GetByID(ctx context.Context, userID, id int) (*model.MemberFavorite, error) {
+	query := `SELECT ` + favoriteColumns + ` FROM member_favorites WHERE id = $1 AND user_id = $2`
+
+	f, err := scanFavorite(r.db.QueryRow(ctx, query, id, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get favorite: %w", err)
+	}
+	return f, nil
+}
+
+// favoriteSorts maps the sort query parameter to an ORDER BY clause
+var favoriteSorts = map[string]string{
+	"recent":  "last_used_at DESC NULLS LAST, created_at DESC",
+	"popular": "use_count DESC, last_used_at DESC NULLS LAST",
+	"label":   "LOWER(label), id",
+	"created": "created_at DESC",
+}
+
+// List returns a member's favorites, optionally filtered by brand.
+// sort is recent (default), popular, label or created.
+func (r *FavoriteRepository) List(ctx context.Context, userID int, brand, sort string) ([]model.MemberFavorite, error) {
+	orderBy, ok := favoriteSorts[sort]
+	if !ok {
+		orderBy = favoriteSorts["recent"]
+	}
+
+	query := `
+		SELECT ` + favoriteColumns + `
+		FROM member_favorites
+		WHERE user_id = $1 AND ($2 = '' OR LOWER(REPLACE(brand, ' ', '')) = LOWER(REPLACE($2, ' ', '')))
+		ORDER BY ` + orderBy
+
+	rows, err := r.db.Query(ctx, query, userID, brand)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get favorites: %w", err)
+	}
+	defer rows.Close()
+
+	var favorites []model.MemberFavorite
+	for rows.Next() {
+		f, err := scanFavorite(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan favorite: %w", err)
+		}
+		favorites = append(favorites, *f)
+	}
+
+	return favorites, nil
+}
+
+// MarkUsed bumps the usage of a favorite after an order
+func (r *FavoriteRepository) MarkUsed(ctx context.Context, userID, id int) error {
+	query := `
+		UPDATE member_favorites
+		SET use_count = use_count + 1, last_used_at = NOW()
+		WHERE id = $1 AND user_id = $2
+	`
+
+	if _, err := r.db.Exec(ctx, query, id, userID); err != nil {
+		return fmt.Errorf("failed to mark favorite used: %w", err)
+	}
+	return nil
+}
+
+// SetAccountName stores the validated account name on a member's favorites for brand and customerNo
+func (r *FavoriteRepository) SetAccountName(ctx context.Context, userID int, brand, customerNo, accountName string) error {
+	query := `
+		UPDATE member_favorites
+		SET account_name = $4, updated_at = NOW()
+		WHERE user_id = $1 AND LOWER(REPLACE(brand, ' ', '')) = LOWER(REPLACE($2, ' ', '')) AND customer_no = $3
+	`
+
+	if _, err := r.db.Exec(ctx, query, userID, brand, customerNo, accountName); err != nil {
+		return fmt.Errorf("failed to set favorite account name: %w", err)
+	}
+	return nil
+}
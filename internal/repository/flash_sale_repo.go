@@ -0,0 +1,550 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"govershop-api/internal/model"
+)
+
+// ErrFlashSaleSoldOut is returned when the flash sale quota for a SKU is used up
+var ErrFlashSaleSoldOut = errors.New("flash sale sold out")
+
+// flashSaleSoldFilter counts orders (alias o) that hold flash sale quota.
+// Cancelled/expired/failed/refunded orders release their quota automatically, and
+// unpaid orders stop holding it once their payment expires, before the sweep
+// marks them expired.
+const flashSaleSoldFilter = `(o.status IN ('paid', 'processing', 'success')
+	OR (o.status IN ('pending', 'waiting_payment')
+	    AND NOT EXISTS (SELECT 1 FROM payments fp WHERE fp.order_id = o.id AND fp.status <> 'completed'
+	                    AND fp.expired_at < NOW() - ` + paymentExpiryGrace + `)))`
+
+// FlashSaleRepository handles database operations for flash sales
+type FlashSaleRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewFlashSaleRepository creates a new FlashSaleRepository
+func NewFlashSaleRepository(db *pgxpool.Pool) *FlashSaleRepository {
+	return &FlashSaleRepository{db: db}
+}
+
+// GetAll retrieves flash sales, optionally filtered by status
+func (r *FlashSaleRepository) GetAll(ctx context.Context, status string) ([]model.FlashSale, error) {
+	query := `
+		SELECT id, name, discount_type, discount_value, category, brand, quota_per_sku,
+		       start_at, end_at, status, created_at, updated_at
+		FROM flash_sales
+		WHERE ($1 = '' OR status = $1)
+		ORDER BY start_at DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query flash sales: %w", err)
+	}
+	defer rows.Close()
+
+	var sales []model.FlashSale
+	for rows.Next() {
+		var fs model.FlashSale
+		err := rows.Scan(
+			&fs.ID, &fs.Name, &fs.DiscountType, &fs.DiscountValue, &fs.Category, &fs.Brand, &fs.QuotaPerSKU,
+			&fs.StartAt, &fs.EndAt, &fs.Status, &fs.CreatedAt, &fs.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan flash sale: %w", err)
+		}
+		sales = append(sales, fs)
+	}
+
+	return sales, nil
+}
+
+// GetByID retrieves a flash sale with its items and sold counts
+func (r *FlashSaleRepository) GetByID(ctx context.Context, id int64) (*model.FlashSale, error) {
+	query := `
+		SELECT id, name, discount_type, discount_value, category, brand, quota_per_sku,
+		       start_at, end_at, status, created_at, updated_at
+		FROM flash_sales
+		WHERE id = $1
+	`
+
+	var fs model.FlashSale
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&fs.ID, &fs.Name, &fs.DiscountType, &fs.DiscountValue, &fs.Category, &fs.Brand, &fs.QuotaPerSKU,
+		&fs.StartAt, &fs.EndAt, &fs.Status, &fs.CreatedAt, &fs.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get flash sale: %w", err)
+	}
+
+	itemsQuery := `
+		SELECT i.id, i.flash_sale_id, i.buyer_sku_code, i.flash_price, i.quota,
+		       (SELECT COUNT(*) FROM orders o WHERE o.flash_sale_item_id = i.id AND ` + flashSaleSoldFilter + `),
+		       i.original_discount_price, i.is_applied
+		FROM flash_sale_items i
+		WHERE i.flash_sale_id = $1
+		ORDER BY i.buyer_sku_code
+	`
+
+	rows, err := r.db.Query(ctx, itemsQuery, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query flash sale items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item model.FlashSaleItem
+		err := rows.Scan(
+			&item.ID, &item.FlashSaleID, &item.BuyerSKUCode, &item.FlashPrice, &item.Quota,
+			&item.Sold, &item.OriginalDiscountPrice, &item.IsApplied,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan flash sale item: %w", err)
+		}
+		fs.Items = append(fs.Items, item)
+	}
+
+	return &fs, nil
+}
+
+// Create creates a flash sale with an explicit SKU list (may be empty when using a selector)
+func (r *FlashSaleRepository) Create(ctx context.Context, fs *model.FlashSale, skus []string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO flash_sales (
+			name, discount_type, discount_value, category, brand, quota_per_sku, start_at, end_at, status
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, 'scheduled')
+		RETURNING id, status, created_at, updated_at
+	`
+
+	err = tx.QueryRow(ctx, query,
+		fs.Name, fs.DiscountType, fs.DiscountValue, fs.Category, fs.Brand, fs.QuotaPerSKU, fs.StartAt, fs.EndAt,
+	).Scan(&fs.ID, &fs.Status, &fs.CreatedAt, &fs.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create flash sale: %w", err)
+	}
+
+	if err := insertFlashSaleItemsTx(ctx, tx, fs.ID, skus, fs.QuotaPerSKU); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Update updates a scheduled flash sale and replaces its SKU list.
+// Running or finished campaigns cannot be edited.
+func (r *FlashSaleRepository) Update(ctx context.Context, fs *model.FlashSale, skus []string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE flash_sales SET
+			name = $2, discount_type = $3, discount_value = $4, category = $5, brand = $6,
+			quota_per_sku = $7, start_at = $8, end_at = $9, updated_at = NOW()
+		WHERE id = $1 AND status = 'scheduled'
+	`
+
+	result, err := tx.Exec(ctx, query,
+		fs.ID, fs.Name, fs.DiscountType, fs.DiscountValue, fs.Category, fs.Brand, fs.QuotaPerSKU, fs.StartAt, fs.EndAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update flash sale: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("flash sale not editable")
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM flash_sale_items WHERE flash_sale_id = $1`, fs.ID); err != nil {
+		return fmt.Errorf("failed to clear flash sale items: %w", err)
+	}
+
+	if err := insertFlashSaleItemsTx(ctx, tx, fs.ID, skus, fs.QuotaPerSKU); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Stop ends a flash sale early and reverts product prices
+func (r *FlashSaleRepository) Stop(ctx context.Context, id int64) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `
+		UPDATE flash_sales SET status = 'cancelled', updated_at = NOW()
+		WHERE id = $1 AND status IN ('scheduled', 'active')
+	`, id)
+	if err != nil {
+		return fmt.Errorf("failed to stop flash sale: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("flash sale not running")
+	}
+
+	if err := revertFlashSalesTx(ctx, tx, []int64{id}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Delete deletes a flash sale, reverting product prices first if it is running
+func (r *FlashSaleRepository) Delete(ctx context.Context, id int64) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := revertFlashSalesTx(ctx, tx, []int64{id}); err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(ctx, `DELETE FROM flash_sales WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete flash sale: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("flash sale not found")
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// ActivateDue marks scheduled campaigns whose start time has passed as active.
+// Selector campaigns (brand/category) get their items resolved from the current catalog.
+func (r *FlashSaleRepository) ActivateDue(ctx context.Context) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		UPDATE flash_sales SET status = 'active', updated_at = NOW()
+		WHERE status = 'scheduled' AND start_at <= NOW() AND end_at > NOW()
+		RETURNING id
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to activate flash sales: %w", err)
+	}
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan flash sale id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	if len(ids) > 0 {
+		selectorQuery := `
+			INSERT INTO flash_sale_items (flash_sale_id, buyer_sku_code, quota)
+			SELECT fs.id, p.buyer_sku_code, fs.quota_per_sku
+			FROM flash_sales fs
+			JOIN products p ON (fs.category IS NULL OR p.category ILIKE fs.category)
+			               AND (fs.brand IS NULL OR p.brand ILIKE fs.brand)
+			WHERE fs.id = ANY($1)
+			  AND (fs.category IS NOT NULL OR fs.brand IS NOT NULL)
+			  AND p.is_available = true
+			ON CONFLICT (flash_sale_id, buyer_sku_code) DO NOTHING
+		`
+		if _, err := tx.Exec(ctx, selectorQuery, ids); err != nil {
+			return 0, fmt.Errorf("failed to resolve flash sale selector: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return len(ids), nil
+}
+
+// ApplyPendingItems sets products.discount_price for items of active campaigns that
+// are not applied yet and still have quota (also re-applies items whose quota was released).
+// A SKU is only driven by one flash sale at a time.
+func (r *FlashSaleRepository) ApplyPendingItems(ctx context.Context) (int, error) {
+	query := `
+		WITH candidates AS (
+			SELECT DISTINCT ON (i.buyer_sku_code)
+			       i.id, i.buyer_sku_code, p.discount_price AS original_discount_price,
+			       CASE WHEN fs.discount_type = 'percent'
+			            THEN CEIL(p.selling_price * (100 - fs.discount_value) / 100)
+			            ELSE fs.discount_value END AS flash_price,
+			       p.selling_price
+			FROM flash_sale_items i
+			JOIN flash_sales fs ON fs.id = i.flash_sale_id
+			JOIN products p ON p.buyer_sku_code = i.buyer_sku_code
+			WHERE fs.status = 'active'
+			  AND fs.end_at > NOW()
+			  AND i.is_applied = false
+			  AND (i.quota IS NULL OR
+			       (SELECT COUNT(*) FROM orders o WHERE o.flash_sale_item_id = i.id AND ` + flashSaleSoldFilter + `) < i.quota)
+			  AND NOT EXISTS (
+			       SELECT 1 FROM flash_sale_items other
+			       WHERE other.buyer_sku_code = i.buyer_sku_code AND other.is_applied = true
+			  )
+			ORDER BY i.buyer_sku_code, fs.start_at DESC
+		),
+		applied AS (
+			UPDATE flash_sale_items i SET
+				flash_price = c.flash_price,
+				original_discount_price = c.original_discount_price,
+				is_applied = true,
+				updated_at = NOW()
+			FROM candidates c
+			WHERE i.id = c.id AND c.flash_price > 0 AND c.flash_price < c.selling_price
+			  AND (c.original_discount_price IS NULL OR c.original_discount_price <= 0 OR c.flash_price < c.original_discount_price)
+			RETURNING i.buyer_sku_code, i.flash_price
+		)
+		UPDATE products p SET discount_price = a.flash_price, updated_at = NOW()
+		FROM applied a
+		WHERE p.buyer_sku_code = a.buyer_sku_code
+	`
+
+	result, err := r.db.Exec(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to apply flash sale items: %w", err)
+	}
+
+	return int(result.RowsAffected()), nil
+}
+
+// EndExpired ends campaigns past their end time and reverts product prices
+func (r *FlashSaleRepository) EndExpired(ctx context.Context) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		UPDATE flash_sales SET status = 'ended', updated_at = NOW()
+		WHERE status IN ('scheduled', 'active') AND end_at <= NOW()
+		RETURNING id
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to end flash sales: %w", err)
+	}
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan flash sale id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	if len(ids) > 0 {
+		if err := revertFlashSalesTx(ctx, tx, ids); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return len(ids), nil
+}
+
+// GetActiveInfo returns public flash sale info for all SKUs currently on flash sale
+func (r *FlashSaleRepository) GetActiveInfo(ctx context.Context) (map[string]model.FlashSaleInfo, error) {
+	query := `
+		SELECT i.buyer_sku_code, fs.id, fs.name, i.id, i.flash_price, fs.end_at, i.quota,
+		       (SELECT COUNT(*) FROM orders o WHERE o.flash_sale_item_id = i.id AND ` + flashSaleSoldFilter + `)
+		FROM flash_sale_items i
+		JOIN flash_sales fs ON fs.id = i.flash_sale_id
+		WHERE i.is_applied = true AND fs.status = 'active'
+	`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query active flash sales: %w", err)
+	}
+	defer rows.Close()
+
+	infos := make(map[string]model.FlashSaleInfo)
+	for rows.Next() {
+		var sku string
+		var sold int
+		var info model.FlashSaleInfo
+		err := rows.Scan(&sku, &info.FlashSaleID, &info.Name, &info.ItemID, &info.FlashPrice, &info.EndAt, &info.Quota, &sold)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan flash sale info: %w", err)
+		}
+		if info.Quota != nil {
+			remaining := *info.Quota - sold
+			if remaining < 0 {
+				remaining = 0
+			}
+			info.RemainingQuota = &remaining
+		}
+		infos[sku] = info
+	}
+
+	return infos, nil
+}
+
+// GetActiveInfoBySKU returns the running flash sale for a SKU, or nil if none
+func (r *FlashSaleRepository) GetActiveInfoBySKU(ctx context.Context, sku string) (*model.FlashSaleInfo, error) {
+	query := `
+		SELECT fs.id, fs.name, i.id, i.flash_price, fs.end_at, i.quota,
+		       (SELECT COUNT(*) FROM orders o WHERE o.flash_sale_item_id = i.id AND ` + flashSaleSoldFilter + `)
+		FROM flash_sale_items i
+		JOIN flash_sales fs ON fs.id = i.flash_sale_id
+		WHERE i.buyer_sku_code = $1 AND i.is_applied = true AND fs.status = 'active'
+		LIMIT 1
+	`
+
+	var sold int
+	var info model.FlashSaleInfo
+	err := r.db.QueryRow(ctx, query, sku).Scan(&info.FlashSaleID, &info.Name, &info.ItemID, &info.FlashPrice, &info.EndAt, &info.Quota, &sold)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get flash sale info: %w", err)
+	}
+
+	if info.Quota != nil {
+		remaining := *info.Quota - sold
+		if remaining < 0 {
+			remaining = 0
+		}
+		info.RemainingQuota = &remaining
+	}
+
+	return &info, nil
+}
+
+func insertFlashSaleItemsTx(ctx context.Context, tx pgx.Tx, flashSaleID int64, skus []string, quota *int) error {
+	for _, sku := range skus {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO flash_sale_items (flash_sale_id, buyer_sku_code, quota)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (flash_sale_id, buyer_sku_code) DO NOTHING
+		`, flashSaleID, sku, quota)
+		if err != nil {
+			return fmt.Errorf("failed to insert flash sale item: %w", err)
+		}
+	}
+	return nil
+}
+
+// revertFlashSalesTx restores discount_price for every applied item of the given campaigns.
+// Products whose discount_price was changed manually in the meantime are left untouched.
+func revertFlashSalesTx(ctx context.Context, tx pgx.Tx, flashSaleIDs []int64) error {
+	query := `
+		WITH reverted AS (
+			UPDATE flash_sale_items SET is_applied = false, updated_at = NOW()
+			WHERE flash_sale_id = ANY($1) AND is_applied = true
+			RETURNING buyer_sku_code, flash_price, original_discount_price
+		)
+		UPDATE products p SET discount_price = r.original_discount_price, updated_at = NOW()
+		FROM reverted r
+		WHERE p.buyer_sku_code = r.buyer_sku_code AND p.discount_price = r.flash_price
+	`
+
+	if _, err := tx.Exec(ctx, query, flashSaleIDs); err != nil {
+		return fmt.Errorf("failed to revert flash sale prices: %w", err)
+	}
+	return nil
+}
+
+// reserveFlashSaleTx locks the flash sale item and checks its quota inside the order transaction.
+// Returns lastUnit = true when the order being created takes the final unit.
+func reserveFlashSaleTx(ctx context.Context, tx pgx.Tx, itemID int64) (bool, error) {
+	var quota *int
+	err := tx.QueryRow(ctx, `
+		SELECT i.quota
+		FROM flash_sale_items i
+		JOIN flash_sales fs ON fs.id = i.flash_sale_id
+		WHERE i.id = $1 AND i.is_applied = true AND fs.status = 'active' AND fs.end_at > NOW()
+		FOR UPDATE OF i
+	`, itemID).Scan(&quota)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, ErrFlashSaleSoldOut
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to lock flash sale item: %w", err)
+	}
+
+	if quota == nil {
+		return false, nil
+	}
+
+	var sold int
+	err = tx.QueryRow(ctx, `SELECT COUNT(*) FROM orders o WHERE o.flash_sale_item_id = $1 AND `+flashSaleSoldFilter, itemID).Scan(&sold)
+	if err != nil {
+		return false, fmt.Errorf("failed to count flash sale orders: %w", err)
+	}
+
+	if sold >= *quota {
+		return false, ErrFlashSaleSoldOut
+	}
+
+	return sold+1 >= *quota, nil
+}
+
+// endFlashSaleItemTx reverts a single sold-out item back to its normal price
+func endFlashSaleItemTx(ctx context.Context, tx pgx.Tx, itemID int64) error {
+	query := `
+		WITH reverted AS (
+			UPDATE flash_sale_items SET is_applied = false, updated_at = NOW()
+			WHERE id = $1 AND is_applied = true
+			RETURNING buyer_sku_code, flash_price, original_discount_price
+		)
+		UPDATE products p SET discount_price = r.original_discount_price, updated_at = NOW()
+		FROM reverted r
+		WHERE p.buyer_sku_code = r.buyer_sku_code AND p.discount_price = r.flash_price
+	`
+
+	if _, err := tx.Exec(ctx, query, itemID); err != nil {
+		return fmt.Errorf("failed to revert sold out flash sale item: %w", err)
+	}
+	return nil
+}
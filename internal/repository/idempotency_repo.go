@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"govershop-api/internal/model"
+)
+
+// IdempotencyRepository stores idempotency keys and the responses they produced
+type IdempotencyRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewIdempotencyRepository creates a new IdempotencyRepository
+func NewIdempotencyRepository(db *pgxpool.Pool) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+// Reserve claims a key for a new request. Returns nil when the caller owns the key
+// and should process the request, or the existing record when the key was already used.
+// An expired record, or one left processing past its lease, is replaced.
+func (r *IdempotencyRepository) Reserve(ctx context.Context, scope, key, requestHash string, ttl, lease time.Duration) (*model.IdempotencyRecord, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM idempotency_keys
+		WHERE scope = $1 AND idem_key = $2
+		  AND (expires_at < NOW() OR (status = 'processing' AND COALESCE(locked_until, NOW()) <= NOW()))
+	`, scope, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to clear expired idempotency key: %w", err)
+	}
+
+	result, err := tx.Exec(ctx, `
+		INSERT INTO idempotency_keys (scope, idem_key, request_hash, expires_at, locked_until)
+		VALUES ($1, $2, $3, NOW() + make_interval(secs => $4), NOW() + make_interval(secs => $5))
+		ON CONFLICT (scope, idem_key) DO NOTHING
+	`, scope, key, requestHash, ttl.Seconds(), lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+
+	if result.RowsAffected() == 1 {
+		if err := tx.Commit(ctx); err != nil {
+			return nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		return nil, nil
+	}
+
+	var rec model.IdempotencyRecord
+	var responseStatus *int
+	var contentType *string
+	err = tx.QueryRow(ctx, `
+		SELECT scope, idem_key, request_hash, status, response_status, content_type, response_body, created_at, expires_at
+		FROM idempotency_keys
+		WHERE scope = $1 AND idem_key = $2
+	`, scope, key).Scan(
+		&rec.Scope, &rec.Key, &rec.RequestHash, &rec.Status, &responseStatus, &contentType,
+		&rec.ResponseBody, &rec.CreatedAt, &rec.ExpiresAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Released by its owner between our insert and select; let the client retry
+		return &model.IdempotencyRecord{Scope: scope, Key: key, RequestHash: requestHash, Status: model.IdempotencyStatusProcessing}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+	if responseStatus != nil {
+		rec.ResponseStatus = *responseStatus
+	}
+	if contentType != nil {
+		rec.ContentType = *contentType
+	}
+
+	return &rec, nil
+}
+
+// Complete stores the response of a reserved key
+func (r *IdempotencyRepository) Complete(ctx context.Context, scope, key string, status int, contentType string, body []byte) error {
+	query := `
+		UPDATE idempotency_keys
+		SET status = 'completed', response_status = $3, content_type = $4, response_body = $5, locked_until = NULL
+		WHERE scope = $1 AND idem_key = $2
+	`
+
+	if _, err := r.db.Exec(ctx, query, scope, key, status, contentType, body); err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+	return nil
+}
+
+// Release removes a reserved key so the request can be retried
+func (r *IdempotencyRepository) Release(ctx context.Context, scope, key string) error {
+	query := `DELETE FROM idempotency_keys WHERE scope = $1 AND idem_key = $2 AND status = 'processing'`
+
+	if _, err := r.db.Exec(ctx, query, scope, key); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired removes keys past their retention window
+func (r *IdempotencyRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	result, err := r.db.Exec(ctx, `DELETE FROM idempotency_keys WHERE expires_at < NOW()`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
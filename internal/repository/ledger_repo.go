@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"govershop-api/internal/model"
+)
+
+// Ledger errors
+var (
+	ErrInsufficientBalance  = errors.New("insufficient balance")
+	ErrDuplicateLedgerEntry = errors.New("ledger entry already posted")
+)
+
+// LedgerRepository handles balance reconciliation against the deposits ledger
+type LedgerRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewLedgerRepository creates a new LedgerRepository
+func NewLedgerRepository(db *pgxpool.Pool) *LedgerRepository {
+	return &LedgerRepository{db: db}
+}
+
+// postLedgerTx is the single place where users.balance changes. It locks the user row,
+// applies the entry and records it in deposits with the balance before and after.
+// Posting the same ledger_ref twice returns ErrDuplicateLedgerEntry.
+func postLedgerTx(ctx context.Context, tx pgx.Tx, e model.LedgerEntry) (*model.Deposit, error) {
+	if e.Amount <= 0 {
+		return nil, fmt.Errorf("invalid ledger amount: %v", e.Amount)
+	}
+
+	ledgerRef := e.LedgerRef
+	if ledgerRef == "" {
+		ledgerRef = defaultLedgerRef(e.Type, e.ReferenceID)
+	}
+	createdBy := e.CreatedBy
+	if createdBy == "" {
+		createdBy = "system"
+	}
+
+	var before float64
+	err := tx.QueryRow(ctx, "SELECT balance FROM users WHERE id = $1 FOR UPDATE", e.UserID).Scan(&before)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current balance: %w", err)
+	}
+
+	after := before + e.Amount
+	if model.IsDebitDepositType(e.Type) {
+		after = before - e.Amount
+		if after < 0 && !e.AllowNegative {
+			return nil, ErrInsufficientBalance
+		}
+	}
+
+	d := model.Deposit{
+		UserID:        e.UserID,
+		Amount:        e.Amount,
+		Type:          e.Type,
+		Status:        "success",
+		CreatedBy:     createdBy,
+		BalanceBefore: &before,
+		BalanceAfter:  &after,
+	}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO deposits (user_id, amount, type, description, reference_id, status, created_by,
+			balance_before, balance_after, ledger_ref)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), 'success', $6, $7, $8, $9)
+		RETURNING id, description, reference_id, created_at
+	`, e.UserID, e.Amount, e.Type, e.Description, e.ReferenceID, createdBy, before, after, ledgerRef,
+	).Scan(&d.ID, &d.Description, &d.ReferenceID, &d.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "idx_deposits_ledger_ref" {
+			return nil, ErrDuplicateLedgerEntry
+		}
+		return nil, fmt.Errorf("failed to create deposit log: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, "UPDATE users SET balance = $1 WHERE id = $2", after, e.UserID); err != nil {
+		return nil, fmt.Errorf("failed to update balance: %w", err)
+	}
+
+	return &d, nil
+}
+
+// defaultLedgerRef derives the unique ledger ref of an entry. Entries tied to a reference
+// (order, redeem) can be posted once per type; the rest get a random ref.
+func defaultLedgerRef(depositType, referenceID string) string {
+	if referenceID != "" {
+		return depositType + ":" + referenceID
+	}
+	b := make([]byte, 12)
+	rand.Read(b)
+	return depositType + ":" + hex.EncodeToString(b)
+}
+
+// postLedger posts a single entry in its own transaction
+func postLedger(ctx context.Context, db *pgxpool.Pool, e model.LedgerEntry) (*model.Deposit, error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	d, err := postLedgerTx(ctx, tx, e)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return d, nil
+}
+
+// Reconcile compares every member's balance with the sum of their ledger and stores the result
+func (r *LedgerRepository) Reconcile(ctx context.Context, triggeredBy string) (*model.LedgerReconciliation, error) {
+	var checked int
+	if err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM users WHERE role = 'member'").Scan(&checked); err != nil {
+		return nil, fmt.Errorf("failed to count members: %w", err)
+	}
+
+	query := `
+		WITH ledger AS (
+			SELECT user_id,
+				SUM(CASE WHEN type = ANY($1) THEN -amount ELSE amount END) AS total,
+				COUNT(*) AS entries
+			FROM deposits
+			WHERE status = 'success'
+			GROUP BY user_id
+		), last_entry AS (
+			SELECT DISTINCT ON (user_id) user_id, balance_after
+			FROM deposits
+			WHERE balance_after IS NOT NULL
+			ORDER BY user_id, id DESC
+		)
+		SELECT u.id, u.username, u.balance, COALESCE(l.total, 0), COALESCE(l.entries, 0), le.balance_after
+		FROM users u
+		LEFT JOIN ledger l ON l.user_id = u.id
+		LEFT JOIN last_entry le ON le.user_id = u.id
+		WHERE u.role = 'member' AND u.balance <> COALESCE(l.total, 0)
+		ORDER BY ABS(u.balance - COALESCE(l.total, 0)) DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, model.DebitDepositTypes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconcile balances: %w", err)
+	}
+	defer rows.Close()
+
+	mismatches := []model.LedgerMismatch{}
+	for rows.Next() {
+		var m model.LedgerMismatch
+		if err := rows.Scan(&m.UserID, &m.Username, &m.Balance, &m.LedgerBalance, &m.Entries, &m.LastBalanceAfter); err != nil {
+			return nil, fmt.Errorf("failed to scan mismatch: %w", err)
+		}
+		m.Difference = m.Balance - m.LedgerBalance
+		mismatches = append(mismatches, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to reconcile balances: %w", err)
+	}
+
+	payload, err := json.Marshal(mismatches)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode mismatches: %w", err)
+	}
+
+	result := &model.LedgerReconciliation{
+		CheckedUsers:  checked,
+		MismatchCount: len(mismatches),
+		Mismatches:    mismatches,
+		TriggeredBy:   triggeredBy,
+	}
+	err = r.db.QueryRow(ctx, `
+		INSERT INTO ledger_reconciliations (checked_users, mismatch_count, mismatches, triggered_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, checked, len(mismatches), payload, triggeredBy).Scan(&result.ID, &result.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save reconciliation: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetLatest returns the most recent reconciliation run (nil if none)
+func (r *LedgerRepository) GetLatest(ctx context.Context) (*model.LedgerReconciliation, error) {
+	query := `
+		SELECT id, checked_users, mismatch_count, mismatches, COALESCE(triggered_by, 'system'), created_at
+		FROM ledger_reconciliations
+		ORDER BY id DESC
+		LIMIT 1
+	`
+
+	var result model.LedgerReconciliation
+	var payload []byte
+	err := r.db.QueryRow(ctx, query).Scan(
+		&result.ID, &result.CheckedUsers, &result.MismatchCount, &payload, &result.TriggeredBy, &result.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reconciliation: %w", err)
+	}
+
+	if err := json.Unmarshal(payload, &result.Mismatches); err != nil {
+		return nil, fmt.Errorf("failed to decode mismatches: %w", err)
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"govershop-api/internal/model"
+)
+
+// LoginSecurityRepository handles failed login lockouts and the login history
+type LoginSecurityRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewLoginSecurityRepository creates a new LoginSecurityRepository
+func NewLoginSecurityRepository(db *pgxpool.Pool) *LoginSecurityRepository {
+	return &LoginSecurityRepository{db: db}
+}
+
+const lockoutColumns = `
+	l.user_id, u.username, u.role, l.failed_attempts, l.lockout_count,
+	l.locked_until, l.last_failed_at, l.last_failed_ip, l.updated_at
+`
+
+func scanLockout(row pgx.Row) (*model.LoginLockout, error) {
+	var l model.LoginLockout
+	err := row.Scan(
+		&l.UserID, &l.Username, &l.Role, &l.FailedAttempts, &l.LockoutCount,
+		&l.LockedUntil, &l.LastFailedAt, &l.LastFailedIP, &l.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// GetLockout returns the failed login state of a user (nil if there is none)
+func (r *LoginSecurityRepository) GetLockout(ctx context.Context, userID int) (*model.LoginLockout, error) {
+	query := `SELECT ` + lockoutColumns + `
+		FROM login_lockouts l
+		JOIN users u ON u.id = l.user_id
+		WHERE l.user_id = $1`
+
+	l, err := scanLockout(r.db.QueryRow(ctx, query, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get login lockout: %w", err)
+	}
+	return l, nil
+}
+
+// RecordFailure counts a failed login. When the count reaches maxAttempts the
+// account is locked for baseLockout, doubled for every earlier lockout since the
+// last successful login and capped at maxLockout. Returns the lock duration when
+// this failure locked the account, 0 otherwise.
+func (r *LoginSecurityRepository) RecordFailure(ctx context.Context, userID int, ip string, maxAttempts int, baseLockout, maxLockout time.Duration) (time.Duration, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `INSERT INTO login_lockouts (user_id) VALUES ($1) ON CONFLICT (user_id) DO NOTHING`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create login lockout: %w", err)
+	}
+
+	var failed, lockouts int
+	err = tx.QueryRow(ctx, `
+		SELECT failed_attempts, lockout_count FROM login_lockouts WHERE user_id = $1 FOR UPDATE
+	`, userID).Scan(&failed, &lockouts)
+	if err != nil {
+		return 0, fmt.Errorf("failed to lock login lockout: %w", err)
+	}
+
+	failed++
+	var lockFor time.Duration
+	if failed >= maxAttempts {
+		lockFor = lockoutDuration(lockouts, baseLockout, maxLockout)
+		failed = 0
+		lockouts++
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE login_lockouts
+		SET failed_attempts = $2,
+			lockout_count = $3,
+			locked_until = CASE WHEN $4::float8 > 0 THEN NOW() + make_interval(secs => $4::float8) ELSE locked_until END,
+			last_failed_at = NOW(),
+			last_failed_ip = $5,
+			updated_at = NOW()
+		WHERE user_id = $1
+	`, userID, failed, lockouts, lockFor.Seconds(), ip)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update login lockout: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return lockFor, nil
+}
+
+// lockoutDuration is baseLockout doubled for every earlier lockout, capped at maxLockout
+func lockoutDuration(earlierLockouts int, baseLockout, maxLockout time.Duration) time.Duration {
+	lockFor := baseLockout
+	for i := 0; i < earlierLockouts && lockFor < maxLockout; i++ {
+		lockFor *= 2
+	}
+	if lockFor > maxLockout {
+		lockFor = maxLockout
+	}
+	return lockFor
+}
+
+// ClearLockout removes the failed login state of a user (on a successful login or
+// by an admin). Returns false if there was nothing to clear.
+func (r *LoginSecurityRepository) ClearLockout(ctx context.Context, userID int) (bool, error) {
+	result, err := r.db.Exec(ctx, `DELETE FROM login_lockouts WHERE user_id = $1`, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to clear login lockout: %w", err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// ListLockouts returns accounts that are locked or have failed attempts, most recent first
+func (r *LoginSecurityRepository) ListLockouts(ctx context.Context, lockedOnly bool) ([]model.LoginLockout, error) {
+	query := `SELECT ` + lockoutColumns + `
+		FROM login_lockouts l
+		JOIN users u ON u.id = l.user_id
+		WHERE ($1 = false OR l.locked_until > NOW())
+		ORDER BY l.last_failed_at DESC NULLS LAST`
+
+	rows, err := r.db.Query(ctx, query, lockedOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get login lockouts: %w", err)
+	}
+	defer rows.Close()
+
+	var lockouts []model.LoginLockout
+	for rows.Next() {
+		l, err := scanLockout(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan login lockout: %w", err)
+		}
+		lockouts = append(lockouts, *l)
+	}
+	return lockouts, nil
+}
+
+// KnownDevice reports whether the user logged in successfully before, and whether
+// one of those logins came from the given device
+func (r *LoginSecurityRepository) KnownDevice(ctx context.Context, userID int, device string) (hasHistory, known bool, err error) {
+	err = r.db.QueryRow(ctx, `
+		SELECT COUNT(*) > 0, COUNT(*) FILTER (WHERE device = $2) > 0
+		FROM login_events
+		WHERE user_id = $1 AND outcome = $3
+	`, userID, device, model.LoginOutcomeSuccess).Scan(&hasHistory, &known)
+	if err != nil {
+		return false, false, fmt.Errorf("failed to check login device: %w", err)
+	}
+	return hasHistory, known, nil
+}
+
+// CreateEvent records a login attempt
+func (r *LoginSecurityRepository) CreateEvent(ctx context.Context, e *model.LoginEvent) error {
+	query := `
+		INSERT INTO login_events (user_id, username, channel, outcome, ip_address, user_agent, device, new_device)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		e.UserID, e.Username, e.Channel, e.Outcome, e.IPAddress, e.UserAgent, e.Device, e.NewDevice,
+	).Scan(&e.ID, &e.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create login event: %w", err)
+	}
+	return nil
+}
+
+// ListEvents returns a user's login attempts, newest first, and the total count
+func (r *LoginSecurityRepository) ListEvents(ctx context.Context, userID, limit, offset int) ([]model.LoginEvent, int, error) {
+	var total int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM login_events WHERE user_id = $1`, userID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count login events: %w", err)
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT id, user_id, username, channel, outcome, COALESCE(ip_address, ''), COALESCE(user_agent, ''),
+			COALESCE(device, ''), new_device, created_at
+		FROM login_events
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`, userID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get login events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []model.LoginEvent
+	for rows.Next() {
+		var e model.LoginEvent
+		err := rows.Scan(
+			&e.ID, &e.UserID, &e.Username, &e.Channel, &e.Outcome, &e.IPAddress, &e.UserAgent,
+			&e.Device, &e.NewDevice, &e.CreatedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan login event: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, total, nil
+}
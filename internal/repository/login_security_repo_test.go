@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockoutDuration(t *testing.T) {
+	tests := []struct {
+		name            string
+		earlierLockouts int
+		base, max       time.Duration
+		want            time.Duration
+	}{
+		{name: "first lockout", earlierLockouts: 0, base: 15 * time.Minute, max: 24 * time.Hour, want: 15 * time.Minute},
+		{name: "second doubles", earlierLockouts: 1, base: 15 * time.Minute, max: 24 * time.Hour, want: 30 * time.Minute},
+		{name: "third doubles again", earlierLockouts: 2, base: 15 * time.Minute, max: 24 * time.Hour, want: time.Hour},
+		{name: "capped", earlierLockouts: 10, base: 15 * time.Minute, max: 24 * time.Hour, want: 24 * time.Hour},
+		{name: "exactly at cap", earlierLockouts: 2, base: 15 * time.Minute, max: time.Hour, want: time.Hour},
+		{name: "base above cap", earlierLockouts: 0, base: 2 * time.Hour, max: time.Hour, want: time.Hour},
+		{name: "many lockouts do not overflow", earlierLockouts: 1000, base: 15 * time.Minute, max: 24 * time.Hour, want: 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lockoutDuration(tt.earlierLockouts, tt.base, tt.max); got != tt.want {
+				t.Errorf("lockoutDuration(%d, %v, %v) = %v, want %v", tt.earlierLockouts, tt.base, tt.max, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"govershop-api/internal/model"
+	"govershop-api/internal/service/envelope"
+)
+
+// MemberSecurityRepository handles member TOTP settings
+type MemberSecurityRepository struct {
+	db *pgxpool.Pool
+
+	keyring *envelope.Keyring // TOTP secrets are stored encrypted
+}
+
+// NewMemberSecurityRepository creates a new MemberSecurityRepository
+func NewMemberSecurityRepository(db *pgxpool.Pool, keyring *envelope.Keyring) *MemberSecurityRepository {
+	return &MemberSecurityRepository{db: db, keyring: keyring}
+}
+
+// GetByUserID gets a member's security settings (nil if TOTP was never set up)
+func (r *MemberSecurityRepository) GetByUserID(ctx context.Context, userID int) (*model.MemberSecurity, error) {
+	query := `
+		SELECT user_id, COALESCE(totp_secret, ''), COALESCE(totp_enabled, false), totp_enabled_at,
+			COALESCE(require_totp_password, false), COALESCE(require_totp_orders, false),
+			COALESCE(order_totp_threshold, 0), created_at, updated_at
+		FROM member_security
+		WHERE user_id = $1
+	`
+
+	var s model.MemberSecurity
+	err := r.db.QueryRow(ctx, query, userID).Scan(
+		&s.UserID, &s.TOTPSecret, &s.TOTPEnabled, &s.TOTPEnabledAt,
+		&s.RequireTOTPPassword, &s.RequireTOTPOrders,
+		&s.OrderTOTPThreshold, &s.CreatedAt, &s.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get member security: %w", err)
+	}
+
+	if s.TOTPSecret, err = r.keyring.Decrypt(s.TOTPSecret); err != nil {
+		return nil, fmt.Errorf("failed to decrypt member TOTP secret: %w", err)
+	}
+
+	return &s, nil
+}
+
+// SetTOTPSecret stores a new (not yet enabled) TOTP secret for a member
+func (r *MemberSecurityRepository) SetTOTPSecret(ctx context.Context, userID int, secret string) error {
+	query := `
+		INSERT INTO member_security (user_id, totp_secret, totp_enabled)
+		VALUES ($1, $2, false)
+		ON CONFLICT (user_id) DO UPDATE SET
+			totp_secret = EXCLUDED.totp_secret,
+			totp_enabled = false,
+			totp_enabled_at = NULL
+	`
+
+	encrypted, err := r.keyring.Encrypt(secret)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt member TOTP secret: %w", err)
+	}
+
+	if _, err := r.db.Exec(ctx, query, userID, encrypted); err != nil {
+		return fmt.Errorf("failed to set member TOTP secret: %w", err)
+	}
+	return nil
+}
+
+// EnableTOTP enables TOTP for a member
+func (r *MemberSecurityRepository) EnableTOTP(ctx context.Context, userID int) error {
+	query := `
+		UPDATE member_security
+		SET totp_enabled = true, totp_enabled_at = NOW()
+		WHERE user_id = $1 AND COALESCE(totp_secret, '') <> ''
+	`
+
+	result, err := r.db.Exec(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("failed to enable member TOTP: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("totp not set up")
+	}
+	return nil
+}
+
+// DisableTOTP disables TOTP, clears the secret and resets the requirements
+func (r *MemberSecurityRepository) DisableTOTP(ctx context.Context, userID int) error {
+	query := `
+		UPDATE member_security
+		SET totp_enabled = false, totp_enabled_at = NULL, totp_secret = NULL,
+			require_totp_password = false, require_totp_orders = false
+		WHERE user_id = $1
+	`
+
+	if _, err := r.db.Exec(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed to disable member TOTP: %w", err)
+	}
+	return nil
+}
+
+// UpdateRequirements sets when a TOTP code is required
+func (r *MemberSecurityRepository) UpdateRequirements(ctx context.Context, userID int, forPassword, forOrders bool, orderThreshold float64) error {
+	query := `
+		UPDATE member_security
+		SET require_totp_password = $2, require_totp_orders = $3, order_totp_threshold = $4
+		WHERE user_id = $1
+	`
+
+	if _, err := r.db.Exec(ctx, query, userID, forPassword, forOrders, orderThreshold); err != nil {
+		return fmt.Errorf("failed to update member TOTP settings: %w", err)
+	}
+	return nil
+}
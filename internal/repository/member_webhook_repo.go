@@ -0,0 +1,329 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"govershop-api/internal/model"
+	"govershop-api/internal/service/envelope"
+)
+
+// Delivery retry policy
+const (
+	WebhookMaxAttempts  = 8
+	webhookBaseBackoff  = 30 * time.Second
+	webhookMaxBackoff   = time.Hour
+	webhookClaimLease   = 2 * time.Minute // Claimed rows are not picked again while in flight
+	webhookResponseSize = 1000
+)
+
+// MemberWebhookRepository handles database operations for member callbacks
+type MemberWebhookRepository struct {
+	db *pgxpool.Pool
+
+	keyring *envelope.Keyring // Signing secrets are stored encrypted
+}
+
+// NewMemberWebhookRepository creates a new MemberWebhookRepository
+func NewMemberWebhookRepository(db *pgxpool.Pool, keyring *envelope.Keyring) *MemberWebhookRepository {
+	return &MemberWebhookRepository{db: db, keyring: keyring}
+}
+
+// GetByUserID retrieves the member's callback registration (nil if none)
+func (r *MemberWebhookRepository) GetByUserID(ctx context.Context, userID int) (*model.MemberWebhook, error) {
+	query := `
+		SELECT user_id, url, secret, COALESCE(is_active, false), created_at, updated_at
+		FROM member_webhooks WHERE user_id = $1
+	`
+
+	var w model.MemberWebhook
+	err := r.db.QueryRow(ctx, query, userID).Scan(
+		&w.UserID, &w.URL, &w.Secret, &w.IsActive, &w.CreatedAt, &w.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get member webhook: %w", err)
+	}
+
+	if w.Secret, err = r.keyring.Decrypt(w.Secret); err != nil {
+		return nil, fmt.Errorf("failed to decrypt webhook secret: %w", err)
+	}
+
+	return &w, nil
+}
+
+// Upsert registers or updates the callback URL. The secret is only set on first registration.
+func (r *MemberWebhookRepository) Upsert(ctx context.Context, userID int, url, secret string, isActive bool) (*model.MemberWebhook, error) {
+	query := `
+		INSERT INTO member_webhooks (user_id, url, secret, is_active)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE SET
+			url = EXCLUDED.url,
+			is_active = EXCLUDED.is_active,
+			updated_at = NOW()
+		RETURNING user_id, url, secret, COALESCE(is_active, false), created_at, updated_at
+	`
+
+	encrypted, err := r.keyring.Encrypt(secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt webhook secret: %w", err)
+	}
+
+	var w model.MemberWebhook
+	err = r.db.QueryRow(ctx, query, userID, url, encrypted, isActive).Scan(
+		&w.UserID, &w.URL, &w.Secret, &w.IsActive, &w.CreatedAt, &w.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save member webhook: %w", err)
+	}
+
+	if w.Secret, err = r.keyring.Decrypt(w.Secret); err != nil {
+		return nil, fmt.Errorf("failed to decrypt webhook secret: %w", err)
+	}
+
+	return &w, nil
+}
+
+// SetSecret replaces the signing secret
+func (r *MemberWebhookRepository) SetSecret(ctx context.Context, userID int, secret string) error {
+	encrypted, err := r.keyring.Encrypt(secret)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt webhook secret: %w", err)
+	}
+
+	result, err := r.db.Exec(ctx, `UPDATE member_webhooks SET secret = $2, updated_at = NOW() WHERE user_id = $1`, userID, encrypted)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook secret: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("member webhook not found")
+	}
+	return nil
+}
+
+// Enqueue queues a delivery for the member's active callback URL.
+// It is a no-op (returns 0) when the member has no active registration.
+func (r *MemberWebhookRepository) Enqueue(ctx context.Context, userID int, orderID, event string, payload []byte) (int, error) {
+	query := `
+		INSERT INTO member_webhook_deliveries (user_id, order_id, event, url, payload)
+		SELECT user_id, NULLIF($2, '')::uuid, $3, url, $4
+		FROM member_webhooks
+		WHERE user_id = $1 AND is_active = true
+		RETURNING id
+	`
+
+	var id int
+	err := r.db.QueryRow(ctx, query, userID, orderID, event, payload).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue webhook delivery: %w", err)
+	}
+
+	return id, nil
+}
+
+const webhookDeliveryColumns = `
+	d.id, d.user_id, d.order_id::text, d.event, d.url, d.payload, d.status, d.attempts,
+	d.next_attempt_at, d.last_status_code, d.last_error, d.last_response,
+	d.created_at, d.updated_at, d.delivered_at
+`
+
+func scanWebhookDelivery(row pgx.Row, extra ...interface{}) (*model.MemberWebhookDelivery, error) {
+	var d model.MemberWebhookDelivery
+	dest := []interface{}{
+		&d.ID, &d.UserID, &d.OrderID, &d.Event, &d.URL, &d.Payload, &d.Status, &d.Attempts,
+		&d.NextAttemptAt, &d.LastStatusCode, &d.LastError, &d.LastResponse,
+		&d.CreatedAt, &d.UpdatedAt, &d.DeliveredAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// ClaimDue picks pending deliveries that are due and leases them to the caller.
+// Uses SKIP LOCKED so several workers never send the same delivery concurrently.
+func (r *MemberWebhookRepository) ClaimDue(ctx context.Context, limit int) ([]model.MemberWebhookDelivery, error) {
+	query := `
+		WITH due AS (
+			SELECT id FROM member_webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE member_webhook_deliveries d
+		SET next_attempt_at = NOW() + make_interval(secs => $2), updated_at = NOW()
+		FROM due, member_webhooks w
+		WHERE d.id = due.id AND w.user_id = d.user_id
+		RETURNING ` + webhookDeliveryColumns + `, w.secret
+	`
+
+	rows, err := r.db.Query(ctx, query, limit, webhookClaimLease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries, undecryptable []model.MemberWebhookDelivery
+	for rows.Next() {
+		var secret string
+		d, err := scanWebhookDelivery(rows, &secret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		if d.Secret, err = r.keyring.Decrypt(secret); err != nil {
+			// Only this member's deliveries are affected; the rest of the batch goes out
+			log.Printf("[MemberWebhook] Failed to decrypt webhook secret of user %d for delivery %d: %v", d.UserID, d.ID, err)
+			d.Secret = ""
+			undecryptable = append(undecryptable, *d)
+			continue
+		}
+		deliveries = append(deliveries, *d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	rows.Close()
+
+	for _, d := range undecryptable {
+		if err := r.MarkFailure(ctx, d.ID, d.Attempts, nil, "", "failed to decrypt webhook secret"); err != nil {
+			log.Printf("[MemberWebhook] %v", err)
+		}
+	}
+
+	return deliveries, nil
+}
+
+// MarkSuccess records a successful delivery attempt
+func (r *MemberWebhookRepository) MarkSuccess(ctx context.Context, id, statusCode int, response string) error {
+	query := `
+		UPDATE member_webhook_deliveries
+		SET status = 'success', attempts = attempts + 1, last_status_code = $2,
+			last_response = $3, last_error = NULL, next_attempt_at = NULL,
+			delivered_at = NOW(), updated_at = NOW()
+		WHERE id = $1
+	`
+
+	_, err := r.db.Exec(ctx, query, id, statusCode, truncate(response, webhookResponseSize))
+	if err != nil {
+		return fmt.Errorf("failed to mark webhook delivery success: %w", err)
+	}
+	return nil
+}
+
+// MarkFailure records a failed attempt and schedules the next retry with exponential
+// backoff, or marks the delivery failed once WebhookMaxAttempts is reached.
+func (r *MemberWebhookRepository) MarkFailure(ctx context.Context, id, attempts int, statusCode *int, response, errMsg string) error {
+	attempts++
+
+	status := model.WebhookDeliveryPending
+	var nextAttempt *time.Time
+	if attempts >= WebhookMaxAttempts {
+		status = model.WebhookDeliveryFailed
+	} else {
+		next := time.Now().Add(webhookBackoff(attempts))
+		nextAttempt = &next
+	}
+
+	query := `
+		UPDATE member_webhook_deliveries
+		SET status = $2, attempts = $3, next_attempt_at = $4, last_status_code = $5,
+			last_response = NULLIF($6, ''), last_error = $7, updated_at = NOW()
+		WHERE id = $1
+	`
+
+	_, err := r.db.Exec(ctx, query, id, status, attempts, nextAttempt, statusCode,
+		truncate(response, webhookResponseSize), truncate(errMsg, webhookResponseSize))
+	if err != nil {
+		return fmt.Errorf("failed to mark webhook delivery failure: %w", err)
+	}
+	return nil
+}
+
+// ListByUser returns a member's delivery log, newest first
+func (r *MemberWebhookRepository) ListByUser(ctx context.Context, userID int, status string, limit, offset int) ([]model.MemberWebhookDelivery, int, error) {
+	where := "WHERE d.user_id = $1"
+	args := []interface{}{userID}
+	if status != "" {
+		where += " AND d.status = $2"
+		args = append(args, status)
+	}
+
+	var total int
+	if err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM member_webhook_deliveries d "+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count webhook deliveries: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM member_webhook_deliveries d
+		%s
+		ORDER BY d.created_at DESC, d.id DESC
+		LIMIT $%d OFFSET $%d
+	`, webhookDeliveryColumns, where, len(args)+1, len(args)+2)
+	args = append(args, limit, offset)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []model.MemberWebhookDelivery
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, *d)
+	}
+
+	return deliveries, total, nil
+}
+
+// Resend requeues a failed delivery of the member for immediate delivery
+func (r *MemberWebhookRepository) Resend(ctx context.Context, userID, id int) error {
+	query := `
+		UPDATE member_webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND status = 'failed'
+	`
+
+	result, err := r.db.Exec(ctx, query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to resend webhook delivery: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("delivery not found or not failed")
+	}
+	return nil
+}
+
+// webhookBackoff returns 30s * 2^(attempts-1), capped at one hour
+func webhookBackoff(attempts int) time.Duration {
+	d := webhookBaseBackoff
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= webhookMaxBackoff {
+			return webhookMaxBackoff
+		}
+	}
+	return d
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max]
+}
@@ -2,9 +2,11 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"govershop-api/internal/model"
@@ -46,30 +48,118 @@ func (r *OrderRepository) CreateWithSource(ctx context.Context, refID, sku, prod
 	return orderID, nil
 }
 
-// Create creates a new order
+// rowQuerier is satisfied by both *pgxpool.Pool and pgx.Tx
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// HasPreviousOrder reports whether a member, or a guest under any of the given
+// phone variants, has a paid, in-progress or successful order
+func (r *OrderRepository) HasPreviousOrder(ctx context.Context, memberID *int, phones []string) (bool, error) {
+	return hasPreviousOrder(ctx, r.db, memberID, phones)
+}
+
+func hasPreviousOrder(ctx context.Context, q rowQuerier, memberID *int, phones []string) (bool, error) {
+	var exists bool
+	err := q.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM orders
+			WHERE status IN ('paid', 'processing', 'success')
+			  AND (member_id = $1 OR ($1::int IS NULL AND customer_phone = ANY($2::text[])))
+		)
+	`, memberID, phones).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check previous orders: %w", err)
+	}
+	return exists, nil
+}
+
+// Create creates a new order.
+// When the order uses a promo or flash sale quota, the redemption and the insert
+// happen in a single transaction so limits cannot be exceeded concurrently.
+// Returns ErrPromoUnavailable / ErrPromoCustomerLimit / ErrPromoNotFirstOrder / ErrFlashSaleSoldOut if a limit was reached in the meantime.
 func (r *OrderRepository) Create(ctx context.Context, order *model.Order) error {
+	if order.PromoID == nil && order.FlashSaleItemID == nil {
+		return insertOrder(ctx, r.db, order)
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if order.PromoID != nil {
+		if err := redeemPromoTx(ctx, tx, order); err != nil {
+			return err
+		}
+	}
+
+	var lastUnit bool
+	if order.FlashSaleItemID != nil {
+		lastUnit, err = reserveFlashSaleTx(ctx, tx, *order.FlashSaleItemID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := insertOrder(ctx, tx, order); err != nil {
+		return err
+	}
+
+	if order.PromoID != nil {
+		if err := insertPromoRedemptionTx(ctx, tx, order); err != nil {
+			return err
+		}
+	}
+
+	// Sold out: revert the product to its normal price right away
+	if lastUnit {
+		if err := endFlashSaleItemTx(ctx, tx, *order.FlashSaleItemID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func insertOrder(ctx context.Context, q rowQuerier, order *model.Order) error {
 	// Default order source to website if empty
 	if order.OrderSource == "" {
 		order.OrderSource = "website"
 	}
 
+	var promoCode *string
+	if order.PromoCode != "" {
+		promoCode = &order.PromoCode
+	}
+
 	query := `
 		INSERT INTO orders (
 			ref_id, buyer_sku_code, product_name, customer_no,
 			buy_price, selling_price, status,
 			customer_email, customer_phone, customer_name,
-			member_id, member_price, order_source
+			member_id, member_price, order_source,
+			promo_id, promo_code, discount_amount, flash_sale_item_id,
+			client_ref_id
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17,
+			NULLIF($18, '')
 		)
 		RETURNING id, created_at, updated_at
 	`
 
-	err := r.db.QueryRow(ctx, query,
+	err := q.QueryRow(ctx, query,
 		order.RefID, order.BuyerSKUCode, order.ProductName, order.CustomerNo,
 		order.BuyPrice, order.SellingPrice, order.Status,
 		order.CustomerEmail, order.CustomerPhone, order.CustomerName,
 		order.MemberID, order.MemberPrice, order.OrderSource,
+		order.PromoID, promoCode, order.DiscountAmount, order.FlashSaleItemID,
+		order.ClientRefID,
 	).Scan(&order.ID, &order.CreatedAt, &order.UpdatedAt)
 
 	if err != nil {
@@ -88,6 +178,8 @@ func (r *OrderRepository) GetByID(ctx context.Context, id string) (*model.Order,
 		       COALESCE(digiflazz_status, ''), COALESCE(digiflazz_rc, ''), COALESCE(serial_number, ''), COALESCE(digiflazz_message, ''),
 		       COALESCE(customer_email, ''), COALESCE(customer_phone, ''), COALESCE(customer_name, ''),
 		       member_id, member_price,
+		       promo_id, COALESCE(promo_code, ''), COALESCE(discount_amount, 0),
+		       COALESCE(client_ref_id, ''), COALESCE(order_source, 'website'), COALESCE(retry_ref_id, ''),
 		       created_at, updated_at, completed_at
 		FROM orders
 		WHERE id = $1
@@ -100,6 +192,8 @@ func (r *OrderRepository) GetByID(ctx context.Context, id string) (*model.Order,
 		&o.DigiflazzStatus, &o.DigiflazzRC, &o.SerialNumber, &o.DigiflazzMsg,
 		&o.CustomerEmail, &o.CustomerPhone, &o.CustomerName,
 		&o.MemberID, &o.MemberPrice,
+		&o.PromoID, &o.PromoCode, &o.DiscountAmount,
+		&o.ClientRefID, &o.OrderSource, &o.RetryRefID,
 		&o.CreatedAt, &o.UpdatedAt, &o.CompletedAt,
 	)
 	if err != nil {
@@ -109,7 +203,7 @@ func (r *OrderRepository) GetByID(ctx context.Context, id string) (*model.Order,
 	return &o, nil
 }
 
-// GetByRefID retrieves an order by RefID (for Digiflazz webhook)
+// GetByRefID retrieves an order by RefID or manual topup retry ref (for Digiflazz webhook)
 func (r *OrderRepository) GetByRefID(ctx context.Context, refID string) (*model.Order, error) {
 	query := `
 		SELECT id, ref_id, buyer_sku_code, product_name, customer_no,
@@ -117,9 +211,11 @@ func (r *OrderRepository) GetByRefID(ctx context.Context, refID string) (*model.
 		       COALESCE(digiflazz_status, ''), COALESCE(digiflazz_rc, ''), COALESCE(serial_number, ''), COALESCE(digiflazz_message, ''),
 		       COALESCE(customer_email, ''), COALESCE(customer_phone, ''), COALESCE(customer_name, ''),
 		       member_id, member_price,
+		       promo_id, COALESCE(promo_code, ''), COALESCE(discount_amount, 0),
+		       COALESCE(client_ref_id, ''), COALESCE(order_source, 'website'), COALESCE(retry_ref_id, ''),
 		       created_at, updated_at, completed_at
 		FROM orders
-		WHERE ref_id = $1
+		WHERE ref_id = $1 OR retry_ref_id = $1
 	`
 
 	var o model.Order
@@ -129,6 +225,8 @@ func (r *OrderRepository) GetByRefID(ctx context.Context, refID string) (*model.
 		&o.DigiflazzStatus, &o.DigiflazzRC, &o.SerialNumber, &o.DigiflazzMsg,
 		&o.CustomerEmail, &o.CustomerPhone, &o.CustomerName,
 		&o.MemberID, &o.MemberPrice,
+		&o.PromoID, &o.PromoCode, &o.DiscountAmount,
+		&o.ClientRefID, &o.OrderSource, &o.RetryRefID,
 		&o.CreatedAt, &o.UpdatedAt, &o.CompletedAt,
 	)
 	if err != nil {
@@ -138,6 +236,21 @@ func (r *OrderRepository) GetByRefID(ctx context.Context, refID string) (*model.
 	return &o, nil
 }
 
+// GetByClientRefID retrieves a member order by the reseller's own ref ID (H2H).
+// Returns nil if not found.
+func (r *OrderRepository) GetByClientRefID(ctx context.Context, memberID int, clientRefID string) (*model.Order, error) {
+	var id string
+	err := r.db.QueryRow(ctx, `SELECT id FROM orders WHERE member_id = $1 AND client_ref_id = $2`, memberID, clientRefID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order by client_ref_id: %w", err)
+	}
+
+	return r.GetByID(ctx, id)
+}
+
 // UpdateStatus updates the order status
 func (r *OrderRepository) UpdateStatus(ctx context.Context, id string, status model.OrderStatus) error {
 	query := `UPDATE orders SET status = $2, updated_at = NOW() WHERE id = $1`
@@ -150,10 +263,12 @@ func (r *OrderRepository) UpdateStatus(ctx context.Context, id string, status mo
 	return nil
 }
 
-// UpdateDigiflazzResponse updates the order with Digiflazz response
-func (r *OrderRepository) UpdateDigiflazzResponse(ctx context.Context, id string, status model.OrderStatus, dfStatus, rc, sn, message string) error {
+// UpdateDigiflazzResponse records a Digiflazz result on an order that is still open
+// (not yet success, failed, expired, cancelled or refunded). Returns false when
+// nothing changed: the order was already settled, or the result repeats the
+// current one. Only StartRetry reopens a failed order.
+func (r *OrderRepository) UpdateDigiflazzResponse(ctx context.Context, id string, status model.OrderStatus, dfStatus, rc, sn, message string) (bool, error) {
 	var query string
-	var args []interface{}
 
 	if status == model.OrderStatusSuccess || status == model.OrderStatusFailed {
 		query = `
@@ -161,38 +276,46 @@ func (r *OrderRepository) UpdateDigiflazzResponse(ctx context.Context, id string
 				status = $2, digiflazz_status = $3, digiflazz_rc = $4, 
 				serial_number = $5, digiflazz_message = $6,
 				updated_at = NOW(), completed_at = NOW()
-			WHERE id = $1
+			WHERE id = $1 AND status IN ('pending', 'waiting_payment', 'paid', 'processing')
 		`
-		args = []interface{}{id, status, dfStatus, rc, sn, message}
 	} else {
 		query = `
 			UPDATE orders SET 
 				status = $2, digiflazz_status = $3, digiflazz_rc = $4, 
 				serial_number = $5, digiflazz_message = $6,
 				updated_at = NOW()
-			WHERE id = $1
+			WHERE id = $1 AND status IN ('pending', 'waiting_payment', 'paid', 'processing')
+			  AND (status <> $2 OR digiflazz_status IS DISTINCT FROM $3 OR digiflazz_rc IS DISTINCT FROM $4)
 		`
-		args = []interface{}{id, status, dfStatus, rc, sn, message}
 	}
 
-	_, err := r.db.Exec(ctx, query, args...)
+	result, err := r.db.Exec(ctx, query, id, status, dfStatus, rc, sn, message)
 	if err != nil {
-		return fmt.Errorf("failed to update digiflazz response: %w", err)
+		return false, fmt.Errorf("failed to update digiflazz response: %w", err)
 	}
 
-	return nil
+	return result.RowsAffected() > 0, nil
 }
 
-// UpdateCustomerNo updates the customer number (for manual topup retries)
-func (r *OrderRepository) UpdateCustomerNo(ctx context.Context, id, customerNo string) error {
-	query := `UPDATE orders SET customer_no = $2, updated_at = NOW() WHERE id = $1`
+// StartRetry moves a failed order back to processing for a manual topup retry
+// under a new Digiflazz ref, clearing the previous result. Returns false when the
+// order is no longer failed (e.g. another retry already started).
+func (r *OrderRepository) StartRetry(ctx context.Context, id, retryRefID, customerNo string) (bool, error) {
+	query := `
+		UPDATE orders SET
+			status = 'processing', retry_ref_id = $2, customer_no = $3,
+			digiflazz_status = NULL, digiflazz_rc = NULL,
+			serial_number = NULL, digiflazz_message = NULL,
+			completed_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND status = 'failed'
+	`
 
-	_, err := r.db.Exec(ctx, query, id, customerNo)
+	tag, err := r.db.Exec(ctx, query, id, retryRefID, customerNo)
 	if err != nil {
-		return fmt.Errorf("failed to update customer_no: %w", err)
+		return false, fmt.Errorf("failed to start order retry: %w", err)
 	}
 
-	return nil
+	return tag.RowsAffected() == 1, nil
 }
 
 // GetTotalRevenue calculate total revenue from successful orders
@@ -284,7 +407,8 @@ func (r *OrderRepository) getAllInternal(ctx context.Context, limit, offset int,
 		       COALESCE(customer_email, ''), COALESCE(customer_phone, ''), COALESCE(customer_name, ''),
 		       created_at, updated_at, completed_at,
 		       COALESCE(order_source, 'website'), COALESCE(admin_notes, ''),
-		       member_id, member_price
+		       member_id, member_price,
+		       promo_id, COALESCE(promo_code, ''), COALESCE(discount_amount, 0)
 		FROM orders
 		%s
 		ORDER BY created_at DESC
@@ -310,6 +434,7 @@ func (r *OrderRepository) getAllInternal(ctx context.Context, limit, offset int,
 			&o.CreatedAt, &o.UpdatedAt, &o.CompletedAt,
 			&o.OrderSource, &o.AdminNotes,
 			&o.MemberID, &o.MemberPrice,
+			&o.PromoID, &o.PromoCode, &o.DiscountAmount,
 		)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan order: %w", err)
@@ -440,6 +565,7 @@ func (r *OrderRepository) GetByMemberID(ctx context.Context, memberID, limit, of
 		       COALESCE(digiflazz_status, ''), COALESCE(digiflazz_rc, ''), COALESCE(serial_number, ''), COALESCE(digiflazz_message, ''),
 		       COALESCE(customer_email, ''), COALESCE(customer_phone, ''), COALESCE(customer_name, ''),
 		       member_id, member_price,
+		       promo_id, COALESCE(promo_code, ''), COALESCE(discount_amount, 0),
 		       created_at, updated_at, completed_at
 		FROM orders
 		%s
@@ -464,6 +590,7 @@ func (r *OrderRepository) GetByMemberID(ctx context.Context, memberID, limit, of
 			&o.DigiflazzStatus, &o.DigiflazzRC, &o.SerialNumber, &o.DigiflazzMsg,
 			&o.CustomerEmail, &o.CustomerPhone, &o.CustomerName,
 			&o.MemberID, &o.MemberPrice,
+			&o.PromoID, &o.PromoCode, &o.DiscountAmount,
 			&o.CreatedAt, &o.UpdatedAt, &o.CompletedAt,
 		)
 		if err != nil {
@@ -475,25 +602,83 @@ func (r *OrderRepository) GetByMemberID(ctx context.Context, memberID, limit, of
 	return orders, total, nil
 }
 
-// CleanupExpiredPendingOrders cancels orders that have been pending too long
-func (r *OrderRepository) CleanupExpiredPendingOrders(ctx context.Context, maxAge time.Duration) (int, error) {
+// MarkPaid moves an unpaid order to paid. Returns false when the order is no
+// longer pending (already paid by an earlier notification, or expired).
+func (r *OrderRepository) MarkPaid(ctx context.Context, id string) (bool, error) {
+	query := `UPDATE orders SET status = 'paid', updated_at = NOW() WHERE id = $1 AND status IN ('pending', 'waiting_payment')`
+
+	result, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark order paid: %w", err)
+	}
+
+	return result.RowsAffected() > 0, nil
+}
+
+// ExpireUnpaid marks an unpaid order and its pending payment as expired.
+// Returns false if the order was paid or closed in the meantime.
+func (r *OrderRepository) ExpireUnpaid(ctx context.Context, id string) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `
+		UPDATE orders SET status = $2, updated_at = NOW()
+		WHERE id = $1 AND status IN ($3, $4)
+	`, id, model.OrderStatusExpired, model.OrderStatusPending, model.OrderStatusWaitingPayment)
+	if err != nil {
+		return false, fmt.Errorf("failed to expire order: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE payments SET status = $2 WHERE order_id = $1 AND status = $3
+	`, id, model.PaymentStatusExpired, model.PaymentStatusPending)
+	if err != nil {
+		return false, fmt.Errorf("failed to expire payment: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+// paymentExpiryGrace is how long after payments.expired_at an unpaid order still
+// holds its flash sale quota and promo usage, so a payment completed right at the
+// deadline and reported late can still be fulfilled
+const paymentExpiryGrace = `INTERVAL '10 minutes'`
+
+// GetOverdueUnpaidIDs returns unpaid orders whose payment expired more than
+// paymentExpiryGrace ago, and pending orders without a payment older than pendingMaxAge
+func (r *OrderRepository) GetOverdueUnpaidIDs(ctx context.Context, pendingMaxAge time.Duration) ([]string, error) {
 	query := `
-		UPDATE orders 
-		SET status = 'cancelled', updated_at = NOW()
-		WHERE status = 'pending' AND created_at < NOW() - $1::interval
-		RETURNING id
+		SELECT DISTINCT o.id::text
+		FROM orders o
+		LEFT JOIN payments p ON p.order_id = o.id
+		WHERE o.status IN ('pending', 'waiting_payment')
+		  AND ((p.status <> 'completed' AND p.expired_at < NOW() - ` + paymentExpiryGrace + `)
+		       OR (p.id IS NULL AND o.created_at < NOW() - make_interval(secs => $1)))
+		LIMIT 500
 	`
 
-	rows, err := r.db.Query(ctx, query, maxAge.String())
+	rows, err := r.db.Query(ctx, query, pendingMaxAge.Seconds())
 	if err != nil {
-		return 0, fmt.Errorf("failed to cleanup expired orders: %w", err)
+		return nil, fmt.Errorf("failed to get overdue unpaid orders: %w", err)
 	}
 	defer rows.Close()
 
-	count := 0
+	var ids []string
 	for rows.Next() {
-		count++
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan order id: %w", err)
+		}
+		ids = append(ids, id)
 	}
-
-	return count, nil
+	return ids, nil
 }
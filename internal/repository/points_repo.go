@@ -0,0 +1,426 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"govershop-api/internal/model"
+)
+
+// ErrInsufficientPoints is returned when a member redeems more points than available
+var ErrInsufficientPoints = errors.New("insufficient points")
+
+// PointsRepository handles database operations for loyalty points
+type PointsRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewPointsRepository creates a new PointsRepository
+func NewPointsRepository(db *pgxpool.Pool) *PointsRepository {
+	return &PointsRepository{db: db}
+}
+
+// ==========================================
+// EARN RULES
+// ==========================================
+
+const pointRuleColumns = `
+	id, name, brand, category, earn_type, earn_value,
+	COALESCE(min_order_amount, 0), expiry_days, COALESCE(is_active, false), created_at, updated_at
+`
+
+func scanPointRule(row pgx.Row) (*model.PointRule, error) {
+	var rule model.PointRule
+	err := row.Scan(
+		&rule.ID, &rule.Name, &rule.Brand, &rule.Category, &rule.EarnType, &rule.EarnValue,
+		&rule.MinOrderAmount, &rule.ExpiryDays, &rule.IsActive, &rule.CreatedAt, &rule.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// GetRules retrieves point rules (activeOnly filters inactive rules)
+func (r *PointsRepository) GetRules(ctx context.Context, activeOnly bool) ([]model.PointRule, error) {
+	query := `SELECT ` + pointRuleColumns + ` FROM point_rules`
+	if activeOnly {
+		query += ` WHERE is_active = true`
+	}
+	query += ` ORDER BY id DESC`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get point rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []model.PointRule
+	for rows.Next() {
+		rule, err := scanPointRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan point rule: %w", err)
+		}
+		rules = append(rules, *rule)
+	}
+
+	return rules, nil
+}
+
+// GetRuleByID retrieves a point rule by ID
+func (r *PointsRepository) GetRuleByID(ctx context.Context, id int64) (*model.PointRule, error) {
+	query := `SELECT ` + pointRuleColumns + ` FROM point_rules WHERE id = $1`
+
+	rule, err := scanPointRule(r.db.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get point rule: %w", err)
+	}
+
+	return rule, nil
+}
+
+// CreateRule creates a new point rule
+func (r *PointsRepository) CreateRule(ctx context.Context, rule *model.PointRule) error {
+	query := `
+		INSERT INTO point_rules (name, brand, category, earn_type, earn_value, min_order_amount, expiry_days, is_active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at, updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		rule.Name, rule.Brand, rule.Category, rule.EarnType, rule.EarnValue,
+		rule.MinOrderAmount, rule.ExpiryDays, rule.IsActive,
+	).Scan(&rule.ID, &rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create point rule: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateRule updates a point rule
+func (r *PointsRepository) UpdateRule(ctx context.Context, rule *model.PointRule) error {
+	query := `
+		UPDATE point_rules SET
+			name = $2, brand = $3, category = $4, earn_type = $5, earn_value = $6,
+			min_order_amount = $7, expiry_days = $8, is_active = $9, updated_at = NOW()
+		WHERE id = $1
+	`
+
+	result, err := r.db.Exec(ctx, query,
+		rule.ID, rule.Name, rule.Brand, rule.Category, rule.EarnType, rule.EarnValue,
+		rule.MinOrderAmount, rule.ExpiryDays, rule.IsActive,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update point rule: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("point rule not found")
+	}
+
+	return nil
+}
+
+// DeleteRule deletes a point rule (earned points are kept)
+func (r *PointsRepository) DeleteRule(ctx context.Context, id int64) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM point_rules WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete point rule: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("point rule not found")
+	}
+	return nil
+}
+
+// ==========================================
+// LEDGER
+// ==========================================
+
+// Earn records points earned by an order, expiring after expiryDays.
+// Returns false if the order already earned points.
+func (r *PointsRepository) Earn(ctx context.Context, entry *model.PointEntry, expiryDays int) (bool, error) {
+	query := `
+		INSERT INTO member_points (user_id, type, points, remaining, order_id, rule_id, reference_id, description, expires_at)
+		VALUES ($1, 'earn', $2, $2, $3, $4, $5, $6, NOW() + make_interval(days => $7))
+		ON CONFLICT (order_id) WHERE type = 'earn' DO NOTHING
+		RETURNING id, expires_at, created_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		entry.UserID, entry.Points, entry.OrderID, entry.RuleID,
+		entry.ReferenceID, entry.Description, expiryDays,
+	).Scan(&entry.ID, &entry.ExpiresAt, &entry.CreatedAt)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to record earned points: %w", err)
+	}
+
+	entry.Type = model.PointTypeEarn
+	entry.Remaining = entry.Points
+	return true, nil
+}
+
+// Clawback takes back the points earned by a refunded order.
+// Unspent points of the order go first; points already spent are taken from other available points.
+// Returns the number of points clawed back (0 if nothing was earned or already clawed back).
+func (r *PointsRepository) Clawback(ctx context.Context, orderID, refID string) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var earnID, userID, points int
+	err = tx.QueryRow(ctx, `
+		SELECT id, user_id, points FROM member_points
+		WHERE order_id = $1 AND type = 'earn'
+	`, orderID).Scan(&earnID, &userID, &points)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get earned points: %w", err)
+	}
+
+	if err := lockUserTx(ctx, tx, userID); err != nil {
+		return 0, err
+	}
+
+	var exists bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1 FROM member_points WHERE order_id = $1 AND type = 'clawback')
+	`, orderID).Scan(&exists)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check clawback: %w", err)
+	}
+	if exists {
+		return 0, nil
+	}
+
+	taken, err := consumePointsTx(ctx, tx, userID, points, earnID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO member_points (user_id, type, points, order_id, reference_id, description)
+		VALUES ($1, 'clawback', $2, $3, $4, $5)
+	`, userID, taken, orderID, refID, fmt.Sprintf("Pembatalan poin %s", refID))
+	if err != nil {
+		return 0, fmt.Errorf("failed to record clawback: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit clawback: %w", err)
+	}
+
+	return taken, nil
+}
+
+// Redeem converts points into balance (deposits.type = 'points_redeem') in one transaction
+func (r *PointsRepository) Redeem(ctx context.Context, userID, points int, rate float64, referenceID string) (float64, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockUserTx(ctx, tx, userID); err != nil {
+		return 0, err
+	}
+
+	available, err := availablePointsTx(ctx, tx, userID)
+	if err != nil {
+		return 0, err
+	}
+	if available < points {
+		return 0, ErrInsufficientPoints
+	}
+
+	if _, err := consumePointsTx(ctx, tx, userID, points, 0); err != nil {
+		return 0, err
+	}
+
+	description := fmt.Sprintf("Tukar %d poin ke saldo", points)
+	_, err = tx.Exec(ctx, `
+		INSERT INTO member_points (user_id, type, points, reference_id, description)
+		VALUES ($1, 'redeem', $2, $3, $4)
+	`, userID, points, referenceID, description)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record redeem: %w", err)
+	}
+
+	amount := float64(points) * rate
+	if err := creditBalanceTx(ctx, tx, userID, amount, model.DepositTypePointsRedeem, description, referenceID, "SYSTEM"); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit redeem: %w", err)
+	}
+
+	return amount, nil
+}
+
+// ExpirePoints zeroes out unspent points past their expiry and records expire entries
+func (r *PointsRepository) ExpirePoints(ctx context.Context) (int64, error) {
+	query := `
+		WITH due AS (
+			SELECT id, user_id, remaining FROM member_points
+			WHERE type = 'earn' AND remaining > 0 AND expires_at <= NOW()
+			FOR UPDATE SKIP LOCKED
+		), expired AS (
+			UPDATE member_points mp SET remaining = 0
+			FROM due WHERE mp.id = due.id
+			RETURNING due.id, due.user_id, due.remaining
+		)
+		INSERT INTO member_points (user_id, type, points, reference_id, description)
+		SELECT user_id, 'expire', remaining, 'EXP-' || id, 'Poin kedaluwarsa'
+		FROM expired
+	`
+
+	result, err := r.db.Exec(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire points: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// GetSummary returns the available points balance and upcoming expiry for a member
+func (r *PointsRepository) GetSummary(ctx context.Context, userID int) (*model.PointsSummary, error) {
+	query := `
+		SELECT
+			COALESCE(SUM(remaining), 0),
+			COALESCE(SUM(remaining) FILTER (WHERE expires_at <= NOW() + INTERVAL '7 days'), 0),
+			MIN(expires_at)
+		FROM member_points
+		WHERE user_id = $1 AND type = 'earn' AND remaining > 0
+		  AND (expires_at IS NULL OR expires_at > NOW())
+	`
+
+	var summary model.PointsSummary
+	err := r.db.QueryRow(ctx, query, userID).Scan(&summary.Balance, &summary.ExpiringPoints, &summary.NextExpiry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get points summary: %w", err)
+	}
+
+	return &summary, nil
+}
+
+// GetHistory retrieves the points ledger for a member
+func (r *PointsRepository) GetHistory(ctx context.Context, userID, limit, offset int) ([]model.PointEntry, int, error) {
+	var total int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM member_points WHERE user_id = $1`, userID).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count points history: %w", err)
+	}
+
+	query := `
+		SELECT id, user_id, type, points, remaining, order_id::text, rule_id,
+		       reference_id, description, expires_at, created_at
+		FROM member_points
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.Query(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get points history: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []model.PointEntry
+	for rows.Next() {
+		var e model.PointEntry
+		err := rows.Scan(
+			&e.ID, &e.UserID, &e.Type, &e.Points, &e.Remaining, &e.OrderID, &e.RuleID,
+			&e.ReferenceID, &e.Description, &e.ExpiresAt, &e.CreatedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan points entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, total, nil
+}
+
+// lockUserTx locks the user row so points/balance changes for one member are serialized
+func lockUserTx(ctx context.Context, tx pgx.Tx, userID int) error {
+	var id int
+	if err := tx.QueryRow(ctx, `SELECT id FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&id); err != nil {
+		return fmt.Errorf("failed to lock user: %w", err)
+	}
+	return nil
+}
+
+// availablePointsTx returns the unspent, unexpired points of a member
+func availablePointsTx(ctx context.Context, tx pgx.Tx, userID int) (int, error) {
+	var available int
+	err := tx.QueryRow(ctx, `
+		SELECT COALESCE(SUM(remaining), 0) FROM member_points
+		WHERE user_id = $1 AND type = 'earn' AND remaining > 0
+		  AND (expires_at IS NULL OR expires_at > NOW())
+	`, userID).Scan(&available)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get available points: %w", err)
+	}
+	return available, nil
+}
+
+// consumePointsTx spends up to `points` from earn entries, soonest expiry first.
+// preferID (if > 0) is consumed before any other entry. Returns the points actually consumed.
+func consumePointsTx(ctx context.Context, tx pgx.Tx, userID, points, preferID int) (int, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT id, remaining FROM member_points
+		WHERE user_id = $1 AND type = 'earn' AND remaining > 0
+		  AND (expires_at IS NULL OR expires_at > NOW())
+		ORDER BY (id = $2) DESC, expires_at ASC NULLS LAST, id ASC
+		FOR UPDATE
+	`, userID, preferID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get available points: %w", err)
+	}
+
+	type lot struct{ id, remaining int }
+	var lots []lot
+	for rows.Next() {
+		var l lot
+		if err := rows.Scan(&l.id, &l.remaining); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan points entry: %w", err)
+		}
+		lots = append(lots, l)
+	}
+	rows.Close()
+
+	consumed := 0
+	for _, l := range lots {
+		if consumed >= points {
+			break
+		}
+		take := l.remaining
+		if take > points-consumed {
+			take = points - consumed
+		}
+		if _, err := tx.Exec(ctx, `UPDATE member_points SET remaining = remaining - $1 WHERE id = $2`, take, l.id); err != nil {
+			return 0, fmt.Errorf("failed to consume points: %w", err)
+		}
+		consumed += take
+	}
+
+	return consumed, nil
+}
@@ -0,0 +1,358 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"govershop-api/internal/model"
+)
+
+// ErrPromoUnavailable is returned when a promo can no longer be redeemed
+// (inactive, outside its validity period, or usage limit reached)
+var ErrPromoUnavailable = errors.New("promo unavailable")
+
+// ErrPromoCustomerLimit is returned when the customer already used the promo as many times as allowed
+var ErrPromoCustomerLimit = errors.New("promo customer limit reached")
+
+// ErrPromoNotFirstOrder is returned when a first-order promo is used by a customer with earlier orders
+var ErrPromoNotFirstOrder = errors.New("promo is for first orders only")
+
+// PromoRepository handles database operations for promos
+type PromoRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewPromoRepository creates a new PromoRepository
+func NewPromoRepository(db *pgxpool.Pool) *PromoRepository {
+	return &PromoRepository{db: db}
+}
+
+// GetAll retrieves promos for admin with optional search and status filter
+func (r *PromoRepository) GetAll(ctx context.Context, limit, offset int, search, status string) ([]model.Promo, int, error) {
+	whereClause := " WHERE 1=1"
+	var args []interface{}
+	argCounter := 1
+
+	if search != "" {
+		whereClause += fmt.Sprintf(" AND (name ILIKE $%d OR code ILIKE $%d)", argCounter, argCounter)
+		args = append(args, "%"+search+"%")
+		argCounter++
+	}
+
+	switch status {
+	case "active":
+		whereClause += " AND is_active = true AND NOW() BETWEEN start_date AND end_date"
+	case "inactive":
+		whereClause += " AND is_active = false"
+	case "expired":
+		whereClause += " AND end_date < NOW()"
+	case "scheduled":
+		whereClause += " AND start_date > NOW()"
+	}
+
+	var total int
+	countQuery := "SELECT COUNT(*) FROM promos" + whereClause
+	if err := r.db.QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count promos: %w", err)
+	}
+
+	query := `
+		SELECT id, name, code, COALESCE(description, ''), discount_type, discount_value,
+		       COALESCE(min_purchase, 0), max_discount, usage_limit, COALESCE(usage_count, 0),
+		       category, brand, buyer_sku_code, per_customer_limit, COALESCE(first_order_only, false),
+		       start_date, end_date, is_active, created_at, updated_at
+		FROM promos
+	` + whereClause + fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d OFFSET $%d", argCounter, argCounter+1)
+
+	args = append(args, limit, offset)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query promos: %w", err)
+	}
+	defer rows.Close()
+
+	var promos []model.Promo
+	for rows.Next() {
+		var p model.Promo
+		err := rows.Scan(
+			&p.ID, &p.Name, &p.Code, &p.Description, &p.DiscountType, &p.DiscountValue,
+			&p.MinPurchase, &p.MaxDiscount, &p.UsageLimit, &p.UsageCount,
+			&p.Category, &p.Brand, &p.BuyerSKUCode, &p.PerCustomerLimit, &p.FirstOrderOnly,
+			&p.StartDate, &p.EndDate, &p.IsActive, &p.CreatedAt, &p.UpdatedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan promo: %w", err)
+		}
+		promos = append(promos, p)
+	}
+
+	return promos, total, nil
+}
+
+// GetByID retrieves a promo by ID
+func (r *PromoRepository) GetByID(ctx context.Context, id int64) (*model.Promo, error) {
+	query := `
+		SELECT id, name, code, COALESCE(description, ''), discount_type, discount_value,
+		       COALESCE(min_purchase, 0), max_discount, usage_limit, COALESCE(usage_count, 0),
+		       category, brand, buyer_sku_code, per_customer_limit, COALESCE(first_order_only, false),
+		       start_date, end_date, is_active, created_at, updated_at
+		FROM promos
+		WHERE id = $1
+	`
+
+	var p model.Promo
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&p.ID, &p.Name, &p.Code, &p.Description, &p.DiscountType, &p.DiscountValue,
+		&p.MinPurchase, &p.MaxDiscount, &p.UsageLimit, &p.UsageCount,
+		&p.Category, &p.Brand, &p.BuyerSKUCode, &p.PerCustomerLimit, &p.FirstOrderOnly,
+		&p.StartDate, &p.EndDate, &p.IsActive, &p.CreatedAt, &p.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get promo: %w", err)
+	}
+
+	return &p, nil
+}
+
+// GetByCode retrieves a promo by its code (case-insensitive)
+func (r *PromoRepository) GetByCode(ctx context.Context, code string) (*model.Promo, error) {
+	query := `
+		SELECT id, name, code, COALESCE(description, ''), discount_type, discount_value,
+		       COALESCE(min_purchase, 0), max_discount, usage_limit, COALESCE(usage_count, 0),
+		       category, brand, buyer_sku_code, per_customer_limit, COALESCE(first_order_only, false),
+		       start_date, end_date, is_active, created_at, updated_at
+		FROM promos
+		WHERE code = $1
+	`
+
+	var p model.Promo
+	err := r.db.QueryRow(ctx, query, strings.ToUpper(strings.TrimSpace(code))).Scan(
+		&p.ID, &p.Name, &p.Code, &p.Description, &p.DiscountType, &p.DiscountValue,
+		&p.MinPurchase, &p.MaxDiscount, &p.UsageLimit, &p.UsageCount,
+		&p.Category, &p.Brand, &p.BuyerSKUCode, &p.PerCustomerLimit, &p.FirstOrderOnly,
+		&p.StartDate, &p.EndDate, &p.IsActive, &p.CreatedAt, &p.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get promo by code: %w", err)
+	}
+
+	return &p, nil
+}
+
+// Create creates a new promo
+func (r *PromoRepository) Create(ctx context.Context, p *model.Promo) error {
+	query := `
+		INSERT INTO promos (
+			name, code, description, discount_type, discount_value,
+			min_purchase, max_discount, usage_limit,
+			category, brand, buyer_sku_code, per_customer_limit, first_order_only,
+			start_date, end_date, is_active
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		RETURNING id, usage_count, created_at, updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		p.Name, p.Code, p.Description, p.DiscountType, p.DiscountValue,
+		p.MinPurchase, p.MaxDiscount, p.UsageLimit,
+		p.Category, p.Brand, p.BuyerSKUCode, p.PerCustomerLimit, p.FirstOrderOnly,
+		p.StartDate, p.EndDate, p.IsActive,
+	).Scan(&p.ID, &p.UsageCount, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create promo: %w", err)
+	}
+
+	return nil
+}
+
+// Update updates a promo (usage_count is never touched here)
+func (r *PromoRepository) Update(ctx context.Context, p *model.Promo) error {
+	query := `
+		UPDATE promos SET
+			name = $2, code = $3, description = $4, discount_type = $5, discount_value = $6,
+			min_purchase = $7, max_discount = $8, usage_limit = $9,
+			category = $10, brand = $11, buyer_sku_code = $12,
+			per_customer_limit = $13, first_order_only = $14,
+			start_date = $15, end_date = $16, is_active = $17, updated_at = NOW()
+		WHERE id = $1
+	`
+
+	result, err := r.db.Exec(ctx, query,
+		p.ID, p.Name, p.Code, p.Description, p.DiscountType, p.DiscountValue,
+		p.MinPurchase, p.MaxDiscount, p.UsageLimit,
+		p.Category, p.Brand, p.BuyerSKUCode,
+		p.PerCustomerLimit, p.FirstOrderOnly,
+		p.StartDate, p.EndDate, p.IsActive,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update promo: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("promo not found")
+	}
+
+	return nil
+}
+
+// Delete deletes a promo. Orders keep their promo_code snapshot.
+func (r *PromoRepository) Delete(ctx context.Context, id int64) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM promos WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete promo: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("promo not found")
+	}
+
+	return nil
+}
+
+// ReverseRedemption gives back the promo usage of a failed/cancelled/refunded order.
+// Safe to call more than once: only active redemptions are reversed.
+func (r *PromoRepository) ReverseRedemption(ctx context.Context, orderID string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var promoID int64
+	err = tx.QueryRow(ctx, `
+		UPDATE promo_redemptions SET status = 'reversed', reversed_at = NOW()
+		WHERE order_id = $1 AND status = 'active'
+		RETURNING promo_id
+	`, orderID).Scan(&promoID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil // No promo used or already reversed
+	}
+	if err != nil {
+		return fmt.Errorf("failed to reverse promo redemption: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE promos SET usage_count = GREATEST(COALESCE(usage_count, 0) - 1, 0), updated_at = NOW()
+		WHERE id = $1
+	`, promoID)
+	if err != nil {
+		return fmt.Errorf("failed to decrement promo usage: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// redeemPromoTx atomically increments usage_count inside an existing transaction and
+// enforces per-customer limits. The promo row is locked by the UPDATE, so concurrent
+// redemptions cannot exceed usage_limit or per_customer_limit.
+func redeemPromoTx(ctx context.Context, tx pgx.Tx, order *model.Order) error {
+	query := `
+		UPDATE promos
+		SET usage_count = COALESCE(usage_count, 0) + 1, updated_at = NOW()
+		WHERE id = $1
+		  AND is_active = true
+		  AND NOW() BETWEEN start_date AND end_date
+		  AND (usage_limit IS NULL OR COALESCE(usage_count, 0) < usage_limit)
+		RETURNING per_customer_limit, COALESCE(first_order_only, false)
+	`
+
+	var perCustomerLimit *int
+	var firstOrderOnly bool
+	err := tx.QueryRow(ctx, query, *order.PromoID).Scan(&perCustomerLimit, &firstOrderOnly)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrPromoUnavailable
+	}
+	if err != nil {
+		return fmt.Errorf("failed to redeem promo: %w", err)
+	}
+
+	if perCustomerLimit == nil && !firstOrderOnly {
+		return nil
+	}
+
+	// First-order promos can only be redeemed once per customer
+	limit := 1
+	if perCustomerLimit != nil && (!firstOrderOnly || *perCustomerLimit < limit) {
+		limit = *perCustomerLimit
+	}
+
+	memberID, phone, email := promoCustomerKeys(order)
+	if memberID == nil && phone == nil && email == nil {
+		return ErrPromoCustomerLimit
+	}
+
+	var used int
+	err = tx.QueryRow(ctx, `
+		SELECT COUNT(*) FROM promo_redemptions
+		WHERE promo_id = $1 AND status = 'active'
+		  AND (member_id = $2 OR customer_phone = $3 OR customer_email = $4)
+	`, *order.PromoID, memberID, phone, email).Scan(&used)
+	if err != nil {
+		return fmt.Errorf("failed to count promo redemptions: %w", err)
+	}
+
+	if used >= limit {
+		return ErrPromoCustomerLimit
+	}
+
+	// Checked under the promo row lock so concurrent first orders cannot both pass
+	if firstOrderOnly {
+		var phones []string
+		if order.MemberID == nil {
+			phones = model.PhoneVariants(order.CustomerPhone)
+		}
+		hasOrder, err := hasPreviousOrder(ctx, tx, order.MemberID, phones)
+		if err != nil {
+			return err
+		}
+		if hasOrder {
+			return ErrPromoNotFirstOrder
+		}
+	}
+
+	return nil
+}
+
+// insertPromoRedemptionTx records the redemption once the order row exists
+func insertPromoRedemptionTx(ctx context.Context, tx pgx.Tx, order *model.Order) error {
+	memberID, phone, email := promoCustomerKeys(order)
+
+	_, err := tx.Exec(ctx, `
+		INSERT INTO promo_redemptions (promo_id, order_id, member_id, customer_phone, customer_email, discount_amount)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, *order.PromoID, order.ID, memberID, phone, email, order.DiscountAmount)
+	if err != nil {
+		return fmt.Errorf("failed to record promo redemption: %w", err)
+	}
+
+	return nil
+}
+
+// promoCustomerKeys returns the customer identity used for per-customer limits (nil = unknown)
+func promoCustomerKeys(order *model.Order) (memberID *int, phone, email *string) {
+	if order.MemberID != nil {
+		return order.MemberID, nil, nil
+	}
+	if p := model.NormalizePhone(order.CustomerPhone); p != "" {
+		phone = &p
+	}
+	if e := model.NormalizeEmail(order.CustomerEmail); e != "" {
+		email = &e
+	}
+	return nil, phone, email
+}
@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"govershop-api/internal/model"
+)
+
+// ReferralRepository handles database operations for referral commissions
+type ReferralRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewReferralRepository creates a new ReferralRepository
+func NewReferralRepository(db *pgxpool.Pool) *ReferralRepository {
+	return &ReferralRepository{db: db}
+}
+
+// CreateCommission records a pending commission for an order.
+// Returns false if the order already has a commission (webhooks can arrive more than once).
+func (r *ReferralRepository) CreateCommission(ctx context.Context, c *model.ReferralCommission) (bool, error) {
+	query := `
+		INSERT INTO referral_commissions (
+			referrer_id, referred_id, order_id, ref_id,
+			commission_type, commission_value, order_margin, amount, status
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, 'pending')
+		ON CONFLICT (order_id) DO NOTHING
+		RETURNING id, status, created_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		c.ReferrerID, c.ReferredID, c.OrderID, c.RefID,
+		c.CommissionType, c.CommissionValue, c.OrderMargin, c.Amount,
+	).Scan(&c.ID, &c.Status, &c.CreatedAt)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create referral commission: %w", err)
+	}
+
+	return true, nil
+}
+
+// MarkCredited marks a pending commission as credited.
+// Returns false if the commission was reversed in the meantime.
+func (r *ReferralRepository) MarkCredited(ctx context.Context, id int) (bool, error) {
+	query := `
+		UPDATE referral_commissions
+		SET status = 'credited', credited_at = NOW()
+		WHERE id = $1 AND status = 'pending'
+	`
+
+	result, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark referral commission credited: %w", err)
+	}
+
+	return result.RowsAffected() > 0, nil
+}
+
+// Reverse marks the commission of an order as reversed.
+// Returns the commission and whether it had already been credited (and so must be clawed back),
+// or nil if the order has no active commission.
+func (r *ReferralRepository) Reverse(ctx context.Context, orderID string) (*model.ReferralCommission, bool, error) {
+	query := `
+		WITH prev AS (
+			SELECT id, status FROM referral_commissions
+			WHERE order_id = $1 AND status <> 'reversed'
+			FOR UPDATE
+		)
+		UPDATE referral_commissions rc
+		SET status = 'reversed', reversed_at = NOW()
+		FROM prev
+		WHERE rc.id = prev.id
+		RETURNING rc.id, rc.referrer_id, rc.referred_id, rc.ref_id, rc.amount, prev.status
+	`
+
+	var c model.ReferralCommission
+	var prevStatus string
+	err := r.db.QueryRow(ctx, query, orderID).Scan(
+		&c.ID, &c.ReferrerID, &c.ReferredID, &c.RefID, &c.Amount, &prevStatus,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to reverse referral commission: %w", err)
+	}
+
+	c.OrderID = orderID
+	c.Status = model.ReferralStatusReversed
+	return &c, prevStatus == model.ReferralStatusCredited, nil
+}
+
+// GetSummary returns referral totals for a referrer
+func (r *ReferralRepository) GetSummary(ctx context.Context, referrerID int) (totalReferrals int, totalEarned, totalReversed float64, err error) {
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM users WHERE referred_by = $1),
+			COALESCE(SUM(amount) FILTER (WHERE status = 'credited'), 0),
+			COALESCE(SUM(amount) FILTER (WHERE status = 'reversed' AND credited_at IS NOT NULL), 0)
+		FROM referral_commissions WHERE referrer_id = $1
+	`
+
+	err = r.db.QueryRow(ctx, query, referrerID).Scan(&totalReferrals, &totalEarned, &totalReversed)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("failed to get referral summary: %w", err)
+	}
+
+	return totalReferrals, totalEarned, totalReversed, nil
+}
+
+// GetReferredMembers retrieves members referred by a referrer with their earned commission
+func (r *ReferralRepository) GetReferredMembers(ctx context.Context, referrerID, limit, offset int) ([]model.ReferredMember, int, error) {
+	var total int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM users WHERE referred_by = $1`, referrerID).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count referred members: %w", err)
+	}
+
+	query := `
+		SELECT u.id, u.username, u.full_name, u.created_at,
+		       COUNT(rc.id) FILTER (WHERE rc.status = 'credited'),
+		       COALESCE(SUM(rc.amount) FILTER (WHERE rc.status = 'credited'), 0)
+		FROM users u
+		LEFT JOIN referral_commissions rc ON rc.referred_id = u.id AND rc.referrer_id = $1
+		WHERE u.referred_by = $1
+		GROUP BY u.id
+		ORDER BY u.created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.Query(ctx, query, referrerID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get referred members: %w", err)
+	}
+	defer rows.Close()
+
+	var members []model.ReferredMember
+	for rows.Next() {
+		var m model.ReferredMember
+		if err := rows.Scan(&m.ID, &m.Username, &m.FullName, &m.JoinedAt, &m.TotalOrders, &m.Earned); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan referred member: %w", err)
+		}
+		members = append(members, m)
+	}
+
+	return members, total, nil
+}
+
+// GetCommissions retrieves commission history for a referrer
+func (r *ReferralRepository) GetCommissions(ctx context.Context, referrerID, limit, offset int) ([]model.ReferralCommission, int, error) {
+	var total int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM referral_commissions WHERE referrer_id = $1`, referrerID).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count referral commissions: %w", err)
+	}
+
+	query := `
+		SELECT rc.id, rc.referrer_id, rc.referred_id, u.username, rc.order_id::text, rc.ref_id,
+		       rc.commission_type, rc.commission_value, rc.order_margin, rc.amount, rc.status,
+		       rc.created_at, rc.credited_at, rc.reversed_at
+		FROM referral_commissions rc
+		JOIN users u ON u.id = rc.referred_id
+		WHERE rc.referrer_id = $1
+		ORDER BY rc.created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.Query(ctx, query, referrerID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get referral commissions: %w", err)
+	}
+	defer rows.Close()
+
+	var commissions []model.ReferralCommission
+	for rows.Next() {
+		var c model.ReferralCommission
+		err := rows.Scan(
+			&c.ID, &c.ReferrerID, &c.ReferredID, &c.ReferredName, &c.OrderID, &c.RefID,
+			&c.CommissionType, &c.CommissionValue, &c.OrderMargin, &c.Amount, &c.Status,
+			&c.CreatedAt, &c.CreditedAt, &c.ReversedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan referral commission: %w", err)
+		}
+		commissions = append(commissions, c)
+	}
+
+	return commissions, total, nil
+}
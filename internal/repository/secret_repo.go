@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// EncryptedColumn is a column holding an envelope-encrypted credential
+type EncryptedColumn struct {
+	Table  string
+	Key    string // integer primary key
+	Column string
+}
+
+// EncryptedColumns lists every credential column written through envelope.Keyring
+var EncryptedColumns = []EncryptedColumn{
+	{Table: "admin_security", Key: "id", Column: "totp_secret"},
+	{Table: "member_security", Key: "user_id", Column: "totp_secret"},
+	{Table: "member_api_keys", Key: "id", Column: "api_secret"},
+	{Table: "member_webhooks", Key: "user_id", Column: "secret"},
+}
+
+// SecretRepository rewrites encrypted credential columns in bulk
+// (encrypting legacy plain text values and rotating master keys)
+type SecretRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewSecretRepository creates a new SecretRepository
+func NewSecretRepository(db *pgxpool.Pool) *SecretRepository {
+	return &SecretRepository{db: db}
+}
+
+// Rewrite passes every non-empty value of a column through transform and stores
+// the values it reports as changed, in one transaction. Returns the number of
+// rows updated.
+func (r *SecretRepository) Rewrite(ctx context.Context, col EncryptedColumn, transform func(value string) (string, bool, error)) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Table and column names come from EncryptedColumns, never from input
+	query := fmt.Sprintf(`SELECT %s, %s FROM %s WHERE COALESCE(%s, '') <> '' FOR UPDATE`, col.Key, col.Column, col.Table, col.Column)
+	rows, err := tx.Query(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s.%s: %w", col.Table, col.Column, err)
+	}
+
+	type update struct {
+		key   int
+		value string
+	}
+	var updates []update
+	for rows.Next() {
+		var key int
+		var value string
+		if err := rows.Scan(&key, &value); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan %s.%s: %w", col.Table, col.Column, err)
+		}
+		newValue, changed, err := transform(value)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("%s.%s %s=%d: %w", col.Table, col.Column, col.Key, key, err)
+		}
+		if changed {
+			updates = append(updates, update{key: key, value: newValue})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read %s.%s: %w", col.Table, col.Column, err)
+	}
+
+	updateQuery := fmt.Sprintf(`UPDATE %s SET %s = $2 WHERE %s = $1`, col.Table, col.Column, col.Key)
+	for _, u := range updates {
+		if _, err := tx.Exec(ctx, updateQuery, u.key, u.value); err != nil {
+			return 0, fmt.Errorf("failed to update %s.%s: %w", col.Table, col.Column, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return len(updates), nil
+}
@@ -0,0 +1,236 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"govershop-api/internal/model"
+)
+
+// Refresh errors
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionExpired  = errors.New("session expired or revoked")
+	ErrRefreshReused   = errors.New("refresh token reused")
+)
+
+// SessionRepository handles database operations for login sessions
+type SessionRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewSessionRepository creates a new SessionRepository
+func NewSessionRepository(db *pgxpool.Pool) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+const sessionColumns = `
+	id::text, user_id, channel, COALESCE(device, ''), COALESCE(ip_address, ''), COALESCE(user_agent, ''),
+	created_at, last_used_at, expires_at, revoked_at, revoked_reason
+`
+
+func scanSession(row pgx.Row) (*model.Session, error) {
+	var s model.Session
+	err := row.Scan(
+		&s.ID, &s.UserID, &s.Channel, &s.Device, &s.IPAddress, &s.UserAgent,
+		&s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt, &s.RevokedAt, &s.RevokedReason,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Create starts a new session for a user
+func (r *SessionRepository) Create(ctx context.Context, s *model.Session, refreshHash string, ttl time.Duration) error {
+	query := `
+		INSERT INTO sessions (user_id, channel, refresh_token_hash, device, ip_address, user_agent, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW() + make_interval(secs => $7))
+		RETURNING ` + sessionColumns
+
+	created, err := scanSession(r.db.QueryRow(ctx, query,
+		s.UserID, s.Channel, refreshHash, s.Device, s.IPAddress, s.UserAgent, ttl.Seconds(),
+	))
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+
+	*s = *created
+	return nil
+}
+
+// Rotate exchanges a refresh token for a new one and extends the session.
+// Presenting an already rotated-out token revokes the session (ErrRefreshReused).
+func (r *SessionRepository) Rotate(ctx context.Context, presentedHash, newHash string, ttl time.Duration) (*model.Session, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var sessionID string
+	var usable bool
+	err = tx.QueryRow(ctx, `
+		SELECT id::text, (revoked_at IS NULL AND expires_at > NOW())
+		FROM sessions WHERE refresh_token_hash = $1
+		FOR UPDATE
+	`, presentedHash).Scan(&sessionID, &usable)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// A stolen, already rotated token: kill the whole session
+		result, rErr := tx.Exec(ctx, `
+			UPDATE sessions SET revoked_at = NOW(), revoked_reason = $2
+			WHERE previous_token_hash = $1 AND revoked_at IS NULL
+		`, presentedHash, model.SessionRevokeRefreshReuse)
+		if rErr != nil {
+			return nil, fmt.Errorf("failed to revoke reused session: %w", rErr)
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		if result.RowsAffected() > 0 {
+			return nil, ErrRefreshReused
+		}
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	if !usable {
+		return nil, ErrSessionExpired
+	}
+
+	rotated, err := scanSession(tx.QueryRow(ctx, `
+		UPDATE sessions
+		SET previous_token_hash = refresh_token_hash, refresh_token_hash = $2,
+			last_used_at = NOW(), expires_at = NOW() + make_interval(secs => $3)
+		WHERE id = $1::uuid
+		RETURNING `+sessionColumns, sessionID, newHash, ttl.Seconds()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate session: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return rotated, nil
+}
+
+// IsAccessValid checks an access token's session: not revoked or expired, user still
+// active, and the token was not issued before the user's tokens_valid_after.
+func (r *SessionRepository) IsAccessValid(ctx context.Context, sessionID string, userID int, issuedAt time.Time) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM sessions s
+			JOIN users u ON u.id = s.user_id
+			WHERE s.id = $1::uuid AND s.user_id = $2
+				AND s.revoked_at IS NULL AND s.expires_at > NOW()
+				AND u.status = 'active'
+				AND (u.tokens_valid_after IS NULL OR $3 >= date_trunc('second', u.tokens_valid_after))
+		)
+	`
+
+	var valid bool
+	if err := r.db.QueryRow(ctx, query, sessionID, userID, issuedAt).Scan(&valid); err != nil {
+		return false, fmt.Errorf("failed to validate session: %w", err)
+	}
+	return valid, nil
+}
+
+// Revoke revokes one session of a user
+func (r *SessionRepository) Revoke(ctx context.Context, userID int, sessionID, reason string) error {
+	query := `
+		UPDATE sessions SET revoked_at = NOW(), revoked_reason = $3
+		WHERE id = $1::uuid AND user_id = $2 AND revoked_at IS NULL
+	`
+
+	result, err := r.db.Exec(ctx, query, sessionID, userID, reason)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeAllForUser revokes every session of a user and invalidates all
+// access tokens issued so far
+func (r *SessionRepository) RevokeAllForUser(ctx context.Context, userID int, reason string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := revokeUserSessionsTx(ctx, tx, userID, reason); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ListActive returns the active sessions of a user, most recently used first
+func (r *SessionRepository) ListActive(ctx context.Context, userID int) ([]model.Session, error) {
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY last_used_at DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []model.Session
+	for rows.Next() {
+		s, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, *s)
+	}
+
+	return sessions, nil
+}
+
+// DeleteExpired removes sessions that expired or were revoked more than 30 days ago
+func (r *SessionRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	query := `
+		DELETE FROM sessions
+		WHERE expires_at < NOW() - INTERVAL '30 days'
+			OR revoked_at < NOW() - INTERVAL '30 days'
+	`
+
+	result, err := r.db.Exec(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+// revokeUserSessionsTx revokes all sessions of a user and bumps tokens_valid_after
+func revokeUserSessionsTx(ctx context.Context, tx pgx.Tx, userID int, reason string) error {
+	if _, err := tx.Exec(ctx, `UPDATE users SET tokens_valid_after = NOW() WHERE id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to invalidate tokens: %w", err)
+	}
+
+	_, err := tx.Exec(ctx, `
+		UPDATE sessions SET revoked_at = NOW(), revoked_reason = $2
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`, userID, reason)
+	if err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// SettingsRepository handles database operations for app settings
+type SettingsRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewSettingsRepository creates a new SettingsRepository
+func NewSettingsRepository(db *pgxpool.Pool) *SettingsRepository {
+	return &SettingsRepository{db: db}
+}
+
+// Get returns the value of a setting, or def if it is not set
+func (r *SettingsRepository) Get(ctx context.Context, key, def string) (string, error) {
+	var value string
+	err := r.db.QueryRow(ctx, `SELECT value FROM app_settings WHERE key = $1`, key).Scan(&value)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return def, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get setting %s: %w", key, err)
+	}
+	return value, nil
+}
+
+// Set creates or updates a setting
+func (r *SettingsRepository) Set(ctx context.Context, key, value, updatedBy string) error {
+	query := `
+		INSERT INTO app_settings (key, value, updated_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, updated_by = EXCLUDED.updated_by
+	`
+
+	if _, err := r.db.Exec(ctx, query, key, value, updatedBy); err != nil {
+		return fmt.Errorf("failed to save setting %s: %w", key, err)
+	}
+	return nil
+}
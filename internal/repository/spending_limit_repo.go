@@ -0,0 +1,318 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"govershop-api/internal/model"
+)
+
+// ErrMemberLevelNotFound is returned when a member is moved to a level that does not exist
+var ErrMemberLevelNotFound = errors.New("member level not found")
+
+// SpendingLimitError is returned when an order would exceed a member's spending limit
+type SpendingLimitError struct {
+	Code  string  // One of model.LimitCode*
+	Limit float64 // The limit that was hit (0 for brand limits)
+}
+
+func (e *SpendingLimitError) Error() string {
+	return fmt.Sprintf("spending limit exceeded: %s (%v)", e.Code, e.Limit)
+}
+
+// SpendingLimitRepository handles member levels, per-member limit overrides and usage
+type SpendingLimitRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewSpendingLimitRepository creates a new SpendingLimitRepository
+func NewSpendingLimitRepository(db *pgxpool.Pool) *SpendingLimitRepository {
+	return &SpendingLimitRepository{db: db}
+}
+
+// loadSpendingLimits returns a member's level, effective limits and override (nil if none)
+func loadSpendingLimits(ctx context.Context, q rowQuerier, userID int) (string, model.SpendingLimits, *model.MemberLimitOverride, error) {
+	var level string
+	var lvl, ovr model.SpendingLimits
+	var hasOverride bool
+	var note, updatedBy string
+	var updatedAt *time.Time
+
+	err := q.QueryRow(ctx, `
+		SELECT u.member_level,
+			l.max_order_amount, l.max_daily_spend, l.max_orders_per_hour, l.allowed_brands,
+			o.user_id IS NOT NULL, o.max_order_amount, o.max_daily_spend, o.max_orders_per_hour, o.allowed_brands,
+			COALESCE(o.note, ''), COALESCE(o.updated_by, ''), o.updated_at
+		FROM users u
+		LEFT JOIN member_levels l ON l.level = u.member_level
+		LEFT JOIN member_limits o ON o.user_id = u.id
+		WHERE u.id = $1
+	`, userID).Scan(
+		&level,
+		&lvl.MaxOrderAmount, &lvl.MaxDailySpend, &lvl.MaxOrdersPerHour, &lvl.AllowedBrands,
+		&hasOverride, &ovr.MaxOrderAmount, &ovr.MaxDailySpend, &ovr.MaxOrdersPerHour, &ovr.AllowedBrands,
+		&note, &updatedBy, &updatedAt,
+	)
+	if err != nil {
+		return "", model.SpendingLimits{}, nil, fmt.Errorf("failed to get spending limits: %w", err)
+	}
+
+	effective := model.SpendingLimits{
+		MaxOrderAmount:   effectiveAmount(ovr.MaxOrderAmount, lvl.MaxOrderAmount),
+		MaxDailySpend:    effectiveAmount(ovr.MaxDailySpend, lvl.MaxDailySpend),
+		MaxOrdersPerHour: effectiveCount(ovr.MaxOrdersPerHour, lvl.MaxOrdersPerHour),
+		AllowedBrands:    lvl.AllowedBrands,
+	}
+	if ovr.AllowedBrands != nil {
+		effective.AllowedBrands = ovr.AllowedBrands
+	}
+
+	var override *model.MemberLimitOverride
+	if hasOverride {
+		override = &model.MemberLimitOverride{
+			UserID:         userID,
+			Note:           note,
+			UpdatedBy:      updatedBy,
+			SpendingLimits: ovr,
+		}
+		if updatedAt != nil {
+			override.UpdatedAt = *updatedAt
+		}
+	}
+
+	return level, effective, override, nil
+}
+
+// effectiveAmount picks the override over the level value; 0 or less means unlimited
+func effectiveAmount(override, level *float64) *float64 {
+	v := level
+	if override != nil {
+		v = override
+	}
+	if v == nil || *v <= 0 {
+		return nil
+	}
+	return v
+}
+
+// effectiveCount is effectiveAmount for count limits
+func effectiveCount(override, level *int) *int {
+	v := level
+	if override != nil {
+		v = override
+	}
+	if v == nil || *v <= 0 {
+		return nil
+	}
+	return v
+}
+
+// spendingUsage returns a member's net order spend today (refunds excluded) and the
+// number of orders paid in the last hour
+func spendingUsage(ctx context.Context, q rowQuerier, userID int) (float64, int, error) {
+	var spent float64
+	var lastHour int
+	err := q.QueryRow(ctx, `
+		WITH today AS (
+			SELECT reference_id, amount, created_at
+			FROM deposits
+			WHERE user_id = $1 AND type = 'debit' AND status = 'success'
+				AND reference_id LIKE 'INV-%'
+				AND created_at >= LEAST(CURRENT_DATE, NOW() - INTERVAL '1 hour')
+		)
+		SELECT
+			COALESCE((SELECT SUM(amount) FROM today WHERE created_at >= CURRENT_DATE), 0)
+			- COALESCE((
+				SELECT SUM(r.amount) FROM deposits r
+				WHERE r.user_id = $1 AND r.type = 'refund' AND r.status = 'success'
+					AND r.reference_id IN (SELECT reference_id FROM today WHERE created_at >= CURRENT_DATE)
+			), 0),
+			(SELECT COUNT(*) FROM today WHERE created_at >= NOW() - INTERVAL '1 hour')
+	`, userID).Scan(&spent, &lastHour)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get spending usage: %w", err)
+	}
+	return spent, lastHour, nil
+}
+
+// checkSpendingLimitsTx rejects an order that would exceed the member's limits with a
+// *SpendingLimitError. It locks the user row so concurrent orders are checked one at a time.
+func checkSpendingLimitsTx(ctx context.Context, tx pgx.Tx, userID int, amount float64, brand string) error {
+	if _, err := tx.Exec(ctx, "SELECT id FROM users WHERE id = $1 FOR UPDATE", userID); err != nil {
+		return fmt.Errorf("failed to lock user: %w", err)
+	}
+
+	_, limits, _, err := loadSpendingLimits(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+
+	if len(limits.AllowedBrands) > 0 && !brandAllowed(limits.AllowedBrands, brand) {
+		return &SpendingLimitError{Code: model.LimitCodeBrand}
+	}
+	if limits.MaxOrderAmount != nil && amount > *limits.MaxOrderAmount {
+		return &SpendingLimitError{Code: model.LimitCodeOrderAmount, Limit: *limits.MaxOrderAmount}
+	}
+	if limits.MaxDailySpend == nil && limits.MaxOrdersPerHour == nil {
+		return nil
+	}
+
+	spent, lastHour, err := spendingUsage(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+	if limits.MaxOrdersPerHour != nil && lastHour+1 > *limits.MaxOrdersPerHour {
+		return &SpendingLimitError{Code: model.LimitCodeHourlyOrder, Limit: float64(*limits.MaxOrdersPerHour)}
+	}
+	if limits.MaxDailySpend != nil && spent+amount > *limits.MaxDailySpend {
+		return &SpendingLimitError{Code: model.LimitCodeDailySpend, Limit: *limits.MaxDailySpend}
+	}
+	return nil
+}
+
+// brandAllowed compares brand names ignoring case and spaces
+func brandAllowed(allowed []string, brand string) bool {
+	normalize := func(s string) string { return strings.ToLower(strings.ReplaceAll(s, " ", "")) }
+	for _, b := range allowed {
+		if normalize(b) == normalize(brand) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetUsage returns a member's effective limits and usage
+func (r *SpendingLimitRepository) GetUsage(ctx context.Context, userID int) (*model.SpendingUsage, error) {
+	level, limits, override, err := loadSpendingLimits(ctx, r.db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	spent, lastHour, err := spendingUsage(ctx, r.db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &model.SpendingUsage{
+		Level:          level,
+		Limits:         limits,
+		SpentToday:     spent,
+		OrdersLastHour: lastHour,
+		Override:       override,
+	}
+	if limits.AllowedBrands == nil {
+		usage.Limits.AllowedBrands = []string{}
+	}
+	if limits.MaxDailySpend != nil {
+		remaining := *limits.MaxDailySpend - spent
+		if remaining < 0 {
+			remaining = 0
+		}
+		usage.RemainingToday = &remaining
+	}
+	return usage, nil
+}
+
+// SetOverride replaces a member's limit override and optionally moves them to another level
+func (r *SpendingLimitRepository) SetOverride(ctx context.Context, o *model.MemberLimitOverride, level string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if level != "" {
+		result, err := tx.Exec(ctx, `
+			UPDATE users SET member_level = $2, updated_at = NOW()
+			WHERE id = $1 AND EXISTS (SELECT 1 FROM member_levels WHERE level = $2)
+		`, o.UserID, level)
+		if err != nil {
+			return fmt.Errorf("failed to update member level: %w", err)
+		}
+		if result.RowsAffected() == 0 {
+			return ErrMemberLevelNotFound
+		}
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO member_limits (user_id, max_order_amount, max_daily_spend, max_orders_per_hour, allowed_brands, note, updated_by)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7)
+		ON CONFLICT (user_id) DO UPDATE SET
+			max_order_amount = EXCLUDED.max_order_amount,
+			max_daily_spend = EXCLUDED.max_daily_spend,
+			max_orders_per_hour = EXCLUDED.max_orders_per_hour,
+			allowed_brands = EXCLUDED.allowed_brands,
+			note = EXCLUDED.note,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = NOW()
+		RETURNING updated_at
+	`, o.UserID, o.MaxOrderAmount, o.MaxDailySpend, o.MaxOrdersPerHour, o.AllowedBrands, o.Note, o.UpdatedBy).Scan(&o.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save member limits: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// DeleteOverride removes a member's limit override so level limits apply again
+func (r *SpendingLimitRepository) DeleteOverride(ctx context.Context, userID int) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM member_limits WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete member limits: %w", err)
+	}
+	return nil
+}
+
+// ListLevels returns all member levels
+func (r *SpendingLimitRepository) ListLevels(ctx context.Context) ([]model.MemberLevel, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT level, name, max_order_amount, max_daily_spend, max_orders_per_hour, allowed_brands,
+			COALESCE(updated_by, ''), updated_at
+		FROM member_levels
+		ORDER BY level
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get member levels: %w", err)
+	}
+	defer rows.Close()
+
+	var levels []model.MemberLevel
+	for rows.Next() {
+		var l model.MemberLevel
+		if err := rows.Scan(
+			&l.Level, &l.Name, &l.MaxOrderAmount, &l.MaxDailySpend, &l.MaxOrdersPerHour, &l.AllowedBrands,
+			&l.UpdatedBy, &l.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan member level: %w", err)
+		}
+		levels = append(levels, l)
+	}
+
+	return levels, nil
+}
+
+// UpsertLevel creates or updates a member level
+func (r *SpendingLimitRepository) UpsertLevel(ctx context.Context, l *model.MemberLevel) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO member_levels (level, name, max_order_amount, max_daily_spend, max_orders_per_hour, allowed_brands, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (level) DO UPDATE SET
+			name = EXCLUDED.name,
+			max_order_amount = EXCLUDED.max_order_amount,
+			max_daily_spend = EXCLUDED.max_daily_spend,
+			max_orders_per_hour = EXCLUDED.max_orders_per_hour,
+			allowed_brands = EXCLUDED.allowed_brands,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = NOW()
+		RETURNING updated_at
+	`, l.Level, l.Name, l.MaxOrderAmount, l.MaxDailySpend, l.MaxOrdersPerHour, l.AllowedBrands, l.UpdatedBy).Scan(&l.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save member level: %w", err)
+	}
+	return nil
+}
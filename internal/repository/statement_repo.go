@@ -0,0 +1,247 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"govershop-api/internal/model"
+)
+
+// StatementRepository builds member statements and stores background statement jobs
+type StatementRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewStatementRepository creates a new StatementRepository
+func NewStatementRepository(db *pgxpool.Pool) *StatementRepository {
+	return &StatementRepository{db: db}
+}
+
+// Build returns a member's ledger between dateFrom and dateTo (YYYY-MM-DD, inclusive)
+// joined with orders. The opening balance is the current balance minus every entry
+// posted since dateFrom, so it also holds for entries from before the ledger migration.
+func (r *StatementRepository) Build(ctx context.Context, userID int, dateFrom, dateTo string) (*model.Statement, error) {
+	// One snapshot so the balance and the entries agree
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	st := model.Statement{
+		UserID:      userID,
+		DateFrom:    dateFrom,
+		DateTo:      dateTo,
+		GeneratedAt: time.Now(),
+	}
+
+	var balance, sinceFrom float64
+	err = tx.QueryRow(ctx, `
+		SELECT u.username, u.full_name, u.balance,
+			COALESCE((
+				SELECT SUM(CASE WHEN d.type = ANY($3) THEN -d.amount ELSE d.amount END)
+				FROM deposits d
+				WHERE d.user_id = u.id AND d.status = 'success' AND d.created_at >= $2::date
+			), 0)
+		FROM users u
+		WHERE u.id = $1
+	`, userID, dateFrom, model.DebitDepositTypes).Scan(&st.Username, &st.FullName, &balance, &sinceFrom)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get statement balance: %w", err)
+	}
+	st.OpeningBalance = balance - sinceFrom
+
+	rows, err := tx.Query(ctx, `
+		SELECT d.created_at, d.type, COALESCE(d.description, ''), COALESCE(d.reference_id, ''), d.amount,
+			COALESCE(o.product_name, ''), COALESCE(o.customer_no, ''), COALESCE(o.serial_number, ''), COALESCE(o.status, '')
+		FROM deposits d
+		LEFT JOIN orders o ON o.ref_id = d.reference_id AND o.member_id = d.user_id
+		WHERE d.user_id = $1 AND d.status = 'success'
+			AND d.created_at >= $2::date AND d.created_at < ($3::date + interval '1 day')
+		ORDER BY d.created_at, d.id
+	`, userID, dateFrom, dateTo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get statement entries: %w", err)
+	}
+	defer rows.Close()
+
+	running := st.OpeningBalance
+	for rows.Next() {
+		var e model.StatementEntry
+		var amount float64
+		if err := rows.Scan(
+			&e.Date, &e.Type, &e.Description, &e.ReferenceID, &amount,
+			&e.ProductName, &e.Destination, &e.SerialNumber, &e.OrderStatus,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan statement entry: %w", err)
+		}
+
+		if model.IsDebitDepositType(e.Type) {
+			e.Debit = amount
+			running -= amount
+			st.TotalDebit += amount
+		} else {
+			e.Credit = amount
+			running += amount
+			st.TotalCredit += amount
+		}
+		e.Balance = running
+		st.Entries = append(st.Entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get statement entries: %w", err)
+	}
+
+	st.ClosingBalance = running
+	return &st, nil
+}
+
+const statementJobColumns = `
+	id::text, user_id, to_char(date_from, 'YYYY-MM-DD'), to_char(date_to, 'YYYY-MM-DD'), format, status,
+	COALESCE(file_name, ''), COALESCE(error_message, ''), created_at, completed_at, expires_at
+`
+
+func scanStatementJob(row pgx.Row) (*model.StatementJob, error) {
+	var j model.StatementJob
+	err := row.Scan(
+		&j.ID, &j.UserID, &j.DateFrom, &j.DateTo, &j.Format, &j.Status,
+		&j.FileName, &j.ErrorMessage, &j.CreatedAt, &j.CompletedAt, &j.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// CreateJob queues a statement export
+func (r *StatementRepository) CreateJob(ctx context.Context, userID int, dateFrom, dateTo, format string) (*model.StatementJob, error) {
+	query := `
+		INSERT INTO member_statements (user_id, date_from, date_to, format)
+		VALUES ($1, $2::date, $3::date, $4)
+		RETURNING ` + statementJobColumns
+
+	job, err := scanStatementJob(r.db.QueryRow(ctx, query, userID, dateFrom, dateTo, format))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create statement job: %w", err)
+	}
+	return job, nil
+}
+
+// ClaimJob locks the oldest pending job (or one whose worker died) for 10 minutes.
+// Returns nil when there is nothing to do.
+func (r *StatementRepository) ClaimJob(ctx context.Context) (*model.StatementJob, error) {
+	query := `
+		UPDATE member_statements
+		SET status = 'processing', locked_until = NOW() + INTERVAL '10 minutes'
+		WHERE id = (
+			SELECT id FROM member_statements
+			WHERE status = 'pending' OR (status = 'processing' AND locked_until < NOW())
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + statementJobColumns
+
+	job, err := scanStatementJob(r.db.QueryRow(ctx, query))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim statement job: %w", err)
+	}
+	return job, nil
+}
+
+// CompleteJob stores the generated file of a job
+func (r *StatementRepository) CompleteJob(ctx context.Context, id, fileName string, data []byte) error {
+	query := `
+		UPDATE member_statements
+		SET status = 'ready', file_name = $2, file_data = $3, locked_until = NULL,
+			completed_at = NOW(), expires_at = NOW() + INTERVAL '7 days'
+		WHERE id = $1::uuid
+	`
+
+	if _, err := r.db.Exec(ctx, query, id, fileName, data); err != nil {
+		return fmt.Errorf("failed to complete statement job: %w", err)
+	}
+	return nil
+}
+
+// FailJob marks a job as failed
+func (r *StatementRepository) FailJob(ctx context.Context, id, message string) error {
+	query := `
+		UPDATE member_statements
+		SET status = 'failed', error_message = $2, locked_until = NULL, completed_at = NOW()
+		WHERE id = $1::uuid
+	`
+
+	if _, err := r.db.Exec(ctx, query, id, message); err != nil {
+		return fmt.Errorf("failed to mark statement job failed: %w", err)
+	}
+	return nil
+}
+
+// ListJobs returns a member's unexpired statement jobs, newest first
+func (r *StatementRepository) ListJobs(ctx context.Context, userID int) ([]model.StatementJob, error) {
+	query := `
+		SELECT ` + statementJobColumns + `
+		FROM member_statements
+		WHERE user_id = $1 AND expires_at > NOW()
+		ORDER BY created_at DESC
+		LIMIT 50
+	`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get statement jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []model.StatementJob
+	for rows.Next() {
+		j, err := scanStatementJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan statement job: %w", err)
+		}
+		jobs = append(jobs, *j)
+	}
+
+	return jobs, nil
+}
+
+// GetJobFile returns a member's job and its file (nil if not found or expired)
+func (r *StatementRepository) GetJobFile(ctx context.Context, userID int, id string) (*model.StatementJob, []byte, error) {
+	query := `
+		SELECT ` + statementJobColumns + `, file_data
+		FROM member_statements
+		WHERE id = $1::uuid AND user_id = $2 AND expires_at > NOW()
+	`
+
+	var j model.StatementJob
+	var data []byte
+	err := r.db.QueryRow(ctx, query, id, userID).Scan(
+		&j.ID, &j.UserID, &j.DateFrom, &j.DateTo, &j.Format, &j.Status,
+		&j.FileName, &j.ErrorMessage, &j.CreatedAt, &j.CompletedAt, &j.ExpiresAt, &data,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get statement job: %w", err)
+	}
+	return &j, data, nil
+}
+
+// DeleteExpired removes expired statement files
+func (r *StatementRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	result, err := r.db.Exec(ctx, `DELETE FROM member_statements WHERE expires_at < NOW()`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired statements: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"govershop-api/internal/model"
+)
+
+// ErrTransferDailyCap is returned when a transfer would exceed the sender's daily cap
+var ErrTransferDailyCap = errors.New("transfer daily cap exceeded")
+
+// TransferRepository handles member-to-member balance transfers
+type TransferRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewTransferRepository creates a new TransferRepository
+func NewTransferRepository(db *pgxpool.Pool) *TransferRepository {
+	return &TransferRepository{db: db}
+}
+
+// Create moves balance from one member to another in a single transaction, posting
+// a transfer_out entry for the sender and a transfer_in entry for the recipient.
+// dailyCap limits the sender's total transfers for today (0 = unlimited).
+func (r *TransferRepository) Create(ctx context.Context, t *model.MemberTransfer, dailyCap float64) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock both members in id order so opposite transfers can't deadlock
+	_, err = tx.Exec(ctx, `
+		SELECT id FROM users WHERE id = ANY($1) ORDER BY id FOR UPDATE
+	`, []int{t.FromUserID, t.ToUserID})
+	if err != nil {
+		return fmt.Errorf("failed to lock users: %w", err)
+	}
+
+	if dailyCap > 0 {
+		var sentToday float64
+		err = tx.QueryRow(ctx, `
+			SELECT COALESCE(SUM(amount), 0) FROM member_transfers
+			WHERE from_user_id = $1 AND created_at >= CURRENT_DATE
+		`, t.FromUserID).Scan(&sentToday)
+		if err != nil {
+			return fmt.Errorf("failed to get today's transfers: %w", err)
+		}
+		if sentToday+t.Amount > dailyCap {
+			return ErrTransferDailyCap
+		}
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO member_transfers (ref_id, from_user_id, to_user_id, amount, note)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+		RETURNING id, created_at
+	`, t.RefID, t.FromUserID, t.ToUserID, t.Amount, t.Note).Scan(&t.ID, &t.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create transfer: %w", err)
+	}
+
+	_, err = postLedgerTx(ctx, tx, model.LedgerEntry{
+		UserID:      t.FromUserID,
+		Amount:      t.Amount,
+		Type:        model.DepositTypeTransferOut,
+		Description: fmt.Sprintf("Transfer saldo ke %s", t.ToUsername),
+		ReferenceID: t.RefID,
+		CreatedBy:   t.FromUsername,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = postLedgerTx(ctx, tx, model.LedgerEntry{
+		UserID:      t.ToUserID,
+		Amount:      t.Amount,
+		Type:        model.DepositTypeTransferIn,
+		Description: fmt.Sprintf("Transfer saldo dari %s", t.FromUsername),
+		ReferenceID: t.RefID,
+		CreatedBy:   t.FromUsername,
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"govershop-api/internal/model"
@@ -16,6 +17,12 @@ type UserRepository struct {
 	db *pgxpool.Pool
 }
 
+// Uniqueness errors returned by Create and Update
+var (
+	ErrUsernameTaken = errors.New("username already taken")
+	ErrEmailTaken    = errors.New("email already taken")
+)
+
 // NewUserRepository creates a new UserRepository
 func NewUserRepository(db *pgxpool.Pool) *UserRepository {
 	return &UserRepository{db: db}
@@ -24,8 +31,8 @@ func NewUserRepository(db *pgxpool.Pool) *UserRepository {
 // Create creates a new user
 func (r *UserRepository) Create(ctx context.Context, user *model.User) error {
 	query := `
-		INSERT INTO users (username, password, email, full_name, role, balance, status, whatsapp)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO users (username, password, email, full_name, role, balance, status, whatsapp, referral_code, referred_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 		RETURNING id, created_at, updated_at
 	`
 
@@ -38,9 +45,14 @@ func (r *UserRepository) Create(ctx context.Context, user *model.User) error {
 		user.Balance,
 		user.Status,
 		user.WhatsApp,
+		user.ReferralCode,
+		user.ReferredBy,
 	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
+		if uErr := uniqueUserError(err); uErr != nil {
+			return uErr
+		}
 		return fmt.Errorf("failed to create user: %w", err)
 	}
 
@@ -50,7 +62,8 @@ func (r *UserRepository) Create(ctx context.Context, user *model.User) error {
 // GetByID retrieves a user by ID
 func (r *UserRepository) GetByID(ctx context.Context, id int) (*model.User, error) {
 	query := `
-		SELECT id, username, password, email, full_name, role, balance, status, whatsapp, created_at, updated_at
+		SELECT id, username, password, email, full_name, role, balance, status, whatsapp, created_at, updated_at,
+		       referral_code, referred_by
 		FROM users WHERE id = $1
 	`
 
@@ -67,6 +80,8 @@ func (r *UserRepository) GetByID(ctx context.Context, id int) (*model.User, erro
 		&user.WhatsApp,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.ReferralCode,
+		&user.ReferredBy,
 	)
 
 	if errors.Is(err, pgx.ErrNoRows) {
@@ -82,7 +97,8 @@ func (r *UserRepository) GetByID(ctx context.Context, id int) (*model.User, erro
 // GetByUsername retrieves a user by username (for login)
 func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*model.User, error) {
 	query := `
-		SELECT id, username, password, email, full_name, role, balance, status, whatsapp, created_at, updated_at
+		SELECT id, username, password, email, full_name, role, balance, status, whatsapp, created_at, updated_at,
+		       referral_code, referred_by
 		FROM users WHERE username = $1
 	`
 
@@ -99,6 +115,8 @@ func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*m
 		&user.WhatsApp,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.ReferralCode,
+		&user.ReferredBy,
 	)
 
 	if errors.Is(err, pgx.ErrNoRows) {
@@ -114,7 +132,8 @@ func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*m
 // GetByEmail retrieves a user by email (for password reset)
 func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
 	query := `
-		SELECT id, username, password, email, full_name, role, balance, status, whatsapp, created_at, updated_at
+		SELECT id, username, password, email, full_name, role, balance, status, whatsapp, created_at, updated_at,
+		       referral_code, referred_by
 		FROM users WHERE email = $1
 	`
 
@@ -131,6 +150,8 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*model.U
 		&user.WhatsApp,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.ReferralCode,
+		&user.ReferredBy,
 	)
 
 	if errors.Is(err, pgx.ErrNoRows) {
@@ -143,6 +164,51 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*model.U
 	return &user, nil
 }
 
+// GetByReferralCode retrieves a member by referral code (case-insensitive)
+func (r *UserRepository) GetByReferralCode(ctx context.Context, code string) (*model.User, error) {
+	query := `
+		SELECT id, username, password, email, full_name, role, balance, status, whatsapp, created_at, updated_at,
+		       referral_code, referred_by
+		FROM users WHERE referral_code = UPPER($1)
+	`
+
+	var user model.User
+	err := r.db.QueryRow(ctx, query, code).Scan(
+		&user.ID,
+		&user.Username,
+		&user.Password,
+		&user.Email,
+		&user.FullName,
+		&user.Role,
+		&user.Balance,
+		&user.Status,
+		&user.WhatsApp,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&user.ReferralCode,
+		&user.ReferredBy,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by referral code: %w", err)
+	}
+
+	return &user, nil
+}
+
+// SetReferralCode assigns a referral code to a user that doesn't have one yet
+func (r *UserRepository) SetReferralCode(ctx context.Context, id int, code string) error {
+	query := `UPDATE users SET referral_code = $1 WHERE id = $2 AND referral_code IS NULL`
+	_, err := r.db.Exec(ctx, query, code, id)
+	if err != nil {
+		return fmt.Errorf("failed to set referral code: %w", err)
+	}
+	return nil
+}
+
 // GetAllMembers retrieves all members with pagination
 func (r *UserRepository) GetAllMembers(ctx context.Context, limit, offset int, search string) ([]model.User, int, error) {
 	// Count query
@@ -164,7 +230,8 @@ func (r *UserRepository) GetAllMembers(ctx context.Context, limit, offset int, s
 
 	// Data query
 	dataQuery := `
-		SELECT id, username, password, email, full_name, role, balance, status, whatsapp, created_at, updated_at
+		SELECT id, username, password, email, full_name, role, balance, status, whatsapp, created_at, updated_at,
+		       referral_code, referred_by
 		FROM users WHERE role = 'member'
 	`
 
@@ -201,6 +268,8 @@ func (r *UserRepository) GetAllMembers(ctx context.Context, limit, offset int, s
 			&user.WhatsApp,
 			&user.CreatedAt,
 			&user.UpdatedAt,
+			&user.ReferralCode,
+			&user.ReferredBy,
 		)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan user: %w", err)
@@ -211,10 +280,16 @@ func (r *UserRepository) GetAllMembers(ctx context.Context, limit, offset int, s
 	return users, total, nil
 }
 
-// Update updates user data
-func (r *UserRepository) Update(ctx context.Context, id int, updates map[string]interface{}) error {
+// Update updates a member's data. Admin accounts are never touched; returns false
+// when id is not a member.
+func (r *UserRepository) Update(ctx context.Context, id int, updates map[string]interface{}) (bool, error) {
 	if len(updates) == 0 {
-		return nil
+		var exists bool
+		err := r.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM users WHERE id = $1 AND role = 'member')`, id).Scan(&exists)
+		if err != nil {
+			return false, fmt.Errorf("failed to get user: %w", err)
+		}
+		return exists, nil
 	}
 
 	query := "UPDATE users SET "
@@ -230,146 +305,205 @@ func (r *UserRepository) Update(ctx context.Context, id int, updates map[string]
 		i++
 	}
 
-	query += fmt.Sprintf(" WHERE id = $%d", i)
+	query += fmt.Sprintf(" WHERE id = $%d AND role = 'member'", i)
 	args = append(args, id)
 
-	_, err := r.db.Exec(ctx, query, args...)
+	result, err := r.db.Exec(ctx, query, args...)
 	if err != nil {
-		return fmt.Errorf("failed to update user: %w", err)
+		if uErr := uniqueUserError(err); uErr != nil {
+			return false, uErr
+		}
+		return false, fmt.Errorf("failed to update user: %w", err)
 	}
 
-	return nil
+	return result.RowsAffected() > 0, nil
 }
 
-// UpdatePassword updates user password
-func (r *UserRepository) UpdatePassword(ctx context.Context, id int, hashedPassword string) error {
-	query := `UPDATE users SET password = $1 WHERE id = $2`
-	_, err := r.db.Exec(ctx, query, hashedPassword, id)
+// VerifyEmail activates a pending member whose email still matches the verified address.
+// Returns false if the account is not pending or the email has changed.
+func (r *UserRepository) VerifyEmail(ctx context.Context, id int, email string) (bool, error) {
+	query := `
+		UPDATE users
+		SET status = 'active', email_verified_at = NOW()
+		WHERE id = $1 AND status = 'pending_verification' AND LOWER(email) = LOWER($2)
+	`
+	result, err := r.db.Exec(ctx, query, id, email)
 	if err != nil {
-		return fmt.Errorf("failed to update password: %w", err)
+		return false, fmt.Errorf("failed to verify email: %w", err)
 	}
-	return nil
+	return result.RowsAffected() > 0, nil
 }
 
-// UpdateBalance updates user balance
-func (r *UserRepository) UpdateBalance(ctx context.Context, id int, newBalance float64) error {
-	query := `UPDATE users SET balance = $1 WHERE id = $2`
-	_, err := r.db.Exec(ctx, query, newBalance, id)
-	if err != nil {
-		return fmt.Errorf("failed to update balance: %w", err)
+// uniqueUserError maps unique violations on users to ErrUsernameTaken / ErrEmailTaken
+func uniqueUserError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "23505" {
+		return nil
+	}
+	switch pgErr.ConstraintName {
+	case "users_username_key":
+		return ErrUsernameTaken
+	case "users_email_key":
+		return ErrEmailTaken
 	}
 	return nil
 }
 
-// TopupBalance adds balance and creates deposit log (transaction)
-func (r *UserRepository) TopupBalance(ctx context.Context, userID int, amount float64, description, createdBy string) error {
+// UpdatePassword updates user password and revokes all existing sessions
+func (r *UserRepository) UpdatePassword(ctx context.Context, id int, hashedPassword string) error {
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
-	// Get current balance
-	var currentBalance float64
-	err = tx.QueryRow(ctx, "SELECT balance FROM users WHERE id = $1 FOR UPDATE", userID).Scan(&currentBalance)
-	if err != nil {
-		return fmt.Errorf("failed to get current balance: %w", err)
-	}
-
-	newBalance := currentBalance + amount
-
-	// Update balance
-	_, err = tx.Exec(ctx, "UPDATE users SET balance = $1 WHERE id = $2", newBalance, userID)
-	if err != nil {
-		return fmt.Errorf("failed to update balance: %w", err)
+	query := `UPDATE users SET password = $1 WHERE id = $2`
+	if _, err := tx.Exec(ctx, query, hashedPassword, id); err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
 	}
 
-	// Create deposit log
-	_, err = tx.Exec(ctx, `
-		INSERT INTO deposits (user_id, amount, type, description, status, created_by)
-		VALUES ($1, $2, 'credit', $3, 'success', $4)
-	`, userID, amount, description, createdBy)
-	if err != nil {
-		return fmt.Errorf("failed to create deposit log: %w", err)
+	if err := revokeUserSessionsTx(ctx, tx, id, model.SessionRevokePasswordChange); err != nil {
+		return err
 	}
 
 	return tx.Commit(ctx)
 }
 
-// DeductBalance subtracts balance and creates deposit log (transaction)
-func (r *UserRepository) DeductBalance(ctx context.Context, userID int, amount float64, description, referenceID string) error {
+// UpdateBalance sets a user's balance by posting the difference to the ledger as an adjustment
+func (r *UserRepository) UpdateBalance(ctx context.Context, id int, newBalance float64, description, createdBy string) error {
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
-	// Get current balance
 	var currentBalance float64
-	err = tx.QueryRow(ctx, "SELECT balance FROM users WHERE id = $1 FOR UPDATE", userID).Scan(&currentBalance)
-	if err != nil {
+	if err := tx.QueryRow(ctx, "SELECT balance FROM users WHERE id = $1 FOR UPDATE", id).Scan(&currentBalance); err != nil {
 		return fmt.Errorf("failed to get current balance: %w", err)
 	}
 
-	if currentBalance < amount {
-		return fmt.Errorf("insufficient balance")
+	entry := model.LedgerEntry{
+		UserID:        id,
+		Amount:        newBalance - currentBalance,
+		Type:          model.DepositTypeAdjustmentCredit,
+		Description:   description,
+		CreatedBy:     createdBy,
+		AllowNegative: true,
 	}
-
-	newBalance := currentBalance - amount
-
-	// Update balance
-	_, err = tx.Exec(ctx, "UPDATE users SET balance = $1 WHERE id = $2", newBalance, userID)
-	if err != nil {
-		return fmt.Errorf("failed to update balance: %w", err)
+	if entry.Amount == 0 {
+		return nil
+	}
+	if entry.Amount < 0 {
+		entry.Amount = -entry.Amount
+		entry.Type = model.DepositTypeAdjustmentDebit
 	}
 
-	// Create deposit log
-	_, err = tx.Exec(ctx, `
-		INSERT INTO deposits (user_id, amount, type, description, reference_id, status, created_by)
-		VALUES ($1, $2, 'debit', $3, $4, 'success', 'system')
-	`, userID, amount, description, referenceID)
-	if err != nil {
-		return fmt.Errorf("failed to create deposit log: %w", err)
+	if _, err := postLedgerTx(ctx, tx, entry); err != nil {
+		return err
 	}
 
 	return tx.Commit(ctx)
 }
 
-// RefundBalance adds balance back and creates refund log (transaction)
-func (r *UserRepository) RefundBalance(ctx context.Context, userID int, amount float64, description, referenceID string) error {
+// TopupBalance adds balance and creates deposit log (transaction)
+func (r *UserRepository) TopupBalance(ctx context.Context, userID int, amount float64, description, createdBy string) error {
+	return r.TopupBalanceWithType(ctx, userID, amount, model.DepositTypeCredit, description, "", createdBy)
+}
+
+// TopupBalanceWithType adds balance and creates a deposit log with the given deposit type (transaction)
+func (r *UserRepository) TopupBalanceWithType(ctx context.Context, userID int, amount float64, depositType, description, referenceID, createdBy string) error {
+	_, err := postLedger(ctx, r.db, model.LedgerEntry{
+		UserID:      userID,
+		Amount:      amount,
+		Type:        depositType,
+		Description: description,
+		ReferenceID: referenceID,
+		CreatedBy:   createdBy,
+	})
+	return err
+}
+
+// creditBalanceTx adds balance and creates a deposit log inside an existing transaction
+func creditBalanceTx(ctx context.Context, tx pgx.Tx, userID int, amount float64, depositType, description, referenceID, createdBy string) error {
+	_, err := postLedgerTx(ctx, tx, model.LedgerEntry{
+		UserID:      userID,
+		Amount:      amount,
+		Type:        depositType,
+		Description: description,
+		ReferenceID: referenceID,
+		CreatedBy:   createdBy,
+	})
+	return err
+}
+
+// DeductBalance subtracts balance and creates deposit log (transaction).
+// Returns ErrInsufficientBalance if the balance is too low.
+func (r *UserRepository) DeductBalance(ctx context.Context, userID int, amount float64, description, referenceID string) error {
+	_, err := postLedger(ctx, r.db, model.LedgerEntry{
+		UserID:      userID,
+		Amount:      amount,
+		Type:        model.DepositTypeDebit,
+		Description: description,
+		ReferenceID: referenceID,
+	})
+	return err
+}
+
+// DeductOrderBalance pays for a member order. The member's spending limits are checked
+// in the same transaction as the debit; a limit hit returns a *SpendingLimitError.
+func (r *UserRepository) DeductOrderBalance(ctx context.Context, userID int, amount float64, brand, description, referenceID string) error {
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
-	// Get current balance
-	var currentBalance float64
-	err = tx.QueryRow(ctx, "SELECT balance FROM users WHERE id = $1 FOR UPDATE", userID).Scan(&currentBalance)
-	if err != nil {
-		return fmt.Errorf("failed to get current balance: %w", err)
-	}
-
-	newBalance := currentBalance + amount
-
-	// Update balance
-	_, err = tx.Exec(ctx, "UPDATE users SET balance = $1 WHERE id = $2", newBalance, userID)
-	if err != nil {
-		return fmt.Errorf("failed to update balance: %w", err)
+	if err := checkSpendingLimitsTx(ctx, tx, userID, amount, brand); err != nil {
+		return err
 	}
 
-	// Create deposit log
-	_, err = tx.Exec(ctx, `
-		INSERT INTO deposits (user_id, amount, type, description, reference_id, status, created_by)
-		VALUES ($1, $2, 'refund', $3, $4, 'success', 'system')
-	`, userID, amount, description, referenceID)
+	_, err = postLedgerTx(ctx, tx, model.LedgerEntry{
+		UserID:      userID,
+		Amount:      amount,
+		Type:        model.DepositTypeDebit,
+		Description: description,
+		ReferenceID: referenceID,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to create deposit log: %w", err)
+		return err
 	}
 
 	return tx.Commit(ctx)
 }
 
+// ClawbackBalance takes back a previously credited amount (e.g. reversed commission).
+// Unlike DeductBalance it does not require sufficient balance, so the balance may go negative.
+func (r *UserRepository) ClawbackBalance(ctx context.Context, userID int, amount float64, depositType, description, referenceID string) error {
+	_, err := postLedger(ctx, r.db, model.LedgerEntry{
+		UserID:        userID,
+		Amount:        amount,
+		Type:          depositType,
+		Description:   description,
+		ReferenceID:   referenceID,
+		AllowNegative: true,
+	})
+	return err
+}
+
+// RefundBalance adds balance back and creates refund log (transaction).
+// An order can only be refunded once: a second refund for the same reference
+// returns ErrDuplicateLedgerEntry.
+func (r *UserRepository) RefundBalance(ctx context.Context, userID int, amount float64, description, referenceID string) error {
+	_, err := postLedger(ctx, r.db, model.LedgerEntry{
+		UserID:      userID,
+		Amount:      amount,
+		Type:        model.DepositTypeRefund,
+		Description: description,
+		ReferenceID: referenceID,
+	})
+	return err
+}
+
 // GetDeposits retrieves deposit history for a user with filters
 func (r *UserRepository) GetDeposits(ctx context.Context, userID int, limit, offset int, dateFrom, dateTo, depositType string) ([]model.Deposit, int, error) {
 	// Build dynamic WHERE clause
@@ -403,9 +537,10 @@ func (r *UserRepository) GetDeposits(ctx context.Context, userID int, limit, off
 
 	// Data query
 	query := fmt.Sprintf(`
-		SELECT id, user_id, amount, type, description, reference_id, status, created_by, created_at
+		SELECT id, user_id, amount, type, description, reference_id, status, created_by, created_at,
+			balance_before, balance_after
 		FROM deposits %s
-		ORDER BY created_at DESC
+		ORDER BY created_at DESC, id DESC
 		LIMIT $%d OFFSET $%d
 	`, whereClause, paramIdx, paramIdx+1)
 
@@ -430,6 +565,8 @@ func (r *UserRepository) GetDeposits(ctx context.Context, userID int, limit, off
 			&d.Status,
 			&d.CreatedBy,
 			&d.CreatedAt,
+			&d.BalanceBefore,
+			&d.BalanceAfter,
 		)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan deposit: %w", err)
@@ -459,12 +596,13 @@ func (r *UserRepository) GetMemberStats(ctx context.Context, userID int) (total,
 	return total, success, pending, today, nil
 }
 
-// Delete soft-deletes a user by setting status to suspended
-func (r *UserRepository) Delete(ctx context.Context, id int) error {
-	query := `UPDATE users SET status = 'suspended' WHERE id = $1`
-	_, err := r.db.Exec(ctx, query, id)
+// Delete soft-deletes a member by setting status to suspended.
+// Returns false when id is not a member.
+func (r *UserRepository) Delete(ctx context.Context, id int) (bool, error) {
+	query := `UPDATE users SET status = 'suspended' WHERE id = $1 AND role = 'member'`
+	result, err := r.db.Exec(ctx, query, id)
 	if err != nil {
-		return fmt.Errorf("failed to delete user: %w", err)
+		return false, fmt.Errorf("failed to delete user: %w", err)
 	}
-	return nil
+	return result.RowsAffected() > 0, nil
 }
@@ -0,0 +1,123 @@
+package callback
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"govershop-api/internal/config"
+)
+
+// Signature headers sent with every callback
+const (
+	HeaderEvent     = "X-Govershop-Event"
+	HeaderDelivery  = "X-Govershop-Delivery"
+	HeaderTimestamp = "X-Govershop-Timestamp"
+	HeaderSignature = "X-Govershop-Signature"
+)
+
+// Service posts signed callbacks to member URLs
+type Service struct {
+	config     *config.Config
+	httpClient *http.Client
+}
+
+// NewService creates a new callback Service.
+// Outside development, connections to private/loopback addresses are refused.
+func NewService(cfg *config.Config) *Service {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	if !cfg.IsDevelopment() {
+		dialer.Control = rejectPrivateAddress
+	}
+
+	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: 5 * time.Second,
+		MaxIdleConns:        20,
+		IdleConnTimeout:     60 * time.Second,
+	}
+
+	return &Service{
+		config: cfg,
+		httpClient: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: transport,
+			// Do not follow redirects; the registered URL must answer directly
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Result is the outcome of a delivery attempt
+type Result struct {
+	StatusCode int
+	Body       string // Truncated response body
+}
+
+// Sign returns hex(HMAC-SHA256(secret, timestamp + "." + body))
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Send posts a signed JSON body. A non-2xx response is returned as an error along with the result.
+func (s *Service) Send(ctx context.Context, url, secret, event string, deliveryID int, body []byte) (*Result, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader(string(body)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Govershop-Webhook/1.0")
+	req.Header.Set(HeaderEvent, event)
+	req.Header.Set(HeaderDelivery, strconv.Itoa(deliveryID))
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(secret, timestamp, body))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send callback: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	result := &Result{StatusCode: resp.StatusCode, Body: string(respBody)}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return result, fmt.Errorf("callback returned HTTP %d", resp.StatusCode)
+	}
+
+	return result, nil
+}
+
+// rejectPrivateAddress blocks callbacks to internal networks
+func rejectPrivateAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("invalid callback address %s", host)
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("callback address %s is not allowed", host)
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package email
 
 import (
 	"fmt"
+	"html"
 	"net/smtp"
 
 	"govershop-api/internal/config"
@@ -58,6 +59,170 @@ func (s *Service) SendResetPasswordEmail(toEmail, resetLink string) error {
 	return nil
 }
 
+// SendVerificationEmail sends the account verification link to a newly registered member
+func (s *Service) SendVerificationEmail(toEmail, fullName, verifyLink string) error {
+	from := s.config.SMTPFrom
+	pass := s.config.SMTPPass
+	host := s.config.SMTPHost
+	port := s.config.SMTPPort
+
+	auth := smtp.PlainAuth("", s.config.SMTPUser, pass, host)
+
+	subject := "Verifikasi Email Akun Govershop"
+	body := fmt.Sprintf(`
+		<html>
+		<body>
+			<h2>Halo %s,</h2>
+			<p>Terima kasih telah mendaftar sebagai member Govershop.</p>
+			<p>Silakan klik link di bawah ini untuk mengaktifkan akun Anda:</p>
+			<p><a href="%s">Verifikasi Email</a></p>
+			<p>Atau copy link ini: %s</p>
+			<p>Link ini valid selama 24 jam.</p>
+			<p>Jika Anda tidak merasa mendaftar, abaikan saja email ini.</p>
+		</body>
+		</html>
+	`, html.EscapeString(fullName), verifyLink, verifyLink)
+
+	msg := []byte("To: " + toEmail + "\r\n" +
+		"From: " + from + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/html; charset=\"UTF-8\"\r\n" +
+		"\r\n" +
+		body)
+
+	addr := fmt.Sprintf("%s:%d", host, port)
+
+	if err := smtp.SendMail(addr, auth, s.config.SMTPUser, []string{toEmail}, msg); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return nil
+}
+
+// SendAdminTOTPResetEmail sends the link that confirms an admin's break-glass TOTP reset
+func (s *Service) SendAdminTOTPResetEmail(toEmail, fullName, resetLink, requestIP string) error {
+	from := s.config.SMTPFrom
+	pass := s.config.SMTPPass
+	host := s.config.SMTPHost
+	port := s.config.SMTPPort
+
+	auth := smtp.PlainAuth("", s.config.SMTPUser, pass, host)
+
+	subject := "Konfirmasi Reset 2FA Admin Govershop"
+	body := fmt.Sprintf(`
+		<html>
+		<body>
+			<h2>Halo %s,</h2>
+			<p>Ada permintaan untuk menonaktifkan 2FA (TOTP) akun admin Anda dari IP %s.</p>
+			<p>Jika ini Anda, klik link di bawah ini untuk mengonfirmasi:</p>
+			<p><a href="%s">Konfirmasi Reset 2FA</a></p>
+			<p>Atau copy link ini: %s</p>
+			<p>Link ini valid selama 30 menit dan hanya bisa dipakai sekali.</p>
+			<p><b>Jika Anda tidak meminta ini, segera ganti password admin Anda.</b></p>
+		</body>
+		</html>
+	`, html.EscapeString(fullName), html.EscapeString(requestIP), resetLink, resetLink)
+
+	msg := []byte("To: " + toEmail + "\r\n" +
+		"From: " + from + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/html; charset=\"UTF-8\"\r\n" +
+		"\r\n" +
+		body)
+
+	addr := fmt.Sprintf("%s:%d", host, port)
+
+	if err := smtp.SendMail(addr, auth, s.config.SMTPUser, []string{toEmail}, msg); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return nil
+}
+
+// SendNewDeviceLoginEmail tells a user their account was just used from a device it has not seen before
+func (s *Service) SendNewDeviceLoginEmail(toEmail, fullName, device, ip, loginTime string) error {
+	from := s.config.SMTPFrom
+	pass := s.config.SMTPPass
+	host := s.config.SMTPHost
+	port := s.config.SMTPPort
+
+	auth := smtp.PlainAuth("", s.config.SMTPUser, pass, host)
+
+	subject := "Login Baru di Akun Govershop Anda"
+	body := fmt.Sprintf(`
+		<html>
+		<body>
+			<h2>Halo %s,</h2>
+			<p>Akun Govershop Anda baru saja login dari perangkat baru:</p>
+			<ul>
+				<li>Perangkat: %s</li>
+				<li>IP: %s</li>
+				<li>Waktu: %s</li>
+			</ul>
+			<p>Jika ini Anda, abaikan saja email ini.</p>
+			<p><b>Jika bukan Anda, segera ganti password dan akhiri semua sesi dari halaman akun.</b></p>
+		</body>
+		</html>
+	`, html.EscapeString(fullName), html.EscapeString(device), html.EscapeString(ip), loginTime)
+
+	msg := []byte("To: " + toEmail + "\r\n" +
+		"From: " + from + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/html; charset=\"UTF-8\"\r\n" +
+		"\r\n" +
+		body)
+
+	addr := fmt.Sprintf("%s:%d", host, port)
+
+	if err := smtp.SendMail(addr, auth, s.config.SMTPUser, []string{toEmail}, msg); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return nil
+}
+
+// SendAccountLockedEmail tells a user their account was locked after too many failed logins
+func (s *Service) SendAccountLockedEmail(toEmail, fullName, ip, lockedUntil string) error {
+	from := s.config.SMTPFrom
+	pass := s.config.SMTPPass
+	host := s.config.SMTPHost
+	port := s.config.SMTPPort
+
+	auth := smtp.PlainAuth("", s.config.SMTPUser, pass, host)
+
+	subject := "Akun Govershop Anda Dikunci Sementara"
+	body := fmt.Sprintf(`
+		<html>
+		<body>
+			<h2>Halo %s,</h2>
+			<p>Akun Govershop Anda dikunci sementara karena terlalu banyak percobaan login gagal.</p>
+			<p>Percobaan terakhir dari IP %s. Akun bisa dipakai lagi mulai %s.</p>
+			<p>Jika Anda lupa password, gunakan fitur lupa password setelah kunci berakhir.</p>
+			<p><b>Jika bukan Anda yang mencoba login, sebaiknya ganti password Anda.</b></p>
+		</body>
+		</html>
+	`, html.EscapeString(fullName), html.EscapeString(ip), lockedUntil)
+
+	msg := []byte("To: " + toEmail + "\r\n" +
+		"From: " + from + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/html; charset=\"UTF-8\"\r\n" +
+		"\r\n" +
+		body)
+
+	addr := fmt.Sprintf("%s:%d", host, port)
+
+	if err := smtp.SendMail(addr, auth, s.config.SMTPUser, []string{toEmail}, msg); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return nil
+}
+
 // BalanceAlertData holds data for the admin balance alert email
 type BalanceAlertData struct {
 	Date           string // e.g. "20 Februari 2026"
@@ -0,0 +1,228 @@
+package envelope
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Encrypted values look like enc:v1:<key id>:<wrapped data key>:<ciphertext>.
+// Each value has its own random AES-256 data key, sealed with AES-GCM under a
+// master key from config. Rotating the master key only re-wraps the data keys.
+const (
+	prefix  = "enc:v1:"
+	keySize = 32
+)
+
+// Errors returned when a value cannot be decrypted
+var (
+	ErrUnknownKey = errors.New("encryption key not configured")
+	ErrMalformed  = errors.New("malformed encrypted value")
+)
+
+var b64 = base64.RawStdEncoding
+
+// Keyring holds the master keys. New values are encrypted under the primary key;
+// values under any configured key can be decrypted.
+type Keyring struct {
+	primary string
+	keys    map[string][]byte
+}
+
+// NewKeyring parses a key list of the form "id1:base64key,id2:base64key" (32-byte
+// keys). primaryID selects the key for new values and defaults to the first one.
+// An empty list gives a disabled keyring that stores values as plain text.
+func NewKeyring(keyList, primaryID string) (*Keyring, error) {
+	k := &Keyring{keys: map[string][]byte{}}
+
+	for _, entry := range strings.Split(keyList, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("invalid encryption key entry %q, want id:base64key", entry)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != keySize {
+			return nil, fmt.Errorf("encryption key %q must be %d bytes, base64 encoded", id, keySize)
+		}
+		if _, dup := k.keys[id]; dup {
+			return nil, fmt.Errorf("duplicate encryption key id %q", id)
+		}
+		k.keys[id] = key
+		if k.primary == "" {
+			k.primary = id
+		}
+	}
+
+	if primaryID != "" {
+		if _, ok := k.keys[primaryID]; !ok {
+			return nil, fmt.Errorf("primary encryption key %q is not in the key list", primaryID)
+		}
+		k.primary = primaryID
+	}
+	return k, nil
+}
+
+// Enabled reports whether a master key is configured
+func (k *Keyring) Enabled() bool {
+	return k.primary != ""
+}
+
+// PrimaryKeyID returns the ID of the key used for new values
+func (k *Keyring) PrimaryKeyID() string {
+	return k.primary
+}
+
+// IsEncrypted reports whether a stored value was written by Encrypt
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// KeyID returns the master key ID of an encrypted value ("" for plain text)
+func KeyID(value string) string {
+	if !IsEncrypted(value) {
+		return ""
+	}
+	id, _, _ := strings.Cut(strings.TrimPrefix(value, prefix), ":")
+	return id
+}
+
+// Encrypt seals a value under the primary key. Empty values stay empty, and
+// values pass through unchanged when the keyring is disabled.
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	if plaintext == "" || !k.Enabled() {
+		return plaintext, nil
+	}
+
+	dataKey := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return "", fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	ciphertext, err := seal(dataKey, []byte(plaintext), nil)
+	if err != nil {
+		return "", err
+	}
+	return k.wrap(k.primary, dataKey, ciphertext)
+}
+
+// Decrypt opens a value written by Encrypt. Plain text values written before
+// encryption was enabled are returned as they are.
+func (k *Keyring) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+
+	_, dataKey, ciphertext, err := k.unwrap(value)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(dataKey, ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// Rewrap moves an encrypted value to the primary key without touching its
+// ciphertext. Returns false when the value is plain text or already uses the
+// primary key.
+func (k *Keyring) Rewrap(value string) (string, bool, error) {
+	if !IsEncrypted(value) || !k.Enabled() || KeyID(value) == k.primary {
+		return value, false, nil
+	}
+
+	_, dataKey, ciphertext, err := k.unwrap(value)
+	if err != nil {
+		return "", false, err
+	}
+	rewrapped, err := k.wrap(k.primary, dataKey, ciphertext)
+	if err != nil {
+		return "", false, err
+	}
+	return rewrapped, true, nil
+}
+
+// wrap seals the data key under a master key and formats the stored value.
+// The key ID is authenticated so a wrapped key cannot be relabelled.
+func (k *Keyring) wrap(keyID string, dataKey, ciphertext []byte) (string, error) {
+	wrapped, err := seal(k.keys[keyID], dataKey, []byte(keyID))
+	if err != nil {
+		return "", err
+	}
+	return prefix + keyID + ":" + b64.EncodeToString(wrapped) + ":" + b64.EncodeToString(ciphertext), nil
+}
+
+// unwrap parses a stored value and opens its data key
+func (k *Keyring) unwrap(value string) (keyID string, dataKey, ciphertext []byte, err error) {
+	parts := strings.Split(strings.TrimPrefix(value, prefix), ":")
+	if len(parts) != 3 {
+		return "", nil, nil, ErrMalformed
+	}
+	keyID = parts[0]
+
+	masterKey, ok := k.keys[keyID]
+	if !ok {
+		return "", nil, nil, fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+	}
+	wrapped, err := b64.DecodeString(parts[1])
+	if err != nil {
+		return "", nil, nil, ErrMalformed
+	}
+	ciphertext, err = b64.DecodeString(parts[2])
+	if err != nil {
+		return "", nil, nil, ErrMalformed
+	}
+
+	dataKey, err = open(masterKey, wrapped, []byte(keyID))
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return keyID, dataKey, ciphertext, nil
+}
+
+// seal encrypts with AES-GCM and returns nonce || ciphertext
+func seal(key, plaintext, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return gcm.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+// open decrypts nonce || ciphertext written by seal
+func open(key, sealed, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, ErrMalformed
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt value: %w", err)
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,312 @@
+package envelope
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, keySize))
+}
+
+func mustKeyring(t *testing.T, keyList, primaryID string) *Keyring {
+	t.Helper()
+	k, err := NewKeyring(keyList, primaryID)
+	if err != nil {
+		t.Fatalf("NewKeyring(%q, %q): %v", keyList, primaryID, err)
+	}
+	return k
+}
+
+func TestNewKeyring(t *testing.T) {
+	tests := []struct {
+		name        string
+		keyList     string
+		primaryID   string
+		wantErr     bool
+		wantPrimary string
+	}{
+		{name: "empty list is disabled", keyList: "", wantPrimary: ""},
+		{name: "first key is primary", keyList: "a:" + testKey(1) + ",b:" + testKey(2), wantPrimary: "a"},
+		{name: "explicit primary", keyList: "a:" + testKey(1) + ",b:" + testKey(2), primaryID: "b", wantPrimary: "b"},
+		{name: "spaces and empty entries", keyList: " a:" + testKey(1) + " ,,", wantPrimary: "a"},
+		{name: "missing id", keyList: ":" + testKey(1), wantErr: true},
+		{name: "no separator", keyList: testKey(1), wantErr: true},
+		{name: "bad base64", keyList: "a:not-base64!", wantErr: true},
+		{name: "short key", keyList: "a:" + base64.StdEncoding.EncodeToString([]byte("short")), wantErr: true},
+		{name: "duplicate id", keyList: "a:" + testKey(1) + ",a:" + testKey(2), wantErr: true},
+		{name: "unknown primary", keyList: "a:" + testKey(1), primaryID: "b", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, err := NewKeyring(tt.keyList, tt.primaryID)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := k.PrimaryKeyID(); got != tt.wantPrimary {
+				t.Errorf("PrimaryKeyID() = %q, want %q", got, tt.wantPrimary)
+			}
+			if got := k.Enabled(); got != (tt.wantPrimary != "") {
+				t.Errorf("Enabled() = %v", got)
+			}
+		})
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	k := mustKeyring(t, "k1:"+testKey(1), "")
+
+	tests := []struct {
+		name      string
+		plaintext string
+	}{
+		{name: "ascii", plaintext: "JBSWY3DPEHPK3PXP"},
+		{name: "unicode", plaintext: "rahasia ✓ 秘密"},
+		{name: "contains separator", plaintext: "a:b:c:enc:v1:"},
+		{name: "long", plaintext: strings.Repeat("x", 4096)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encrypted, err := k.Encrypt(tt.plaintext)
+			if err != nil {
+				t.Fatalf("Encrypt: %v", err)
+			}
+			if !IsEncrypted(encrypted) {
+				t.Fatalf("Encrypt returned %q without the prefix", encrypted)
+			}
+			if strings.Contains(encrypted, tt.plaintext) {
+				t.Fatal("ciphertext contains the plaintext")
+			}
+			if got := KeyID(encrypted); got != "k1" {
+				t.Errorf("KeyID() = %q, want k1", got)
+			}
+
+			decrypted, err := k.Decrypt(encrypted)
+			if err != nil {
+				t.Fatalf("Decrypt: %v", err)
+			}
+			if decrypted != tt.plaintext {
+				t.Errorf("Decrypt() = %q, want %q", decrypted, tt.plaintext)
+			}
+		})
+	}
+}
+
+func TestEncryptUsesFreshDataKeys(t *testing.T) {
+	k := mustKeyring(t, "k1:"+testKey(1), "")
+
+	a, err := k.Encrypt("same")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := k.Encrypt("same")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == b {
+		t.Error("encrypting the same value twice gave the same ciphertext")
+	}
+}
+
+func TestEncryptPassThrough(t *testing.T) {
+	disabled := mustKeyring(t, "", "")
+	enabled := mustKeyring(t, "k1:"+testKey(1), "")
+
+	tests := []struct {
+		name  string
+		k     *Keyring
+		value string
+	}{
+		{name: "disabled keyring", k: disabled, value: "secret"},
+		{name: "empty value", k: enabled, value: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.k.Encrypt(tt.value)
+			if err != nil {
+				t.Fatalf("Encrypt: %v", err)
+			}
+			if got != tt.value {
+				t.Errorf("Encrypt() = %q, want %q unchanged", got, tt.value)
+			}
+		})
+	}
+}
+
+func TestDecryptPlainText(t *testing.T) {
+	k := mustKeyring(t, "k1:"+testKey(1), "")
+
+	for _, value := range []string{"", "JBSWY3DPEHPK3PXP", "enc:v2:not-ours"} {
+		got, err := k.Decrypt(value)
+		if err != nil {
+			t.Fatalf("Decrypt(%q): %v", value, err)
+		}
+		if got != value {
+			t.Errorf("Decrypt(%q) = %q, want it unchanged", value, got)
+		}
+	}
+}
+
+func TestDecryptErrors(t *testing.T) {
+	k1 := mustKeyring(t, "k1:"+testKey(1), "")
+	encrypted, err := k1.Encrypt("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(strings.TrimPrefix(encrypted, prefix), ":")
+
+	tests := []struct {
+		name    string
+		k       *Keyring
+		value   string
+		wantErr error // nil accepts any error
+	}{
+		{name: "key not configured", k: mustKeyring(t, "k2:"+testKey(2), ""), value: encrypted, wantErr: ErrUnknownKey},
+		{name: "disabled keyring", k: mustKeyring(t, "", ""), value: encrypted, wantErr: ErrUnknownKey},
+		{name: "same id, different key", k: mustKeyring(t, "k1:"+testKey(9), ""), value: encrypted},
+		{name: "relabelled key id", k: mustKeyring(t, "k1:"+testKey(1)+",k2:"+testKey(1), ""), value: prefix + "k2:" + parts[1] + ":" + parts[2]},
+		{name: "too few parts", k: k1, value: prefix + "k1:" + parts[1], wantErr: ErrMalformed},
+		{name: "too many parts", k: k1, value: encrypted + ":extra", wantErr: ErrMalformed},
+		{name: "bad wrapped key encoding", k: k1, value: prefix + "k1:!!!:" + parts[2], wantErr: ErrMalformed},
+		{name: "bad ciphertext encoding", k: k1, value: prefix + "k1:" + parts[1] + ":!!!", wantErr: ErrMalformed},
+		{name: "truncated wrapped key", k: k1, value: prefix + "k1:AAAA:" + parts[2], wantErr: ErrMalformed},
+		{name: "tampered ciphertext", k: k1, value: prefix + "k1:" + parts[1] + ":" + tamper(t, parts[2])},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.k.Decrypt(tt.value)
+			if err == nil {
+				t.Fatalf("Decrypt() = %q, want an error", got)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Decrypt() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRewrap(t *testing.T) {
+	old := mustKeyring(t, "k1:"+testKey(1), "")
+	rotated := mustKeyring(t, "k1:"+testKey(1)+",k2:"+testKey(2), "k2")
+
+	encrypted, err := old.Encrypt("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rewrapped, changed, err := rotated.Rewrap(encrypted)
+	if err != nil {
+		t.Fatalf("Rewrap: %v", err)
+	}
+	if !changed {
+		t.Fatal("Rewrap reported no change for a value under the old key")
+	}
+	if got := KeyID(rewrapped); got != "k2" {
+		t.Errorf("KeyID() = %q, want k2", got)
+	}
+	// Only the data key is re-wrapped; the ciphertext stays the same
+	if oldCT, newCT := encrypted[strings.LastIndex(encrypted, ":"):], rewrapped[strings.LastIndex(rewrapped, ":"):]; oldCT != newCT {
+		t.Error("Rewrap changed the ciphertext")
+	}
+
+	newOnly := mustKeyring(t, "k2:"+testKey(2), "")
+	decrypted, err := newOnly.Decrypt(rewrapped)
+	if err != nil {
+		t.Fatalf("Decrypt after Rewrap: %v", err)
+	}
+	if decrypted != "secret" {
+		t.Errorf("Decrypt() = %q, want secret", decrypted)
+	}
+
+	// Rewrapping again is a no-op
+	again, changed, err := rotated.Rewrap(rewrapped)
+	if err != nil || changed || again != rewrapped {
+		t.Errorf("Rewrap of a value under the primary key = (%q, %v, %v), want it unchanged", again, changed, err)
+	}
+}
+
+func TestRewrapUnchanged(t *testing.T) {
+	k := mustKeyring(t, "k1:"+testKey(1), "")
+	disabled := mustKeyring(t, "", "")
+	encrypted, err := k.Encrypt("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		k     *Keyring
+		value string
+	}{
+		{name: "plain text", k: k, value: "secret"},
+		{name: "already primary", k: k, value: encrypted},
+		{name: "disabled keyring", k: disabled, value: encrypted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed, err := tt.k.Rewrap(tt.value)
+			if err != nil {
+				t.Fatalf("Rewrap: %v", err)
+			}
+			if changed || got != tt.value {
+				t.Errorf("Rewrap() = (%q, %v), want the value unchanged", got, changed)
+			}
+		})
+	}
+}
+
+func TestRewrapUnknownKey(t *testing.T) {
+	old := mustKeyring(t, "k1:"+testKey(1), "")
+	encrypted, err := old.Encrypt("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other := mustKeyring(t, "k2:"+testKey(2), "")
+	if _, _, err := other.Rewrap(encrypted); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Rewrap() error = %v, want %v", err, ErrUnknownKey)
+	}
+}
+
+func TestKeyID(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "", want: ""},
+		{value: "plain", want: ""},
+		{value: "enc:v1:k1:abc:def", want: "k1"},
+		{value: "enc:v1:", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := KeyID(tt.value); got != tt.want {
+			t.Errorf("KeyID(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+// tamper flips a bit in the last byte of a base64 encoded value
+func tamper(t *testing.T, s string) string {
+	t.Helper()
+	raw, err := b64.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw[len(raw)-1] ^= 1
+	return b64.EncodeToString(raw)
+}
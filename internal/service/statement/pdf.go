@@ -0,0 +1,152 @@
+package statement
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// pdfDoc is a minimal PDF writer for text, lines and filled rectangles using the
+// built-in Helvetica fonts, so statements can be rendered without extra dependencies.
+type pdfDoc struct {
+	width, height float64
+	pages         []*bytes.Buffer
+	cur           *bytes.Buffer
+}
+
+func newPDFDoc(width, height float64) *pdfDoc {
+	return &pdfDoc{width: width, height: height}
+}
+
+func (d *pdfDoc) addPage() {
+	d.cur = &bytes.Buffer{}
+	d.pages = append(d.pages, d.cur)
+}
+
+// rect fills a rectangle with an RGB color (0-255 per channel)
+func (d *pdfDoc) rect(x, y, w, h float64, c rgb) {
+	fmt.Fprintf(d.cur, "%s rg %.2f %.2f %.2f %.2f re f\n", c.pdf(), x, y, w, h)
+}
+
+// line draws a thin line
+func (d *pdfDoc) line(x1, y1, x2, y2 float64, c rgb) {
+	fmt.Fprintf(d.cur, "%s RG 0.5 w %.2f %.2f m %.2f %.2f l S\n", c.pdf(), x1, y1, x2, y2)
+}
+
+// text writes s with its baseline at (x, y)
+func (d *pdfDoc) text(x, y, size float64, bold bool, c rgb, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(d.cur, "BT %s rg /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", c.pdf(), font, size, x, y, pdfEscape(s))
+}
+
+// textRight writes s right-aligned to x
+func (d *pdfDoc) textRight(x, y, size float64, bold bool, c rgb, s string) {
+	d.text(x-textWidth(s, size, bold), y, size, bold, c, s)
+}
+
+// bytes assembles the document
+func (d *pdfDoc) bytes() []byte {
+	var out bytes.Buffer
+	var offsets []int
+
+	obj := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// 1: catalog, 2: page tree, 3-4: fonts, then a page + content stream per page
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+i*2)
+	}
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, page := range d.pages {
+		obj(fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			d.width, d.height, 6+i*2,
+		))
+		obj(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return out.Bytes()
+}
+
+// rgb is a fill/stroke color
+type rgb struct{ r, g, b uint8 }
+
+func (c rgb) pdf() string {
+	return fmt.Sprintf("%.3f %.3f %.3f", float64(c.r)/255, float64(c.g)/255, float64(c.b)/255)
+}
+
+// pdfEscape encodes s for a PDF string literal in WinAnsiEncoding.
+// Characters outside Latin-1 are replaced with '?'.
+func pdfEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(byte(r))
+		case r == '\n' || r == '\r' || r == '\t':
+			b.WriteByte(' ')
+		case r < 32 || r > 255:
+			b.WriteByte('?')
+		default:
+			b.WriteByte(byte(r))
+		}
+	}
+	return b.String()
+}
+
+// textWidth approximates the width of s in Helvetica at the given size
+func textWidth(s string, size float64, bold bool) float64 {
+	var units float64
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			units += 556
+		case r == '.' || r == ',' || r == ' ' || r == ':' || r == 'i' || r == 'l' || r == 'j':
+			units += 278
+		case r == '-' || r == '(' || r == ')' || r == 'f' || r == 't' || r == 'r':
+			units += 333
+		case r == 'm' || r == 'w' || r == 'M' || r == 'W':
+			units += 833
+		case r >= 'A' && r <= 'Z':
+			units += 667
+		default:
+			units += 556
+		}
+	}
+	if bold {
+		units *= 1.06
+	}
+	return units * size / 1000
+}
+
+// fitText shortens s with "..." so it fits within width
+func fitText(s string, width, size float64, bold bool) string {
+	if textWidth(s, size, bold) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && textWidth(string(runes)+"...", size, bold) > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
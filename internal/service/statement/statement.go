@@ -0,0 +1,265 @@
+package statement
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"govershop-api/internal/model"
+)
+
+// FileName returns the download name of a statement
+func FileName(st *model.Statement, format string) string {
+	return fmt.Sprintf("mutasi-%s-%s-%s.%s", st.Username, st.DateFrom, st.DateTo, format)
+}
+
+// WriteCSV writes a statement as CSV. The first and last rows carry the opening
+// and closing balance so the file can be reconciled on its own.
+func WriteCSV(w io.Writer, st *model.Statement) error {
+	out := csv.NewWriter(w)
+
+	out.Write([]string{
+		"tanggal", "tipe", "keterangan", "ref_id", "produk", "tujuan", "sn", "status_order",
+		"kredit", "debit", "saldo",
+	})
+	out.Write([]string{st.DateFrom, "", "SALDO AWAL", "", "", "", "", "", "", "", csvAmount(st.OpeningBalance)})
+
+	for _, e := range st.Entries {
+		out.Write([]string{
+			e.Date.Format("2006-01-02 15:04:05"), e.Type, e.Description, e.ReferenceID,
+			e.ProductName, e.Destination, e.SerialNumber, e.OrderStatus,
+			csvAmount(e.Credit), csvAmount(e.Debit), csvAmount(e.Balance),
+		})
+	}
+
+	out.Write([]string{
+		st.DateTo, "", "SALDO AKHIR", "", "", "", "", "",
+		csvAmount(st.TotalCredit), csvAmount(st.TotalDebit), csvAmount(st.ClosingBalance),
+	})
+
+	out.Flush()
+	return out.Error()
+}
+
+func csvAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+// Page layout (A4 landscape, points)
+const (
+	pageWidth    = 842.0
+	pageHeight   = 595.0
+	pageMargin   = 30.0
+	rowHeight    = 14.0
+	tableFont    = 7.0
+	footerHeight = 30.0
+)
+
+var (
+	brandColor = rgb{30, 58, 138}
+	white      = rgb{255, 255, 255}
+	black      = rgb{33, 33, 33}
+	grey       = rgb{117, 117, 117}
+	lightGrey  = rgb{238, 240, 245}
+	creditText = rgb{21, 128, 61}
+	debitText  = rgb{185, 28, 28}
+)
+
+type pdfColumn struct {
+	title string
+	width float64
+	right bool
+}
+
+var statementColumns = []pdfColumn{
+	{"Tanggal", 70, false},
+	{"Tipe", 62, false},
+	{"Keterangan", 158, false},
+	{"Ref ID", 98, false},
+	{"Produk", 96, false},
+	{"Tujuan", 66, false},
+	{"SN", 72, false},
+	{"Kredit", 52, true},
+	{"Debit", 52, true},
+	{"Saldo", 56, true},
+}
+
+// RenderPDF renders a statement as a branded PDF
+func RenderPDF(st *model.Statement) []byte {
+	doc := newPDFDoc(pageWidth, pageHeight)
+
+	doc.addPage()
+	y := drawFirstPageHeader(doc, st)
+	y = drawTableHeader(doc, y)
+
+	// Opening balance row
+	doc.text(pageMargin+4, y-10, tableFont, true, black, "SALDO AWAL")
+	doc.textRight(pageWidth-pageMargin-4, y-10, tableFont, true, black, formatRupiah(st.OpeningBalance))
+	y -= rowHeight
+
+	for i, e := range st.Entries {
+		if y-rowHeight < pageMargin+footerHeight {
+			doc.addPage()
+			y = drawPageHeader(doc, st)
+			y = drawTableHeader(doc, y)
+		}
+
+		if i%2 == 1 {
+			doc.rect(pageMargin, y-rowHeight, pageWidth-2*pageMargin, rowHeight, lightGrey)
+		}
+
+		product := e.ProductName
+		if e.OrderStatus != "" {
+			product = fmt.Sprintf("%s (%s)", e.ProductName, e.OrderStatus)
+		}
+		cells := []string{
+			e.Date.Format("02/01/06 15:04"),
+			e.Type,
+			e.Description,
+			e.ReferenceID,
+			product,
+			e.Destination,
+			e.SerialNumber,
+			amountOrBlank(e.Credit),
+			amountOrBlank(e.Debit),
+			formatRupiah(e.Balance),
+		}
+		drawRow(doc, y, cells, false)
+		y -= rowHeight
+	}
+
+	if y-3*rowHeight < pageMargin+footerHeight {
+		doc.addPage()
+		y = drawPageHeader(doc, st)
+	}
+	doc.line(pageMargin, y, pageWidth-pageMargin, y, brandColor)
+	drawRow(doc, y, []string{"", "", "SALDO AKHIR", "", "", "", "",
+		formatRupiah(st.TotalCredit), formatRupiah(st.TotalDebit), formatRupiah(st.ClosingBalance)}, true)
+
+	// Footer on every page now that the page count is known
+	for i, page := range doc.pages {
+		doc.cur = page
+		doc.line(pageMargin, pageMargin+footerHeight-8, pageWidth-pageMargin, pageMargin+footerHeight-8, lightGrey)
+		doc.text(pageMargin, pageMargin+6, 7, false, grey, "Dokumen ini dibuat otomatis oleh sistem Govershop dan sah tanpa tanda tangan.")
+		doc.textRight(pageWidth-pageMargin, pageMargin+6, 7, false, grey, fmt.Sprintf("Halaman %d dari %d", i+1, len(doc.pages)))
+	}
+
+	return doc.bytes()
+}
+
+// drawFirstPageHeader draws the brand band and the statement summary; returns the next y
+func drawFirstPageHeader(doc *pdfDoc, st *model.Statement) float64 {
+	top := drawPageHeader(doc, st)
+
+	y := top - 4
+	doc.text(pageMargin, y, 10, true, black, fmt.Sprintf("%s (%s)", st.FullName, st.Username))
+	doc.text(pageMargin, y-14, 8, false, grey, fmt.Sprintf("Dicetak %s WIB", st.GeneratedAt.Format("02 Jan 2006 15:04")))
+
+	// Summary boxes
+	boxes := []struct {
+		label string
+		value float64
+		color rgb
+	}{
+		{"Saldo Awal", st.OpeningBalance, black},
+		{"Total Kredit", st.TotalCredit, creditText},
+		{"Total Debit", st.TotalDebit, debitText},
+		{"Saldo Akhir", st.ClosingBalance, black},
+	}
+	boxWidth := (pageWidth - 2*pageMargin - 3*10) / 4
+	boxTop := y - 26
+	for i, b := range boxes {
+		x := pageMargin + float64(i)*(boxWidth+10)
+		doc.rect(x, boxTop-36, boxWidth, 36, lightGrey)
+		doc.text(x+8, boxTop-13, 7.5, false, grey, b.label)
+		doc.text(x+8, boxTop-28, 11, true, b.color, "Rp "+formatRupiah(b.value))
+	}
+
+	return boxTop - 50
+}
+
+// drawPageHeader draws the brand band; returns the y below it
+func drawPageHeader(doc *pdfDoc, st *model.Statement) float64 {
+	bandHeight := 56.0
+	doc.rect(0, pageHeight-bandHeight, pageWidth, bandHeight, brandColor)
+	doc.text(pageMargin, pageHeight-30, 20, true, white, "GOVERSHOP")
+	doc.text(pageMargin, pageHeight-45, 9, false, white, "Laporan Mutasi Saldo Member")
+	doc.textRight(pageWidth-pageMargin, pageHeight-30, 9, true, white, fmt.Sprintf("Periode %s s/d %s", formatDate(st.DateFrom), formatDate(st.DateTo)))
+	doc.textRight(pageWidth-pageMargin, pageHeight-45, 8, false, white, st.Username)
+	return pageHeight - bandHeight - 16
+}
+
+// drawTableHeader draws the column titles; returns the y of the first row
+func drawTableHeader(doc *pdfDoc, y float64) float64 {
+	doc.rect(pageMargin, y-rowHeight, pageWidth-2*pageMargin, rowHeight, brandColor)
+	x := pageMargin
+	for _, col := range statementColumns {
+		if col.right {
+			doc.textRight(x+col.width-4, y-10, tableFont, true, white, col.title)
+		} else {
+			doc.text(x+4, y-10, tableFont, true, white, col.title)
+		}
+		x += col.width
+	}
+	return y - rowHeight
+}
+
+func drawRow(doc *pdfDoc, y float64, cells []string, bold bool) {
+	x := pageMargin
+	for i, col := range statementColumns {
+		text := fitText(cells[i], col.width-8, tableFont, bold)
+		color := black
+		if i == 7 && cells[i] != "" && !bold {
+			color = creditText
+		}
+		if i == 8 && cells[i] != "" && !bold {
+			color = debitText
+		}
+		if col.right {
+			doc.textRight(x+col.width-4, y-10, tableFont, bold, color, text)
+		} else {
+			doc.text(x+4, y-10, tableFont, bold, color, text)
+		}
+		x += col.width
+	}
+}
+
+func amountOrBlank(amount float64) string {
+	if amount == 0 {
+		return ""
+	}
+	return formatRupiah(amount)
+}
+
+// formatDate turns YYYY-MM-DD into DD/MM/YYYY
+func formatDate(date string) string {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return date
+	}
+	return t.Format("02/01/2006")
+}
+
+// formatRupiah formats an amount with dots as thousand separators (no decimals)
+func formatRupiah(amount float64) string {
+	negative := amount < 0
+	if negative {
+		amount = -amount
+	}
+
+	str := strconv.FormatInt(int64(amount+0.5), 10)
+	var result []byte
+	for i := range str {
+		if i > 0 && (len(str)-i)%3 == 0 {
+			result = append(result, '.')
+		}
+		result = append(result, str[i])
+	}
+
+	if negative {
+		return "-" + string(result)
+	}
+	return string(result)
+}
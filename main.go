@@ -14,9 +14,12 @@ import (
 	"govershop-api/internal/config"
 	"govershop-api/internal/handler"
 	"govershop-api/internal/middleware"
+	"govershop-api/internal/model"
 	"govershop-api/internal/repository"
+	"govershop-api/internal/service/callback"
 	"govershop-api/internal/service/digiflazz"
 	"govershop-api/internal/service/email"
+	"govershop-api/internal/service/envelope"
 	"govershop-api/internal/service/pakasir"
 	"govershop-api/internal/service/qrispw"
 )
@@ -46,11 +49,29 @@ func main() {
 	// Run auto-migrations
 	config.RunMigrations(db)
 
+	// Credential encryption at rest
+	keyring, err := envelope.NewKeyring(cfg.EncryptionKeys, cfg.EncryptionKeyID)
+	if err != nil {
+		log.Fatalf("❌ Invalid encryption keys: %v", err)
+	}
+	if !keyring.Enabled() {
+		log.Println("⚠️ ENCRYPTION_KEYS not set, TOTP and API secrets are stored unencrypted")
+	}
+
+	// Maintenance commands (./main secrets encrypt|rotate) run instead of the server
+	if len(os.Args) > 1 {
+		if err := runCommand(context.Background(), db, keyring, os.Args[1:]); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		return
+	}
+
 	// Initialize services
 	digiflazzSvc := digiflazz.NewService(cfg)
 	pakasirSvc := pakasir.NewService(cfg)
 	qrispwSvc := qrispw.NewService(cfg)
 	emailSvc := email.NewService(cfg)
+	callbackSvc := callback.NewService(cfg)
 
 	// Initialize repositories
 	productRepo := repository.NewProductRepository(db)
@@ -59,25 +80,76 @@ func main() {
 	webhookRepo := repository.NewWebhookLogRepository(db)
 	syncLogRepo := repository.NewSyncLogRepository(db)
 	contentRepo := repository.NewContentRepository(db)
-	adminSecurityRepo := repository.NewAdminSecurityRepository(db)
+	adminSecurityRepo := repository.NewAdminSecurityRepository(db, keyring)
 	userRepo := repository.NewUserRepository(db)
+	promoRepo := repository.NewPromoRepository(db)
+	flashSaleRepo := repository.NewFlashSaleRepository(db)
+	referralRepo := repository.NewReferralRepository(db)
+	pointsRepo := repository.NewPointsRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db, keyring)
+	memberWebhookRepo := repository.NewMemberWebhookRepository(db, keyring)
+	settingsRepo := repository.NewSettingsRepository(db)
+	memberSecurityRepo := repository.NewMemberSecurityRepository(db, keyring)
+	sessionRepo := repository.NewSessionRepository(db)
+	ledgerRepo := repository.NewLedgerRepository(db)
+	transferRepo := repository.NewTransferRepository(db)
+	batchRepo := repository.NewBatchRepository(db)
+	statementRepo := repository.NewStatementRepository(db)
+	idempotencyRepo := repository.NewIdempotencyRepository(db)
+	adjustmentRepo := repository.NewAdjustmentRepository(db)
+	favoriteRepo := repository.NewFavoriteRepository(db)
+	limitRepo := repository.NewSpendingLimitRepository(db)
+	adminRepo := repository.NewAdminRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	loginSecurityRepo := repository.NewLoginSecurityRepository(db)
 
 	// Initialize handlers
-	productHandler := handler.NewProductHandler(productRepo)
-	orderHandler := handler.NewOrderHandler(cfg, orderRepo, paymentRepo, productRepo, digiflazzSvc, pakasirSvc, qrispwSvc, emailSvc)
-	webhookHandler := handler.NewWebhookHandler(cfg, orderRepo, paymentRepo, webhookRepo, userRepo, digiflazzSvc)
-	adminHandler := handler.NewAdminHandler(cfg, digiflazzSvc, productRepo, orderRepo, syncLogRepo, paymentRepo, pakasirSvc, webhookRepo, userRepo)
+	productHandler := handler.NewProductHandler(productRepo, flashSaleRepo)
+	fulfillment := handler.NewOrderFulfillment(cfg, orderRepo, userRepo, promoRepo, referralRepo, pointsRepo, productRepo, digiflazzSvc, memberWebhookRepo)
+	orderHandler := handler.NewOrderHandler(cfg, orderRepo, paymentRepo, productRepo, promoRepo, flashSaleRepo, digiflazzSvc, pakasirSvc, qrispwSvc, emailSvc, fulfillment)
+	loginGuard := handler.NewLoginGuard(cfg, loginSecurityRepo, emailSvc)
+	webhookHandler := handler.NewWebhookHandler(cfg, orderRepo, paymentRepo, webhookRepo, fulfillment)
+	adminHandler := handler.NewAdminHandler(cfg, digiflazzSvc, productRepo, orderRepo, syncLogRepo, paymentRepo, pakasirSvc, webhookRepo, userRepo, promoRepo, sessionRepo, adminRepo, loginGuard)
 
 	// Start background jobs
 	adminHandler.StartSyncJob(context.Background())
+	orderHandler.StartExpiryJob(context.Background())
 
-	validationHandler := handler.NewValidationHandler(cfg, productRepo, orderRepo, digiflazzSvc)
+	validationHandler := handler.NewValidationHandler(cfg, productRepo, orderRepo, promoRepo, digiflazzSvc)
 	contentHandler := handler.NewContentHandler(contentRepo)
-	totpHandler := handler.NewTOTPHandler(cfg, adminSecurityRepo, orderRepo, paymentRepo, digiflazzSvc)
-	memberHandler := handler.NewMemberHandler(cfg, userRepo, productRepo, orderRepo, digiflazzSvc, emailSvc)
+	promoHandler := handler.NewPromoHandler(promoRepo)
+	flashSaleHandler := handler.NewFlashSaleHandler(flashSaleRepo, productRepo)
+	flashSaleHandler.StartScheduler(context.Background())
+	totpHandler := handler.NewTOTPHandler(cfg, adminSecurityRepo, orderRepo, paymentRepo, digiflazzSvc, memberWebhookRepo, fulfillment, userRepo, emailSvc)
+	memberHandler := handler.NewMemberHandler(cfg, userRepo, productRepo, orderRepo, promoRepo, flashSaleRepo, pointsRepo, digiflazzSvc, emailSvc, memberWebhookRepo, memberSecurityRepo, sessionRepo, fulfillment, favoriteRepo, limitRepo, loginGuard)
+	referralHandler := handler.NewReferralHandler(cfg, userRepo, referralRepo)
+	pointsHandler := handler.NewPointsHandler(cfg, pointsRepo, userRepo)
+	pointsHandler.StartExpiryJob(context.Background())
+	h2hHandler := handler.NewH2HHandler(cfg, apiKeyRepo, userRepo, productRepo, orderRepo, memberHandler)
+	memberWebhookHandler := handler.NewMemberWebhookHandler(cfg, memberWebhookRepo, callbackSvc)
+	memberWebhookHandler.StartDeliveryWorker(context.Background())
+	registrationHandler := handler.NewRegistrationHandler(cfg, userRepo, settingsRepo, emailSvc)
+	sessionHandler := handler.NewSessionHandler(cfg, sessionRepo, userRepo)
+	sessionHandler.StartCleanupJob(context.Background())
+	ledgerHandler := handler.NewLedgerHandler(ledgerRepo)
+	ledgerHandler.StartReconciliationJob(context.Background())
+	transferHandler := handler.NewTransferHandler(cfg, transferRepo, userRepo, memberSecurityRepo)
+	batchHandler := handler.NewBatchHandler(cfg, batchRepo, productRepo, orderRepo, memberSecurityRepo, memberHandler)
+	batchHandler.StartBatchWorker(context.Background())
+	statementHandler := handler.NewStatementHandler(statementRepo)
+	statementHandler.StartStatementWorker(context.Background())
+	adjustmentHandler := handler.NewAdjustmentHandler(cfg, adjustmentRepo, userRepo, adminSecurityRepo)
+	favoriteHandler := handler.NewFavoriteHandler(favoriteRepo)
+	limitHandler := handler.NewSpendingLimitHandler(limitRepo, userRepo)
+	adminAccountHandler := handler.NewAdminAccountHandler(adminRepo)
+	stepUpHandler := handler.NewStepUpHandler(cfg, userRepo, adminSecurityRepo)
+	loginSecurityHandler := handler.NewLoginSecurityHandler(loginSecurityRepo, userRepo)
+	auditLogHandler := handler.NewAuditLogHandler(auditRepo)
 
 	// Initialize middleware
-	authMiddleware := middleware.NewAuthMiddleware(cfg)
+	authMiddleware := middleware.NewAuthMiddleware(cfg, sessionRepo, adminRepo)
+	audit := middleware.NewAudit(auditRepo)
+	idempotency := middleware.NewIdempotency(idempotencyRepo, time.Duration(cfg.IdempotencyTTLHours)*time.Hour)
 
 	// Initialize rate limiters (4-tier strategy)
 	strictRL := middleware.NewRateLimiter(5, time.Minute)    // Auth endpoints: 5 req/min
@@ -139,7 +211,7 @@ func main() {
 	mux.HandleFunc("POST /api/v1/calculate-price", moderateRL.Limit(validationHandler.CalculatePrice))
 
 	// Order endpoints (Moderate for writes, Standard for reads)
-	mux.HandleFunc("POST /api/v1/orders", moderateRL.Limit(orderHandler.CreateOrder))
+	mux.HandleFunc("POST /api/v1/orders", moderateRL.Limit(idempotency.Guest(orderHandler.CreateOrder)))
 	mux.HandleFunc("GET /api/v1/orders/{id}", standardRL.Limit(orderHandler.GetOrder))
 	mux.HandleFunc("POST /api/v1/orders/{id}/pay", moderateRL.Limit(orderHandler.InitiatePayment))
 	mux.HandleFunc("POST /api/v1/orders/{id}/cancel", moderateRL.Limit(orderHandler.CancelOrder))
@@ -151,11 +223,37 @@ func main() {
 
 	// Admin Auth (Strict: 5 req/min)
 	mux.HandleFunc("POST /api/v1/admin/login", strictRL.Limit(adminHandler.Login))
+	mux.HandleFunc("POST /api/v1/admin/logout", standardRL.Limit(authMiddleware.AdminAuth(model.PermSelf, audit.Record("session.logout", model.AuditEntitySession, "", sessionHandler.Logout))))
+	mux.HandleFunc("POST /api/v1/admin/logout-all", standardRL.Limit(authMiddleware.AdminAuth(model.PermSelf, audit.Record("session.logout_all", model.AuditEntitySession, "", sessionHandler.LogoutAll))))
+	mux.HandleFunc("GET /api/v1/admin/sessions", standardRL.Limit(authMiddleware.AdminAuth(model.PermSelf, sessionHandler.GetSessions)))
+	mux.HandleFunc("DELETE /api/v1/admin/sessions/{id}", standardRL.Limit(authMiddleware.AdminAuth(model.PermSelf, audit.Record("session.revoke", model.AuditEntitySession, "id", sessionHandler.RevokeSession))))
+	mux.HandleFunc("GET /api/v1/admin/me", standardRL.Limit(authMiddleware.AdminAuth(model.PermSelf, adminAccountHandler.GetMe)))
+	mux.HandleFunc("POST /api/v1/admin/reauth", strictRL.Limit(authMiddleware.AdminAuth(model.PermSelf, audit.Record("session.step_up", model.AuditEntitySession, "", stepUpHandler.Reauth))))
+
+	// Admin accounts & roles (owner only)
+	mux.HandleFunc("GET /api/v1/admin/admins", standardRL.Limit(authMiddleware.AdminAuth(model.PermAdminsManage, adminAccountHandler.GetAdmins)))
+	mux.HandleFunc("POST /api/v1/admin/admins", moderateRL.Limit(authMiddleware.AdminAuth(model.PermAdminsManage, audit.Record("admin.create", model.AuditEntityAdmin, "", adminAccountHandler.CreateAdmin))))
+	mux.HandleFunc("PUT /api/v1/admin/admins/{id}", moderateRL.Limit(authMiddleware.AdminAuth(model.PermAdminsManage, audit.Record("admin.update", model.AuditEntityAdmin, "id", adminAccountHandler.UpdateAdmin))))
+
+	// Audit trail (every admin write is recorded by audit.Record)
+	mux.HandleFunc("GET /api/v1/admin/audit-logs", standardRL.Limit(authMiddleware.AdminAuth(model.PermAuditView, auditLogHandler.GetAuditLogs)))
+
+	// Session refresh (Moderate: 20 req/min) - rotates the refresh token, admin & member
+	mux.HandleFunc("POST /api/v1/auth/refresh", moderateRL.Limit(sessionHandler.Refresh))
 
 	// Member Auth (Strict: 5 req/min)
 	mux.HandleFunc("POST /api/v1/member/login", strictRL.Limit(memberHandler.Login))
+	mux.HandleFunc("POST /api/v1/member/login/totp", strictRL.Limit(memberHandler.LoginTOTP))
 	mux.HandleFunc("POST /api/v1/member/forgot-password", strictRL.Limit(memberHandler.ForgotPassword))
 	mux.HandleFunc("POST /api/v1/member/reset-password", strictRL.Limit(memberHandler.ResetPassword))
+	mux.HandleFunc("GET /api/v1/member/register/settings", standardRL.Limit(registrationHandler.GetPublicSettings))
+	mux.HandleFunc("POST /api/v1/member/register", strictRL.Limit(registrationHandler.Register))
+	mux.HandleFunc("POST /api/v1/member/verify-email", strictRL.Limit(registrationHandler.VerifyEmail))
+	mux.HandleFunc("POST /api/v1/member/resend-verification", strictRL.Limit(registrationHandler.ResendVerification))
+	mux.HandleFunc("POST /api/v1/member/logout", standardRL.Limit(authMiddleware.MemberAuth(sessionHandler.Logout)))
+	mux.HandleFunc("POST /api/v1/member/logout-all", standardRL.Limit(authMiddleware.MemberAuth(sessionHandler.LogoutAll)))
+	mux.HandleFunc("GET /api/v1/member/sessions", standardRL.Limit(authMiddleware.MemberAuth(sessionHandler.GetSessions)))
+	mux.HandleFunc("DELETE /api/v1/member/sessions/{id}", standardRL.Limit(authMiddleware.MemberAuth(sessionHandler.RevokeSession)))
 
 	// ==========================================
 	// WEBHOOK ROUTES
@@ -167,61 +265,102 @@ func main() {
 	// ==========================================
 	// ADMIN ROUTES (Protected with Auth Middleware)
 	// ==========================================
-	mux.HandleFunc("GET /api/v1/admin/balance", standardRL.Limit(authMiddleware.AdminAuth(adminHandler.GetBalance)))
-	mux.HandleFunc("GET /api/v1/admin/dashboard", standardRL.Limit(authMiddleware.AdminAuth(adminHandler.GetDashboard)))
-	mux.HandleFunc("GET /api/v1/admin/orders", standardRL.Limit(authMiddleware.AdminAuth(adminHandler.GetOrders)))
-	mux.HandleFunc("POST /api/v1/admin/orders/{id}/check-status", standardRL.Limit(authMiddleware.AdminAuth(adminHandler.CheckOrderStatus)))
-	mux.HandleFunc("POST /api/v1/admin/sync/products", standardRL.Limit(authMiddleware.AdminAuth(adminHandler.SyncProducts)))
-	mux.HandleFunc("GET /api/v1/admin/logs/sync", standardRL.Limit(authMiddleware.AdminAuth(adminHandler.GetSyncLogs)))
-	mux.HandleFunc("GET /api/v1/admin/logs/webhook", standardRL.Limit(authMiddleware.AdminAuth(adminHandler.GetWebhookLogs)))
+	mux.HandleFunc("GET /api/v1/admin/balance", standardRL.Limit(authMiddleware.AdminAuth(model.PermDashboardView, adminHandler.GetBalance)))
+	mux.HandleFunc("GET /api/v1/admin/dashboard", standardRL.Limit(authMiddleware.AdminAuth(model.PermDashboardView, adminHandler.GetDashboard)))
+	mux.HandleFunc("GET /api/v1/admin/orders", standardRL.Limit(authMiddleware.AdminAuth(model.PermOrdersView, adminHandler.GetOrders)))
+	mux.HandleFunc("POST /api/v1/admin/orders/{id}/check-status", standardRL.Limit(authMiddleware.AdminAuth(model.PermOrdersManage, audit.Record("order.check_status", model.AuditEntityOrder, "id", adminHandler.CheckOrderStatus))))
+	mux.HandleFunc("POST /api/v1/admin/sync/products", standardRL.Limit(authMiddleware.AdminAuth(model.PermCatalogManage, audit.Record("product.sync", model.AuditEntityProduct, "", adminHandler.SyncProducts))))
+	mux.HandleFunc("GET /api/v1/admin/logs/sync", standardRL.Limit(authMiddleware.AdminAuth(model.PermLogsView, adminHandler.GetSyncLogs)))
+	mux.HandleFunc("GET /api/v1/admin/logs/webhook", standardRL.Limit(authMiddleware.AdminAuth(model.PermLogsView, adminHandler.GetWebhookLogs)))
 
 	// Admin Product CRUD
-	mux.HandleFunc("GET /api/v1/admin/products", standardRL.Limit(authMiddleware.AdminAuth(adminHandler.GetAdminProducts)))
-	mux.HandleFunc("GET /api/v1/admin/products/filters", standardRL.Limit(authMiddleware.AdminAuth(adminHandler.GetProductFilters)))
-	mux.HandleFunc("GET /api/v1/admin/products/tags", standardRL.Limit(authMiddleware.AdminAuth(adminHandler.GetAllTags)))
-	mux.HandleFunc("GET /api/v1/admin/products/best-sellers", standardRL.Limit(authMiddleware.AdminAuth(adminHandler.GetBestSellers)))
-	mux.HandleFunc("GET /api/v1/admin/products/{sku}", standardRL.Limit(authMiddleware.AdminAuth(adminHandler.GetAdminProduct)))
-	mux.HandleFunc("PUT /api/v1/admin/products/{sku}", standardRL.Limit(authMiddleware.AdminAuth(adminHandler.UpdateAdminProduct)))
-	mux.HandleFunc("PUT /api/v1/admin/products/{sku}/image", standardRL.Limit(authMiddleware.AdminAuth(adminHandler.UpdateProductImage)))
-	mux.HandleFunc("DELETE /api/v1/admin/products/{sku}/image", standardRL.Limit(authMiddleware.AdminAuth(adminHandler.DeleteProductImage)))
-	mux.HandleFunc("POST /api/v1/admin/products/{sku}/tags", standardRL.Limit(authMiddleware.AdminAuth(adminHandler.AddProductTag)))
-	mux.HandleFunc("DELETE /api/v1/admin/products/{sku}/tags/{tag}", standardRL.Limit(authMiddleware.AdminAuth(adminHandler.RemoveProductTag)))
+	mux.HandleFunc("GET /api/v1/admin/products", standardRL.Limit(authMiddleware.AdminAuth(model.PermCatalogView, adminHandler.GetAdminProducts)))
+	mux.HandleFunc("GET /api/v1/admin/products/filters", standardRL.Limit(authMiddleware.AdminAuth(model.PermCatalogView, adminHandler.GetProductFilters)))
+	mux.HandleFunc("GET /api/v1/admin/products/tags", standardRL.Limit(authMiddleware.AdminAuth(model.PermCatalogView, adminHandler.GetAllTags)))
+	mux.HandleFunc("GET /api/v1/admin/products/best-sellers", standardRL.Limit(authMiddleware.AdminAuth(model.PermCatalogView, adminHandler.GetBestSellers)))
+	mux.HandleFunc("GET /api/v1/admin/products/{sku}", standardRL.Limit(authMiddleware.AdminAuth(model.PermCatalogView, adminHandler.GetAdminProduct)))
+	mux.HandleFunc("PUT /api/v1/admin/products/{sku}", standardRL.Limit(authMiddleware.AdminAuth(model.PermCatalogManage, audit.Record("product.update", model.AuditEntityProduct, "sku", adminHandler.UpdateAdminProduct))))
+	mux.HandleFunc("PUT /api/v1/admin/products/{sku}/image", standardRL.Limit(authMiddleware.AdminAuth(model.PermCatalogManage, audit.Record("product.image_update", model.AuditEntityProduct, "sku", adminHandler.UpdateProductImage))))
+	mux.HandleFunc("DELETE /api/v1/admin/products/{sku}/image", standardRL.Limit(authMiddleware.AdminAuth(model.PermCatalogManage, audit.Record("product.image_delete", model.AuditEntityProduct, "sku", adminHandler.DeleteProductImage))))
+	mux.HandleFunc("POST /api/v1/admin/products/{sku}/tags", standardRL.Limit(authMiddleware.AdminAuth(model.PermCatalogManage, audit.Record("product.tag_add", model.AuditEntityProduct, "sku", adminHandler.AddProductTag))))
+	mux.HandleFunc("DELETE /api/v1/admin/products/{sku}/tags/{tag}", standardRL.Limit(authMiddleware.AdminAuth(model.PermCatalogManage, audit.Record("product.tag_remove", model.AuditEntityProduct, "sku", adminHandler.RemoveProductTag))))
 
 	// Admin Content CRUD
-	mux.HandleFunc("GET /api/v1/admin/content", standardRL.Limit(authMiddleware.AdminAuth(contentHandler.GetAllContent)))
-	mux.HandleFunc("GET /api/v1/admin/content/{id}", standardRL.Limit(authMiddleware.AdminAuth(contentHandler.GetContentByID)))
-	mux.HandleFunc("POST /api/v1/admin/content", standardRL.Limit(authMiddleware.AdminAuth(contentHandler.CreateContent)))
-	mux.HandleFunc("PUT /api/v1/admin/content/{id}", standardRL.Limit(authMiddleware.AdminAuth(contentHandler.UpdateContent)))
-	mux.HandleFunc("DELETE /api/v1/admin/content/{id}", standardRL.Limit(authMiddleware.AdminAuth(contentHandler.DeleteContent)))
+	mux.HandleFunc("GET /api/v1/admin/content", standardRL.Limit(authMiddleware.AdminAuth(model.PermCatalogView, contentHandler.GetAllContent)))
+	mux.HandleFunc("GET /api/v1/admin/content/{id}", standardRL.Limit(authMiddleware.AdminAuth(model.PermCatalogView, contentHandler.GetContentByID)))
+	mux.HandleFunc("POST /api/v1/admin/content", standardRL.Limit(authMiddleware.AdminAuth(model.PermCatalogManage, audit.Record("content.create", model.AuditEntityContent, "", contentHandler.CreateContent))))
+	mux.HandleFunc("PUT /api/v1/admin/content/{id}", standardRL.Limit(authMiddleware.AdminAuth(model.PermCatalogManage, audit.Record("content.update", model.AuditEntityContent, "id", contentHandler.UpdateContent))))
+	mux.HandleFunc("DELETE /api/v1/admin/content/{id}", standardRL.Limit(authMiddleware.AdminAuth(model.PermCatalogManage, audit.Record("content.delete", model.AuditEntityContent, "id", contentHandler.DeleteContent))))
+
+	// Admin Promo CRUD
+	mux.HandleFunc("GET /api/v1/admin/promos", standardRL.Limit(authMiddleware.AdminAuth(model.PermCatalogView, promoHandler.GetPromos)))
+	mux.HandleFunc("GET /api/v1/admin/promos/{id}", standardRL.Limit(authMiddleware.AdminAuth(model.PermCatalogView, promoHandler.GetPromoByID)))
+	mux.HandleFunc("POST /api/v1/admin/promos", standardRL.Limit(authMiddleware.AdminAuth(model.PermPromosManage, audit.Record("promo.create", model.AuditEntityPromo, "", promoHandler.CreatePromo))))
+	mux.HandleFunc("PUT /api/v1/admin/promos/{id}", standardRL.Limit(authMiddleware.AdminAuth(model.PermPromosManage, audit.Record("promo.update", model.AuditEntityPromo, "id", promoHandler.UpdatePromo))))
+	mux.HandleFunc("DELETE /api/v1/admin/promos/{id}", standardRL.Limit(authMiddleware.AdminAuth(model.PermPromosManage, audit.Record("promo.delete", model.AuditEntityPromo, "id", promoHandler.DeletePromo))))
+
+	// Admin Flash Sales
+	mux.HandleFunc("GET /api/v1/admin/flash-sales", standardRL.Limit(authMiddleware.AdminAuth(model.PermCatalogView, flashSaleHandler.GetFlashSales)))
+	mux.HandleFunc("GET /api/v1/admin/flash-sales/{id}", standardRL.Limit(authMiddleware.AdminAuth(model.PermCatalogView, flashSaleHandler.GetFlashSaleByID)))
+	mux.HandleFunc("POST /api/v1/admin/flash-sales", standardRL.Limit(authMiddleware.AdminAuth(model.PermPromosManage, audit.Record("flash_sale.create", model.AuditEntityFlashSale, "", flashSaleHandler.CreateFlashSale))))
+	mux.HandleFunc("PUT /api/v1/admin/flash-sales/{id}", standardRL.Limit(authMiddleware.AdminAuth(model.PermPromosManage, audit.Record("flash_sale.update", model.AuditEntityFlashSale, "id", flashSaleHandler.UpdateFlashSale))))
+	mux.HandleFunc("POST /api/v1/admin/flash-sales/{id}/stop", standardRL.Limit(authMiddleware.AdminAuth(model.PermPromosManage, audit.Record("flash_sale.stop", model.AuditEntityFlashSale, "id", flashSaleHandler.StopFlashSale))))
+	mux.HandleFunc("DELETE /api/v1/admin/flash-sales/{id}", standardRL.Limit(authMiddleware.AdminAuth(model.PermPromosManage, audit.Record("flash_sale.delete", model.AuditEntityFlashSale, "id", flashSaleHandler.DeleteFlashSale))))
+
+	// Admin Loyalty Point Rules
+	mux.HandleFunc("GET /api/v1/admin/point-rules", standardRL.Limit(authMiddleware.AdminAuth(model.PermCatalogView, pointsHandler.GetPointRules)))
+	mux.HandleFunc("POST /api/v1/admin/point-rules", standardRL.Limit(authMiddleware.AdminAuth(model.PermPromosManage, audit.Record("point_rule.create", model.AuditEntityPointRule, "", pointsHandler.CreatePointRule))))
+	mux.HandleFunc("PUT /api/v1/admin/point-rules/{id}", standardRL.Limit(authMiddleware.AdminAuth(model.PermPromosManage, audit.Record("point_rule.update", model.AuditEntityPointRule, "id", pointsHandler.UpdatePointRule))))
+	mux.HandleFunc("DELETE /api/v1/admin/point-rules/{id}", standardRL.Limit(authMiddleware.AdminAuth(model.PermPromosManage, audit.Record("point_rule.delete", model.AuditEntityPointRule, "id", pointsHandler.DeletePointRule))))
 
 	// Admin Brand Settings
-	mux.HandleFunc("GET /api/v1/admin/brands", standardRL.Limit(authMiddleware.AdminAuth(contentHandler.GetBrandSettings)))
-	mux.HandleFunc("PUT /api/v1/admin/brands/{brand}", standardRL.Limit(authMiddleware.AdminAuth(contentHandler.UpdateBrandSetting)))
+	mux.HandleFunc("GET /api/v1/admin/brands", standardRL.Limit(authMiddleware.AdminAuth(model.PermCatalogView, contentHandler.GetBrandSettings)))
+	mux.HandleFunc("PUT /api/v1/admin/brands/{brand}", standardRL.Limit(authMiddleware.AdminAuth(model.PermCatalogManage, audit.Record("brand.update", model.AuditEntityBrand, "brand", contentHandler.UpdateBrandSetting))))
 
 	// Admin TOTP / 2FA Security
-	mux.HandleFunc("GET /api/v1/admin/totp/status", standardRL.Limit(authMiddleware.AdminAuth(totpHandler.GetTOTPStatus)))
-	mux.HandleFunc("POST /api/v1/admin/totp/setup", standardRL.Limit(authMiddleware.AdminAuth(totpHandler.SetupTOTP)))
-	mux.HandleFunc("POST /api/v1/admin/totp/enable", standardRL.Limit(authMiddleware.AdminAuth(totpHandler.EnableTOTP)))
-	mux.HandleFunc("POST /api/v1/admin/totp/disable", standardRL.Limit(authMiddleware.AdminAuth(totpHandler.DisableTOTP)))
+	mux.HandleFunc("GET /api/v1/admin/totp/status", standardRL.Limit(authMiddleware.AdminAuth(model.PermSelf, totpHandler.GetTOTPStatus)))
+	mux.HandleFunc("POST /api/v1/admin/totp/setup", standardRL.Limit(authMiddleware.AdminAuth(model.PermSelf, audit.Record("totp.setup", model.AuditEntityTOTP, "", totpHandler.SetupTOTP))))
+	mux.HandleFunc("POST /api/v1/admin/totp/enable", standardRL.Limit(authMiddleware.AdminAuth(model.PermSelf, audit.Record("totp.enable", model.AuditEntityTOTP, "", totpHandler.EnableTOTP))))
+	mux.HandleFunc("POST /api/v1/admin/totp/disable", standardRL.Limit(authMiddleware.AdminAuth(model.PermSelf, audit.Record("totp.disable", model.AuditEntityTOTP, "", authMiddleware.StepUp(totpHandler.DisableTOTP)))))
+	mux.HandleFunc("POST /api/v1/admin/totp/recovery-codes", strictRL.Limit(authMiddleware.AdminAuth(model.PermSelf, audit.Record("totp.recovery_regenerate", model.AuditEntityTOTP, "", totpHandler.RegenerateRecoveryCodes))))
+	mux.HandleFunc("POST /api/v1/admin/totp/reset", strictRL.Limit(authMiddleware.AdminAuth(model.PermSelf, audit.Record("totp.reset_request", model.AuditEntityTOTP, "", totpHandler.RequestTOTPReset))))
+	mux.HandleFunc("POST /api/v1/admin/totp/reset/confirm", strictRL.Limit(authMiddleware.AdminAuth(model.PermSelf, audit.Record("totp.reset", model.AuditEntityTOTP, "", totpHandler.ConfirmTOTPReset))))
 
 	// Admin Manual Topup (requires TOTP verification)
-	mux.HandleFunc("POST /api/v1/admin/orders/{id}/manual-topup", moderateRL.Limit(authMiddleware.AdminAuth(totpHandler.ManualTopup)))
+	mux.HandleFunc("POST /api/v1/admin/orders/{id}/manual-topup", moderateRL.Limit(authMiddleware.AdminAuth(model.PermOrdersTopup, audit.Record("order.manual_topup", model.AuditEntityOrder, "id", authMiddleware.StepUp(totpHandler.ManualTopup)))))
 
 	// Admin Custom Topup (for cash/gift - requires password + TOTP)
-	mux.HandleFunc("POST /api/v1/admin/topup/custom", moderateRL.Limit(authMiddleware.AdminAuth(totpHandler.CustomTopup)))
+	mux.HandleFunc("POST /api/v1/admin/topup/custom", moderateRL.Limit(authMiddleware.AdminAuth(model.PermOrdersTopup, audit.Record("order.custom_topup", model.AuditEntityOrder, "", authMiddleware.StepUp(totpHandler.CustomTopup)))))
+
+	// Admin Ledger Reconciliation (users.balance vs deposits ledger)
+	mux.HandleFunc("GET /api/v1/admin/ledger/reconciliation", standardRL.Limit(authMiddleware.AdminAuth(model.PermLedgerManage, ledgerHandler.GetReconciliation)))
+	mux.HandleFunc("POST /api/v1/admin/ledger/reconciliation", moderateRL.Limit(authMiddleware.AdminAuth(model.PermLedgerManage, audit.Record("ledger.reconcile", model.AuditEntityLedger, "", ledgerHandler.RunReconciliation))))
 
 	// Admin Member Management
-	mux.HandleFunc("GET /api/v1/admin/members", standardRL.Limit(authMiddleware.AdminAuth(memberHandler.GetMembers)))
-	mux.HandleFunc("POST /api/v1/admin/members", standardRL.Limit(authMiddleware.AdminAuth(memberHandler.CreateMember)))
-	mux.HandleFunc("GET /api/v1/admin/members/{id}", standardRL.Limit(authMiddleware.AdminAuth(memberHandler.GetMember)))
-	mux.HandleFunc("PUT /api/v1/admin/members/{id}", standardRL.Limit(authMiddleware.AdminAuth(memberHandler.UpdateMember)))
-	mux.HandleFunc("DELETE /api/v1/admin/members/{id}", standardRL.Limit(authMiddleware.AdminAuth(memberHandler.DeleteMember)))
-	mux.HandleFunc("POST /api/v1/admin/members/{id}/topup", moderateRL.Limit(authMiddleware.AdminAuth(memberHandler.TopupMember)))
+	mux.HandleFunc("GET /api/v1/admin/members", standardRL.Limit(authMiddleware.AdminAuth(model.PermMembersView, memberHandler.GetMembers)))
+	mux.HandleFunc("POST /api/v1/admin/members", standardRL.Limit(authMiddleware.AdminAuth(model.PermMembersManage, audit.Record("member.create", model.AuditEntityMember, "", memberHandler.CreateMember))))
+	mux.HandleFunc("GET /api/v1/admin/members/{id}", standardRL.Limit(authMiddleware.AdminAuth(model.PermMembersView, memberHandler.GetMember)))
+	mux.HandleFunc("PUT /api/v1/admin/members/{id}", standardRL.Limit(authMiddleware.AdminAuth(model.PermMembersManage, audit.Record("member.update", model.AuditEntityMember, "id", memberHandler.UpdateMember))))
+	mux.HandleFunc("DELETE /api/v1/admin/members/{id}", standardRL.Limit(authMiddleware.AdminAuth(model.PermMembersManage, audit.Record("member.delete", model.AuditEntityMember, "id", memberHandler.DeleteMember))))
+	mux.HandleFunc("POST /api/v1/admin/members/{id}/topup", moderateRL.Limit(authMiddleware.AdminAuth(model.PermMembersBalance, audit.Record("member.topup", model.AuditEntityMember, "id", memberHandler.TopupMember))))
+	mux.HandleFunc("POST /api/v1/admin/members/{id}/adjustments", moderateRL.Limit(authMiddleware.AdminAuth(model.PermMembersBalance, audit.Record("member.adjust_balance", model.AuditEntityMember, "id", authMiddleware.StepUp(adjustmentHandler.CreateAdjustment)))))
+	mux.HandleFunc("GET /api/v1/admin/members/{id}/adjustments", standardRL.Limit(authMiddleware.AdminAuth(model.PermMembersView, adjustmentHandler.GetAdjustments)))
+	mux.HandleFunc("GET /api/v1/admin/members/{id}/login-history", standardRL.Limit(authMiddleware.AdminAuth(model.PermMembersView, loginSecurityHandler.GetMemberLoginHistory)))
+	mux.HandleFunc("GET /api/v1/admin/login-lockouts", standardRL.Limit(authMiddleware.AdminAuth(model.PermMembersView, loginSecurityHandler.GetLockouts)))
+	mux.HandleFunc("DELETE /api/v1/admin/login-lockouts/{id}", standardRL.Limit(authMiddleware.AdminAuth(model.PermMembersManage, audit.Record("login_lockout.clear", model.AuditEntityLoginLockout, "id", loginSecurityHandler.ClearLockout))))
+	mux.HandleFunc("GET /api/v1/admin/members/{id}/limits", standardRL.Limit(authMiddleware.AdminAuth(model.PermMembersView, limitHandler.GetMemberLimits)))
+	mux.HandleFunc("PUT /api/v1/admin/members/{id}/limits", standardRL.Limit(authMiddleware.AdminAuth(model.PermMembersBalance, audit.Record("member.limits_update", model.AuditEntityMemberLimit, "id", limitHandler.UpdateMemberLimits))))
+	mux.HandleFunc("DELETE /api/v1/admin/members/{id}/limits", standardRL.Limit(authMiddleware.AdminAuth(model.PermMembersBalance, audit.Record("member.limits_delete", model.AuditEntityMemberLimit, "id", limitHandler.DeleteMemberLimits))))
+	mux.HandleFunc("GET /api/v1/admin/member-levels", standardRL.Limit(authMiddleware.AdminAuth(model.PermMembersView, limitHandler.GetLevels)))
+	mux.HandleFunc("PUT /api/v1/admin/member-levels/{level}", standardRL.Limit(authMiddleware.AdminAuth(model.PermMembersBalance, audit.Record("member_level.update", model.AuditEntityMemberLevel, "level", limitHandler.UpdateLevel))))
+	mux.HandleFunc("GET /api/v1/admin/settings/registration", standardRL.Limit(authMiddleware.AdminAuth(model.PermSettingsManage, registrationHandler.GetSettings)))
+	mux.HandleFunc("PUT /api/v1/admin/settings/registration", standardRL.Limit(authMiddleware.AdminAuth(model.PermSettingsManage, audit.Record("setting.registration_update", model.AuditEntitySetting, "", registrationHandler.UpdateSettings))))
 
 	// ==========================================
 	// MEMBER ROUTES (Protected with Member Auth Middleware)
 	// ==========================================
 	mux.HandleFunc("GET /api/v1/member/dashboard", standardRL.Limit(authMiddleware.MemberAuth(memberHandler.GetDashboard)))
+	mux.HandleFunc("GET /api/v1/member/limits", standardRL.Limit(authMiddleware.MemberAuth(limitHandler.GetMyLimits)))
 	mux.HandleFunc("GET /api/v1/member/profile", standardRL.Limit(authMiddleware.MemberAuth(memberHandler.GetProfile)))
 	mux.HandleFunc("PUT /api/v1/member/profile", standardRL.Limit(authMiddleware.MemberAuth(memberHandler.UpdateProfile)))
 	mux.HandleFunc("GET /api/v1/member/deposits", standardRL.Limit(authMiddleware.MemberAuth(memberHandler.GetDeposits)))
@@ -229,9 +368,53 @@ func main() {
 	mux.HandleFunc("GET /api/v1/member/products/{sku}", standardRL.Limit(authMiddleware.MemberAuth(memberHandler.GetProductBySku)))
 	mux.HandleFunc("GET /api/v1/member/orders", standardRL.Limit(authMiddleware.MemberAuth(memberHandler.GetOrders)))
 	mux.HandleFunc("GET /api/v1/member/orders/{id}", standardRL.Limit(authMiddleware.MemberAuth(memberHandler.GetOrderByID)))
-	mux.HandleFunc("POST /api/v1/member/orders", moderateRL.Limit(authMiddleware.MemberAuth(memberHandler.CreateOrder)))
+	mux.HandleFunc("POST /api/v1/member/orders", moderateRL.Limit(authMiddleware.MemberAuth(idempotency.Member(memberHandler.CreateOrder))))
 	mux.HandleFunc("POST /api/v1/member/validate-account", moderateRL.Limit(authMiddleware.MemberAuth(memberHandler.ValidateMemberAccount)))
 	mux.HandleFunc("PUT /api/v1/member/password", strictRL.Limit(authMiddleware.MemberAuth(memberHandler.ChangePassword)))
+	mux.HandleFunc("GET /api/v1/member/totp/status", standardRL.Limit(authMiddleware.MemberAuth(memberHandler.GetTOTPStatus)))
+	mux.HandleFunc("POST /api/v1/member/totp/setup", strictRL.Limit(authMiddleware.MemberAuth(memberHandler.SetupTOTP)))
+	mux.HandleFunc("POST /api/v1/member/totp/enable", strictRL.Limit(authMiddleware.MemberAuth(memberHandler.EnableTOTP)))
+	mux.HandleFunc("POST /api/v1/member/totp/disable", strictRL.Limit(authMiddleware.MemberAuth(memberHandler.DisableTOTP)))
+	mux.HandleFunc("PUT /api/v1/member/totp/settings", strictRL.Limit(authMiddleware.MemberAuth(memberHandler.UpdateTOTPSettings)))
+	mux.HandleFunc("GET /api/v1/member/referrals", standardRL.Limit(authMiddleware.MemberAuth(referralHandler.GetReferrals)))
+	mux.HandleFunc("GET /api/v1/member/points", standardRL.Limit(authMiddleware.MemberAuth(pointsHandler.GetPoints)))
+	mux.HandleFunc("POST /api/v1/member/points/redeem", moderateRL.Limit(authMiddleware.MemberAuth(pointsHandler.RedeemPoints)))
+	mux.HandleFunc("POST /api/v1/member/transfers", moderateRL.Limit(authMiddleware.MemberAuth(transferHandler.CreateTransfer)))
+
+	// Member Bulk Orders (JSON or CSV, fulfilled in the background)
+	mux.HandleFunc("POST /api/v1/member/batches", moderateRL.Limit(authMiddleware.MemberAuth(batchHandler.CreateBatch)))
+	mux.HandleFunc("GET /api/v1/member/batches", standardRL.Limit(authMiddleware.MemberAuth(batchHandler.GetBatches)))
+	mux.HandleFunc("GET /api/v1/member/batches/{id}", standardRL.Limit(authMiddleware.MemberAuth(batchHandler.GetBatch)))
+	mux.HandleFunc("GET /api/v1/member/batches/{id}/results", standardRL.Limit(authMiddleware.MemberAuth(batchHandler.DownloadBatchResults)))
+
+	// Member Statements (CSV/PDF; ranges over 31 days are generated in the background)
+	mux.HandleFunc("GET /api/v1/member/statement", moderateRL.Limit(authMiddleware.MemberAuth(statementHandler.DownloadStatement)))
+	mux.HandleFunc("POST /api/v1/member/statements", moderateRL.Limit(authMiddleware.MemberAuth(statementHandler.CreateStatementJob)))
+	mux.HandleFunc("GET /api/v1/member/statements", standardRL.Limit(authMiddleware.MemberAuth(statementHandler.GetStatementJobs)))
+	mux.HandleFunc("GET /api/v1/member/statements/{id}/download", standardRL.Limit(authMiddleware.MemberAuth(statementHandler.DownloadStatementJob)))
+
+	// Member Favorites
+	mux.HandleFunc("GET /api/v1/member/favorites", standardRL.Limit(authMiddleware.MemberAuth(favoriteHandler.GetFavorites)))
+	mux.HandleFunc("POST /api/v1/member/favorites", standardRL.Limit(authMiddleware.MemberAuth(favoriteHandler.CreateFavorite)))
+	mux.HandleFunc("PUT /api/v1/member/favorites/{id}", standardRL.Limit(authMiddleware.MemberAuth(favoriteHandler.UpdateFavorite)))
+	mux.HandleFunc("DELETE /api/v1/member/favorites/{id}", standardRL.Limit(authMiddleware.MemberAuth(favoriteHandler.DeleteFavorite)))
+
+	mux.HandleFunc("GET /api/v1/member/api-key", standardRL.Limit(authMiddleware.MemberAuth(h2hHandler.GetAPIKey)))
+	mux.HandleFunc("POST /api/v1/member/api-key", strictRL.Limit(authMiddleware.MemberAuth(h2hHandler.GenerateAPIKey)))
+	mux.HandleFunc("PUT /api/v1/member/api-key", standardRL.Limit(authMiddleware.MemberAuth(h2hHandler.UpdateAPIKey)))
+	mux.HandleFunc("GET /api/v1/member/webhook", standardRL.Limit(authMiddleware.MemberAuth(memberWebhookHandler.GetWebhook)))
+	mux.HandleFunc("PUT /api/v1/member/webhook", standardRL.Limit(authMiddleware.MemberAuth(memberWebhookHandler.UpdateWebhook)))
+	mux.HandleFunc("POST /api/v1/member/webhook/secret", strictRL.Limit(authMiddleware.MemberAuth(memberWebhookHandler.RegenerateSecret)))
+	mux.HandleFunc("GET /api/v1/member/webhook/deliveries", standardRL.Limit(authMiddleware.MemberAuth(memberWebhookHandler.GetDeliveries)))
+	mux.HandleFunc("POST /api/v1/member/webhook/deliveries/{id}/resend", moderateRL.Limit(authMiddleware.MemberAuth(memberWebhookHandler.ResendDelivery)))
+
+	// ==========================================
+	// H2H RESELLER API (API key + signature, versioned)
+	// ==========================================
+	mux.HandleFunc("POST /api/h2h/v1/balance", standardRL.Limit(h2hHandler.Balance))
+	mux.HandleFunc("POST /api/h2h/v1/price-list", standardRL.Limit(h2hHandler.PriceList))
+	mux.HandleFunc("POST /api/h2h/v1/transaction", standardRL.Limit(h2hHandler.Transaction))
+	mux.HandleFunc("POST /api/h2h/v1/status", standardRL.Limit(h2hHandler.Status))
 
 	// Apply middleware to API routes
 	var apiHandler http.Handler = mux
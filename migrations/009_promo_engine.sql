@@ -0,0 +1,39 @@
+-- ====================================
+-- GOVERSHOP - PROMO CODE ENGINE
+-- ====================================
+-- Links orders to the promo that was applied so discounts can be reported
+
+-- Promo codes are matched case-insensitively (stored uppercase).
+-- Codes that differ only by case would collide on the UNIQUE constraint, so
+-- stop with the list of them instead of failing halfway through the UPDATE.
+DO $$
+DECLARE
+    duplicates TEXT;
+BEGIN
+    SELECT string_agg(upper_code, ', ') INTO duplicates
+    FROM (
+        SELECT UPPER(code) AS upper_code
+        FROM promos
+        WHERE code IS NOT NULL
+        GROUP BY UPPER(code)
+        HAVING COUNT(*) > 1
+    ) d;
+
+    IF duplicates IS NOT NULL THEN
+        RAISE EXCEPTION 'Promo codes differ only by letter case: %. Rename or delete the duplicates, then rerun this migration.', duplicates;
+    END IF;
+END $$;
+
+UPDATE promos SET code = UPPER(code) WHERE code IS NOT NULL AND code <> UPPER(code);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_promos_code_upper ON promos(UPPER(code));
+
+-- Applied promo snapshot on orders
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS promo_id INT REFERENCES promos(id) ON DELETE SET NULL;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS promo_code VARCHAR(50);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS discount_amount DECIMAL(15,2) DEFAULT 0;
+
+COMMENT ON COLUMN orders.promo_code IS 'Promo code snapshot at order time (kept even if the promo is deleted)';
+COMMENT ON COLUMN orders.discount_amount IS 'Discount given by the promo, already deducted from selling_price';
+
+-- Index for promo reporting
+CREATE INDEX IF NOT EXISTS idx_orders_promo_id ON orders(promo_id);
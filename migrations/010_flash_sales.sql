@@ -0,0 +1,55 @@
+-- ====================================
+-- GOVERSHOP - FLASH SALES
+-- ====================================
+-- Scheduled campaigns that temporarily set products.discount_price
+-- Waktu disimpan dalam WIB (timezone database Asia/Jakarta)
+
+CREATE TABLE IF NOT EXISTS flash_sales (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+
+    -- Discount configuration
+    discount_type VARCHAR(20) NOT NULL,         -- 'price' (harga flash sale) atau 'percent'
+    discount_value DECIMAL(15,2) NOT NULL,
+
+    -- Selector (dipakai jika tidak ada daftar SKU)
+    category VARCHAR(100),
+    brand VARCHAR(100),
+
+    -- Kuota per SKU (NULL = tanpa batas)
+    quota_per_sku INTEGER,
+
+    -- Schedule
+    start_at TIMESTAMP NOT NULL,
+    end_at TIMESTAMP NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'scheduled', -- scheduled, active, ended, cancelled
+
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_flash_sales_status ON flash_sales(status);
+CREATE INDEX IF NOT EXISTS idx_flash_sales_schedule ON flash_sales(start_at, end_at);
+
+CREATE TABLE IF NOT EXISTS flash_sale_items (
+    id SERIAL PRIMARY KEY,
+    flash_sale_id INT NOT NULL REFERENCES flash_sales(id) ON DELETE CASCADE,
+    buyer_sku_code VARCHAR(50) NOT NULL,
+
+    flash_price DECIMAL(15,2),                  -- Dihitung saat campaign aktif
+    quota INTEGER,                              -- NULL = tanpa batas
+    original_discount_price DECIMAL(15,2),      -- discount_price sebelum flash sale (untuk revert)
+    is_applied BOOLEAN NOT NULL DEFAULT false,  -- true jika sedang mengubah products.discount_price
+
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+
+    UNIQUE (flash_sale_id, buyer_sku_code)
+);
+
+CREATE INDEX IF NOT EXISTS idx_flash_sale_items_sku ON flash_sale_items(buyer_sku_code);
+CREATE INDEX IF NOT EXISTS idx_flash_sale_items_applied ON flash_sale_items(is_applied) WHERE is_applied = true;
+
+-- Orders that consumed flash sale quota
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS flash_sale_item_id INT REFERENCES flash_sale_items(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_orders_flash_sale_item_id ON orders(flash_sale_item_id);
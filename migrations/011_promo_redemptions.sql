@@ -0,0 +1,40 @@
+-- ====================================
+-- GOVERSHOP - PER-CUSTOMER PROMO LIMITS
+-- ====================================
+-- Tracks every promo redemption so limits can be enforced per customer
+-- and usage can be given back when an order fails / is refunded
+
+ALTER TABLE promos ADD COLUMN IF NOT EXISTS per_customer_limit INTEGER;          -- NULL = tanpa batas per customer
+ALTER TABLE promos ADD COLUMN IF NOT EXISTS first_order_only BOOLEAN DEFAULT false; -- Hanya untuk order pertama
+
+CREATE TABLE IF NOT EXISTS promo_redemptions (
+    id SERIAL PRIMARY KEY,
+    promo_id INT NOT NULL REFERENCES promos(id) ON DELETE CASCADE,
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+
+    -- Customer identity (member, or normalized phone/email for guests)
+    member_id INT REFERENCES users(id) ON DELETE SET NULL,
+    customer_phone VARCHAR(20),                 -- Format 62xxxxxxxx
+    customer_email VARCHAR(255),                -- Lowercase
+
+    discount_amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+    status VARCHAR(20) NOT NULL DEFAULT 'active', -- active, reversed
+
+    created_at TIMESTAMP DEFAULT NOW(),
+    reversed_at TIMESTAMP,
+
+    UNIQUE (order_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_promo_redemptions_promo ON promo_redemptions(promo_id, status);
+CREATE INDEX IF NOT EXISTS idx_promo_redemptions_member ON promo_redemptions(promo_id, member_id) WHERE member_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_promo_redemptions_phone ON promo_redemptions(promo_id, customer_phone) WHERE customer_phone IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_promo_redemptions_email ON promo_redemptions(promo_id, customer_email) WHERE customer_email IS NOT NULL;
+
+-- Backfill redemptions for orders that already used a promo
+INSERT INTO promo_redemptions (promo_id, order_id, member_id, customer_phone, customer_email, discount_amount, status)
+SELECT o.promo_id, o.id, o.member_id, NULLIF(o.customer_phone, ''), NULLIF(LOWER(TRIM(o.customer_email)), ''), COALESCE(o.discount_amount, 0),
+       CASE WHEN o.status IN ('failed', 'cancelled', 'expired', 'refunded') THEN 'reversed' ELSE 'active' END
+FROM orders o
+WHERE o.promo_id IS NOT NULL
+ON CONFLICT (order_id) DO NOTHING;
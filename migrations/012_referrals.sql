@@ -0,0 +1,40 @@
+-- ====================================
+-- GOVERSHOP - MEMBER REFERRAL PROGRAM
+-- ====================================
+-- Referral code per member, referred_by link, and commission history
+-- Commission is credited to the referrer's balance (deposits.type = 'referral')
+-- and clawed back (deposits.type = 'referral_reversal') when the order is refunded
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS referral_code VARCHAR(20) UNIQUE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS referred_by INT REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_users_referred_by ON users(referred_by) WHERE referred_by IS NOT NULL;
+
+-- Backfill referral codes for existing members
+UPDATE users
+SET referral_code = UPPER(SUBSTRING(MD5(id::text || username || RANDOM()::text) FROM 1 FOR 8))
+WHERE role = 'member' AND referral_code IS NULL;
+
+CREATE TABLE IF NOT EXISTS referral_commissions (
+    id SERIAL PRIMARY KEY,
+    referrer_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    referred_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    ref_id VARCHAR(100) NOT NULL,               -- orders.ref_id (INV-...)
+
+    commission_type VARCHAR(20) NOT NULL,       -- percent (dari margin), flat (per order)
+    commission_value DECIMAL(15,2) NOT NULL,
+    order_margin DECIMAL(15,2) NOT NULL DEFAULT 0,
+    amount DECIMAL(15,2) NOT NULL,
+
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, credited, reversed
+
+    created_at TIMESTAMP DEFAULT NOW(),
+    credited_at TIMESTAMP,
+    reversed_at TIMESTAMP,
+
+    UNIQUE (order_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_referral_commissions_referrer ON referral_commissions(referrer_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_referral_commissions_referred ON referral_commissions(referred_id);
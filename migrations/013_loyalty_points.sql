@@ -0,0 +1,59 @@
+-- ====================================
+-- GOVERSHOP - MEMBER LOYALTY POINTS
+-- ====================================
+-- Points earned per brand/category rule on successful member orders,
+-- recorded in a ledger next to deposits, with expiry.
+-- Points are redeemed into balance (deposits.type = 'points_redeem')
+
+-- ====================================
+-- 1. EARN RULES
+-- ====================================
+CREATE TABLE IF NOT EXISTS point_rules (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+
+    -- Target: brand rules win over category rules, NULL/NULL = all products
+    brand VARCHAR(255),
+    category VARCHAR(255),
+
+    earn_type VARCHAR(20) NOT NULL,             -- percent (dari harga bayar), fixed (poin per order)
+    earn_value DECIMAL(15,2) NOT NULL,
+    min_order_amount DECIMAL(15,2) DEFAULT 0,
+    expiry_days INTEGER NOT NULL DEFAULT 90,
+
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_point_rules_active ON point_rules(is_active);
+
+CREATE TRIGGER update_point_rules_updated_at
+    BEFORE UPDATE ON point_rules
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- ====================================
+-- 2. POINTS LEDGER
+-- ====================================
+CREATE TABLE IF NOT EXISTS member_points (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(20) NOT NULL,                  -- earn, redeem, clawback, expire
+    points INTEGER NOT NULL,                    -- Always positive, direction follows type
+    remaining INTEGER NOT NULL DEFAULT 0,       -- Unspent points of an earn entry (FIFO)
+
+    order_id UUID REFERENCES orders(id) ON DELETE SET NULL,
+    rule_id INT REFERENCES point_rules(id) ON DELETE SET NULL,
+    reference_id VARCHAR(100),                  -- orders.ref_id or redeem reference
+    description TEXT,
+
+    expires_at TIMESTAMP,                       -- Only for earn entries
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_member_points_user ON member_points(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_member_points_available ON member_points(user_id, expires_at) WHERE type = 'earn' AND remaining > 0;
+
+-- One earn / one clawback per order
+CREATE UNIQUE INDEX IF NOT EXISTS idx_member_points_order_earn ON member_points(order_id) WHERE type = 'earn';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_member_points_order_clawback ON member_points(order_id) WHERE type = 'clawback';
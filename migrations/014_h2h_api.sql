@@ -0,0 +1,27 @@
+-- ====================================
+-- GOVERSHOP - H2H RESELLER API
+-- ====================================
+-- API key + secret per member for host-to-host integrations,
+-- and the reseller's own ref ID on orders
+
+CREATE TABLE IF NOT EXISTS member_api_keys (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    api_key VARCHAR(64) NOT NULL UNIQUE,        -- Public key, sent with every request
+    api_secret VARCHAR(128) NOT NULL,           -- Used to sign requests, never sent
+    ip_allowlist TEXT[],                        -- NULL/empty = allow all IPs
+    is_active BOOLEAN DEFAULT true,
+    last_used_at TIMESTAMP,
+    last_used_ip VARCHAR(64),
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TRIGGER update_member_api_keys_updated_at
+    BEFORE UPDATE ON member_api_keys
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Reseller's own ref ID (unique per member)
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS client_ref_id VARCHAR(100);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_orders_member_client_ref ON orders(member_id, client_ref_id) WHERE client_ref_id IS NOT NULL;
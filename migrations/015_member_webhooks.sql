@@ -0,0 +1,40 @@
+-- ====================================
+-- GOVERSHOP - MEMBER OUTBOUND WEBHOOKS
+-- ====================================
+-- Callback URL + secret per member, and a delivery log with retries
+
+CREATE TABLE IF NOT EXISTS member_webhooks (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    secret VARCHAR(128) NOT NULL,               -- HMAC-SHA256 signing secret
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TRIGGER update_member_webhooks_updated_at
+    BEFORE UPDATE ON member_webhooks
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TABLE IF NOT EXISTS member_webhook_deliveries (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    order_id UUID REFERENCES orders(id) ON DELETE SET NULL,
+    event VARCHAR(50) NOT NULL,                 -- order.status
+    url TEXT NOT NULL,
+    payload JSONB NOT NULL,
+
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, success, failed
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP DEFAULT NOW(),
+    last_status_code INTEGER,
+    last_error TEXT,
+    last_response TEXT,                         -- Truncated response body
+
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    delivered_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_member_webhook_deliveries_due ON member_webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_member_webhook_deliveries_user ON member_webhook_deliveries(user_id, created_at DESC);
@@ -0,0 +1,25 @@
+-- ====================================
+-- GOVERSHOP - MEMBER SELF-REGISTRATION
+-- ====================================
+-- Public registration creates members in 'pending_verification' status
+-- until the email verification link is confirmed.
+-- users.status: active, suspended, pending_verification
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP;
+
+-- Key/value application settings editable from the admin panel
+CREATE TABLE IF NOT EXISTS app_settings (
+    key VARCHAR(100) PRIMARY KEY,
+    value TEXT NOT NULL,
+    updated_by VARCHAR(100),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TRIGGER update_app_settings_updated_at
+    BEFORE UPDATE ON app_settings
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Registration mode: open, invite (referral code required), closed
+INSERT INTO app_settings (key, value, updated_by)
+VALUES ('registration_mode', 'closed', 'SYSTEM')
+ON CONFLICT (key) DO NOTHING;
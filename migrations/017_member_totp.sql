@@ -0,0 +1,22 @@
+-- ====================================
+-- GOVERSHOP - MEMBER TOTP (2FA)
+-- ====================================
+-- Per-member TOTP secret and when a code is required
+
+CREATE TABLE IF NOT EXISTS member_security (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    totp_secret VARCHAR(255),
+    totp_enabled BOOLEAN DEFAULT false,
+    totp_enabled_at TIMESTAMP,
+
+    require_totp_password BOOLEAN DEFAULT false,  -- Require code to change password
+    require_totp_orders BOOLEAN DEFAULT false,    -- Require code for large orders
+    order_totp_threshold DECIMAL(15,2) DEFAULT 0, -- Orders >= this amount need a code (0 = every order)
+
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TRIGGER update_member_security_updated_at
+    BEFORE UPDATE ON member_security
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
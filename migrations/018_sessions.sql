@@ -0,0 +1,30 @@
+-- ====================================
+-- GOVERSHOP - SESSIONS & REVOCATION
+-- ====================================
+-- Short-lived access JWTs carry a session ID (sid). Each session holds a
+-- rotating refresh token (SHA-256 hash only). Revoking a session, or
+-- bumping users.tokens_valid_after, invalidates its access tokens.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tokens_valid_after TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    channel VARCHAR(20) NOT NULL DEFAULT 'member', -- admin (unified login), member
+
+    refresh_token_hash VARCHAR(64) NOT NULL UNIQUE,
+    previous_token_hash VARCHAR(64),               -- Last rotated-out token, for reuse detection
+
+    device VARCHAR(100),
+    ip_address VARCHAR(100),
+    user_agent TEXT,
+
+    created_at TIMESTAMP DEFAULT NOW(),
+    last_used_at TIMESTAMP DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    revoked_reason VARCHAR(50)                     -- logout, logout_all, password_change, suspended, refresh_reuse
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_id) WHERE revoked_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_sessions_previous_token ON sessions(previous_token_hash) WHERE previous_token_hash IS NOT NULL;
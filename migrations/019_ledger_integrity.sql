@@ -0,0 +1,26 @@
+-- ====================================
+-- GOVERSHOP - LEDGER INTEGRITY
+-- ====================================
+-- Every balance mutation is posted as a deposits row that records the
+-- balance before and after. ledger_ref is unique so the same mutation
+-- (e.g. the refund of one order) can never be posted twice.
+-- Rows created before this migration keep NULL for the new columns.
+
+ALTER TABLE deposits ADD COLUMN IF NOT EXISTS balance_before NUMERIC(15, 2);
+ALTER TABLE deposits ADD COLUMN IF NOT EXISTS balance_after NUMERIC(15, 2);
+ALTER TABLE deposits ADD COLUMN IF NOT EXISTS ledger_ref VARCHAR(150);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_deposits_ledger_ref ON deposits(ledger_ref);
+CREATE INDEX IF NOT EXISTS idx_deposits_user_id_id ON deposits(user_id, id DESC);
+
+-- Results of the balance reconciliation job (users.balance vs sum of ledger)
+CREATE TABLE IF NOT EXISTS ledger_reconciliations (
+    id SERIAL PRIMARY KEY,
+    checked_users INT NOT NULL DEFAULT 0,
+    mismatch_count INT NOT NULL DEFAULT 0,
+    mismatches JSONB NOT NULL DEFAULT '[]',
+    triggered_by VARCHAR(100) DEFAULT 'system',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_reconciliations_created_at ON ledger_reconciliations(created_at DESC);
@@ -0,0 +1,20 @@
+-- ====================================
+-- GOVERSHOP - MEMBER BALANCE TRANSFERS
+-- ====================================
+-- A member moves balance to another member. Each transfer posts a
+-- transfer_out deposit for the sender and a transfer_in deposit for the
+-- recipient, both with reference_id = member_transfers.ref_id.
+
+CREATE TABLE IF NOT EXISTS member_transfers (
+    id SERIAL PRIMARY KEY,
+    ref_id VARCHAR(100) UNIQUE NOT NULL,
+    from_user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    to_user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount NUMERIC(15, 2) NOT NULL CHECK (amount > 0),
+    note TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (from_user_id <> to_user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_member_transfers_from ON member_transfers(from_user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_member_transfers_to ON member_transfers(to_user_id, created_at DESC);
@@ -0,0 +1,42 @@
+-- ====================================
+-- GOVERSHOP - MEMBER BULK ORDERS
+-- ====================================
+-- A member submits many order lines at once. The total is reserved from
+-- balance up front (deposits.type = 'batch_reserve'); each line releases its
+-- share right before its order is charged ('batch_release'), and whatever is
+-- left when the batch completes is released back in one final entry.
+
+CREATE TABLE IF NOT EXISTS member_order_batches (
+    id SERIAL PRIMARY KEY,
+    ref_id VARCHAR(100) UNIQUE NOT NULL,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'processing',  -- processing, completed
+    total_lines INT NOT NULL DEFAULT 0,
+    reserved_amount NUMERIC(15, 2) NOT NULL DEFAULT 0,
+    refunded_amount NUMERIC(15, 2) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_member_order_batches_user ON member_order_batches(user_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS member_order_batch_lines (
+    id SERIAL PRIMARY KEY,
+    batch_id INT NOT NULL REFERENCES member_order_batches(id) ON DELETE CASCADE,
+    line_no INT NOT NULL,
+    buyer_sku_code VARCHAR(100) NOT NULL,
+    destination_number VARCHAR(100) NOT NULL,
+    client_ref_id VARCHAR(100),
+    price NUMERIC(15, 2) NOT NULL,                      -- Amount reserved for this line
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',      -- pending, processing, submitted, failed
+    order_ref VARCHAR(100),                             -- orders.ref_id once the line was charged
+    error_code VARCHAR(50),
+    error_message TEXT,
+    attempts INT NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP,
+    processed_at TIMESTAMP,
+    UNIQUE (batch_id, line_no)
+);
+
+CREATE INDEX IF NOT EXISTS idx_member_order_batch_lines_open ON member_order_batch_lines(status, id)
+    WHERE status IN ('pending', 'processing');
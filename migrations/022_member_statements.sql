@@ -0,0 +1,26 @@
+-- ====================================
+-- GOVERSHOP - MEMBER STATEMENTS
+-- ====================================
+-- Statement exports (CSV/PDF) of the balance ledger joined with orders.
+-- Large date ranges are generated by a background job; the file is kept
+-- here for 7 days.
+
+CREATE TABLE IF NOT EXISTS member_statements (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    date_from DATE NOT NULL,
+    date_to DATE NOT NULL,
+    format VARCHAR(10) NOT NULL,                    -- csv, pdf
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',  -- pending, processing, ready, failed
+    file_name VARCHAR(255),
+    file_data BYTEA,
+    error_message TEXT,
+    locked_until TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP,
+    expires_at TIMESTAMP DEFAULT (CURRENT_TIMESTAMP + INTERVAL '7 days')
+);
+
+CREATE INDEX IF NOT EXISTS idx_member_statements_user ON member_statements(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_member_statements_pending ON member_statements(status, created_at)
+    WHERE status IN ('pending', 'processing');
@@ -0,0 +1,21 @@
+-- ====================================
+-- GOVERSHOP - IDEMPOTENCY KEYS
+-- ====================================
+-- Stores the response of order creation requests sent with an Idempotency-Key
+-- header (or a member client_ref_id) so a retried request returns the original
+-- result instead of placing a second order.
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    scope VARCHAR(100) NOT NULL,                      -- member:<user_id> or guest:<ip>
+    idem_key VARCHAR(255) NOT NULL,
+    request_hash VARCHAR(64) NOT NULL,                -- sha256 of method, path and body
+    status VARCHAR(20) NOT NULL DEFAULT 'processing', -- processing, completed
+    response_status INT,
+    content_type VARCHAR(100),
+    response_body BYTEA,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (scope, idem_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires ON idempotency_keys(expires_at);
@@ -0,0 +1,25 @@
+-- ====================================
+-- GOVERSHOP - ADMIN BALANCE ADJUSTMENTS
+-- ====================================
+-- Manual credits, debits and reversals of a specific deposit entry by an admin.
+-- The balance change itself is posted to the deposits ledger as
+-- adjustment_credit / adjustment_debit; this table keeps the reason.
+
+CREATE TABLE IF NOT EXISTS balance_adjustments (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL,                        -- credit, debit, reversal
+    amount DECIMAL(15, 2) NOT NULL,
+    reason_code VARCHAR(50) NOT NULL,
+    note TEXT NOT NULL,
+    deposit_id INT REFERENCES deposits(id),           -- ledger entry posted by this adjustment
+    reversed_deposit_id INT REFERENCES deposits(id),  -- entry undone by a reversal
+    totp_verified BOOLEAN DEFAULT false,
+    created_by VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_balance_adjustments_user ON balance_adjustments(user_id, created_at DESC);
+-- A deposit can only be reversed once
+CREATE UNIQUE INDEX IF NOT EXISTS idx_balance_adjustments_reversed ON balance_adjustments(reversed_deposit_id)
+    WHERE reversed_deposit_id IS NOT NULL;
@@ -0,0 +1,23 @@
+-- ====================================
+-- GOVERSHOP - MEMBER FAVORITES
+-- ====================================
+-- Saved destination numbers (game IDs, phone numbers) per member.
+-- account_name is filled from ValidateMemberAccount when the provider returns one.
+
+CREATE TABLE IF NOT EXISTS member_favorites (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    label VARCHAR(100) NOT NULL,
+    brand VARCHAR(100) NOT NULL,
+    customer_no VARCHAR(100) NOT NULL,
+    account_name VARCHAR(255),
+    use_count INT NOT NULL DEFAULT 0,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_member_favorites_unique
+    ON member_favorites(user_id, LOWER(REPLACE(brand, ' ', '')), customer_no);
+CREATE INDEX IF NOT EXISTS idx_member_favorites_recent
+    ON member_favorites(user_id, last_used_at DESC NULLS LAST);
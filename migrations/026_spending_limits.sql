@@ -0,0 +1,37 @@
+-- ====================================
+-- GOVERSHOP - MEMBER SPENDING LIMITS
+-- ====================================
+-- Limits are set per member level and can be overridden per member.
+-- In member_levels a NULL limit means unlimited. In member_limits a NULL
+-- column inherits the level value and 0 lifts the limit for that member;
+-- allowed_brands NULL inherits and an empty array allows every brand.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS member_level VARCHAR(30) NOT NULL DEFAULT 'regular';
+
+CREATE TABLE IF NOT EXISTS member_levels (
+    level VARCHAR(30) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    max_order_amount DECIMAL(15, 2),
+    max_daily_spend DECIMAL(15, 2),
+    max_orders_per_hour INT,
+    allowed_brands TEXT[],
+    updated_by VARCHAR(100),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO member_levels (level, name) VALUES ('regular', 'Regular')
+ON CONFLICT (level) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS member_limits (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    max_order_amount DECIMAL(15, 2),
+    max_daily_spend DECIMAL(15, 2),
+    max_orders_per_hour INT,
+    allowed_brands TEXT[],
+    note TEXT,
+    updated_by VARCHAR(100) NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Order debits of a member by time (spend and rate checks)
+CREATE INDEX IF NOT EXISTS idx_deposits_user_type_created ON deposits(user_id, type, created_at);
@@ -0,0 +1,20 @@
+-- ====================================
+-- GOVERSHOP - MULTI-ADMIN ROLES
+-- ====================================
+-- Admin users keep role = 'admin'; admin_role decides what they may do
+-- (owner, finance, cs, content). Existing admins become owners.
+-- TOTP settings and audit logs are tied to the individual admin.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS admin_role VARCHAR(20);
+UPDATE users SET admin_role = 'owner' WHERE role = 'admin' AND admin_role IS NULL;
+
+-- Per-admin TOTP: the old shared 'primary' row goes to the first admin
+ALTER TABLE admin_security ADD COLUMN IF NOT EXISTS user_id INT UNIQUE REFERENCES users(id) ON DELETE CASCADE;
+UPDATE admin_security
+SET user_id = (SELECT id FROM users WHERE role = 'admin' ORDER BY id LIMIT 1)
+WHERE admin_identifier = 'primary' AND user_id IS NULL;
+
+-- Audit attribution
+ALTER TABLE admin_audit_logs ADD COLUMN IF NOT EXISTS admin_user_id INT;
+ALTER TABLE admin_audit_logs ADD COLUMN IF NOT EXISTS admin_username VARCHAR(100);
+CREATE INDEX IF NOT EXISTS idx_admin_audit_logs_admin ON admin_audit_logs(admin_user_id, created_at DESC);
@@ -0,0 +1,17 @@
+-- ====================================
+-- GOVERSHOP - ADMIN AUDIT TRAIL
+-- ====================================
+-- Every admin write is recorded in admin_audit_logs with the target entity,
+-- a before/after snapshot and the changed fields.
+
+ALTER TABLE admin_audit_logs ADD COLUMN IF NOT EXISTS entity_type VARCHAR(50);
+ALTER TABLE admin_audit_logs ADD COLUMN IF NOT EXISTS entity_id VARCHAR(100);
+ALTER TABLE admin_audit_logs ADD COLUMN IF NOT EXISTS before_data JSONB;
+ALTER TABLE admin_audit_logs ADD COLUMN IF NOT EXISTS after_data JSONB;
+ALTER TABLE admin_audit_logs ADD COLUMN IF NOT EXISTS diff JSONB;            -- {"field": {"from": x, "to": y}}
+ALTER TABLE admin_audit_logs ADD COLUMN IF NOT EXISTS user_agent TEXT;
+ALTER TABLE admin_audit_logs ADD COLUMN IF NOT EXISTS method VARCHAR(10);
+ALTER TABLE admin_audit_logs ADD COLUMN IF NOT EXISTS path VARCHAR(255);
+ALTER TABLE admin_audit_logs ADD COLUMN IF NOT EXISTS status_code INT;
+
+CREATE INDEX IF NOT EXISTS idx_admin_audit_logs_entity ON admin_audit_logs(entity_type, entity_id, created_at DESC);
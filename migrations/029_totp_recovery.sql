@@ -0,0 +1,27 @@
+-- ====================================
+-- GOVERSHOP - ADMIN TOTP RECOVERY
+-- ====================================
+-- One-time recovery codes (SHA-256 hashed) usable in place of a TOTP code,
+-- and email-confirmed break-glass resets for admins who lost their device.
+
+CREATE TABLE IF NOT EXISTS admin_totp_recovery_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_admin_totp_recovery_codes_hash ON admin_totp_recovery_codes(user_id, code_hash);
+
+CREATE TABLE IF NOT EXISTS admin_totp_resets (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    requested_ip VARCHAR(45),
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_admin_totp_resets_user ON admin_totp_resets(user_id, created_at DESC);
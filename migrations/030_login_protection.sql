@@ -0,0 +1,31 @@
+-- ====================================
+-- GOVERSHOP - LOGIN PROTECTION
+-- ====================================
+-- Per-account failed login counter with progressive lockout, and a history
+-- of every login attempt (admin and member) for new-device alerts and review.
+
+CREATE TABLE IF NOT EXISTS login_lockouts (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    failed_attempts INT NOT NULL DEFAULT 0,    -- Failures since the last lockout or success
+    lockout_count INT NOT NULL DEFAULT 0,      -- Lockouts since the last success (doubles the duration)
+    locked_until TIMESTAMP,
+    last_failed_at TIMESTAMP,
+    last_failed_ip VARCHAR(45),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS login_events (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT REFERENCES users(id) ON DELETE SET NULL, -- NULL for unknown usernames
+    username VARCHAR(100) NOT NULL,
+    channel VARCHAR(20) NOT NULL,                        -- admin, member
+    outcome VARCHAR(30) NOT NULL,                        -- success, invalid_password, invalid_totp, unknown_user, inactive, locked
+    ip_address VARCHAR(45),
+    user_agent TEXT,
+    device VARCHAR(100),
+    new_device BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_events_user ON login_events(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_login_events_created ON login_events(created_at);
@@ -0,0 +1,10 @@
+-- ====================================
+-- GOVERSHOP - ENCRYPTED SECRETS
+-- ====================================
+-- Credentials are stored envelope-encrypted (enc:v1:<key id>:...), which no
+-- longer fits the original column sizes. Existing plain text values are
+-- encrypted with `./main secrets encrypt`.
+
+ALTER TABLE member_security ALTER COLUMN totp_secret TYPE TEXT;
+ALTER TABLE member_api_keys ALTER COLUMN api_secret TYPE TEXT;
+ALTER TABLE member_webhooks ALTER COLUMN secret TYPE TEXT;
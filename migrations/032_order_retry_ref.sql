@@ -0,0 +1,10 @@
+-- ====================================
+-- GOVERSHOP - MANUAL TOPUP RETRY REF
+-- ====================================
+-- An admin retry of a failed order is sent to Digiflazz under a new ref_id.
+-- The order keeps its own ref_id (payments and ledger entries point at it) and
+-- stores the retry ref so the Digiflazz webhook can still find the order.
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS retry_ref_id VARCHAR(100);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_orders_retry_ref_id ON orders(retry_ref_id) WHERE retry_ref_id IS NOT NULL;
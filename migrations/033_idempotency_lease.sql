@@ -0,0 +1,11 @@
+-- ====================================
+-- GOVERSHOP - IDEMPOTENCY PROCESSING LEASE
+-- ====================================
+-- A key stays 'processing' only until locked_until. If the server dies before
+-- the response is stored, a retry with the same key takes the key over after
+-- the lease instead of getting "in progress" until the key expires.
+
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP;
+
+-- Keys left processing by earlier versions can be taken over right away
+UPDATE idempotency_keys SET locked_until = NOW() WHERE status = 'processing' AND locked_until IS NULL;